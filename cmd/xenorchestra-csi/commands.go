@@ -0,0 +1,151 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+// command is an administrative subcommand of the driver binary.
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"doctor": {
+		summary: doctorSummary,
+		run:     runDoctor,
+	},
+	"export": {
+		summary: exportSummary,
+		run:     runExport,
+	},
+	"failover": {
+		summary: failoverSummary,
+		run:     runFailover,
+	},
+	"gc": {
+		summary: gcSummary,
+		run:     runGC,
+	},
+	"import": {
+		summary: importSummary,
+		run:     runImport,
+	},
+	"inventory": {
+		summary: inventorySummary,
+		run:     runInventory,
+	},
+	"migrate": {
+		summary: migrateSummary,
+		run:     runMigrate,
+	},
+	"migrate-volume": {
+		summary: migrateVolumeSummary,
+		run:     runMigrateVolume,
+	},
+	"vbd-cleanup": {
+		summary: vbdCleanupSummary,
+		run:     runVBDCleanup,
+	},
+}
+
+func printCommands(out io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-12s %s\n", name, commands[name].summary)
+	}
+}
+
+// commonOptions holds the flags shared by the administrative subcommands. They
+// mirror the driver flags so a command sees the same volumes as the driver.
+type commonOptions struct {
+	configFile string
+	kubeconfig string
+	driverName string
+	clusterTag string
+	xoInstance string
+}
+
+// newCommandFlagSet returns a flag set for the named subcommand with the
+// common and klog flags registered.
+func newCommandFlagSet(name, summary string, opts *commonOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	klog.InitFlags(fs)
+	fs.StringVar(&opts.configFile, "config-file", "/etc/xenorchestra/config.yaml",
+		"Path to XO configuration file. Falls back to the XOA_* environment variables.")
+	fs.StringVar(&opts.kubeconfig, "kubeconfig", defaultKubeconfig(),
+		"Path to a kubeconfig file. Empty uses the in-cluster configuration.")
+	fs.StringVar(&opts.driverName, "driver-name", xenorchestracsi.DriverName, "Driver name")
+	fs.StringVar(&opts.clusterTag, "cluster-tag", xenorchestracsi.DefaultClusterTag,
+		"Tag identifying the VDIs owned by the cluster (same value as the driver --cluster-tag).")
+	fs.StringVar(&opts.xoInstance, "xo-instance", "",
+		"Name of the Xen Orchestra instance to use when the configuration file lists several.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n\n%s.\n\nFlags:\n", filepath.Base(os.Args[0]), name, summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// defaultKubeconfig returns $KUBECONFIG or ~/.kube/config when it exists, so
+// commands work from an admin workstation as well as from inside a pod.
+func defaultKubeconfig() string {
+	if env := os.Getenv(clientcmd.RecommendedConfigPathEnvVar); env != "" {
+		return filepath.SplitList(env)[0]
+	}
+	if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
+		return clientcmd.RecommendedHomeFile
+	}
+	return ""
+}
+
+// commandContext returns a context cancelled on SIGINT or SIGTERM.
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// writeManifests writes YAML manifests to the file output, or to the
+// standard output when output is "-".
+func writeManifests(output, manifests string) error {
+	if output == "-" {
+		_, err := io.WriteString(os.Stdout, manifests)
+		return err
+	}
+	if err := os.WriteFile(output, []byte(manifests), 0o644); err != nil {
+		return fmt.Errorf("failed to write the manifests: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/doctor"
+
+	"k8s.io/klog/v2"
+)
+
+const doctorSummary = "Check that Xen Orchestra and Kubernetes are set up for the driver"
+
+// runDoctor runs the installation checks and fails when one of them does.
+func runDoctor(args []string) error {
+	var (
+		opts         commonOptions
+		poolTag      string
+		metadataFrom string
+		output       string
+	)
+	fs := newCommandFlagSet("doctor", doctorSummary, &opts)
+	fs.StringVar(&poolTag, "kubernetes-pool-tag", xenorchestracsi.DefaultKubernetesPoolTag,
+		"Tag of the pools eligible for automatic volume placement (same value as the driver --kubernetes-pool-tag). Empty skips the pool checks.")
+	fs.StringVar(&metadataFrom, "node-metadata-source", string(xenorchestracsi.NodeMetadataSourceKubernetes),
+		"Node metadata source of the node plugin (same value as the driver --node-metadata-source). With xo-api, nodes need no providerID.")
+	fs.StringVar(&output, "output", "table", "Output format: table or json.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if output != "table" && output != "json" {
+		return fmt.Errorf("unknown output format %q, expected table or json", output)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	instances, err := xenorchestracsi.NewXoInstanceClientsFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+	if err != nil {
+		if opts.kubeconfig != "" {
+			return err
+		}
+		klog.Warningf("No kubeconfig available, the nodes are not checked: %v", err)
+	}
+
+	report, err := doctor.Run(ctx, instances, kubeClient, doctor.Options{
+		DriverName:            opts.driverName,
+		KubernetesPoolTag:     poolTag,
+		NodeMetadataFromXoAPI: metadataFrom == string(xenorchestracsi.NodeMetadataSourceXoAPI),
+	})
+	if err != nil {
+		return err
+	}
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else if err := writeDoctorReport(os.Stdout, report); err != nil {
+		return err
+	}
+	if failed := report.Count(doctor.StatusFail); failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+func writeDoctorReport(out io.Writer, report *doctor.Report) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tINSTANCE\tCHECK\tSUBJECT\tRESULT")
+	for _, check := range report.Checks {
+		instance := check.Instance
+		if instance == "" {
+			instance = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", strings.ToUpper(string(check.Status)), instance, check.Name, check.Subject, check.Message)
+		if check.Hint != "" && check.Status != doctor.StatusPass {
+			fmt.Fprintf(w, "\t\t\t\thint: %s\n", check.Hint)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d check(s): %d passed, %d warning(s), %d failed\n", len(report.Checks),
+		report.Count(doctor.StatusPass), report.Count(doctor.StatusWarn), report.Count(doctor.StatusFail))
+	return nil
+}
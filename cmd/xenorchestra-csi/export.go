@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/uuid"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/exporter"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+)
+
+const exportSummary = "Export the content of a volume, or of a snapshot of its VDI, to a VHD or raw file"
+
+// runExport streams the VDI of a volume to a file, or to the standard output
+// with --output -. The file only appears once the export completed.
+func runExport(args []string) error {
+	var (
+		opts       commonOptions
+		pvName     string
+		volumeID   string
+		snapshotID string
+		format     string
+		output     string
+	)
+	fs := newCommandFlagSet("export", exportSummary, &opts)
+	fs.StringVar(&pvName, "pv", "", "Name of the PersistentVolume to export.")
+	fs.StringVar(&volumeID, "volume-id", "", "Volume handle of the volume to export, instead of --pv.")
+	fs.StringVar(&snapshotID, "snapshot", "",
+		"UUID of a Xen Orchestra snapshot of the VDI of the volume to export instead of the VDI. Required when the volume is attached read-write.")
+	fs.StringVar(&format, "format", string(payloads.VDIFormatVHD), "Format of the exported file: vhd or raw.")
+	fs.StringVar(&output, "output", "", "Path of the exported file. - writes it to the standard output.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (pvName == "") == (volumeID == "") {
+		return errors.New("select the volume to export with --pv or --volume-id, and only one of them")
+	}
+	if output == "" {
+		return errors.New("--output is required")
+	}
+	exportOpts := exporter.Options{VolumeHandle: volumeID, Format: payloads.VDIFormat(format)}
+	if snapshotID != "" {
+		id, err := uuid.FromString(snapshotID)
+		if err != nil {
+			return fmt.Errorf("invalid snapshot UUID %q: %w", snapshotID, err)
+		}
+		exportOpts.Snapshot = id
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if pvName != "" {
+		kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+		if err != nil {
+			return err
+		}
+		if exportOpts.VolumeHandle, err = exporter.PVVolumeHandle(ctx, kubeClient, opts.driverName, pvName); err != nil {
+			return err
+		}
+	}
+	instances, err := xenorchestracsi.NewXoInstanceClientsFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+
+	var result *exporter.Result
+	if output == "-" {
+		result, err = exporter.Run(ctx, instances, exportOpts, os.Stdout)
+	} else {
+		result, err = exportToFile(output, func(w io.Writer) (*exporter.Result, error) {
+			return exporter.Run(ctx, instances, exportOpts, w)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	source := fmt.Sprintf("VDI %s", result.VDI.ID)
+	if result.Exported != result.VDI {
+		source = fmt.Sprintf("snapshot %s of VDI %s", result.Exported.ID, result.VDI.ID)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %s of volume %s: %d bytes in %s format\n", source, exportOpts.VolumeHandle, result.Bytes, result.Format)
+	return nil
+}
+
+// exportToFile runs export into a temporary file next to path, renamed to
+// path once export succeeded, so that a failed export leaves no partial file.
+func exportToFile(path string, export func(io.Writer) (*exporter.Result, error)) (*exporter.Result, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the exported file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	result, err := export(file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write the exported file: %w", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to write the exported file: %w", err)
+	}
+	return result, nil
+}
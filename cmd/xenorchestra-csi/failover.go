@@ -0,0 +1,308 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/importer"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/replication"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const failoverSummary = "Promote the replicas of lost volumes and point their PersistentVolumes to them"
+
+// failoverVolume is a replica selected for promotion, with the
+// PersistentVolume of its volume when it still exists.
+type failoverVolume struct {
+	instance string
+	client   clients.XoClient
+	replica  *replication.Replica
+	pv       *corev1.PersistentVolume
+}
+
+// runFailover promotes the latest replicas of the selected volumes. With
+// --apply, the existing PersistentVolumes are relocated to the replica pool;
+// the manifests of the others are written to --output.
+func runFailover(args []string) error {
+	var (
+		opts          commonOptions
+		pvNames       []string
+		failedPool    string
+		all           bool
+		vdiNamePrefix string
+		storageClass  string
+		reclaimPolicy string
+		volumeMode    string
+		output        string
+		apply         bool
+		dryRun        bool
+	)
+	fs := newCommandFlagSet("failover", failoverSummary, &opts)
+	fs.Func("pv", "Name of a PersistentVolume to fail over. Repeat it or separate the names with commas.", func(value string) error {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				pvNames = append(pvNames, name)
+			}
+		}
+		return nil
+	})
+	fs.StringVar(&failedPool, "failed-pool", "", "UUID of the lost pool: fail over every PersistentVolume of that pool.")
+	fs.BoolVar(&all, "all", false, "Fail over every volume with a replica.")
+	fs.StringVar(&vdiNamePrefix, "vdi-name-prefix", xenorchestracsi.DefaultVDINamePrefix,
+		"Prefix of the name label of the promoted VDIs (same value as the driver --vdi-name-prefix).")
+	fs.StringVar(&storageClass, "storage-class", "", "StorageClass name of the generated PersistentVolumes.")
+	fs.StringVar(&reclaimPolicy, "reclaim-policy", string(corev1.PersistentVolumeReclaimRetain),
+		"Reclaim policy of the generated PersistentVolumes: Retain, or Delete to delete the VDI with its claim.")
+	fs.StringVar(&volumeMode, "volume-mode", string(corev1.PersistentVolumeFilesystem), "Volume mode of the generated PersistentVolumes: Filesystem or Block.")
+	fs.StringVar(&output, "output", "-", "Path of the YAML manifests. - writes them to the standard output.")
+	fs.BoolVar(&apply, "apply", false, "Delete and create again the existing PersistentVolumes so that they reference the replicas.")
+	fs.BoolVar(&dryRun, "dry-run", false, "Only print the replicas that would be promoted.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	selectors := 0
+	for _, set := range []bool{len(pvNames) > 0, failedPool != "", all} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return errors.New("exactly one of --pv, --failed-pool and --all is required")
+	}
+	var failedPoolID uuid.UUID
+	if failedPool != "" {
+		id, err := uuid.FromString(failedPool)
+		if err != nil {
+			return fmt.Errorf("invalid pool UUID %q: %w", failedPool, err)
+		}
+		failedPoolID = id
+	}
+	switch corev1.PersistentVolumeReclaimPolicy(reclaimPolicy) {
+	case corev1.PersistentVolumeReclaimRetain, corev1.PersistentVolumeReclaimDelete:
+	default:
+		return fmt.Errorf("unknown reclaim policy %q, expected Retain or Delete", reclaimPolicy)
+	}
+	switch corev1.PersistentVolumeMode(volumeMode) {
+	case corev1.PersistentVolumeFilesystem, corev1.PersistentVolumeBlock:
+	default:
+		return fmt.Errorf("unknown volume mode %q, expected Filesystem or Block", volumeMode)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+	if err != nil {
+		if opts.kubeconfig != "" || apply || failedPool != "" {
+			return err
+		}
+		klog.Warningf("No kubeconfig available, manifests are generated for every volume: %v", err)
+	}
+	pvs, err := listDriverPersistentVolumes(ctx, kubeClient, opts.driverName)
+	if err != nil {
+		return err
+	}
+	xoInstances, err := xenorchestracsi.NewXoInstanceClientsFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+
+	var volumes []failoverVolume
+	for _, instance := range xoInstances {
+		replicas, err := replication.LatestReplicas(ctx, instance.Client, opts.clusterTag)
+		if err != nil {
+			return err
+		}
+		for _, replica := range replicas {
+			v := failoverVolume{
+				instance: instance.Name,
+				client:   instance.Client,
+				replica:  replica,
+				pv:       pvs[clients.JoinVolumeHandle(instance.Name, replica.VolumeID)],
+			}
+			switch {
+			case all:
+			case failedPool != "":
+				if v.pv == nil || v.pv.Spec.CSI.VolumeAttributes[xenorchestracsi.VolumeContextKeyPoolID] != failedPoolID.String() {
+					continue
+				}
+			default:
+				name := replica.PVName
+				if v.pv != nil {
+					name = v.pv.Name
+				}
+				if !slices.Contains(pvNames, name) {
+					continue
+				}
+			}
+			volumes = append(volumes, v)
+		}
+	}
+	for _, name := range pvNames {
+		if !slices.ContainsFunc(volumes, func(v failoverVolume) bool { return v.replica.PVName == name || v.pv != nil && v.pv.Name == name }) {
+			klog.Warningf("No replica found for PersistentVolume %s", name)
+		}
+	}
+	if err := writeFailoverTable(os.Stderr, volumes); err != nil {
+		return err
+	}
+	if dryRun || len(volumes) == 0 {
+		return nil
+	}
+
+	manifestOpts := xenorchestracsi.ImportedVolumeOptions{
+		DriverName:       opts.driverName,
+		StorageClassName: storageClass,
+		ReclaimPolicy:    corev1.PersistentVolumeReclaimPolicy(reclaimPolicy),
+		VolumeMode:       corev1.PersistentVolumeMode(volumeMode),
+	}
+	promoteOpts := replication.PromoteOptions{
+		VDINamePrefix: vdiNamePrefix,
+		ManagedBy:     opts.driverName + "@" + xenorchestracsi.GetVersion(),
+	}
+	var manifests strings.Builder
+	failed := 0
+	for _, v := range volumes {
+		pv, err := failover(ctx, kubeClient, opts.driverName, v, promoteOpts, manifestOpts, apply)
+		if pv != nil {
+			data, marshalErr := yaml.Marshal(pv)
+			if marshalErr != nil {
+				return fmt.Errorf("failed to generate the manifest of PersistentVolume %s: %w", pv.Name, marshalErr)
+			}
+			manifests.WriteString("---\n")
+			manifests.Write(data)
+		}
+		if err != nil {
+			klog.ErrorS(err, "Failover failed", "pv", v.replica.PVName, "volumeID", v.replica.VolumeID, "vdiID", v.replica.VDI.ID)
+			failed++
+		}
+	}
+	if manifests.Len() > 0 {
+		if err := writeManifests(output, manifests.String()); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d volume(s) could not be failed over, rerun the command to resume", failed)
+	}
+	return nil
+}
+
+// failover promotes the replica of v and returns the PersistentVolume to
+// create. With apply, the existing PersistentVolume is relocated instead, and
+// only returned when it could not be created again.
+func failover(ctx context.Context, kubeClient kube.Interface, driverName string, v failoverVolume, promoteOpts replication.PromoteOptions, manifestOpts xenorchestracsi.ImportedVolumeOptions, apply bool) (*corev1.PersistentVolume, error) {
+	// Relocating the PersistentVolume deletes it: check that it can be done
+	// before touching the replica.
+	if apply && v.pv != nil {
+		if _, err := xenorchestracsi.DetachedPersistentVolume(ctx, kubeClient, driverName, v.pv.Name); err != nil {
+			return nil, err
+		}
+	}
+	sr, err := v.client.SR().Get(ctx, v.replica.VDI.SR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SR %s of replica %s: %w", v.replica.VDI.SR, v.replica.VDI.ID, err)
+	}
+	pool, err := v.client.Pool().Get(ctx, sr.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool %s of replica %s: %w", sr.Pool, v.replica.VDI.ID, err)
+	}
+	if err := replication.Promote(ctx, v.client, v.replica, promoteOpts); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Promoted replica %s of volume %s in SR %s of pool %s\n", v.replica.VDI.ID, v.replica.VolumeID, sr.NameLabel, pool.NameLabel)
+
+	if v.pv == nil {
+		pv, _ := xenorchestracsi.ImportedVolumeManifests(&importer.Volume{
+			Instance: v.instance,
+			VDI:      v.replica.VDI,
+			Pool:     pool,
+			SR:       sr,
+			VolumeID: v.replica.VolumeID,
+			PVName:   v.replica.PVName,
+		}, manifestOpts)
+		return pv, nil
+	}
+	if !apply {
+		return xenorchestracsi.RelocatedPersistentVolume(v.pv, pool, sr), nil
+	}
+	relocated, err := xenorchestracsi.RelocatePersistentVolume(ctx, kubeClient, v.pv, pool, sr)
+	if err != nil {
+		return relocated, err
+	}
+	fmt.Fprintf(os.Stderr, "PersistentVolume %s references pool %s\n", v.pv.Name, pool.NameLabel)
+	return nil, nil
+}
+
+// listDriverPersistentVolumes returns the PersistentVolumes of the driver by
+// volume handle, none without kubeClient.
+func listDriverPersistentVolumes(ctx context.Context, kubeClient kube.Interface, driverName string) (map[string]*corev1.PersistentVolume, error) {
+	pvs := map[string]*corev1.PersistentVolume{}
+	if kubeClient == nil {
+		return pvs, nil
+	}
+	list, err := kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumes: %w", err)
+	}
+	for i := range list.Items {
+		pv := &list.Items[i]
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == driverName {
+			pvs[pv.Spec.CSI.VolumeHandle] = pv
+		}
+	}
+	return pvs, nil
+}
+
+func writeFailoverTable(out io.Writer, volumes []failoverVolume) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tREPLICA\tVOLUME ID\tPV NAME\tREPLICATED AT\tPV EXISTS")
+	for _, v := range volumes {
+		instance := v.instance
+		if instance == "" {
+			instance = "-"
+		}
+		replicatedAt := "-"
+		if !v.replica.ReplicatedAt.IsZero() {
+			replicatedAt = v.replica.ReplicatedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", instance, v.replica.VDI.ID, v.replica.VolumeID, v.replica.PVName, replicatedAt, v.pv != nil)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d replica(s) selected\n", len(volumes))
+	return nil
+}
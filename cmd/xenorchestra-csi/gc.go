@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/orphan"
+)
+
+const gcSummary = "Report, and optionally delete, cluster VDIs no PersistentVolume references"
+
+// runGC runs a single pass of the orphaned VDI garbage collector and prints
+// its report. It is dry-run unless --delete is given.
+func runGC(args []string) error {
+	var (
+		opts        commonOptions
+		deleteVDIs  bool
+		gracePeriod time.Duration
+	)
+	fs := newCommandFlagSet("gc", gcSummary, &opts)
+	fs.BoolVar(&deleteVDIs, "delete", false,
+		"Mark orphans and delete those marked for longer than --grace-period. Without it, only report.")
+	fs.DurationVar(&gracePeriod, "grace-period", xenorchestracsi.DefaultOrphanGCGracePeriod,
+		"Minimum time a VDI must stay orphaned before it is deleted.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	xoClients, err := xenorchestracsi.NewXoClientsFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	collector := orphan.NewCollector(xoClients, kubeClient, nil, orphan.Options{
+		DriverName:  opts.driverName,
+		ClusterTag:  opts.clusterTag,
+		GracePeriod: gracePeriod,
+		Delete:      deleteVDIs,
+	})
+	report, err := collector.RunOnce(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VDI\tVOLUME ID\tPV NAME\tPOOL\tSIZE\tORPHAN SINCE\tACTION")
+	failed := 0
+	for _, o := range report.Orphans {
+		since := "-"
+		if !o.OrphanSince.IsZero() {
+			since = o.OrphanSince.UTC().Format(time.RFC3339)
+		}
+		action := string(o.Action)
+		if o.Err != nil {
+			failed++
+			action = fmt.Sprintf("%s: %v", o.Action, o.Err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", o.VDI.ID, o.VolumeID, o.PVName, o.VDI.PoolID, o.VDI.Size, since, action)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nScanned %d VDIs with tag %q, found %d orphan(s)", report.Scanned, opts.clusterTag, len(report.Orphans))
+	if !deleteVDIs {
+		fmt.Print(" (dry-run, rerun with --delete to mark and delete them)")
+	}
+	fmt.Println()
+
+	if failed > 0 {
+		return fmt.Errorf("%d orphan(s) could not be processed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gofrs/uuid"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/importer"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const importSummary = "Tag existing VDIs as volumes of the driver and generate their PersistentVolume manifests"
+
+// runImport tags the selected VDIs and writes the YAML manifests of their
+// PersistentVolumes, and claims with --claim-namespace. The table of the
+// imported VDIs goes to the standard error.
+func runImport(args []string) error {
+	var (
+		opts           commonOptions
+		selector       importer.Selector
+		srID           string
+		prefix         string
+		storageClass   string
+		reclaimPolicy  string
+		volumeMode     string
+		claimNamespace string
+		output         string
+		force          bool
+		dryRun         bool
+	)
+	fs := newCommandFlagSet("import", importSummary, &opts)
+	fs.Func("vdi", "UUID of a VDI to import. Repeat it or separate the UUIDs with commas.", func(value string) error {
+		for _, s := range strings.Split(value, ",") {
+			id, err := uuid.FromString(strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("invalid VDI UUID %q: %w", s, err)
+			}
+			selector.VDIIDs = append(selector.VDIIDs, id)
+		}
+		return nil
+	})
+	fs.StringVar(&srID, "sr", "", "UUID of an SR whose VDIs are all imported.")
+	fs.StringVar(&selector.Tag, "tag", "", "Tag of the VDIs to import.")
+	fs.StringVar(&prefix, "pv-name-prefix", "xo-", "Prefix of the PersistentVolume names, followed by the VDI UUID.")
+	fs.StringVar(&storageClass, "storage-class", "", "StorageClass name of the PersistentVolumes and claims.")
+	fs.StringVar(&reclaimPolicy, "reclaim-policy", string(corev1.PersistentVolumeReclaimRetain),
+		"Reclaim policy of the PersistentVolumes: Retain, or Delete to delete the VDI with its claim.")
+	fs.StringVar(&volumeMode, "volume-mode", string(corev1.PersistentVolumeFilesystem), "Volume mode of the PersistentVolumes: Filesystem or Block.")
+	fs.StringVar(&claimNamespace, "claim-namespace", "", "Also generate a PersistentVolumeClaim in this namespace, bound to each PersistentVolume.")
+	fs.StringVar(&output, "output", "-", "Path of the YAML manifests. - writes them to the standard output.")
+	fs.BoolVar(&force, "force", false, "Also import the VDIs linked to a VM by a VBD, such as the disks of VMs outside the cluster.")
+	fs.BoolVar(&dryRun, "dry-run", false, "Only print the VDIs that would be imported, without tagging them or generating manifests.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if srID != "" {
+		id, err := uuid.FromString(srID)
+		if err != nil {
+			return fmt.Errorf("invalid SR UUID %q: %w", srID, err)
+		}
+		selector.SRID = id
+	}
+	switch corev1.PersistentVolumeReclaimPolicy(reclaimPolicy) {
+	case corev1.PersistentVolumeReclaimRetain, corev1.PersistentVolumeReclaimDelete:
+	default:
+		return fmt.Errorf("unknown reclaim policy %q, expected Retain or Delete", reclaimPolicy)
+	}
+	switch corev1.PersistentVolumeMode(volumeMode) {
+	case corev1.PersistentVolumeFilesystem, corev1.PersistentVolumeBlock:
+	default:
+		return fmt.Errorf("unknown volume mode %q, expected Filesystem or Block", volumeMode)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	instances, err := xenorchestracsi.NewXoInstanceClientsFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+	if err != nil {
+		if opts.kubeconfig != "" {
+			return err
+		}
+		klog.Warningf("No kubeconfig available, the VDIs are not checked against the existing PersistentVolumes: %v", err)
+	}
+
+	volumes, err := importer.Run(ctx, instances, kubeClient, importer.Options{
+		Selector:     selector,
+		DriverName:   opts.driverName,
+		ClusterTag:   opts.clusterTag,
+		ManagedBy:    opts.driverName + "@" + xenorchestracsi.GetVersion(),
+		PVNamePrefix: prefix,
+		Force:        force,
+		DryRun:       dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeImportTable(os.Stderr, volumes); err != nil {
+		return err
+	}
+	if !dryRun {
+		manifestOpts := xenorchestracsi.ImportedVolumeOptions{
+			DriverName:       opts.driverName,
+			StorageClassName: storageClass,
+			ReclaimPolicy:    corev1.PersistentVolumeReclaimPolicy(reclaimPolicy),
+			VolumeMode:       corev1.PersistentVolumeMode(volumeMode),
+			ClaimNamespace:   claimNamespace,
+		}
+		if err := writeImportManifests(output, volumes, manifestOpts); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, v := range volumes {
+		if v.Status == importer.StatusFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d VDI(s) could not be tagged, rerun the command to resume", failed)
+	}
+	return nil
+}
+
+func writeImportTable(out io.Writer, volumes []importer.Volume) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tVDI\tNAME\tVOLUME ID\tPV NAME\tTAGS ADDED\tSTATUS")
+	for _, v := range volumes {
+		instance := v.Instance
+		if instance == "" {
+			instance = "-"
+		}
+		status := string(v.Status)
+		if v.Reason != "" {
+			status = fmt.Sprintf("%s: %s", v.Status, v.Reason)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", instance, v.VDI.ID, v.VDI.NameLabel, v.VolumeID, v.PVName, len(v.Tags), status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d VDI(s) selected\n", len(volumes))
+	return nil
+}
+
+// writeImportManifests writes the manifests of the imported volumes as a
+// multi-document YAML stream.
+func writeImportManifests(output string, volumes []importer.Volume, opts xenorchestracsi.ImportedVolumeOptions) error {
+	var b strings.Builder
+	for i := range volumes {
+		if volumes[i].Status != importer.StatusImported {
+			continue
+		}
+		pv, pvc := xenorchestracsi.ImportedVolumeManifests(&volumes[i], opts)
+		objects := []any{pv}
+		if pvc != nil {
+			objects = append(objects, pvc)
+		}
+		for _, object := range objects {
+			data, err := yaml.Marshal(object)
+			if err != nil {
+				return fmt.Errorf("failed to generate the manifest of VDI %s: %w", volumes[i].VDI.ID, err)
+			}
+			b.WriteString("---\n")
+			b.Write(data)
+		}
+	}
+	return writeManifests(output, b.String())
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/inventory"
+
+	"k8s.io/klog/v2"
+)
+
+const inventorySummary = "List the cluster VDIs with their volume ID, PersistentVolume, SR, pool, size and VMs"
+
+// runInventory lists the VDIs carrying the cluster tag, cross-referenced
+// against the PersistentVolumes when Kubernetes is reachable.
+func runInventory(args []string) error {
+	var (
+		opts        commonOptions
+		output      string
+		orphans     bool
+		ambiguous   bool
+		missingTags bool
+	)
+	fs := newCommandFlagSet("inventory", inventorySummary, &opts)
+	fs.StringVar(&output, "output", "table", "Output format: table, json or csv.")
+	fs.BoolVar(&orphans, "orphans", false, "Only list the VDIs no PersistentVolume references.")
+	fs.BoolVar(&ambiguous, "ambiguous", false, "Only list the VDIs whose volume ID is carried by several VDIs.")
+	fs.BoolVar(&missingTags, "missing-tags", false, "Only list the VDIs lacking the volume ID or PV name tag.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var write func(io.Writer, *inventory.Inventory) error
+	switch output {
+	case "table":
+		write = writeInventoryTable
+	case "json":
+		write = writeInventoryJSON
+	case "csv":
+		write = writeInventoryCSV
+	default:
+		return fmt.Errorf("unknown output format %q, expected table, json or csv", output)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	instances, err := xenorchestracsi.NewXoInstanceClientsFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+	if err != nil {
+		if opts.kubeconfig != "" {
+			return err
+		}
+		klog.Warningf("No kubeconfig available, the VDIs are not cross-referenced against the PersistentVolumes: %v", err)
+	}
+	if orphans && kubeClient == nil {
+		return errors.New("--orphans requires a kubeconfig")
+	}
+
+	inv, err := inventory.Collect(ctx, instances, kubeClient, inventory.Options{
+		DriverName: opts.driverName,
+		ClusterTag: opts.clusterTag,
+	})
+	if err != nil {
+		return err
+	}
+
+	var filter []inventory.Problem
+	if orphans {
+		filter = append(filter, inventory.ProblemOrphan)
+	}
+	if ambiguous {
+		filter = append(filter, inventory.ProblemAmbiguousID)
+	}
+	if missingTags {
+		filter = append(filter, inventory.ProblemMissingTags)
+	}
+	if len(filter) > 0 {
+		volumes := inv.Volumes[:0]
+		for _, v := range inv.Volumes {
+			if v.HasProblem(filter...) {
+				volumes = append(volumes, v)
+			}
+		}
+		inv.Volumes = volumes
+	}
+	return write(os.Stdout, inv)
+}
+
+// inventoryColumns are the columns of the table and CSV outputs.
+var inventoryColumns = []string{"INSTANCE", "VDI", "VOLUME ID", "PV NAME", "REPLICA OF", "SR", "POOL", "SIZE", "VMS", "PERSISTENT VOLUME", "CLAIM", "PROBLEMS"}
+
+func inventoryRow(v *inventory.Volume) []string {
+	vms := make([]string, 0, len(v.VMs))
+	for _, vm := range v.VMs {
+		name := vm.Name
+		if !vm.Plugged {
+			name += " (unplugged)"
+		}
+		vms = append(vms, name)
+	}
+	problems := make([]string, 0, len(v.Problems))
+	for _, p := range v.Problems {
+		problems = append(problems, string(p))
+	}
+	return []string{
+		v.Instance, v.VDIID.String(), v.VolumeID, v.PVName, v.ReplicaOf, v.SRName, v.PoolName,
+		strconv.FormatInt(v.Size, 10), strings.Join(vms, ","), v.PersistentVolume, v.Claim, strings.Join(problems, ","),
+	}
+}
+
+func writeInventoryTable(out io.Writer, inv *inventory.Inventory) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(inventoryColumns, "\t"))
+	for i := range inv.Volumes {
+		row := inventoryRow(&inv.Volumes[i])
+		for j, cell := range row {
+			if cell == "" {
+				row[j] = "-"
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d volume(s)", len(inv.Volumes))
+	if !inv.Kubernetes {
+		fmt.Fprint(out, " (PersistentVolumes not checked, pass --kubeconfig to find orphans)")
+	}
+	fmt.Fprintln(out)
+	return nil
+}
+
+func writeInventoryJSON(out io.Writer, inv *inventory.Inventory) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(inv)
+}
+
+func writeInventoryCSV(out io.Writer, inv *inventory.Inventory) error {
+	w := csv.NewWriter(out)
+	header := make([]string, len(inventoryColumns))
+	for i, column := range inventoryColumns {
+		header[i] = strings.ToLower(strings.ReplaceAll(column, " ", "_"))
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for i := range inv.Volumes {
+		if err := w.Write(inventoryRow(&inv.Volumes[i])); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -33,6 +33,7 @@ func init() {
 	driverOptions.AddFlags().VisitAll(func(f *flag.Flag) {
 		flag.CommandLine.Var(f.Value, f.Name, f.Usage)
 	})
+	flag.Usage = usage
 }
 
 var (
@@ -41,6 +42,18 @@ var (
 )
 
 func main() {
+	// Administrative subcommands are selected by the first argument. Without
+	// one, the binary runs the CSI driver as it always did.
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s %s: %v\n", path.Base(os.Args[0]), os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	flag.Parse()
 	if *showVersion {
 		baseName := path.Base(os.Args[0])
@@ -57,3 +70,13 @@ func main() {
 	}
 	os.Exit(0)
 }
+
+func usage() {
+	baseName := path.Base(os.Args[0])
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage:\n  %s [flags]            run the CSI driver\n", baseName)
+	fmt.Fprintf(out, "  %s <command> [flags]  run an administrative command\n\nCommands:\n", baseName)
+	printCommands(out)
+	fmt.Fprintf(out, "\nDriver flags:\n")
+	flag.PrintDefaults()
+}
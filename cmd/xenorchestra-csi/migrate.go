@@ -0,0 +1,133 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/migrate"
+
+	"k8s.io/klog/v2"
+)
+
+const migrateSummary = "Convert the other_config metadata of v0.2.0 and v0.3.0 volumes to v0.4.0 tags"
+
+// runMigrate migrates the VDIs of the volumes created before v0.4.0 and writes
+// a JSON report of every change. It is dry-run unless --apply is given.
+func runMigrate(args []string) error {
+	var (
+		opts       commonOptions
+		apply      bool
+		reportFile string
+		prefix     string
+	)
+	fs := newCommandFlagSet("migrate", migrateSummary, &opts)
+	fs.BoolVar(&apply, "apply", false, "Rename and tag the VDIs. Without it, only report the changes.")
+	fs.StringVar(&reportFile, "report", "migration-report.json", "Path of the JSON report of every change. - writes it to the standard output.")
+	fs.StringVar(&prefix, "vdi-name-prefix", xenorchestracsi.DefaultVDINamePrefix, "VDI name label prefix (same value as the driver --vdi-name-prefix).")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	instances, err := xenorchestracsi.NewXoInstanceClientsFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+	if err != nil {
+		if opts.kubeconfig != "" {
+			return err
+		}
+		klog.Warningf("No kubeconfig available, the VDIs are not checked against their PersistentVolume: %v", err)
+	}
+
+	report, err := migrate.Run(ctx, instances, kubeClient, migrate.Options{
+		DriverName:    opts.driverName,
+		VDINamePrefix: prefix,
+		Apply:         apply,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeMigrationReport(reportFile, report); err != nil {
+		return err
+	}
+	if reportFile == "-" {
+		return migrationError(report)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VDI\tVERSION\tVOLUME ID\tPV NAME\tCHANGES\tSTATUS")
+	for _, v := range report.VDIs {
+		status := string(v.Status)
+		if v.Reason != "" {
+			status = fmt.Sprintf("%s: %s", v.Status, v.Reason)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", v.VDIID, v.Version, v.VolumeID, v.PVName, len(v.Changes), status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nFound %d VDI(s) with legacy metadata: %d up to date, %d skipped", len(report.VDIs),
+		report.Count(migrate.StatusUpToDate), report.Count(migrate.StatusSkipped))
+	if apply {
+		fmt.Printf(", %d migrated, %d failed", report.Count(migrate.StatusMigrated), report.Count(migrate.StatusFailed))
+	} else {
+		fmt.Printf(", %d to migrate (dry-run, rerun with --apply to migrate them)", report.Count(migrate.StatusPlanned))
+	}
+	fmt.Printf("\nReport written to %s\n", reportFile)
+	return migrationError(report)
+}
+
+func writeMigrationReport(reportFile string, report *migrate.Report) error {
+	if reportFile == "-" {
+		return encodeMigrationReport(os.Stdout, report)
+	}
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the report: %w", err)
+	}
+	if err := encodeMigrationReport(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func encodeMigrationReport(out io.Writer, report *migrate.Report) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write the report: %w", err)
+	}
+	return nil
+}
+
+func migrationError(report *migrate.Report) error {
+	if failed := report.Count(migrate.StatusFailed); failed > 0 {
+		return fmt.Errorf("%d VDI(s) could not be migrated, rerun the command to resume", failed)
+	}
+	return nil
+}
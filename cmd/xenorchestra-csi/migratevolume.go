@@ -0,0 +1,106 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gofrs/uuid"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/volumemigration"
+
+	"sigs.k8s.io/yaml"
+)
+
+const migrateVolumeSummary = "Copy a detached volume to an SR of another pool, keeping its volume handle"
+
+// runMigrateVolume copies the VDI of the volume of a PersistentVolume to an SR
+// of another pool, points the PersistentVolume to that pool, and only then
+// deletes the source VDI.
+func runMigrateVolume(args []string) error {
+	var (
+		opts     commonOptions
+		pvName   string
+		targetSR string
+	)
+	fs := newCommandFlagSet("migrate-volume", migrateVolumeSummary, &opts)
+	fs.StringVar(&pvName, "pv", "", "Name of the PersistentVolume of the volume to migrate.")
+	fs.StringVar(&targetSR, "target-sr", "", "UUID of the SR to copy the volume to, in another pool.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if pvName == "" || targetSR == "" {
+		return errors.New("--pv and --target-sr are required")
+	}
+	targetSRID, err := uuid.FromString(targetSR)
+	if err != nil {
+		return fmt.Errorf("invalid SR UUID %q: %w", targetSR, err)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+	if err != nil {
+		return err
+	}
+	pv, err := xenorchestracsi.DetachedPersistentVolume(ctx, kubeClient, opts.driverName, pvName)
+	if err != nil {
+		return err
+	}
+	instances, err := xenorchestracsi.NewXoInstanceClientsFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+
+	migration, err := volumemigration.Copy(ctx, instances, volumemigration.Options{
+		VolumeHandle: pv.Spec.CSI.VolumeHandle,
+		TargetSR:     targetSRID,
+	})
+	if err != nil {
+		return err
+	}
+	if migration.Source != nil {
+		fmt.Printf("Copied VDI %s of volume %s to VDI %s in SR %s of pool %s\n",
+			migration.Source.ID, pv.Spec.CSI.VolumeHandle, migration.Target.ID, migration.SR.NameLabel, migration.Pool.NameLabel)
+	}
+
+	relocated, err := xenorchestracsi.RelocatePersistentVolume(ctx, kubeClient, pv, migration.Pool, migration.SR)
+	if err != nil {
+		if relocated != nil {
+			if data, marshalErr := yaml.Marshal(relocated); marshalErr == nil {
+				fmt.Fprintf(os.Stderr, "Create the PersistentVolume again with:\n---\n%s", data)
+			}
+		}
+		if migration.Source != nil {
+			return fmt.Errorf("%w; the volume is in VDI %s, delete the source VDI %s once the PersistentVolume references pool %s", err, migration.Target.ID, migration.Source.ID, migration.Pool.ID)
+		}
+		return err
+	}
+	fmt.Printf("PersistentVolume %s references pool %s\n", pv.Name, migration.Pool.NameLabel)
+
+	if err := migration.DeleteSource(ctx); err != nil {
+		return fmt.Errorf("%w; the volume is migrated, delete the source VDI by hand", err)
+	}
+	if migration.Source != nil {
+		fmt.Printf("Deleted the source VDI %s\n", migration.Source.ID)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/orphan"
+)
+
+const vbdCleanupSummary = "Report, and optionally destroy, unplugged VBDs of cluster VDIs left on node VMs"
+
+// runVBDCleanup finds the stale unplugged VBDs earlier driver versions left on
+// the node VMs and prints them. It is dry-run unless --destroy is given.
+func runVBDCleanup(args []string) error {
+	var (
+		opts    commonOptions
+		destroy bool
+	)
+	fs := newCommandFlagSet("vbd-cleanup", vbdCleanupSummary, &opts)
+	fs.BoolVar(&destroy, "destroy", false,
+		"Destroy the stale VBDs. Without it, only report. Run it while no volume is being attached.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	xoClient, err := xenorchestracsi.NewXoClientFromConfig(opts.configFile, opts.xoInstance)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := xenorchestracsi.NewKubeClient(opts.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	report, err := orphan.CleanupStaleVBDs(ctx, xoClient, kubeClient, opts.clusterTag, destroy)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VBD\tNODE\tVDI\tVOLUME ID\tPV NAME\tACTION")
+	failed := 0
+	for _, s := range report.StaleVBDs {
+		action := string(s.Action)
+		if s.Err != nil {
+			failed++
+			action = fmt.Sprintf("%s: %v", s.Action, s.Err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.VBD.ID, s.NodeName, s.VDIID, s.VolumeID, s.PVName, action)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nInspected %d node VM(s), found %d stale VBD(s)", report.Nodes, len(report.StaleVBDs))
+	if !destroy {
+		fmt.Print(" (dry-run, rerun with --destroy to destroy them)")
+	}
+	fmt.Println()
+	if len(report.SkippedNodes) > 0 {
+		fmt.Printf("Skipped nodes without a Xen Orchestra providerID or whose VM is not running: %v\n", report.SkippedNodes)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d stale VBD(s) could not be destroyed", failed)
+	}
+	return nil
+}
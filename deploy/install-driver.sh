@@ -43,6 +43,7 @@ echo "Installing Xen Orchestra CSI driver, version: $ver ..."
 kubectl apply -f $repo/csi-xenorchestra-driver.yaml
 kubectl apply -f $repo/rbac-csi-xenorchestra-node.yaml
 kubectl apply -f $repo/csi-xenorchestra-node.yaml
+kubectl apply -f $repo/crd-csi-xenorchestra-volumeimage.yaml
 kubectl apply -f $repo/rbac-csi-xenorchestra-controller.yaml
 kubectl apply -f $repo/csi-xenorchestra-controller.yaml
 
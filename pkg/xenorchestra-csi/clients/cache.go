@@ -0,0 +1,242 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package clients
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library"
+
+	"k8s.io/klog/v2"
+)
+
+// DefaultCacheTTL is how long slowly changing XO objects are served from the cache.
+const DefaultCacheTTL = 30 * time.Second
+
+// Cached object kinds. They match the "type" field of XO objects so that
+// event notifications can be mapped to cache entries.
+const (
+	cacheKindPool = "pool"
+	cacheKindSR   = "SR"
+	cacheKindPBD  = "PBD"
+	cacheKindHost = "host"
+	cacheKindVM   = "VM"
+)
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context whose XO reads bypass the cache and refill
+// it. Use it on error paths, where a stale entry may be the cause of the error.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(noCacheKey{}).(bool)
+	return bypass
+}
+
+type cacheEntry struct {
+	kind    string
+	list    bool
+	ids     []uuid.UUID
+	value   any
+	expires time.Time
+}
+
+// ObjectCache holds XO objects that change slowly (pools, SRs, PBDs, hosts and
+// VM placement) for a TTL. Entries are also dropped as soon as the event feed
+// reports a change of the object, or of any object of the kind for lists.
+type ObjectCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mux     sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func NewObjectCache(ttl time.Duration) *ObjectCache {
+	return &ObjectCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// ObjectChanged drops the entries holding the object and every list of its
+// kind, or every entry when id is uuid.Nil. It is registered as an
+// EventSource listener.
+func (c *ObjectCache) ObjectChanged(id uuid.UUID, kind string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if id == uuid.Nil {
+		clear(c.entries)
+		return
+	}
+	for key, entry := range c.entries {
+		if slices.Contains(entry.ids, id) || (entry.list && entry.kind == kind) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Invalidate drops the entries holding or filtering on any of ids, or every
+// entry when ids is empty.
+func (c *ObjectCache) Invalidate(ids ...uuid.UUID) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if len(ids) == 0 {
+		clear(c.entries)
+		return
+	}
+	for key, entry := range c.entries {
+		for _, id := range ids {
+			if slices.Contains(entry.ids, id) || strings.Contains(key, id.String()) {
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// cachedGet returns the object cached under key, or fetches and caches it.
+func cachedGet[T any](ctx context.Context, c *ObjectCache, kind string, id uuid.UUID, fetch func() (*T, error)) (*T, error) {
+	key := fmt.Sprintf("%s/%s", kind, id)
+	if value, ok := c.lookup(ctx, key); ok {
+		return value.(*T), nil
+	}
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, cacheEntry{kind: kind, ids: []uuid.UUID{id}, value: value})
+	return value, nil
+}
+
+// cachedList returns the list cached for the query, or fetches and caches it.
+func cachedList[T any](ctx context.Context, c *ObjectCache, kind string, limit int, filter string, idOf func(*T) uuid.UUID, fetch func() ([]*T, error)) ([]*T, error) {
+	key := fmt.Sprintf("%s/list/%d/%s", kind, limit, filter)
+	if value, ok := c.lookup(ctx, key); ok {
+		return value.([]*T), nil
+	}
+	values, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, value := range values {
+		ids = append(ids, idOf(value))
+	}
+	c.store(key, cacheEntry{kind: kind, list: true, ids: ids, value: values})
+	return values, nil
+}
+
+func (c *ObjectCache) lookup(ctx context.Context, key string) (any, bool) {
+	if cacheBypassed(ctx) {
+		return nil, false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || c.now().After(entry.expires) {
+		return nil, false
+	}
+	klog.V(6).InfoS("XO cache hit", "key", key)
+	return entry.value, true
+}
+
+func (c *ObjectCache) store(key string, entry cacheEntry) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	entry.expires = c.now().Add(c.ttl)
+	c.entries[key] = entry
+}
+
+// cachedLibrary serves the pool, SR, PBD and host reads of the library from
+// the cache. Every other service, and every write, goes to XO directly.
+type cachedLibrary struct {
+	library.Library
+	cache *ObjectCache
+}
+
+func (l cachedLibrary) Pool() library.Pool { return cachedPool{Pool: l.Library.Pool(), cache: l.cache} }
+func (l cachedLibrary) SR() library.SR     { return cachedSR{SR: l.Library.SR(), cache: l.cache} }
+func (l cachedLibrary) PBD() library.PBD   { return cachedPBD{PBD: l.Library.PBD(), cache: l.cache} }
+func (l cachedLibrary) Host() library.Host { return cachedHost{Host: l.Library.Host(), cache: l.cache} }
+
+type cachedPool struct {
+	library.Pool
+	cache *ObjectCache
+}
+
+func (p cachedPool) Get(ctx context.Context, id uuid.UUID) (*payloads.Pool, error) {
+	return cachedGet(ctx, p.cache, cacheKindPool, id, func() (*payloads.Pool, error) { return p.Pool.Get(ctx, id) })
+}
+
+func (p cachedPool) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.Pool, error) {
+	return cachedList(ctx, p.cache, cacheKindPool, limit, filter, func(pool *payloads.Pool) uuid.UUID { return pool.ID },
+		func() ([]*payloads.Pool, error) { return p.Pool.GetAll(ctx, limit, filter) })
+}
+
+type cachedSR struct {
+	library.SR
+	cache *ObjectCache
+}
+
+func (s cachedSR) Get(ctx context.Context, id uuid.UUID) (*payloads.StorageRepository, error) {
+	return cachedGet(ctx, s.cache, cacheKindSR, id, func() (*payloads.StorageRepository, error) { return s.SR.Get(ctx, id) })
+}
+
+func (s cachedSR) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.StorageRepository, error) {
+	return cachedList(ctx, s.cache, cacheKindSR, limit, filter, func(sr *payloads.StorageRepository) uuid.UUID { return sr.ID },
+		func() ([]*payloads.StorageRepository, error) { return s.SR.GetAll(ctx, limit, filter) })
+}
+
+type cachedPBD struct {
+	library.PBD
+	cache *ObjectCache
+}
+
+func (p cachedPBD) Get(ctx context.Context, id uuid.UUID) (*payloads.PBD, error) {
+	return cachedGet(ctx, p.cache, cacheKindPBD, id, func() (*payloads.PBD, error) { return p.PBD.Get(ctx, id) })
+}
+
+func (p cachedPBD) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.PBD, error) {
+	return cachedList(ctx, p.cache, cacheKindPBD, limit, filter, func(pbd *payloads.PBD) uuid.UUID { return pbd.ID },
+		func() ([]*payloads.PBD, error) { return p.PBD.GetAll(ctx, limit, filter) })
+}
+
+type cachedHost struct {
+	library.Host
+	cache *ObjectCache
+}
+
+func (h cachedHost) Get(ctx context.Context, id uuid.UUID) (*payloads.Host, error) {
+	return cachedGet(ctx, h.cache, cacheKindHost, id, func() (*payloads.Host, error) { return h.Host.Get(ctx, id) })
+}
+
+func (h cachedHost) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.Host, error) {
+	return cachedList(ctx, h.cache, cacheKindHost, limit, filter, func(host *payloads.Host) uuid.UUID { return host.ID },
+		func() ([]*payloads.Host, error) { return h.Host.GetAll(ctx, limit, filter) })
+}
@@ -0,0 +1,214 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package clients
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+)
+
+type cachedClientMocks struct {
+	pool *xoLibMock.MockPool
+	sr   *xoLibMock.MockSR
+	pbd  *xoLibMock.MockPBD
+	vbd  *xoLibMock.MockVBD
+	vdi  *xoLibMock.MockVDI
+	vm   *xoLibMock.MockVM
+}
+
+// newCachedClient returns a client with a cache whose clock is controlled by
+// the returned function.
+func newCachedClient(t *testing.T, events EventSource) (xoClient, cachedClientMocks, func(time.Duration)) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mocks := cachedClientMocks{
+		pool: xoLibMock.NewMockPool(ctrl),
+		sr:   xoLibMock.NewMockSR(ctrl),
+		pbd:  xoLibMock.NewMockPBD(ctrl),
+		vbd:  xoLibMock.NewMockVBD(ctrl),
+		vdi:  xoLibMock.NewMockVDI(ctrl),
+		vm:   xoLibMock.NewMockVM(ctrl),
+	}
+	lib := stubLibrary{pool: mocks.pool, sr: mocks.sr, pbd: mocks.pbd, vbd: mocks.vbd, vdi: mocks.vdi, vm: mocks.vm}
+	c := NewXoClientWithOptions(lib, XoClientOptions{CacheTTL: time.Minute, Events: events}).(xoClient)
+
+	now := time.Now()
+	c.cache.now = func() time.Time { return now }
+	return c, mocks, func(d time.Duration) { now = now.Add(d) }
+}
+
+// recordingEventSource keeps the listeners registered with OnChange.
+type recordingEventSource struct {
+	fakeEventSource
+	listeners []func(uuid.UUID, string)
+}
+
+func (r *recordingEventSource) OnChange(fn func(uuid.UUID, string)) {
+	r.listeners = append(r.listeners, fn)
+}
+
+func (r *recordingEventSource) emit(id uuid.UUID, objectType string) {
+	for _, fn := range r.listeners {
+		fn(id, objectType)
+	}
+}
+
+func TestObjectCache(t *testing.T) {
+	pool := &payloads.Pool{ID: poolUUID, DefaultSR: localSRID}
+
+	t.Run("ServesFromCacheUntilTTL", func(t *testing.T) {
+		c, mocks, advance := newCachedClient(t, nil)
+		mocks.pool.EXPECT().Get(gomock.Any(), poolUUID).Return(pool, nil).Times(2)
+
+		for range 3 {
+			got, err := c.Pool().Get(context.Background(), poolUUID)
+			require.NoError(t, err)
+			assert.Equal(t, pool, got)
+		}
+		advance(2 * time.Minute)
+		_, err := c.Pool().Get(context.Background(), poolUUID)
+		require.NoError(t, err)
+	})
+
+	t.Run("ErrorsAreNotCached", func(t *testing.T) {
+		c, mocks, _ := newCachedClient(t, nil)
+		gomock.InOrder(
+			mocks.pool.EXPECT().Get(gomock.Any(), poolUUID).Return(nil, fmt.Errorf("unavailable")),
+			mocks.pool.EXPECT().Get(gomock.Any(), poolUUID).Return(pool, nil),
+		)
+
+		_, err := c.Pool().Get(context.Background(), poolUUID)
+		require.Error(t, err)
+		_, err = c.Pool().Get(context.Background(), poolUUID)
+		require.NoError(t, err)
+	})
+
+	t.Run("WithoutCacheRefreshes", func(t *testing.T) {
+		c, mocks, _ := newCachedClient(t, nil)
+		mocks.sr.EXPECT().Get(gomock.Any(), localSRID).Return(&payloads.StorageRepository{ID: localSRID}, nil).Times(2)
+
+		_, err := c.SR().Get(context.Background(), localSRID)
+		require.NoError(t, err)
+		_, err = c.SR().Get(WithoutCache(context.Background()), localSRID)
+		require.NoError(t, err)
+		// The fresh read refilled the cache.
+		_, err = c.SR().Get(context.Background(), localSRID)
+		require.NoError(t, err)
+	})
+
+	t.Run("EventInvalidatesObjectAndListsOfItsKind", func(t *testing.T) {
+		events := &recordingEventSource{}
+		c, mocks, _ := newCachedClient(t, events)
+		filter := "tags:/^k8s-pool$/"
+		mocks.pool.EXPECT().Get(gomock.Any(), poolUUID).Return(pool, nil).Times(2)
+		mocks.pool.EXPECT().GetAll(gomock.Any(), 0, filter).Return([]*payloads.Pool{pool}, nil).Times(2)
+		mocks.sr.EXPECT().Get(gomock.Any(), localSRID).Return(&payloads.StorageRepository{ID: localSRID}, nil).Times(1)
+
+		read := func() {
+			_, err := c.Pool().Get(context.Background(), poolUUID)
+			require.NoError(t, err)
+			_, err = c.Pool().GetAll(context.Background(), 0, filter)
+			require.NoError(t, err)
+			_, err = c.SR().Get(context.Background(), localSRID)
+			require.NoError(t, err)
+		}
+		read()
+		events.emit(poolUUID, "pool")
+		read()
+	})
+
+	t.Run("ReconnectionFlushesEverything", func(t *testing.T) {
+		events := &recordingEventSource{}
+		c, mocks, _ := newCachedClient(t, events)
+		mocks.sr.EXPECT().Get(gomock.Any(), localSRID).Return(&payloads.StorageRepository{ID: localSRID}, nil).Times(2)
+
+		_, err := c.SR().Get(context.Background(), localSRID)
+		require.NoError(t, err)
+		events.emit(uuid.Nil, "")
+		_, err = c.SR().Get(context.Background(), localSRID)
+		require.NoError(t, err)
+	})
+
+	t.Run("InvalidateCacheDropsFilteredLists", func(t *testing.T) {
+		c, mocks, _ := newCachedClient(t, nil)
+		filter := fmt.Sprintf("SR:%s host:%s attached?", localSRID, hostUUID)
+		mocks.pbd.EXPECT().GetAll(gomock.Any(), 1, filter).Return([]*payloads.PBD{}, nil).Times(2)
+
+		_, err := c.PBD().GetAll(context.Background(), 1, filter)
+		require.NoError(t, err)
+		c.InvalidateCache(localSRID)
+		_, err = c.PBD().GetAll(context.Background(), 1, filter)
+		require.NoError(t, err)
+	})
+
+	t.Run("DisabledCacheAlwaysReadsXO", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockPool := xoLibMock.NewMockPool(ctrl)
+		c := NewXoClient(stubLibrary{pool: mockPool})
+		mockPool.EXPECT().Get(gomock.Any(), poolUUID).Return(pool, nil).Times(2)
+
+		for range 2 {
+			_, err := c.Pool().Get(context.Background(), poolUUID)
+			require.NoError(t, err)
+		}
+		c.InvalidateCache()
+	})
+}
+
+func TestIsSRAttachedToHostWithCache(t *testing.T) {
+	filter := fmt.Sprintf("SR:%s host:%s attached?", localSRID, hostUUID)
+
+	t.Run("StaleNegativeAnswerIsRefreshed", func(t *testing.T) {
+		c, mocks, _ := newCachedClient(t, nil)
+		gomock.InOrder(
+			mocks.pbd.EXPECT().GetAll(gomock.Any(), 1, filter).Return([]*payloads.PBD{}, nil),
+			mocks.pbd.EXPECT().GetAll(gomock.Any(), 1, filter).Return([]*payloads.PBD{{ID: uuid.Must(uuid.NewV4())}}, nil),
+		)
+
+		require.NoError(t, c.IsSRAttachedToHost(context.Background(), localSRID, hostUUID))
+		// The positive answer is now cached.
+		require.NoError(t, c.IsSRAttachedToHost(context.Background(), localSRID, hostUUID))
+	})
+
+	t.Run("VMPlacementIsRefreshedWhenItMoved", func(t *testing.T) {
+		c, mocks, _ := newCachedClient(t, nil)
+		vbdID := uuid.Must(uuid.NewV4())
+		vmID := uuid.Must(uuid.NewV4())
+		otherHost := uuid.Must(uuid.NewV4())
+		otherFilter := fmt.Sprintf("SR:%s host:%s attached?", localSRID, otherHost)
+
+		mocks.vbd.EXPECT().Get(gomock.Any(), vbdID).Return(&payloads.VBD{ID: vbdID, VM: vmID, VDI: &vdiUUID}, nil)
+		mocks.vdi.EXPECT().Get(gomock.Any(), vdiUUID).Return(&payloads.VDI{ID: vdiUUID, SR: localSRID}, nil)
+		gomock.InOrder(
+			mocks.vm.EXPECT().GetByID(gomock.Any(), vmID).Return(&payloads.VM{ID: vmID, Container: otherHost}, nil),
+			mocks.vm.EXPECT().GetByID(gomock.Any(), vmID).Return(&payloads.VM{ID: vmID, Container: hostUUID}, nil),
+		)
+		mocks.pbd.EXPECT().GetAll(gomock.Any(), 1, otherFilter).Return([]*payloads.PBD{}, nil).Times(2)
+		mocks.pbd.EXPECT().GetAll(gomock.Any(), 1, filter).Return([]*payloads.PBD{{ID: uuid.Must(uuid.NewV4())}}, nil)
+
+		require.NoError(t, c.IsSRAttachedToVMHost(context.Background(), vbdID))
+	})
+}
@@ -32,3 +32,58 @@ const VDITagKeyPVName = "pvName"
 // the driver that created and manages this VDI.
 // Full tag format: "k8s:managedBy:<driver-name>@<version>"
 const VDITagKeyManagedBy = "managedBy"
+
+// VDITagKeyOrphanSince is the key segment used in the VDI tag that records
+// when the orphaned VDI garbage collector first found the VDI unreferenced by
+// any PersistentVolume. The grace period before deletion starts from this time.
+// Full tag format: "k8s:orphanSince:<RFC3339 timestamp>"
+const VDITagKeyOrphanSince = "orphanSince"
+
+// VDITagKeyMigratedTo is the key segment used in the VDI tag that marks the
+// source VDI of a volume copied to another pool with the VDI of the copy, so
+// that an interrupted migration finds the source to delete.
+// Full tag format: "k8s:migratedTo:<vdi-uuid>"
+const VDITagKeyMigratedTo = "migratedTo"
+
+// VDITagKeyReplicaPoolID is the key segment used in the VDI tag that stores
+// the pool the volume is replicated to, from the replicaPoolId StorageClass
+// parameter.
+// Full tag format: "k8s:replicaPoolId:<pool-uuid>"
+const VDITagKeyReplicaPoolID = "replicaPoolId"
+
+// VDITagKeyReplicaOf is the key segment used in the VDI tag that identifies a
+// complete replica of a volume. Replicas never carry the volume ID tag, so
+// that the driver does not take them for the volume.
+// Full tag format: "k8s:replicaOf:<uuid>"
+const VDITagKeyReplicaOf = "replicaOf"
+
+// VDITagKeyReplicaPVName is the key segment used in the VDI tag that stores
+// the PersistentVolume name of the volume a replica is a copy of.
+// Full tag format: "k8s:replicaPVName:<pv-name>"
+const VDITagKeyReplicaPVName = "replicaPVName"
+
+// VDITagKeyReplicatedAt is the key segment used in the VDI tag that records
+// when the copy of the volume into a replica started.
+// Full tag format: "k8s:replicatedAt:<RFC3339 timestamp>"
+const VDITagKeyReplicatedAt = "replicatedAt"
+
+// VDITagKeyReplicaPending is the key segment used in the VDI tag of a replica
+// being copied. A replica still carrying it after the copy is incomplete.
+// Full tag format: "k8s:replicaPending:<uuid>"
+const VDITagKeyReplicaPending = "replicaPending"
+
+// VDITagKeyStripeIndex is the key segment used in the VDI tag that stores the
+// position of the VDI among the members of a striped volume, from 0. All the
+// members carry the volume ID tag of the volume.
+// Full tag format: "k8s:stripeIndex:<index>"
+const VDITagKeyStripeIndex = "stripeIndex"
+
+// VDITagKeyStripeCount is the key segment used in the VDI tag that stores the
+// number of members of a striped volume.
+// Full tag format: "k8s:stripeCount:<count>"
+const VDITagKeyStripeCount = "stripeCount"
+
+// VolumeHandleInstanceSeparator separates the Xen Orchestra instance from the
+// volume ID in the volume handles of a driver managing several instances.
+// Full handle format: "<instance>/<uuid>"
+const VolumeHandleInstanceSeparator = "/"
@@ -29,6 +29,10 @@ var ErrVolumeNotFound = errors.New("volume not found")
 // ErrVolumeIdAmbiguous is returned when multiple VDIs match the same CSI volume ID.
 var ErrVolumeIdAmbiguous = errors.New("multiple VDIs match volume ID")
 
+// ErrVolumeStriped is returned by GetVDIByVolumeId when the volume is striped
+// over several VDIs, which the caller has to handle as a set.
+var ErrVolumeStriped = errors.New("volume is striped over several VDIs")
+
 // ErrVolumeNameAmbiguous is returned when multiple VDIs match the same Kubernetes PV name.
 var ErrVolumeNameAmbiguous = errors.New("multiple VDIs match volume name")
 
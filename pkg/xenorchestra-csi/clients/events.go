@@ -0,0 +1,416 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/sourcegraph/jsonrpc2"
+	jsonrpc2ws "github.com/sourcegraph/jsonrpc2/websocket"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// eventsMinBackoff and eventsMaxBackoff bound the delay between two
+	// connection attempts to the event feed.
+	eventsMinBackoff = time.Second
+	eventsMaxBackoff = 30 * time.Second
+	// eventsNotificationMethod is the JSON-RPC notification sent by XO for
+	// every object entering, changing in or leaving its object collection.
+	eventsNotificationMethod = "all"
+	// eventsTokenLifetime is the validity of the REST API token created when
+	// the feed signs in with a username and password. The token is renewed
+	// halfway through, or as soon as it is rejected.
+	eventsTokenLifetime = 24 * time.Hour
+	// eventsTokenDeleteTimeout bounds the deletion of the token once the feed
+	// stops.
+	eventsTokenDeleteTimeout = 10 * time.Second
+)
+
+// ErrEventsUnavailable is returned by XoEvents.WaitTask when the event feed
+// cannot serve the request and the caller should fall back to polling.
+var ErrEventsUnavailable = errors.New("xen orchestra event feed unavailable")
+
+// EventSource notifies changes of Xen Orchestra objects. It is used to wake
+// up waiters instead of polling the API at a fixed interval.
+type EventSource interface {
+	// Subscribe returns a channel that receives a value whenever the object
+	// id changes, and a function to unsubscribe. Notifications are coalesced:
+	// the channel holds at most one pending value.
+	Subscribe(id uuid.UUID) (<-chan struct{}, func())
+	// OnChange registers fn to be called with the ID and XO type of every
+	// object reported as changed. After a reconnection, changes may have been
+	// missed: fn is then called once with uuid.Nil. fn must not block.
+	OnChange(fn func(id uuid.UUID, objectType string))
+	// Connected reports whether the feed is currently receiving events. When
+	// it is not, waiters fall back to polling.
+	Connected() bool
+	// WaitTask blocks until the XO task ends and returns it. It returns
+	// ErrEventsUnavailable when the task cannot be waited for this way.
+	WaitTask(ctx context.Context, taskID string) (*payloads.Task, error)
+}
+
+// XoEvents is an EventSource backed by the Xen Orchestra JSON-RPC websocket,
+// on which XO pushes a notification for every object change, and by the
+// long-polling mode of the REST tasks endpoint.
+//
+// The websocket is opened lazily, on the first Subscribe, so that node plugins,
+// which never wait for XO objects, do not keep a connection open.
+type XoEvents struct {
+	config     xok8s.XoConfig
+	httpClient *http.Client
+	dialer     *websocket.Dialer
+
+	connected atomic.Bool
+	// wanted is closed on the first Subscribe to start the connection loop.
+	wanted     chan struct{}
+	wantedOnce sync.Once
+
+	// renew asks the connection loop for a new token after the REST API
+	// rejected the current one.
+	renew chan struct{}
+
+	mux sync.Mutex
+	// token authenticates the REST API calls: the configured token, or the
+	// one created by the connection loop, which deletes it when it stops.
+	token       string
+	subscribers map[uuid.UUID]map[chan struct{}]struct{}
+	listeners   []func(id uuid.UUID, objectType string)
+}
+
+func NewXoEvents(config xok8s.XoConfig) *XoEvents {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.Insecure, // #nosec G402 -- Allow self-signed certificates when configured by user
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &XoEvents{
+		config: config,
+		// No client timeout: long-polling requests are bounded by their context.
+		httpClient:  &http.Client{Transport: transport},
+		dialer:      &websocket.Dialer{TLSClientConfig: tlsConfig, HandshakeTimeout: 30 * time.Second},
+		wanted:      make(chan struct{}),
+		renew:       make(chan struct{}, 1),
+		token:       config.Token,
+		subscribers: make(map[uuid.UUID]map[chan struct{}]struct{}),
+	}
+}
+
+func (e *XoEvents) Connected() bool {
+	return e.connected.Load()
+}
+
+func (e *XoEvents) Subscribe(id uuid.UUID) (<-chan struct{}, func()) {
+	e.wantedOnce.Do(func() { close(e.wanted) })
+
+	ch := make(chan struct{}, 1)
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if e.subscribers[id] == nil {
+		e.subscribers[id] = make(map[chan struct{}]struct{})
+	}
+	e.subscribers[id][ch] = struct{}{}
+	return ch, func() {
+		e.mux.Lock()
+		defer e.mux.Unlock()
+		delete(e.subscribers[id], ch)
+		if len(e.subscribers[id]) == 0 {
+			delete(e.subscribers, id)
+		}
+	}
+}
+
+// OnChange does not open the websocket by itself: listeners only receive
+// events while something else keeps the feed connected.
+func (e *XoEvents) OnChange(fn func(id uuid.UUID, objectType string)) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.listeners = append(e.listeners, fn)
+}
+
+// notify wakes up every subscriber of id without blocking and calls the listeners.
+func (e *XoEvents) notify(id uuid.UUID, objectType string) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	for _, fn := range e.listeners {
+		fn(id, objectType)
+	}
+	for ch := range e.subscribers[id] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run maintains the websocket connection until ctx is done, reconnecting with
+// an exponential backoff. It only connects once something has subscribed.
+func (e *XoEvents) Run(ctx context.Context) {
+	select {
+	case <-e.wanted:
+	case <-ctx.Done():
+		return
+	}
+
+	backoff := eventsMinBackoff
+	for {
+		start := time.Now()
+		err := e.listen(ctx)
+		e.connected.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		// A connection that lived long enough was healthy: start over from the
+		// minimum backoff.
+		if time.Since(start) > eventsMaxBackoff {
+			backoff = eventsMinBackoff
+		}
+		klog.ErrorS(err, "Xen Orchestra event feed disconnected, falling back to polling", "retryIn", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, eventsMaxBackoff)
+	}
+}
+
+// listen opens the websocket, signs in and dispatches notifications until the
+// connection is lost or ctx is done. When signed in with a username and
+// password, it also keeps a token for the REST API, and deletes it once ctx is
+// done.
+func (e *XoEvents) listen(ctx context.Context) error {
+	wsURL, err := e.endpoint("ws", "api/")
+	if err != nil {
+		return err
+	}
+	ws, _, err := e.dialer.DialContext(ctx, wsURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", wsURL.Redacted(), err)
+	}
+	conn := jsonrpc2.NewConn(ctx, jsonrpc2ws.NewObjectStream(ws), jsonrpc2.HandlerWithError(e.handle))
+	defer conn.Close()
+
+	params := map[string]string{"token": e.config.Token}
+	if e.config.Token == "" {
+		params = map[string]string{"email": e.config.Username, "password": e.config.Password}
+	}
+	if err := conn.Call(ctx, "session.signIn", params, nil); err != nil {
+		return fmt.Errorf("failed to sign in to the event feed: %w", err)
+	}
+	var renewals <-chan time.Time
+	if e.config.Token == "" {
+		// The REST API only accepts tokens: create one, replacing the one of
+		// a previous connection.
+		if err := e.renewToken(ctx, conn); err != nil {
+			return err
+		}
+		defer func() {
+			if ctx.Err() != nil {
+				e.deleteToken(conn)
+			}
+		}()
+		ticker := time.NewTicker(eventsTokenLifetime / 2)
+		defer ticker.Stop()
+		renewals = ticker.C
+	}
+
+	e.connected.Store(true)
+	e.notify(uuid.Nil, "")
+	klog.V(2).InfoS("Connected to the Xen Orchestra event feed", "url", wsURL.Redacted())
+	for {
+		select {
+		case <-conn.DisconnectNotify():
+			return errors.New("connection closed")
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-renewals:
+		case <-e.renew:
+		}
+		if err := e.renewToken(ctx, conn); err != nil {
+			return err
+		}
+	}
+}
+
+// renewToken creates a REST API token for the signed in user, and deletes the
+// token it replaces.
+func (e *XoEvents) renewToken(ctx context.Context, conn *jsonrpc2.Conn) error {
+	var token string
+	params := map[string]any{"expiresIn": eventsTokenLifetime.Milliseconds()}
+	if err := conn.Call(ctx, "token.create", params, &token); err != nil {
+		return fmt.Errorf("failed to create an authentication token: %w", err)
+	}
+	e.mux.Lock()
+	previous := e.token
+	e.token = token
+	e.mux.Unlock()
+	if previous != "" {
+		if err := conn.Call(ctx, "token.delete", map[string]any{"token": previous}, nil); err != nil {
+			klog.V(2).InfoS("Failed to delete the previous authentication token of the event feed, it expires on its own", "err", err)
+		}
+	}
+	return nil
+}
+
+// deleteToken deletes the REST API token created by the feed, which WaitTask
+// stops using.
+func (e *XoEvents) deleteToken(conn *jsonrpc2.Conn) {
+	e.mux.Lock()
+	token := e.token
+	e.token = ""
+	e.mux.Unlock()
+	if token == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), eventsTokenDeleteTimeout)
+	defer cancel()
+	if err := conn.Call(ctx, "token.delete", map[string]any{"token": token}, nil); err != nil {
+		klog.V(2).InfoS("Failed to delete the authentication token of the event feed, it expires on its own", "err", err)
+	}
+}
+
+// objectsNotification is the payload of the "all" notification.
+type objectsNotification struct {
+	// Type is "enter" for new or updated objects and "exit" for removed ones.
+	Type  string                     `json:"type"`
+	Items map[string]json.RawMessage `json:"items"`
+}
+
+func (e *XoEvents) handle(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+	if req.Method != eventsNotificationMethod || req.Params == nil {
+		return nil, nil
+	}
+	var notification objectsNotification
+	if err := json.Unmarshal(*req.Params, &notification); err != nil {
+		klog.V(4).InfoS("Ignoring malformed Xen Orchestra notification", "err", err)
+		return nil, nil
+	}
+	for key, item := range notification.Items {
+		id, err := uuid.FromString(key)
+		if err != nil {
+			continue
+		}
+		var object struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal(item, &object)
+		e.notify(id, object.Type)
+	}
+	return nil, nil
+}
+
+// WaitTask long-polls the REST task until it ends.
+func (e *XoEvents) WaitTask(ctx context.Context, taskID string) (*payloads.Task, error) {
+	token := e.authToken()
+	if token == "" {
+		return nil, ErrEventsUnavailable
+	}
+	taskURL, err := e.endpoint("http", "rest/v0/tasks/"+strings.TrimPrefix(taskID, "/rest/v0/tasks/"))
+	if err != nil {
+		return nil, err
+	}
+	taskURL.RawQuery = url.Values{"wait": []string{"result"}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, taskURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.AddCookie(&http.Cookie{Name: "authenticationToken", Value: token})
+	// #nosec G704 -- The URL comes from the driver configuration.
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %w", ErrEventsUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized && e.config.Token == "" {
+		// The token created by the feed expired or was deleted.
+		e.rejectToken(token)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: GET %s returned %s", ErrEventsUnavailable, taskURL.Path, resp.Status)
+	}
+
+	var task payloads.Task
+	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode task %s: %w", ErrEventsUnavailable, taskID, err)
+	}
+	if task.Status != payloads.Success && task.Status != payloads.Failure {
+		// Older XO versions ignore the wait parameter and answer right away.
+		return nil, fmt.Errorf("%w: task %s returned while still %q", ErrEventsUnavailable, taskID, task.Status)
+	}
+	return &task, nil
+}
+
+func (e *XoEvents) authToken() string {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return e.token
+}
+
+// rejectToken stops using token and asks the connection loop for a new one.
+func (e *XoEvents) rejectToken(token string) {
+	e.mux.Lock()
+	if e.token == token {
+		e.token = ""
+	}
+	e.mux.Unlock()
+	select {
+	case e.renew <- struct{}{}:
+	default:
+	}
+}
+
+// endpoint builds the URL of path on the XO server, using the websocket or
+// HTTP flavour of the configured scheme.
+func (e *XoEvents) endpoint(scheme string, path string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(e.config.URL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Xen Orchestra URL: %w", err)
+	}
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	switch {
+	case scheme == "ws" && secure:
+		u.Scheme = "wss"
+	case scheme == "ws":
+		u.Scheme = "ws"
+	case secure:
+		u.Scheme = "https"
+	default:
+		u.Scheme = "http"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + path
+	return u, nil
+}
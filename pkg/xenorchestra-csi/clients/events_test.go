@@ -0,0 +1,240 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+)
+
+// fakeXOFeed is a minimal XO JSON-RPC websocket endpoint: it accepts
+// session.signIn and then sends one "all" notification per id written to
+// changes.
+func fakeXOFeed(t *testing.T, changes <-chan uuid.UUID) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/", r.URL.Path)
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		var signIn struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+		if !assert.NoError(t, ws.ReadJSON(&signIn)) {
+			return
+		}
+		assert.Equal(t, "session.signIn", signIn.Method)
+		assert.Equal(t, "secret", signIn.Params["token"])
+		assert.NoError(t, ws.WriteJSON(map[string]any{"jsonrpc": "2.0", "id": signIn.ID, "result": map[string]string{"id": "user"}}))
+
+		for id := range changes {
+			assert.NoError(t, ws.WriteJSON(map[string]any{
+				"jsonrpc": "2.0",
+				"method":  "all",
+				"params": map[string]any{
+					"type":  "enter",
+					"items": map[string]any{id.String(): map[string]any{"type": "VBD", "attached": true}},
+				},
+			}))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestXoEventsSubscribe(t *testing.T) {
+	watched := uuid.Must(uuid.NewV4())
+	other := uuid.Must(uuid.NewV4())
+
+	t.Run("ConnectsOnFirstSubscribeAndNotifies", func(t *testing.T) {
+		changes := make(chan uuid.UUID)
+		server := fakeXOFeed(t, changes)
+		events := NewXoEvents(xok8s.XoConfig{URL: server.URL, Token: "secret"})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go events.Run(ctx)
+
+		assert.Never(t, events.Connected, 50*time.Millisecond, 10*time.Millisecond)
+		changed, unsubscribe := events.Subscribe(watched)
+		defer unsubscribe()
+		require.Eventually(t, events.Connected, 5*time.Second, 10*time.Millisecond)
+
+		changes <- other
+		changes <- watched
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification received for the watched object")
+		}
+		assert.Empty(t, changed, "notifications of other objects must not be delivered")
+		close(changes)
+	})
+
+	t.Run("UnsubscribeStopsNotifications", func(t *testing.T) {
+		events := NewXoEvents(xok8s.XoConfig{})
+		changed, unsubscribe := events.Subscribe(watched)
+		unsubscribe()
+
+		events.notify(watched, "VBD")
+		assert.Empty(t, changed)
+		assert.Empty(t, events.subscribers)
+	})
+}
+
+// rpcCall is a JSON-RPC call received by fakeXOTokenFeed.
+type rpcCall struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params map[string]any  `json:"params"`
+}
+
+// fakeXOTokenFeed is a XO JSON-RPC websocket handler accepting a username
+// and password, and answering token.create with token-1, token-2... Every
+// call is written to calls.
+func fakeXOTokenFeed(calls chan<- rpcCall) http.HandlerFunc {
+	upgrader := websocket.Upgrader{}
+	tokens := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			var call rpcCall
+			if ws.ReadJSON(&call) != nil {
+				return
+			}
+			calls <- call
+			var result any = true
+			if call.Method == "token.create" {
+				tokens++
+				result = fmt.Sprintf("token-%d", tokens)
+			}
+			if ws.WriteJSON(map[string]any{"jsonrpc": "2.0", "id": call.ID, "result": result}) != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestXoEventsToken(t *testing.T) {
+	calls := make(chan rpcCall, 10)
+	expect := func(method string) rpcCall {
+		t.Helper()
+		select {
+		case call := <-calls:
+			require.Equal(t, method, call.Method)
+			return call
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s was not called", method)
+			return rpcCall{}
+		}
+	}
+	rejected := false
+	mux := http.NewServeMux()
+	mux.Handle("/api/", fakeXOTokenFeed(calls))
+	mux.HandleFunc("/rest/v0/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		rejected = true
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	events := NewXoEvents(xok8s.XoConfig{URL: api.URL, Username: "admin", Password: "pass"})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		events.Run(ctx)
+		close(done)
+	}()
+	_, unsubscribe := events.Subscribe(uuid.Must(uuid.NewV4()))
+	defer unsubscribe()
+
+	assert.Equal(t, "admin", expect("session.signIn").Params["email"])
+	create := expect("token.create")
+	assert.InDelta(t, float64(eventsTokenLifetime.Milliseconds()), create.Params["expiresIn"], 0, "the token must expire")
+	require.Eventually(t, func() bool { return events.authToken() == "token-1" }, 5*time.Second, 10*time.Millisecond)
+
+	// A rejected token is replaced.
+	_, err := events.WaitTask(context.Background(), "0abc")
+	assert.ErrorIs(t, err, ErrEventsUnavailable)
+	assert.True(t, rejected)
+	expect("token.create")
+	require.Eventually(t, func() bool { return events.authToken() == "token-2" }, 5*time.Second, 10*time.Millisecond)
+
+	// Stopping the feed deletes its token.
+	cancel()
+	assert.Equal(t, "token-2", expect("token.delete").Params["token"])
+	<-done
+	assert.Empty(t, events.authToken())
+}
+
+func TestXoEventsWaitTask(t *testing.T) {
+	t.Run("LongPollsUntilResult", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/rest/v0/tasks/0abc", r.URL.Path)
+			assert.Equal(t, "result", r.URL.Query().Get("wait"))
+			cookie, err := r.Cookie("authenticationToken")
+			if assert.NoError(t, err) {
+				assert.Equal(t, "secret", cookie.Value)
+			}
+			_ = json.NewEncoder(w).Encode(payloads.Task{ID: "0abc", Status: payloads.Success})
+		}))
+		defer server.Close()
+
+		events := NewXoEvents(xok8s.XoConfig{URL: server.URL, Token: "secret"})
+		task, err := events.WaitTask(context.Background(), "/rest/v0/tasks/0abc")
+		require.NoError(t, err)
+		assert.Equal(t, payloads.Success, task.Status)
+	})
+
+	t.Run("PendingAnswerIsUnavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(payloads.Task{ID: "0abc", Status: payloads.Pending})
+		}))
+		defer server.Close()
+
+		events := NewXoEvents(xok8s.XoConfig{URL: server.URL, Token: "secret"})
+		_, err := events.WaitTask(context.Background(), "0abc")
+		assert.ErrorIs(t, err, ErrEventsUnavailable)
+	})
+
+	t.Run("NoTokenIsUnavailable", func(t *testing.T) {
+		events := NewXoEvents(xok8s.XoConfig{URL: "https://xo.example.com", Username: "admin", Password: "pass"})
+		_, err := events.WaitTask(context.Background(), "0abc")
+		assert.ErrorIs(t, err, ErrEventsUnavailable)
+	})
+}
@@ -18,6 +18,8 @@ package clients
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
@@ -40,6 +42,46 @@ func BuildVDINameLabel(prefix, volumeId, volumeName string) string {
 	return fmt.Sprintf("%s%s-%s", prefix, volumeId, volumeName)
 }
 
+// BuildStripeMemberNameLabel constructs the VDI.name_label of the member index
+// of a striped volume: the name label of a volume followed by "-<index>".
+func BuildStripeMemberNameLabel(prefix, volumeId, volumeName string, index int) string {
+	return fmt.Sprintf("%s-%d", BuildVDINameLabel(prefix, volumeId, volumeName), index)
+}
+
+// IsStripeMember reports whether vdi is a member of a striped volume.
+func IsStripeMember(vdi *payloads.VDI) bool {
+	return ParseTagValue(vdi.Tags, VDITagKeyStripeCount) != ""
+}
+
+// StripeMembers returns vdis ordered by their stripe index. It fails unless
+// vdis are all the members of one striped volume.
+func StripeMembers(vdis []*payloads.VDI) ([]*payloads.VDI, error) {
+	if len(vdis) == 0 {
+		return nil, ErrVolumeNotFound
+	}
+	count, err := strconv.Atoi(ParseTagValue(vdis[0].Tags, VDITagKeyStripeCount))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("VDI %s has no valid %s tag", vdis[0].ID, VDITagKeyStripeCount)
+	}
+	members := make([]*payloads.VDI, count)
+	for _, vdi := range vdis {
+		index, err := strconv.Atoi(ParseTagValue(vdi.Tags, VDITagKeyStripeIndex))
+		switch {
+		case ParseTagValue(vdi.Tags, VDITagKeyStripeCount) != strconv.Itoa(count):
+			return nil, fmt.Errorf("VDI %s is not a member of a volume striped over %d VDIs", vdi.ID, count)
+		case err != nil || index < 0 || index >= count:
+			return nil, fmt.Errorf("VDI %s has no valid %s tag", vdi.ID, VDITagKeyStripeIndex)
+		case members[index] != nil:
+			return nil, fmt.Errorf("VDIs %s and %s are both member %d", members[index].ID, vdi.ID, index)
+		}
+		members[index] = vdi
+	}
+	if missing := slices.Index(members, nil); missing >= 0 {
+		return nil, fmt.Errorf("member %d of %d is missing", missing, count)
+	}
+	return members, nil
+}
+
 // BuildVDINameDescription constructs the VDI.name_description for a new volume.
 // It appends "; pv-name=<volumeName>" to the standard description so operators
 // can identify the backing Kubernetes PV in the Xen Orchestra UI.
@@ -48,6 +90,35 @@ func BuildVDINameDescription(volumeName string) string {
 	return "VDI managed by the Kubernetes CSI; " + vdiNameDescriptionPVNameMarker + volumeName
 }
 
+// ReadWriteVBD returns the first of vbds plugging its VDI read-write into a
+// VM, whose writes would tear a copy of the VDI, or nil when there is none.
+func ReadWriteVBD(vbds []*payloads.VBD) *payloads.VBD {
+	for _, vbd := range vbds {
+		if vbd.Attached && !vbd.ReadOnly {
+			return vbd
+		}
+	}
+	return nil
+}
+
+// JoinVolumeHandle returns the volume handle of volumeId in the Xen
+// Orchestra instance named instance, or volumeId itself when instance is empty.
+func JoinVolumeHandle(instance, volumeId string) string {
+	if instance == "" {
+		return volumeId
+	}
+	return instance + VolumeHandleInstanceSeparator + volumeId
+}
+
+// SplitVolumeHandle returns the Xen Orchestra instance and the volume ID of a
+// volume handle. The instance is empty for the handles that carry none.
+func SplitVolumeHandle(handle string) (instance, volumeId string) {
+	if instance, volumeId, found := strings.Cut(handle, VolumeHandleInstanceSeparator); found {
+		return instance, volumeId
+	}
+	return "", handle
+}
+
 // BuildTag encodes a key-value pair as a VDI tag string using the format
 // "k8s:<key>:<value>".
 func BuildTag(key, value string) string {
@@ -74,6 +145,13 @@ func BuildTagFilter(key, value string) string {
 	return fmt.Sprintf("tags:/^%s$/", regexp.QuoteMeta(BuildTag(key, value)))
 }
 
+// BuildClusterTagFilter builds an XO REST API filter string that matches
+// objects carrying exactly the given plain tag (e.g. the driver cluster tag).
+// The tag is regex-escaped for safety.
+func BuildClusterTagFilter(tag string) string {
+	return fmt.Sprintf("tags:/^%s$/", regexp.QuoteMeta(tag))
+}
+
 // recoverVolumeNameFromVDI tries to recover the Kubernetes PV name for a VDI.
 // It first reads the VDI name_description and falls back to parsing name_label.
 func recoverVolumeNameFromVDI(vdi *payloads.VDI, volumeId string) string {
@@ -205,6 +205,15 @@ func TestBuildTagFilter(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// BuildClusterTagFilter
+// ---------------------------------------------------------------------------
+
+func TestBuildClusterTagFilter(t *testing.T) {
+	assert.Equal(t, `tags:/^k8s-managed$/`, BuildClusterTagFilter("k8s-managed"))
+	assert.Equal(t, `tags:/^k8s\.prod\+1$/`, BuildClusterTagFilter("k8s.prod+1"))
+}
+
 // ---------------------------------------------------------------------------
 // Round-trip: BuildTag / ParseTagValue
 // ---------------------------------------------------------------------------
@@ -229,6 +238,90 @@ func TestBuildTagParseTagValueRoundTrip(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// JoinVolumeHandle / SplitVolumeHandle
+// ---------------------------------------------------------------------------
+
+func TestVolumeHandle(t *testing.T) {
+	const volumeId = "aaaaaaaa-0000-0000-0000-000000000001"
+
+	assert.Equal(t, volumeId, JoinVolumeHandle("", volumeId))
+	instance, id := SplitVolumeHandle(volumeId)
+	assert.Empty(t, instance)
+	assert.Equal(t, volumeId, id)
+
+	handle := JoinVolumeHandle("dc1", volumeId)
+	assert.Equal(t, "dc1/"+volumeId, handle)
+	instance, id = SplitVolumeHandle(handle)
+	assert.Equal(t, "dc1", instance)
+	assert.Equal(t, volumeId, id)
+}
+
+// ---------------------------------------------------------------------------
+// StripeMembers
+// ---------------------------------------------------------------------------
+
+func TestStripeMembers(t *testing.T) {
+	member := func(index, count string) *payloads.VDI {
+		return &payloads.VDI{NameLabel: index, Tags: []string{
+			BuildTag(VDITagKeyStripeIndex, index),
+			BuildTag(VDITagKeyStripeCount, count),
+		}}
+	}
+	tests := []struct {
+		name    string
+		vdis    []*payloads.VDI
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "members are ordered by index",
+			vdis: []*payloads.VDI{member("2", "3"), member("0", "3"), member("1", "3")},
+			want: []string{"0", "1", "2"},
+		},
+		{
+			name:    "missing member",
+			vdis:    []*payloads.VDI{member("0", "3"), member("2", "3")},
+			wantErr: "member 1 of 3 is missing",
+		},
+		{
+			name:    "duplicate member",
+			vdis:    []*payloads.VDI{member("0", "2"), member("0", "2")},
+			wantErr: "both member 0",
+		},
+		{
+			name:    "different counts",
+			vdis:    []*payloads.VDI{member("0", "2"), member("1", "3")},
+			wantErr: "not a member of a volume striped over 2 VDIs",
+		},
+		{
+			name:    "index out of range",
+			vdis:    []*payloads.VDI{member("0", "2"), member("2", "2")},
+			wantErr: "no valid stripeIndex tag",
+		},
+		{
+			name:    "not a striped volume",
+			vdis:    []*payloads.VDI{{}},
+			wantErr: "no valid stripeCount tag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := StripeMembers(tt.vdis)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			var got []string
+			for _, m := range members {
+				got = append(got, m.NameLabel)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 // ---------------------------------------------------------------------------
 // RecoverVolumeNameFromVDI
 // ---------------------------------------------------------------------------
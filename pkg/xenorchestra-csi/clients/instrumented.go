@@ -0,0 +1,182 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package clients
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/tracing"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library"
+)
+
+// observe records the latency and the failure of one XO API call, and traces
+// it as a child span of ctx.
+func observe[T any](ctx context.Context, service, operation string, call func(ctx context.Context) (T, error)) (value T, err error) {
+	ctx, span := tracing.Start(ctx, "xo."+service+"."+operation)
+	defer tracing.End(span, &err)
+
+	start := time.Now()
+	value, err = call(ctx)
+	metrics.XORequestDurationSeconds.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.XORequestErrors.WithLabelValues(service, operation).Inc()
+	}
+	return value, err
+}
+
+// observeErr is observe for calls returning only an error.
+func observeErr(ctx context.Context, service, operation string, call func(ctx context.Context) error) error {
+	_, err := observe(ctx, service, operation, func(ctx context.Context) (struct{}, error) { return struct{}{}, call(ctx) })
+	return err
+}
+
+// instrumentedLibrary records metrics and spans for the XO API calls made by the
+// driver. Calls the driver does not make go to XO without being observed.
+type instrumentedLibrary struct {
+	library.Library
+}
+
+func (l instrumentedLibrary) Pool() library.Pool { return instrumentedPool{l.Library.Pool()} }
+func (l instrumentedLibrary) SR() library.SR     { return instrumentedSR{l.Library.SR()} }
+func (l instrumentedLibrary) PBD() library.PBD   { return instrumentedPBD{l.Library.PBD()} }
+func (l instrumentedLibrary) Host() library.Host { return instrumentedHost{l.Library.Host()} }
+func (l instrumentedLibrary) VM() library.VM     { return instrumentedVM{l.Library.VM()} }
+func (l instrumentedLibrary) VDI() library.VDI   { return instrumentedVDI{l.Library.VDI()} }
+func (l instrumentedLibrary) VBD() library.VBD   { return instrumentedVBD{l.Library.VBD()} }
+func (l instrumentedLibrary) Task() library.Task { return instrumentedTask{l.Library.Task()} }
+
+type instrumentedPool struct{ library.Pool }
+
+func (p instrumentedPool) Get(ctx context.Context, id uuid.UUID) (*payloads.Pool, error) {
+	return observe(ctx, "pool", "get", func(ctx context.Context) (*payloads.Pool, error) { return p.Pool.Get(ctx, id) })
+}
+
+func (p instrumentedPool) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.Pool, error) {
+	return observe(ctx, "pool", "list", func(ctx context.Context) ([]*payloads.Pool, error) { return p.Pool.GetAll(ctx, limit, filter) })
+}
+
+type instrumentedSR struct{ library.SR }
+
+func (s instrumentedSR) Get(ctx context.Context, id uuid.UUID) (*payloads.StorageRepository, error) {
+	return observe(ctx, "sr", "get", func(ctx context.Context) (*payloads.StorageRepository, error) { return s.SR.Get(ctx, id) })
+}
+
+func (s instrumentedSR) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.StorageRepository, error) {
+	return observe(ctx, "sr", "list", func(ctx context.Context) ([]*payloads.StorageRepository, error) {
+		return s.SR.GetAll(ctx, limit, filter)
+	})
+}
+
+type instrumentedPBD struct{ library.PBD }
+
+func (p instrumentedPBD) Get(ctx context.Context, id uuid.UUID) (*payloads.PBD, error) {
+	return observe(ctx, "pbd", "get", func(ctx context.Context) (*payloads.PBD, error) { return p.PBD.Get(ctx, id) })
+}
+
+func (p instrumentedPBD) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.PBD, error) {
+	return observe(ctx, "pbd", "list", func(ctx context.Context) ([]*payloads.PBD, error) { return p.PBD.GetAll(ctx, limit, filter) })
+}
+
+type instrumentedHost struct{ library.Host }
+
+func (h instrumentedHost) Get(ctx context.Context, id uuid.UUID) (*payloads.Host, error) {
+	return observe(ctx, "host", "get", func(ctx context.Context) (*payloads.Host, error) { return h.Host.Get(ctx, id) })
+}
+
+func (h instrumentedHost) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.Host, error) {
+	return observe(ctx, "host", "list", func(ctx context.Context) ([]*payloads.Host, error) { return h.Host.GetAll(ctx, limit, filter) })
+}
+
+type instrumentedVM struct{ library.VM }
+
+func (v instrumentedVM) GetByID(ctx context.Context, id uuid.UUID) (*payloads.VM, error) {
+	return observe(ctx, "vm", "get", func(ctx context.Context) (*payloads.VM, error) { return v.VM.GetByID(ctx, id) })
+}
+
+func (v instrumentedVM) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.VM, error) {
+	return observe(ctx, "vm", "list", func(ctx context.Context) ([]*payloads.VM, error) { return v.VM.GetAll(ctx, limit, filter) })
+}
+
+type instrumentedVDI struct{ library.VDI }
+
+func (v instrumentedVDI) Get(ctx context.Context, id uuid.UUID) (*payloads.VDI, error) {
+	return observe(ctx, "vdi", "get", func(ctx context.Context) (*payloads.VDI, error) { return v.VDI.Get(ctx, id) })
+}
+
+func (v instrumentedVDI) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.VDI, error) {
+	return observe(ctx, "vdi", "list", func(ctx context.Context) ([]*payloads.VDI, error) { return v.VDI.GetAll(ctx, limit, filter) })
+}
+
+func (v instrumentedVDI) Create(ctx context.Context, params payloads.VDICreateParams) (uuid.UUID, error) {
+	return observe(ctx, "vdi", "create", func(ctx context.Context) (uuid.UUID, error) { return v.VDI.Create(ctx, params) })
+}
+
+func (v instrumentedVDI) Delete(ctx context.Context, id uuid.UUID) error {
+	return observeErr(ctx, "vdi", "delete", func(ctx context.Context) error { return v.VDI.Delete(ctx, id) })
+}
+
+func (v instrumentedVDI) Migrate(ctx context.Context, id uuid.UUID, srID uuid.UUID) (string, error) {
+	return observe(ctx, "vdi", "migrate", func(ctx context.Context) (string, error) { return v.VDI.Migrate(ctx, id, srID) })
+}
+
+func (v instrumentedVDI) AddTag(ctx context.Context, id uuid.UUID, tag string) error {
+	return observeErr(ctx, "vdi", "add_tag", func(ctx context.Context) error { return v.VDI.AddTag(ctx, id, tag) })
+}
+
+func (v instrumentedVDI) RemoveTag(ctx context.Context, id uuid.UUID, tag string) error {
+	return observeErr(ctx, "vdi", "remove_tag", func(ctx context.Context) error { return v.VDI.RemoveTag(ctx, id, tag) })
+}
+
+type instrumentedVBD struct{ library.VBD }
+
+func (v instrumentedVBD) Get(ctx context.Context, id uuid.UUID) (*payloads.VBD, error) {
+	return observe(ctx, "vbd", "get", func(ctx context.Context) (*payloads.VBD, error) { return v.VBD.Get(ctx, id) })
+}
+
+func (v instrumentedVBD) GetAll(ctx context.Context, limit int, filter string) ([]*payloads.VBD, error) {
+	return observe(ctx, "vbd", "list", func(ctx context.Context) ([]*payloads.VBD, error) { return v.VBD.GetAll(ctx, limit, filter) })
+}
+
+func (v instrumentedVBD) Create(ctx context.Context, params *payloads.CreateVBDParams) (uuid.UUID, error) {
+	return observe(ctx, "vbd", "create", func(ctx context.Context) (uuid.UUID, error) { return v.VBD.Create(ctx, params) })
+}
+
+func (v instrumentedVBD) Delete(ctx context.Context, id uuid.UUID) error {
+	return observeErr(ctx, "vbd", "delete", func(ctx context.Context) error { return v.VBD.Delete(ctx, id) })
+}
+
+func (v instrumentedVBD) Connect(ctx context.Context, id uuid.UUID) (string, error) {
+	return observe(ctx, "vbd", "connect", func(ctx context.Context) (string, error) { return v.VBD.Connect(ctx, id) })
+}
+
+func (v instrumentedVBD) Disconnect(ctx context.Context, id uuid.UUID) (string, error) {
+	return observe(ctx, "vbd", "disconnect", func(ctx context.Context) (string, error) { return v.VBD.Disconnect(ctx, id) })
+}
+
+type instrumentedTask struct{ library.Task }
+
+func (t instrumentedTask) Get(ctx context.Context, path string) (*payloads.Task, error) {
+	return observe(ctx, "task", "get", func(ctx context.Context) (*payloads.Task, error) { return t.Task.Get(ctx, path) })
+}
+
+func (t instrumentedTask) Wait(ctx context.Context, id string) (*payloads.Task, error) {
+	return observe(ctx, "task", "wait", func(ctx context.Context) (*payloads.Task, error) { return t.Task.Wait(ctx, id) })
+}
@@ -0,0 +1,149 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package clients
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/tracing"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+)
+
+// sampleCount returns the number of observations of a histogram series.
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, observer.(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentedLibrary(t *testing.T) {
+	t.Run("CountsFailedCalls", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockVBD := xoLibMock.NewMockVBD(ctrl)
+		c := NewXoClient(stubLibrary{vbd: mockVBD})
+		vbdID := uuid.Must(uuid.NewV4())
+		errors := metrics.XORequestErrors.WithLabelValues("vbd", "connect")
+		before := testutil.ToFloat64(errors)
+
+		mockVBD.EXPECT().Connect(gomock.Any(), vbdID).Return("", fmt.Errorf("VBD_IS_EMPTY"))
+		_, err := c.VBD().Connect(context.Background(), vbdID)
+		require.Error(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(errors))
+
+		mockVBD.EXPECT().Connect(gomock.Any(), vbdID).Return("task", nil)
+		_, err = c.VBD().Connect(context.Background(), vbdID)
+		require.NoError(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(errors))
+	})
+
+	t.Run("CacheHitsAreNotObserved", func(t *testing.T) {
+		c, mocks, _ := newCachedClient(t, nil)
+		pool := &payloads.Pool{ID: poolUUID}
+		mocks.pool.EXPECT().Get(gomock.Any(), poolUUID).Return(pool, nil)
+		latency := metrics.XORequestDurationSeconds.WithLabelValues("pool", "get")
+		before := sampleCount(t, latency)
+
+		for range 3 {
+			_, err := c.Pool().Get(context.Background(), poolUUID)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, before+1, sampleCount(t, latency))
+	})
+
+	t.Run("AttachWaitIsObserved", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockVBD := xoLibMock.NewMockVBD(ctrl)
+		c := NewXoClientWithOptions(stubLibrary{vbd: mockVBD}, XoClientOptions{AttachTimeout: 50 * time.Millisecond, PollInterval: 10 * time.Millisecond})
+		vbdID := uuid.Must(uuid.NewV4())
+		mockVBD.EXPECT().Get(gomock.Any(), vbdID).Return(&payloads.VBD{ID: vbdID}, nil).AnyTimes()
+		wait := metrics.AttachWaitSeconds.WithLabelValues(metrics.ResultError)
+		before := sampleCount(t, wait)
+
+		_, err := c.WaitForVDIToBeFullyAttached(context.Background(), vbdID)
+		require.Error(t, err)
+		assert.Equal(t, before+1, sampleCount(t, wait))
+	})
+}
+
+// recordSpans installs a tracer provider recording the spans of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestClientTracing(t *testing.T) {
+	t.Run("MigrationSpansNestXOCalls", func(t *testing.T) {
+		recorder := recordSpans(t)
+		ctrl := gomock.NewController(t)
+		mockVDI := xoLibMock.NewMockVDI(ctrl)
+		mockTask := xoLibMock.NewMockTask(ctrl)
+		c := NewXoClient(stubLibrary{vdi: mockVDI, task: mockTask})
+		mockVDI.EXPECT().Migrate(gomock.Any(), vdiUUID, localSRID).Return(taskID, nil)
+		mockTask.EXPECT().Wait(gomock.Any(), taskID).Return(&payloads.Task{Status: payloads.Success, Result: payloads.Result{ID: newVDIUUID}}, nil)
+
+		_, err := c.MigrateVDIAndWait(context.Background(), vdiTest, localSRID)
+		require.NoError(t, err)
+
+		spans := make(map[string]sdktrace.ReadOnlySpan)
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = span
+		}
+		require.Contains(t, spans, "XoClient.MigrateVDIAndWait")
+		root := spans["XoClient.MigrateVDIAndWait"]
+		assert.Contains(t, root.Attributes(), tracing.AttrVDIID.String(vdiUUID.String()))
+		assert.Contains(t, root.Attributes(), tracing.AttrSRID.String(localSRID.String()))
+		assert.Equal(t, root.SpanContext().SpanID(), spans["xo.vdi.migrate"].Parent().SpanID())
+		assert.Equal(t, root.SpanContext().SpanID(), spans["XoClient.WaitForTask"].Parent().SpanID())
+		assert.Equal(t, spans["XoClient.WaitForTask"].SpanContext().SpanID(), spans["xo.task.wait"].Parent().SpanID())
+		assert.Contains(t, spans["XoClient.WaitForTask"].Attributes(), tracing.AttrTaskID.String(taskID))
+	})
+
+	t.Run("ErrorsAreRecorded", func(t *testing.T) {
+		recorder := recordSpans(t)
+		ctrl := gomock.NewController(t)
+		mockVBD := xoLibMock.NewMockVBD(ctrl)
+		c := NewXoClient(stubLibrary{vbd: mockVBD})
+		vbdID := uuid.Must(uuid.NewV4())
+		mockVBD.EXPECT().Get(gomock.Any(), vbdID).Return(nil, fmt.Errorf("VBD not found"))
+
+		require.Error(t, c.IsSRAttachedToVMHost(context.Background(), vbdID))
+		for _, span := range recorder.Ended() {
+			assert.Equal(t, "Error", span.Status().Code.String(), span.Name())
+		}
+		assert.Len(t, recorder.Ended(), 2)
+	})
+}
@@ -44,6 +44,21 @@ func (m *MockXoClient) EXPECT() *MockXoClientMockRecorder {
 	return m.recorder
 }
 
+// ArePVDriversDetected mocks base method.
+func (m *MockXoClient) ArePVDriversDetected(ctx context.Context, vmID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArePVDriversDetected", ctx, vmID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArePVDriversDetected indicates an expected call of ArePVDriversDetected.
+func (mr *MockXoClientMockRecorder) ArePVDriversDetected(ctx, vmID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArePVDriversDetected", reflect.TypeOf((*MockXoClient)(nil).ArePVDriversDetected), ctx, vmID)
+}
+
 // AttachVDIToVM mocks base method.
 func (m *MockXoClient) AttachVDIToVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (*payloads.VBD, error) {
 	m.ctrl.T.Helper()
@@ -90,18 +105,32 @@ func (mr *MockXoClientMockRecorder) CreateNewVolume(ctx, srID, namePrefix, capac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNewVolume", reflect.TypeOf((*MockXoClient)(nil).CreateNewVolume), ctx, srID, namePrefix, capacityBytes, volumeName, managedBy, clusterTag)
 }
 
-// DisconnectVBDFromVM mocks base method.
-func (m *MockXoClient) DisconnectVBDFromVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) error {
+// DestroyVBD mocks base method.
+func (m *MockXoClient) DestroyVBD(ctx context.Context, vbd payloads.VBD) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DisconnectVBDFromVM", ctx, vdi, vmUUID)
+	ret := m.ctrl.Call(m, "DestroyVBD", ctx, vbd)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DisconnectVBDFromVM indicates an expected call of DisconnectVBDFromVM.
-func (mr *MockXoClientMockRecorder) DisconnectVBDFromVM(ctx, vdi, vmUUID any) *gomock.Call {
+// DestroyVBD indicates an expected call of DestroyVBD.
+func (mr *MockXoClientMockRecorder) DestroyVBD(ctx, vbd any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisconnectVBDFromVM", reflect.TypeOf((*MockXoClient)(nil).DisconnectVBDFromVM), ctx, vdi, vmUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroyVBD", reflect.TypeOf((*MockXoClient)(nil).DestroyVBD), ctx, vbd)
+}
+
+// DetachVDIFromVM mocks base method.
+func (m *MockXoClient) DetachVDIFromVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetachVDIFromVM", ctx, vdi, vmUUID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DetachVDIFromVM indicates an expected call of DetachVDIFromVM.
+func (mr *MockXoClientMockRecorder) DetachVDIFromVM(ctx, vdi, vmUUID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachVDIFromVM", reflect.TypeOf((*MockXoClient)(nil).DetachVDIFromVM), ctx, vdi, vmUUID)
 }
 
 // FindLocalSRForHost mocks base method.
@@ -150,6 +179,20 @@ func (mr *MockXoClientMockRecorder) FindVDIByVolumeName(ctx, volumeName any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindVDIByVolumeName", reflect.TypeOf((*MockXoClient)(nil).FindVDIByVolumeName), ctx, volumeName)
 }
 
+// ForceDestroyVBD mocks base method.
+func (m *MockXoClient) ForceDestroyVBD(ctx context.Context, vbd payloads.VBD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceDestroyVBD", ctx, vbd)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForceDestroyVBD indicates an expected call of ForceDestroyVBD.
+func (mr *MockXoClientMockRecorder) ForceDestroyVBD(ctx, vbd any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceDestroyVBD", reflect.TypeOf((*MockXoClient)(nil).ForceDestroyVBD), ctx, vbd)
+}
+
 // GetVBDFromVDIAndVM mocks base method.
 func (m *MockXoClient) GetVBDFromVDIAndVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (*payloads.VBD, error) {
 	m.ctrl.T.Helper()
@@ -194,6 +237,22 @@ func (mr *MockXoClientMockRecorder) Host() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Host", reflect.TypeOf((*MockXoClient)(nil).Host))
 }
 
+// InvalidateCache mocks base method.
+func (m *MockXoClient) InvalidateCache(ids ...uuid.UUID) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range ids {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "InvalidateCache", varargs...)
+}
+
+// InvalidateCache indicates an expected call of InvalidateCache.
+func (mr *MockXoClientMockRecorder) InvalidateCache(ids ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateCache", reflect.TypeOf((*MockXoClient)(nil).InvalidateCache), ids...)
+}
+
 // IsSRAttachedToHost mocks base method.
 func (m *MockXoClient) IsSRAttachedToHost(ctx context.Context, srID, hostID uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -266,6 +325,20 @@ func (mr *MockXoClientMockRecorder) PBD() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PBD", reflect.TypeOf((*MockXoClient)(nil).PBD))
 }
 
+// Ping mocks base method.
+func (m *MockXoClient) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockXoClientMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockXoClient)(nil).Ping), ctx)
+}
+
 // Pool mocks base method.
 func (m *MockXoClient) Pool() library.Pool {
 	m.ctrl.T.Helper()
@@ -294,6 +367,20 @@ func (mr *MockXoClientMockRecorder) SR() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SR", reflect.TypeOf((*MockXoClient)(nil).SR))
 }
 
+// SetVDINameLabel mocks base method.
+func (m *MockXoClient) SetVDINameLabel(ctx context.Context, vdi payloads.VDI, nameLabel string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVDINameLabel", ctx, vdi, nameLabel)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVDINameLabel indicates an expected call of SetVDINameLabel.
+func (mr *MockXoClientMockRecorder) SetVDINameLabel(ctx, vdi, nameLabel any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVDINameLabel", reflect.TypeOf((*MockXoClient)(nil).SetVDINameLabel), ctx, vdi, nameLabel)
+}
+
 // Task mocks base method.
 func (m *MockXoClient) Task() library.Task {
 	m.ctrl.T.Helper()
@@ -38,6 +38,16 @@ type Mounter interface {
 	// returns the device name, reference count, and error code.
 	GetDeviceNameFromMount(mountPath string) (string, int, error)
 	IsMountPoint(target string) (bool, error)
+
+	// AssembleStripedDevice returns the path of the md RAID 0 array named
+	// name striping devices, assembling or creating it when needed.
+	AssembleStripedDevice(name string, devices []string) (string, error)
+	// StripedDevicePath returns the device of the active md array named name,
+	// or "" when it is not active.
+	StripedDevicePath(name string) (string, error)
+	// StopStripedDevice stops the md array at path. If it is not active, it
+	// does nothing and returns no error.
+	StopStripedDevice(path string) error
 }
 
 type SafeMounter struct {
@@ -16,7 +16,9 @@ package clients
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 
 	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
 
@@ -31,6 +33,9 @@ type NodeMetadataGetter interface {
 type NodeMetadata struct {
 	NodeId string
 	PoolId string
+	// Instance is the name of the Xen Orchestra instance managing the node
+	// VM. It is empty when unnamed or unknown.
+	Instance string
 }
 
 type NodeMetadataFromKubernetes struct {
@@ -63,21 +68,36 @@ func (n *NodeMetadataFromKubernetes) GetNodeMetadata() (*NodeMetadata, error) {
 	}, nil
 }
 
-// NodeMetadataFromXoClient uses the XoClient to get all needed metadata.
+// NodeMetadataFromXoClient uses the XoClient to get all needed metadata. When
+// several Xen Orchestra instances are configured, each is asked in turn.
 type NodeMetadataFromXoClient struct {
-	kclient  kclient.Interface
-	xoClient *xok8s.XoClient
-	nodeName string
+	kclient   kclient.Interface
+	instances []*nodeMetadataXoInstance
+	nodeName  string
+}
+
+type nodeMetadataXoInstance struct {
+	name     string
+	xoClient atomic.Pointer[xok8s.XoClient]
 }
 
-func NewNodeMetadataFromXoClient(kubeClient kclient.Interface, xoClient *xok8s.XoClient, nodeName string) *NodeMetadataFromXoClient {
+func NewNodeMetadataFromXoClient(kubeClient kclient.Interface, nodeName string) *NodeMetadataFromXoClient {
 	return &NodeMetadataFromXoClient{
-		xoClient: xoClient,
 		kclient:  kubeClient,
 		nodeName: nodeName,
 	}
 }
 
+// AddXoClient adds the client of the Xen Orchestra instance named instance
+// to the lookups. The returned function replaces it for the next lookups,
+// after the configuration of the instance has been reloaded.
+func (n *NodeMetadataFromXoClient) AddXoClient(instance string, xoClient *xok8s.XoClient) func(*xok8s.XoClient) {
+	i := &nodeMetadataXoInstance{name: instance}
+	i.xoClient.Store(xoClient)
+	n.instances = append(n.instances, i)
+	return func(xoClient *xok8s.XoClient) { i.xoClient.Store(xoClient) }
+}
+
 // GetNodeMetadata retrieves node identity and topology metadata directly from
 // the XenOrchestra API. This implementation does not depend on the CCM having
 // labeled the node beforehand; it resolves the pool ID by querying XO at startup.
@@ -86,13 +106,21 @@ func (n *NodeMetadataFromXoClient) GetNodeMetadata() (*NodeMetadata, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get node: %w", err)
 	}
-	vm, poolID, err := n.xoClient.FindVMByNode(context.Background(), node)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find VM by node: %w", err)
+	var errs []error
+	for _, instance := range n.instances {
+		vm, poolID, err := instance.xoClient.Load().FindVMByNode(context.Background(), node)
+		if err != nil {
+			if instance.name != "" {
+				err = fmt.Errorf("Xen Orchestra instance %q: %w", instance.name, err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		return &NodeMetadata{
+			NodeId:   vm.ID.String(),
+			PoolId:   poolID.String(),
+			Instance: instance.name,
+		}, nil
 	}
-
-	return &NodeMetadata{
-		NodeId: vm.ID.String(),
-		PoolId: poolID.String(),
-	}, nil
+	return nil, fmt.Errorf("failed to find VM by node: %w", errors.Join(errs...))
 }
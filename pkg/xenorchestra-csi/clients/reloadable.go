@@ -0,0 +1,175 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package clients
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	v1 "github.com/vatesfr/xenorchestra-go-sdk/client"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library"
+)
+
+// XoClientGeneration is one client built from one Xen Orchestra
+// configuration, with the resources it owns.
+type XoClientGeneration struct {
+	Client XoClient
+	// Stop releases the resources of the client, such as its event feed. It
+	// is called once the generation has been replaced and no pinned
+	// operation uses it anymore. It may be nil.
+	Stop func()
+
+	inFlight sync.WaitGroup
+}
+
+// ReloadableXoClient is an XoClient whose underlying client can be replaced
+// while the driver runs. Each call is served by the current client. An
+// operation made of several calls pins the current client with Pin so that
+// it never mixes two of them.
+type ReloadableXoClient struct {
+	mu      sync.RWMutex
+	current *XoClientGeneration
+}
+
+// NewReloadableXoClient returns a ReloadableXoClient serving initial.
+func NewReloadableXoClient(initial *XoClientGeneration) *ReloadableXoClient {
+	return &ReloadableXoClient{current: initial}
+}
+
+// Current returns the current client.
+func (r *ReloadableXoClient) Current() XoClient {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Client
+}
+
+// Pin returns the current client, which stays in use until release is
+// called, even if it is replaced in the meantime.
+func (r *ReloadableXoClient) Pin() (client XoClient, release func()) {
+	r.mu.RLock()
+	generation := r.current
+	generation.inFlight.Add(1)
+	r.mu.RUnlock()
+	return generation.Client, sync.OnceFunc(generation.inFlight.Done)
+}
+
+// Swap makes next the current client. The previous one is stopped in the
+// background once every operation that pinned it has released it.
+func (r *ReloadableXoClient) Swap(next *XoClientGeneration) {
+	r.mu.Lock()
+	previous := r.current
+	r.current = next
+	r.mu.Unlock()
+
+	go func() {
+		previous.inFlight.Wait()
+		if previous.Stop != nil {
+			previous.Stop()
+		}
+	}()
+}
+
+func (r *ReloadableXoClient) VM() library.VM                 { return r.Current().VM() }
+func (r *ReloadableXoClient) Task() library.Task             { return r.Current().Task() }
+func (r *ReloadableXoClient) Pool() library.Pool             { return r.Current().Pool() }
+func (r *ReloadableXoClient) Host() library.Host             { return r.Current().Host() }
+func (r *ReloadableXoClient) VDI() library.VDI               { return r.Current().VDI() }
+func (r *ReloadableXoClient) VBD() library.VBD               { return r.Current().VBD() }
+func (r *ReloadableXoClient) PBD() library.PBD               { return r.Current().PBD() }
+func (r *ReloadableXoClient) SR() library.SR                 { return r.Current().SR() }
+func (r *ReloadableXoClient) V1Client() v1.XOClient          { return r.Current().V1Client() }
+func (r *ReloadableXoClient) Ping(ctx context.Context) error { return r.Current().Ping(ctx) }
+
+func (r *ReloadableXoClient) GetVBDFromVDIAndVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (*payloads.VBD, error) {
+	return r.Current().GetVBDFromVDIAndVM(ctx, vdi, vmUUID)
+}
+
+func (r *ReloadableXoClient) ConnectVBDToVM(ctx context.Context, vbd payloads.VBD) (*payloads.VBD, error) {
+	return r.Current().ConnectVBDToVM(ctx, vbd)
+}
+
+func (r *ReloadableXoClient) DetachVDIFromVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) error {
+	return r.Current().DetachVDIFromVM(ctx, vdi, vmUUID)
+}
+
+func (r *ReloadableXoClient) DestroyVBD(ctx context.Context, vbd payloads.VBD) error {
+	return r.Current().DestroyVBD(ctx, vbd)
+}
+
+func (r *ReloadableXoClient) ForceDestroyVBD(ctx context.Context, vbd payloads.VBD) error {
+	return r.Current().ForceDestroyVBD(ctx, vbd)
+}
+
+func (r *ReloadableXoClient) AttachVDIToVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (*payloads.VBD, error) {
+	return r.Current().AttachVDIToVM(ctx, vdi, vmUUID)
+}
+
+func (r *ReloadableXoClient) CreateNewVolume(ctx context.Context, srID uuid.UUID, namePrefix string, capacityBytes int64, volumeName string, managedBy string, clusterTag string) (uuid.UUID, uuid.UUID, error) {
+	return r.Current().CreateNewVolume(ctx, srID, namePrefix, capacityBytes, volumeName, managedBy, clusterTag)
+}
+
+func (r *ReloadableXoClient) WaitForVDIToBeFullyAttached(ctx context.Context, vbdID uuid.UUID) (*payloads.VBD, error) {
+	return r.Current().WaitForVDIToBeFullyAttached(ctx, vbdID)
+}
+
+func (r *ReloadableXoClient) IsVDIUsedAnywhere(ctx context.Context, vdi *payloads.VDI) ([]*payloads.VBD, error) {
+	return r.Current().IsVDIUsedAnywhere(ctx, vdi)
+}
+
+func (r *ReloadableXoClient) FindVDIByVolumeName(ctx context.Context, volumeName string) (*payloads.VDI, string, error) {
+	return r.Current().FindVDIByVolumeName(ctx, volumeName)
+}
+
+func (r *ReloadableXoClient) IsSRAttachedToHost(ctx context.Context, srID uuid.UUID, hostID uuid.UUID) error {
+	return r.Current().IsSRAttachedToHost(ctx, srID, hostID)
+}
+
+func (r *ReloadableXoClient) IsSRAttachedToVMHost(ctx context.Context, vbdID uuid.UUID) error {
+	return r.Current().IsSRAttachedToVMHost(ctx, vbdID)
+}
+
+func (r *ReloadableXoClient) GetVDIByVolumeId(ctx context.Context, volumeId string) (*payloads.VDI, error) {
+	return r.Current().GetVDIByVolumeId(ctx, volumeId)
+}
+
+func (r *ReloadableXoClient) FindLocalSRForHost(ctx context.Context, hostID uuid.UUID) (*payloads.StorageRepository, error) {
+	return r.Current().FindLocalSRForHost(ctx, hostID)
+}
+
+func (r *ReloadableXoClient) FindLocalSRsForPool(ctx context.Context, poolID uuid.UUID) ([]*payloads.StorageRepository, error) {
+	return r.Current().FindLocalSRsForPool(ctx, poolID)
+}
+
+func (r *ReloadableXoClient) InvalidateCache(ids ...uuid.UUID) {
+	r.Current().InvalidateCache(ids...)
+}
+
+func (r *ReloadableXoClient) MigrateVDIAndWait(ctx context.Context, vdi payloads.VDI, targetSRID uuid.UUID) (uuid.UUID, error) {
+	return r.Current().MigrateVDIAndWait(ctx, vdi, targetSRID)
+}
+
+func (r *ReloadableXoClient) SetVDINameLabel(ctx context.Context, vdi payloads.VDI, nameLabel string) error {
+	return r.Current().SetVDINameLabel(ctx, vdi, nameLabel)
+}
+
+func (r *ReloadableXoClient) ArePVDriversDetected(ctx context.Context, vmID uuid.UUID) (bool, error) {
+	return r.Current().ArePVDriversDetected(ctx, vmID)
+}
+
+// Compile time check to ensure ReloadableXoClient implements the XoClient interface
+var _ XoClient = &ReloadableXoClient{}
@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// StripeChunkBytes is the chunk size of the md RAID 0 arrays striping the
+	// members of a striped volume: the array is a multiple of it.
+	StripeChunkBytes = 512 << 10
+	// StripeDataOffsetBytes is the space reserved for the md superblock at the
+	// start of every member.
+	StripeDataOffsetBytes = 1 << 20
+	// MaxVHDSizeBytes is the largest virtual size of a VDI stored as a VHD,
+	// the format of the VDIs of the XCP-ng SRs, and so the largest member of
+	// a striped volume.
+	MaxVHDSizeBytes = 2040 << 30
+
+	mdadmCommand = "mdadm"
+	// raidMemberFormat is the format blkid reports for an md array member.
+	raidMemberFormat = "linux_raid_member"
+)
+
+var (
+	// sysBlockDir lists the block devices of the node, with the arrays holding
+	// them under <device>/holders.
+	sysBlockDir = "/sys/class/block"
+	// mdDeviceDir holds a link named after each active md array to its device.
+	mdDeviceDir = "/dev/md"
+)
+
+// AssembleStripedDevice returns the path of the md RAID 0 array named name
+// striping devices, in that order. It assembles the array when its members
+// are not active yet, and creates it when none of them holds data.
+func (s *SafeMounter) AssembleStripedDevice(name string, devices []string) (string, error) {
+	// The node may assemble the array by itself as soon as its members show
+	// up.
+	array, err := stripedDeviceOf(devices)
+	if err != nil || array != "" {
+		return array, err
+	}
+
+	members := 0
+	for _, device := range devices {
+		format, err := s.safeMounter.GetDiskFormat(device)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the format of %s: %w", device, err)
+		}
+		switch format {
+		case raidMemberFormat:
+			members++
+		case "":
+		default:
+			return "", fmt.Errorf("device %s holds %s, not a member of an array", device, format)
+		}
+	}
+
+	path := filepath.Join(mdDeviceDir, name)
+	var args []string
+	switch members {
+	case len(devices):
+		args = []string{"--assemble", path, "--run"}
+	case 0:
+		args = []string{
+			"--create", path, "--run",
+			"--level=0",
+			"--raid-devices=" + strconv.Itoa(len(devices)),
+			"--chunk=" + strconv.Itoa(StripeChunkBytes>>10) + "K",
+			"--metadata=1.2",
+			"--data-offset=" + strconv.Itoa(StripeDataOffsetBytes>>10) + "K",
+			"--name=" + name,
+			// Without a homehost, the array is linked as /dev/md/<name> on
+			// any node, whatever its host name.
+			"--homehost=<none>",
+		}
+	default:
+		// The others may have lost their superblock: creating a new array
+		// would overwrite the data of the existing one.
+		return "", fmt.Errorf("only %d of the %d devices are members of an array", members, len(devices))
+	}
+	args = append(args, devices...)
+	if output, err := s.exec.Command(mdadmCommand, args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("mdadm %s %s failed: %w: %s", args[0], path, err, strings.TrimSpace(string(output)))
+	}
+
+	array, err = stripedDeviceOf(devices)
+	if err == nil && array == "" {
+		err = fmt.Errorf("mdadm %s %s did not start an array", args[0], path)
+	}
+	return array, err
+}
+
+// StripedDevicePath returns the device of the active md array named name, or
+// "" when it is not active.
+func (s *SafeMounter) StripedDevicePath(name string) (string, error) {
+	path, err := filepath.EvalSymlinks(filepath.Join(mdDeviceDir, name))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve md array %s: %w", name, err)
+	}
+	return path, nil
+}
+
+// StopStripedDevice stops the md array at path, releasing its members. It
+// does nothing when the array is already stopped.
+func (s *SafeMounter) StopStripedDevice(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if output, err := s.exec.Command(mdadmCommand, "--stop", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("mdadm --stop %s failed: %w: %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// stripedDeviceOf returns the path of the md array holding all of devices,
+// or "" when none of them belongs to an array.
+func stripedDeviceOf(devices []string) (string, error) {
+	array := ""
+	for i, device := range devices {
+		entries, err := os.ReadDir(filepath.Join(sysBlockDir, filepath.Base(device), "holders"))
+		if err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read the holders of %s: %w", device, err)
+		}
+		holder := ""
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), "md") {
+				holder = entry.Name()
+			}
+		}
+		switch {
+		case i == 0:
+			array = holder
+		case holder != array:
+			return "", fmt.Errorf("devices %s and %s are not held by the same array", devices[0], device)
+		}
+	}
+	if array == "" {
+		return "", nil
+	}
+	return "/dev/" + array, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setHolders fakes the sysfs directory of the block devices, with holders
+// mapping each device to the array holding it, if any.
+func setHolders(t *testing.T, holders map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for device, holder := range holders {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, device, "holders"), 0o755))
+		if holder != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, device, "holders", holder), nil, 0o644))
+		}
+	}
+	previous := sysBlockDir
+	sysBlockDir = dir
+	t.Cleanup(func() { sysBlockDir = previous })
+}
+
+func TestStripedDeviceOf(t *testing.T) {
+	devices := []string{"/dev/xvdb", "/dev/xvdc"}
+
+	t.Run("NotAssembled", func(t *testing.T) {
+		setHolders(t, map[string]string{"xvdb": "", "xvdc": ""})
+
+		array, err := stripedDeviceOf(devices)
+		require.NoError(t, err)
+		assert.Empty(t, array)
+	})
+
+	t.Run("Assembled", func(t *testing.T) {
+		setHolders(t, map[string]string{"xvdb": "md127", "xvdc": "md127"})
+
+		array, err := stripedDeviceOf(devices)
+		require.NoError(t, err)
+		assert.Equal(t, "/dev/md127", array)
+	})
+
+	t.Run("HeldByOtherArrays", func(t *testing.T) {
+		setHolders(t, map[string]string{"xvdb": "md127", "xvdc": ""})
+
+		_, err := stripedDeviceOf(devices)
+		assert.Error(t, err)
+	})
+}
+
+func TestStripedDevicePath(t *testing.T) {
+	dir := t.TempDir()
+	previous := mdDeviceDir
+	mdDeviceDir = filepath.Join(dir, "md")
+	t.Cleanup(func() { mdDeviceDir = previous })
+	require.NoError(t, os.MkdirAll(mdDeviceDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "md127"), nil, 0o644))
+	require.NoError(t, os.Symlink("../md127", filepath.Join(mdDeviceDir, "volume")))
+
+	path, err := (&SafeMounter{}).StripedDevicePath("volume")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "md127"), path)
+
+	path, err = (&SafeMounter{}).StripedDevicePath("other")
+	require.NoError(t, err)
+	assert.Empty(t, path)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stub
+
+import (
+	"github.com/gofrs/uuid"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NewXoInstance returns the instance name with a mock client, and the mock
+// its VDI service returns.
+func NewXoInstance(ctrl *gomock.Controller, name string) (clients.XoInstance, *clientsMock.MockXoClient, *xoLibMock.MockVDI) {
+	mockXo := clientsMock.NewMockXoClient(ctrl)
+	mockVDI := xoLibMock.NewMockVDI(ctrl)
+	mockXo.EXPECT().VDI().Return(mockVDI).AnyTimes()
+	return clients.XoInstance{Name: name, Client: mockXo}, mockXo, mockVDI
+}
+
+// NewVolumeVDI returns a VDI of 1 GiB carrying the volume ID and PV name tags,
+// when not empty, followed by tags.
+func NewVolumeVDI(id uuid.UUID, volumeID, pvName string, tags ...string) *payloads.VDI {
+	var volumeTags []string
+	if volumeID != "" {
+		volumeTags = append(volumeTags, clients.BuildTag(clients.VDITagKeyVolumeId, volumeID))
+	}
+	if pvName != "" {
+		volumeTags = append(volumeTags, clients.BuildTag(clients.VDITagKeyPVName, pvName))
+	}
+	return &payloads.VDI{ID: id, Size: 1 << 30, Tags: append(volumeTags, tags...)}
+}
+
+// NewPersistentVolume returns the PersistentVolume name of the CSI driver
+// driverName with the volume handle handle.
+func NewPersistentVolume(name, driverName, handle string) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: handle},
+			},
+		},
+	}
+}
@@ -23,6 +23,9 @@ import (
 
 	"github.com/gofrs/uuid"
 
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/tracing"
+	v1 "github.com/vatesfr/xenorchestra-go-sdk/client"
 	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
 	"github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library"
 
@@ -37,7 +40,15 @@ type XoClient interface {
 	library.Library
 	GetVBDFromVDIAndVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (*payloads.VBD, error)
 	ConnectVBDToVM(ctx context.Context, vbd payloads.VBD) (*payloads.VBD, error)
-	DisconnectVBDFromVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) error
+	// DetachVDIFromVM unplugs and destroys the VBDs linking the VDI to the VM.
+	// It returns ErrVBDNotFound if there is none.
+	DetachVDIFromVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) error
+	// DestroyVBD unplugs the VBD if needed and deletes it.
+	DestroyVBD(ctx context.Context, vbd payloads.VBD) error
+	// ForceDestroyVBD force-unplugs the VBD if needed and deletes it. Unlike
+	// DestroyVBD, it does not need the cooperation of the guest, and works on
+	// the VBDs left attached to a VM that is not running.
+	ForceDestroyVBD(ctx context.Context, vbd payloads.VBD) error
 	AttachVDIToVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (*payloads.VBD, error)
 	CreateNewVolume(ctx context.Context, srID uuid.UUID, namePrefix string, capacityBytes int64, volumeName string, managedBy string, clusterTag string) (uuid.UUID, uuid.UUID, error)
 	WaitForVDIToBeFullyAttached(ctx context.Context, vbdID uuid.UUID) (*payloads.VBD, error)
@@ -58,7 +69,8 @@ type XoClient interface {
 	//  1. VDI tag "k8s:volumeId:<volumeId>" (primary, v0.4.0+ and migrated volumes)
 	//  2. name_label containing the volumeId (migrated v0.3.0 volumes, tag recovery)
 	//  3. Direct VDI UUID lookup (static volumes using raw VDI UUID as volumeHandle)
-	// Returns ErrVolumeNotFound if no VDI matches, ErrVolumeIdAmbiguous if multiple match.
+	// Returns ErrVolumeNotFound if no VDI matches, ErrVolumeStriped if the
+	// members of a striped volume match, ErrVolumeIdAmbiguous if other VDIs match.
 	GetVDIByVolumeId(ctx context.Context, volumeId string) (*payloads.VDI, error)
 
 	// FindLocalSRForHost returns the first local (non-shared) user SR whose
@@ -69,22 +81,122 @@ type XoClient interface {
 	// the given pool. Returns an error if the API call fails or none are found.
 	FindLocalSRsForPool(ctx context.Context, poolID uuid.UUID) ([]*payloads.StorageRepository, error)
 
+	// InvalidateCache drops the cached pools, SRs, PBDs, hosts and VM
+	// placements holding or filtering on any of ids, or all of them when ids
+	// is empty, so that the next reads fetch them from XO.
+	InvalidateCache(ids ...uuid.UUID)
+
+	// Ping makes an authenticated call to the XO API, bypassing the cache. It
+	// fails when XO is unreachable or rejects the token.
+	Ping(ctx context.Context) error
+
 	// MigrateVDIAndWait migrates vdi to targetSRID and blocks until the task
 	// completes. Returns the new VDI UUID assigned by XAPI after migration.
 	MigrateVDIAndWait(ctx context.Context, vdi payloads.VDI, targetSRID uuid.UUID) (uuid.UUID, error)
+
+	// SetVDINameLabel renames vdi, keeping its name_description.
+	SetVDINameLabel(ctx context.Context, vdi payloads.VDI, nameLabel string) error
+	// ArePVDriversDetected reports whether XO detects the PV drivers of the
+	// VM, without which VBDs cannot be hot-plugged.
+	ArePVDriversDetected(ctx context.Context, vmID uuid.UUID) (bool, error)
+}
+
+const (
+	// DefaultAttachTimeout bounds the wait for a plugged VBD to get a device name.
+	DefaultAttachTimeout = 2 * time.Minute
+	// DefaultTaskTimeout bounds the wait for an XO task, such as a VDI migration.
+	DefaultTaskTimeout = 30 * time.Minute
+	// DefaultPollInterval is the polling period used when no event feed is available.
+	DefaultPollInterval = time.Second
+	// eventResyncInterval is the polling period used while the event feed is
+	// connected, so that a lost notification only delays a wait.
+	eventResyncInterval = 15 * time.Second
+)
+
+// XoClientOptions tunes how the client waits for Xen Orchestra. Zero values
+// select the defaults.
+type XoClientOptions struct {
+	// AttachTimeout bounds WaitForVDIToBeFullyAttached.
+	AttachTimeout time.Duration
+	// TaskTimeout bounds the wait for every XO task.
+	TaskTimeout time.Duration
+	// PollInterval is the polling period used when Events is nil or disconnected.
+	PollInterval time.Duration
+	// Events, when set, wakes up waiters as soon as XO reports a change and
+	// invalidates the cache.
+	Events EventSource
+	// CacheTTL is how long pools, SRs, PBDs, hosts and VM placements are
+	// cached. Zero disables the cache.
+	CacheTTL time.Duration
 }
 
 type xoClient struct {
 	library.Library
+	options XoClientOptions
+	// cache is nil when caching is disabled.
+	cache *ObjectCache
 }
 
 func NewXoClient(libraryService library.Library) XoClient {
-	return xoClient{
-		Library: libraryService,
+	return NewXoClientWithOptions(libraryService, XoClientOptions{})
+}
+
+func NewXoClientWithOptions(libraryService library.Library, options XoClientOptions) XoClient {
+	if options.AttachTimeout <= 0 {
+		options.AttachTimeout = DefaultAttachTimeout
+	}
+	if options.TaskTimeout <= 0 {
+		options.TaskTimeout = DefaultTaskTimeout
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultPollInterval
+	}
+	// The cache wraps the instrumentation so that only the calls reaching XO
+	// are observed.
+	c := xoClient{
+		Library: instrumentedLibrary{Library: libraryService},
+		options: options,
 	}
+	if options.CacheTTL > 0 {
+		c.cache = NewObjectCache(options.CacheTTL)
+		c.Library = cachedLibrary{Library: c.Library, cache: c.cache}
+		if options.Events != nil {
+			options.Events.OnChange(c.cache.ObjectChanged)
+		}
+	}
+	return c
 }
 
-func (c xoClient) GetVBDFromVDIAndVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (*payloads.VBD, error) {
+func (c xoClient) InvalidateCache(ids ...uuid.UUID) {
+	if c.cache != nil {
+		c.cache.Invalidate(ids...)
+	}
+}
+
+func (c xoClient) Ping(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.Ping")
+	defer tracing.End(span, &err)
+
+	// VMs are never cached, and listing at most one costs XO little.
+	if _, err := c.VM().GetAll(ctx, 1, ""); err != nil {
+		return fmt.Errorf("failed to list VMs: %w", err)
+	}
+	return nil
+}
+
+// vmPlacement returns the VM with its pool and host, served from the cache
+// when enabled. Its power state may be stale: use VM().GetByID to act on it.
+func (c xoClient) vmPlacement(ctx context.Context, vmID uuid.UUID) (*payloads.VM, error) {
+	if c.cache == nil {
+		return c.VM().GetByID(ctx, vmID)
+	}
+	return cachedGet(ctx, c.cache, cacheKindVM, vmID, func() (*payloads.VM, error) { return c.VM().GetByID(ctx, vmID) })
+}
+
+func (c xoClient) GetVBDFromVDIAndVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (_ *payloads.VBD, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.GetVBDFromVDIAndVM", tracing.ID(tracing.AttrVDIID, vdi.ID), tracing.ID(tracing.AttrVMID, vmUUID))
+	defer tracing.End(span, &err)
+
 	vbs, err := c.VBD().GetAll(ctx, 0, fmt.Sprintf("VDI:%s VM:%s", vdi.ID, vmUUID))
 	if err != nil {
 		klog.ErrorS(err, "Failed to get VBDs for VDI and VM", "vdi", vdi, "vmUUID", vmUUID)
@@ -101,16 +213,18 @@ func (c xoClient) GetVBDFromVDIAndVM(ctx context.Context, vdi payloads.VDI, vmUU
 	return vbs[0], nil
 }
 
-func (c xoClient) ConnectVBDToVM(ctx context.Context, vbd payloads.VBD) (*payloads.VBD, error) {
+func (c xoClient) ConnectVBDToVM(ctx context.Context, vbd payloads.VBD) (_ *payloads.VBD, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.ConnectVBDToVM", tracing.ID(tracing.AttrVBDID, vbd.ID), tracing.ID(tracing.AttrVMID, vbd.VM))
+	defer tracing.End(span, &err)
+
 	taskID, err := c.VBD().Connect(ctx, vbd.ID)
 	if err != nil {
 		klog.ErrorS(err, "Failed to connect existing VBD to the node", "vbd", vbd)
 		return nil, err
 	}
 
-	task, err := c.Task().Wait(ctx, taskID)
-	if err != nil {
-		klog.ErrorS(err, "Failed to wait for task to complete", "taskID", taskID, "taskResult", task.Result)
+	if _, err := c.waitForTask(ctx, taskID); err != nil {
+		klog.ErrorS(err, "Failed to wait for task to complete", "taskID", taskID)
 		return nil, err
 	}
 
@@ -123,7 +237,10 @@ func (c xoClient) ConnectVBDToVM(ctx context.Context, vbd payloads.VBD) (*payloa
 	return updatedVBD, nil
 }
 
-func (c xoClient) AttachVDIToVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (*payloads.VBD, error) {
+func (c xoClient) AttachVDIToVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (_ *payloads.VBD, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.AttachVDIToVM", tracing.ID(tracing.AttrVDIID, vdi.ID), tracing.ID(tracing.AttrVMID, vmUUID))
+	defer tracing.End(span, &err)
+
 	vbdID, err := c.VBD().Create(ctx, &payloads.CreateVBDParams{
 		VM:   vmUUID,
 		VDI:  vdi.ID,
@@ -146,7 +263,10 @@ func (c xoClient) AttachVDIToVM(ctx context.Context, vdi payloads.VDI, vmUUID uu
 	return vbd, nil
 }
 
-func (c xoClient) CreateNewVolume(ctx context.Context, srID uuid.UUID, namePrefix string, capacityBytes int64, volumeName string, managedBy string, clusterTag string) (uuid.UUID, uuid.UUID, error) {
+func (c xoClient) CreateNewVolume(ctx context.Context, srID uuid.UUID, namePrefix string, capacityBytes int64, volumeName string, managedBy string, clusterTag string) (_, _ uuid.UUID, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.CreateNewVolume", tracing.ID(tracing.AttrSRID, srID))
+	defer tracing.End(span, &err)
+
 	volumeId, err := uuid.NewV4()
 	if err != nil {
 		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to generate volume ID UUID: %w", err)
@@ -173,42 +293,113 @@ func (c xoClient) CreateNewVolume(ctx context.Context, srID uuid.UUID, namePrefi
 	if err != nil {
 		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to create VDI: %w", err)
 	}
+	span.SetAttributes(tracing.ID(tracing.AttrVDIID, vdiID), tracing.ID(tracing.AttrVolumeID, volumeId))
 
 	return vdiID, volumeId, nil
 }
 
-// waitForDiskToBeAttached waits for the disk to be attached.
-// This pools every 500ms to check if the disk is attached and if a device name is assigned.
-// Hardcoded timeout of 2 minutes.
+// WaitForVDIToBeFullyAttached waits for the VBD to be attached and to have a
+// device name, for at most AttachTimeout and never beyond the deadline of ctx.
+// When an event feed is connected, the VBD is read again as soon as XO reports
+// a change; otherwise it is polled every PollInterval.
 // NOTE: This is required because the VBD can be attached but returned without a device name when `vm.attach` command succeeded.
 // See: https://github.com/vatesfr/xen-orchestra/pull/9192
-func (c xoClient) WaitForVDIToBeFullyAttached(ctx context.Context, vbdID uuid.UUID) (*payloads.VBD, error) {
-	timeout := time.After(2 * time.Minute)
-	tick := time.Tick(1 * time.Second)
+func (c xoClient) WaitForVDIToBeFullyAttached(ctx context.Context, vbdID uuid.UUID) (vbd *payloads.VBD, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.WaitForVDIToBeFullyAttached", tracing.ID(tracing.AttrVBDID, vbdID))
+	defer tracing.End(span, &err)
+
+	ctx, cancel := withOptionalTimeout(ctx, c.options.AttachTimeout)
+	defer cancel()
+	start := time.Now()
+	defer func() {
+		metrics.AttachWaitSeconds.WithLabelValues(metrics.Result(err)).Observe(time.Since(start).Seconds())
+	}()
+
+	var changed <-chan struct{}
+	if c.options.Events != nil {
+		var unsubscribe func()
+		changed, unsubscribe = c.options.Events.Subscribe(vbdID)
+		defer unsubscribe()
+	}
 
 	for {
-		select {
-		case <-timeout:
-			return nil, fmt.Errorf("timed out waiting for VBD %s to be attached", vbdID)
-		case <-tick:
-			vbd, err := c.VBD().Get(ctx, vbdID)
-			if err != nil {
-				klog.ErrorS(err, "Failed to get VBD while waiting for disk to be attached", "vbd", vbdID)
-				continue
-			}
-			if vbd.Attached && vbd.Device != nil && *vbd.Device != "" {
-				klog.V(4).InfoS("Disk is now attached", "vbd", vbd.ID, "vm", vbd.VM, "device", vbd.Device)
-				return vbd, nil
-			}
+		vbd, err = c.VBD().Get(ctx, vbdID)
+		switch {
+		case err != nil:
+			klog.ErrorS(err, "Failed to get VBD while waiting for disk to be attached", "vbd", vbdID)
+		case vbd.Attached && vbd.Device != nil && *vbd.Device != "":
+			klog.V(4).InfoS("Disk is now attached", "vbd", vbd.ID, "vm", vbd.VM, "device", vbd.Device)
+			return vbd, nil
+		default:
 			klog.V(5).InfoS("Disk not yet attached, waiting...", "vbd", vbd.ID, "vm", vbd.VM)
 		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for VBD %s to be attached: %w", vbdID, ctx.Err())
+		case <-changed:
+		case <-time.After(c.pollInterval()):
+		}
 	}
 }
 
+// pollInterval returns the polling period of the waits, which is relaxed while
+// the event feed is connected.
+func (c xoClient) pollInterval() time.Duration {
+	interval := c.options.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	if c.options.Events != nil && c.options.Events.Connected() {
+		return max(interval, eventResyncInterval)
+	}
+	return interval
+}
+
+// withOptionalTimeout bounds ctx by timeout, unless timeout is not positive.
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// waitForTask waits for the XO task to end, for at most TaskTimeout and never
+// beyond the deadline of ctx. It long-polls the task through the event source
+// when there is one and falls back to the SDK polling otherwise.
+func (c xoClient) waitForTask(ctx context.Context, taskID string) (task *payloads.Task, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.WaitForTask", tracing.AttrTaskID.String(taskID))
+	defer tracing.End(span, &err)
+
+	ctx, cancel := withOptionalTimeout(ctx, c.options.TaskTimeout)
+	defer cancel()
+
+	if c.options.Events != nil {
+		task, err := c.options.Events.WaitTask(ctx, taskID)
+		if err == nil {
+			return task, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out waiting for task %s: %w", taskID, ctx.Err())
+		}
+		klog.V(4).InfoS("Falling back to polling for XO task", "taskID", taskID, "reason", err)
+	}
+
+	task, err = c.Task().Wait(ctx, taskID)
+	if err != nil && ctx.Err() != nil {
+		// The SDK does not wrap the context error: restore it for callers.
+		return nil, fmt.Errorf("timed out waiting for task %s: %w", taskID, ctx.Err())
+	}
+	return task, err
+}
+
 // IsVDIUsedAnywhere checks if a VDI is used by any VM in the Xen Orchestra instance.
 // If it is used, it returns the list of VBDs it is added to.
 // If it is not used, it returns an empty slice.
-func (c xoClient) IsVDIUsedAnywhere(ctx context.Context, vdi *payloads.VDI) ([]*payloads.VBD, error) {
+func (c xoClient) IsVDIUsedAnywhere(ctx context.Context, vdi *payloads.VDI) (_ []*payloads.VBD, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.IsVDIUsedAnywhere", tracing.ID(tracing.AttrVDIID, vdi.ID))
+	defer tracing.End(span, &err)
+
 	vbds, err := c.VBD().GetAll(ctx, 0, fmt.Sprintf("VDI:%s", vdi.ID))
 	if err != nil {
 		return nil, err
@@ -218,7 +409,20 @@ func (c xoClient) IsVDIUsedAnywhere(ctx context.Context, vdi *payloads.VDI) ([]*
 }
 
 // IsSRAttachedToHost checks that the given SR is connected (via a plugged PBD) to the given host.
-func (c xoClient) IsSRAttachedToHost(ctx context.Context, srID uuid.UUID, hostID uuid.UUID) error {
+// A negative answer from the cache is checked again against XO.
+func (c xoClient) IsSRAttachedToHost(ctx context.Context, srID uuid.UUID, hostID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.IsSRAttachedToHost", tracing.ID(tracing.AttrSRID, srID), tracing.ID(tracing.AttrHostID, hostID))
+	defer tracing.End(span, &err)
+
+	err = c.isSRAttachedToHost(ctx, srID, hostID)
+	if err != nil && c.cache != nil && !cacheBypassed(ctx) {
+		klog.V(4).InfoS("SR not attached to host according to the cache, refreshing", "srID", srID, "hostID", hostID)
+		err = c.isSRAttachedToHost(WithoutCache(ctx), srID, hostID)
+	}
+	return err
+}
+
+func (c xoClient) isSRAttachedToHost(ctx context.Context, srID uuid.UUID, hostID uuid.UUID) error {
 	pbds, err := c.PBD().GetAll(ctx, 1, fmt.Sprintf("SR:%s host:%s attached?", srID, hostID))
 	if err != nil {
 		return fmt.Errorf("failed to list PBDs for SR %s on host %s: %w", srID, hostID, err)
@@ -231,7 +435,10 @@ func (c xoClient) IsSRAttachedToHost(ctx context.Context, srID uuid.UUID, hostID
 
 // IsSRAttachedToVMHost checks that the SR backing the given VBD is connected (via a plugged PBD)
 // to the XCP-ng host where the VBD's VM is currently running.
-func (c xoClient) IsSRAttachedToVMHost(ctx context.Context, vbdID uuid.UUID) error {
+func (c xoClient) IsSRAttachedToVMHost(ctx context.Context, vbdID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.IsSRAttachedToVMHost", tracing.ID(tracing.AttrVBDID, vbdID))
+	defer tracing.End(span, &err)
+
 	vbd, err := c.VBD().Get(ctx, vbdID)
 	if err != nil {
 		return fmt.Errorf("failed to get VBD %s: %w", vbdID, err)
@@ -240,7 +447,7 @@ func (c xoClient) IsSRAttachedToVMHost(ctx context.Context, vbdID uuid.UUID) err
 		return fmt.Errorf("VBD %s has no VDI", vbdID)
 	}
 
-	vm, err := c.VM().GetByID(ctx, vbd.VM)
+	vm, err := c.vmPlacement(ctx, vbd.VM)
 	if err != nil {
 		return fmt.Errorf("failed to get VM %s for VBD %s: %w", vbd.VM, vbdID, err)
 	}
@@ -250,27 +457,115 @@ func (c xoClient) IsSRAttachedToVMHost(ctx context.Context, vbdID uuid.UUID) err
 		return fmt.Errorf("failed to get VDI %s: %w", vbd.VDI, err)
 	}
 
-	return c.IsSRAttachedToHost(ctx, vdi.SR, vm.Container)
+	if err := c.IsSRAttachedToHost(ctx, vdi.SR, vm.Container); err != nil {
+		if c.cache == nil || cacheBypassed(ctx) {
+			return err
+		}
+		// The VM may have moved to another host since it was cached.
+		c.cache.Invalidate(vm.ID)
+		if vm, err = c.vmPlacement(WithoutCache(ctx), vbd.VM); err != nil {
+			return fmt.Errorf("failed to get VM %s for VBD %s: %w", vbd.VM, vbdID, err)
+		}
+		return c.IsSRAttachedToHost(ctx, vdi.SR, vm.Container)
+	}
+	return nil
 }
 
-func (c xoClient) DisconnectVBDFromVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) error {
-	vbd, err := c.GetVBDFromVDIAndVM(ctx, vdi, vmUUID)
+// DetachVDIFromVM unplugs and destroys every VBD linking the VDI to the VM, so
+// that node VMs do not accumulate dead VBD records. It returns ErrVBDNotFound
+// when no VBD links them, which callers treat as already detached.
+func (c xoClient) DetachVDIFromVM(ctx context.Context, vdi payloads.VDI, vmUUID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.DetachVDIFromVM", tracing.ID(tracing.AttrVDIID, vdi.ID), tracing.ID(tracing.AttrVMID, vmUUID))
+	defer tracing.End(span, &err)
+
+	vbds, err := c.VBD().GetAll(ctx, 0, fmt.Sprintf("VDI:%s VM:%s", vdi.ID, vmUUID))
 	if err != nil {
+		klog.ErrorS(err, "Failed to get VBDs for VDI and VM", "vdi", vdi.ID, "vmUUID", vmUUID)
 		return err
 	}
-	taskID, err := c.VBD().Disconnect(ctx, vbd.ID)
-	if err != nil {
-		klog.ErrorS(err, "Failed to disconnect VBD from the node", "vbdID", vbd.ID)
-		return err
+	if len(vbds) == 0 {
+		return fmt.Errorf("vdi=%s vm=%s: %w", vdi.ID, vmUUID, ErrVBDNotFound)
 	}
-	task, err := c.Task().Wait(ctx, taskID)
-	if err != nil {
-		klog.ErrorS(err, "Failed to wait for task to complete", "taskID", taskID, "taskResult", task.Result)
+
+	for _, vbd := range vbds {
+		if err := c.DestroyVBD(ctx, *vbd); err != nil {
+			return err
+		}
 	}
-	return err
+	return nil
 }
 
-func (c xoClient) GetVDIByVolumeId(ctx context.Context, volumeId string) (*payloads.VDI, error) {
+// DestroyVBD unplugs the VBD if it is still attached and then deletes it.
+// A VBD that disappears in the meantime is considered destroyed.
+func (c xoClient) DestroyVBD(ctx context.Context, vbd payloads.VBD) (err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.DestroyVBD", tracing.ID(tracing.AttrVBDID, vbd.ID), tracing.ID(tracing.AttrVMID, vbd.VM))
+	defer tracing.End(span, &err)
+
+	if vbd.Attached {
+		taskID, err := c.VBD().Disconnect(ctx, vbd.ID)
+		if err != nil {
+			if IsNotFoundError(err) {
+				return nil
+			}
+			klog.ErrorS(err, "Failed to disconnect VBD from the node", "vbdID", vbd.ID)
+			return fmt.Errorf("failed to unplug VBD %s: %w", vbd.ID, err)
+		}
+		task, err := c.waitForTask(ctx, taskID)
+		if err != nil {
+			return fmt.Errorf("failed to wait for unplug task %s of VBD %s: %w", taskID, vbd.ID, err)
+		}
+		if task.Status != payloads.Success {
+			return fmt.Errorf("unplug task %s of VBD %s finished with status %q: %s", taskID, vbd.ID, task.Status, task.Result.Message)
+		}
+		klog.V(5).InfoS("VBD unplugged", "vbdID", vbd.ID, "vmUUID", vbd.VM)
+	}
+
+	if err := c.VBD().Delete(ctx, vbd.ID); err != nil && !IsNotFoundError(err) {
+		klog.ErrorS(err, "Failed to destroy VBD", "vbdID", vbd.ID)
+		return fmt.Errorf("failed to destroy VBD %s: %w", vbd.ID, err)
+	}
+	klog.V(4).InfoS("VBD destroyed", "vbdID", vbd.ID, "vmUUID", vbd.VM)
+	return nil
+}
+
+// ForceDestroyVBD force-unplugs the VBD if it is still attached and then
+// deletes it. A VBD that disappears in the meantime is considered destroyed.
+func (c xoClient) ForceDestroyVBD(ctx context.Context, vbd payloads.VBD) (err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.ForceDestroyVBD", tracing.ID(tracing.AttrVBDID, vbd.ID), tracing.ID(tracing.AttrVMID, vbd.VM))
+	defer tracing.End(span, &err)
+
+	if vbd.Attached {
+		// The REST API only hot-unplugs VBDs. The vbd.disconnect JSON-RPC
+		// method force-unplugs them (VBD.unplug_force), which XAPI allows
+		// on a halted, paused or suspended VM.
+		if err := c.V1Client().DisconnectDisk(v1.Disk{VBD: v1.VBD{Id: vbd.ID.String()}}); err != nil {
+			current, getErr := c.VBD().Get(ctx, vbd.ID)
+			switch {
+			case getErr != nil && IsNotFoundError(getErr):
+				return nil
+			case getErr != nil || current.Attached:
+				klog.ErrorS(err, "Failed to force-unplug VBD", "vbdID", vbd.ID, "vmUUID", vbd.VM)
+				return fmt.Errorf("failed to force-unplug VBD %s: %w", vbd.ID, err)
+			}
+		}
+		klog.V(5).InfoS("VBD force-unplugged", "vbdID", vbd.ID, "vmUUID", vbd.VM)
+	}
+
+	if err := c.VBD().Delete(ctx, vbd.ID); err != nil && !IsNotFoundError(err) {
+		klog.ErrorS(err, "Failed to destroy VBD", "vbdID", vbd.ID)
+		return fmt.Errorf("failed to destroy VBD %s: %w", vbd.ID, err)
+	}
+	klog.V(4).InfoS("VBD destroyed", "vbdID", vbd.ID, "vmUUID", vbd.VM)
+	return nil
+}
+
+func (c xoClient) GetVDIByVolumeId(ctx context.Context, volumeId string) (_ *payloads.VDI, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.GetVDIByVolumeId", tracing.AttrVolumeID.String(volumeId))
+	defer tracing.End(span, &err)
+
+	// The Xen Orchestra instance of the volume handle is not stored in the VDI.
+	_, volumeId = SplitVolumeHandle(volumeId)
+
 	// 1. Primary: look up by VDI tag "k8s:volumeId:<volumeId>".
 	filter := BuildTagFilter(VDITagKeyVolumeId, volumeId)
 	vdis, err := c.VDI().GetAll(ctx, 2, filter)
@@ -281,6 +576,9 @@ func (c xoClient) GetVDIByVolumeId(ctx context.Context, volumeId string) (*paylo
 		return vdis[0], nil
 	}
 	if len(vdis) > 1 {
+		if IsStripeMember(vdis[0]) && IsStripeMember(vdis[1]) {
+			return nil, fmt.Errorf("%w: volumeId=%s", ErrVolumeStriped, volumeId)
+		}
 		return nil, fmt.Errorf("%w: volumeId=%s matched %d VDIs via tag", ErrVolumeIdAmbiguous, volumeId, len(vdis))
 	}
 
@@ -324,7 +622,10 @@ func (c xoClient) GetVDIByVolumeId(ctx context.Context, volumeId string) (*paylo
 	return nil, ErrVolumeNotFound
 }
 
-func (c xoClient) FindVDIByVolumeName(ctx context.Context, volumeName string) (*payloads.VDI, string, error) {
+func (c xoClient) FindVDIByVolumeName(ctx context.Context, volumeName string) (_ *payloads.VDI, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.FindVDIByVolumeName")
+	defer tracing.End(span, &err)
+
 	filter := BuildTagFilter(VDITagKeyPVName, volumeName)
 	vdis, err := c.VDI().GetAll(ctx, 2, filter)
 	if err != nil {
@@ -340,7 +641,10 @@ func (c xoClient) FindVDIByVolumeName(ctx context.Context, volumeName string) (*
 	}
 }
 
-func (c xoClient) FindLocalSRForHost(ctx context.Context, hostID uuid.UUID) (*payloads.StorageRepository, error) {
+func (c xoClient) FindLocalSRForHost(ctx context.Context, hostID uuid.UUID) (_ *payloads.StorageRepository, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.FindLocalSRForHost", tracing.ID(tracing.AttrHostID, hostID))
+	defer tracing.End(span, &err)
+
 	filter := fmt.Sprintf("content_type:user !shared? !inMaintenanceMode? $PBDs:length:>=1 $container:%s", hostID)
 	srs, err := c.SR().GetAll(ctx, 1, filter)
 	if err != nil {
@@ -352,7 +656,10 @@ func (c xoClient) FindLocalSRForHost(ctx context.Context, hostID uuid.UUID) (*pa
 	return srs[0], nil
 }
 
-func (c xoClient) FindLocalSRsForPool(ctx context.Context, poolID uuid.UUID) ([]*payloads.StorageRepository, error) {
+func (c xoClient) FindLocalSRsForPool(ctx context.Context, poolID uuid.UUID) (_ []*payloads.StorageRepository, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.FindLocalSRsForPool", tracing.ID(tracing.AttrPoolID, poolID))
+	defer tracing.End(span, &err)
+
 	filter := fmt.Sprintf("content_type:user !shared? !inMaintenanceMode? $PBDs:length:>=1 $pool:%s", poolID)
 	srs, err := c.SR().GetAll(ctx, 0, filter)
 	if err != nil {
@@ -364,7 +671,17 @@ func (c xoClient) FindLocalSRsForPool(ctx context.Context, poolID uuid.UUID) ([]
 	return srs, nil
 }
 
-func (c xoClient) MigrateVDIAndWait(ctx context.Context, vdi payloads.VDI, targetSRID uuid.UUID) (uuid.UUID, error) {
+func (c xoClient) MigrateVDIAndWait(ctx context.Context, vdi payloads.VDI, targetSRID uuid.UUID) (newVDIID uuid.UUID, err error) {
+	ctx, span := tracing.Start(ctx, "XoClient.MigrateVDIAndWait", tracing.ID(tracing.AttrVDIID, vdi.ID), tracing.ID(tracing.AttrPoolID, vdi.PoolID), tracing.ID(tracing.AttrSRID, targetSRID))
+	defer tracing.End(span, &err)
+
+	start := time.Now()
+	newVDIID, err = c.migrateVDIAndWait(ctx, vdi, targetSRID)
+	metrics.VDIMigrationDurationSeconds.WithLabelValues(vdi.PoolID.String(), metrics.Result(err)).Observe(time.Since(start).Seconds())
+	return newVDIID, err
+}
+
+func (c xoClient) migrateVDIAndWait(ctx context.Context, vdi payloads.VDI, targetSRID uuid.UUID) (uuid.UUID, error) {
 	// Workaround for the tags that are not copied during migration.
 	oldTags := vdi.Tags
 
@@ -373,7 +690,7 @@ func (c xoClient) MigrateVDIAndWait(ctx context.Context, vdi payloads.VDI, targe
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to start VDI migration (vdiID=%s targetSR=%s): %w", vdi.ID, targetSRID, err)
 	}
-	task, err := c.Task().Wait(ctx, taskID)
+	task, err := c.waitForTask(ctx, taskID)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to wait for VDI migration task %s: %w", taskID, err)
 	}
@@ -391,6 +708,35 @@ func (c xoClient) MigrateVDIAndWait(ctx context.Context, vdi payloads.VDI, targe
 	return newVDIID, nil
 }
 
+func (c xoClient) SetVDINameLabel(ctx context.Context, vdi payloads.VDI, nameLabel string) (err error) {
+	_, span := tracing.Start(ctx, "XoClient.SetVDINameLabel", tracing.ID(tracing.AttrVDIID, vdi.ID))
+	defer tracing.End(span, &err)
+
+	// The REST API cannot rename a VDI yet, so this goes through the JSON-RPC
+	// API, which sets both fields.
+	err = c.V1Client().UpdateVDI(v1.Disk{VDI: v1.VDI{
+		VDIId:           vdi.ID.String(),
+		NameLabel:       nameLabel,
+		NameDescription: vdi.NameDescription,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to rename VDI %s to %q: %w", vdi.ID, nameLabel, err)
+	}
+	return nil
+}
+
+func (c xoClient) ArePVDriversDetected(ctx context.Context, vmID uuid.UUID) (_ bool, err error) {
+	_, span := tracing.Start(ctx, "XoClient.ArePVDriversDetected", tracing.ID(tracing.AttrVMID, vmID))
+	defer tracing.End(span, &err)
+
+	// The REST API does not expose the PV drivers state of a VM yet.
+	vm, err := c.V1Client().GetVm(v1.Vm{Id: vmID.String()})
+	if err != nil {
+		return false, fmt.Errorf("failed to get VM %s: %w", vmID, err)
+	}
+	return vm.PVDriversDetected, nil
+}
+
 func (c xoClient) recoverVolumeLookupTags(ctx context.Context, vdi *payloads.VDI, volumeId string) {
 	tagsToRecover := []string{BuildTag(VDITagKeyVolumeId, volumeId)}
 	if recoveredVolumeName := recoverVolumeNameFromVDI(vdi, volumeId); recoveredVolumeName != "" {
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
@@ -39,12 +40,38 @@ type stubLibrary struct {
 	sr   library.SR
 	vdi  library.VDI
 	task library.Task
+	vbd  library.VBD
+	pool library.Pool
+	pbd  library.PBD
+	vm   library.VM
+	v1   v1.XOClient
 }
 
-func (s stubLibrary) SR() library.SR        { return s.sr }
-func (s stubLibrary) VDI() library.VDI      { return s.vdi }
-func (s stubLibrary) Task() library.Task    { return s.task }
-func (s stubLibrary) V1Client() v1.XOClient { panic("V1Client not expected in this test") }
+func (s stubLibrary) SR() library.SR     { return s.sr }
+func (s stubLibrary) Pool() library.Pool { return s.pool }
+func (s stubLibrary) PBD() library.PBD   { return s.pbd }
+func (s stubLibrary) VM() library.VM     { return s.vm }
+func (s stubLibrary) VDI() library.VDI   { return s.vdi }
+func (s stubLibrary) Task() library.Task { return s.task }
+func (s stubLibrary) VBD() library.VBD   { return s.vbd }
+func (s stubLibrary) V1Client() v1.XOClient {
+	if s.v1 == nil {
+		panic("V1Client not expected in this test")
+	}
+	return s.v1
+}
+
+// fakeV1Client records the VBDs disconnected through the JSON-RPC API.
+type fakeV1Client struct {
+	v1.XOClient
+	disconnected []string
+	err          error
+}
+
+func (f *fakeV1Client) DisconnectDisk(d v1.Disk) error {
+	f.disconnected = append(f.disconnected, d.VBD.Id)
+	return f.err
+}
 
 var (
 	hostUUID   = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
@@ -84,6 +111,15 @@ func newClientWithMockVDIAndTask(t *testing.T) (*xoClient, *xoLibMock.MockVDI, *
 	return &c, mockVDI, mockTask
 }
 
+func newClientWithMockVBDAndTask(t *testing.T) (*xoClient, *xoLibMock.MockVBD, *xoLibMock.MockTask) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockVBD := xoLibMock.NewMockVBD(ctrl)
+	mockTask := xoLibMock.NewMockTask(ctrl)
+	c := xoClient{Library: stubLibrary{vbd: mockVBD, task: mockTask}}
+	return &c, mockVBD, mockTask
+}
+
 func expectedLocalSRFilter(hostID uuid.UUID) string {
 	return fmt.Sprintf("content_type:user !shared? !inMaintenanceMode? $PBDs:length:>=1 $container:%s", hostID)
 }
@@ -468,6 +504,20 @@ func TestGetVDIByVolumeId(t *testing.T) {
 		assert.ErrorIs(t, err, ErrVolumeIdAmbiguous)
 	})
 
+	t.Run("StripedViaTag", func(t *testing.T) {
+		c, mockVDI := newClientWithMockVDI(t)
+
+		volumeId := "aaaaaaaa-0000-0000-0000-000000000016"
+		primaryFilter := BuildTagFilter(VDITagKeyVolumeId, volumeId)
+		stripeCount := BuildTag(VDITagKeyStripeCount, "2")
+		mockVDI.EXPECT().
+			GetAll(gomock.Any(), 2, primaryFilter).
+			Return([]*payloads.VDI{{ID: vdiUUID, Tags: []string{stripeCount}}, {ID: newVDIUUID, Tags: []string{stripeCount}}}, nil)
+
+		_, err := c.GetVDIByVolumeId(context.Background(), volumeId)
+		assert.ErrorIs(t, err, ErrVolumeStriped)
+	})
+
 	t.Run("AmbiguousViaNameLabelFallback", func(t *testing.T) {
 		c, mockVDI := newClientWithMockVDI(t)
 
@@ -651,3 +701,299 @@ func TestFindLocalSRsForPool(t *testing.T) {
 		assert.ErrorIs(t, err, apiErr)
 	})
 }
+
+// ---------------------------------------------------------------------------
+// DetachVDIFromVM / DestroyVBD
+// ---------------------------------------------------------------------------
+
+func TestDetachVDIFromVM(t *testing.T) {
+	vmUUID := uuid.Must(uuid.FromString("ffffffff-0000-0000-0000-000000000006"))
+	vbdFilter := fmt.Sprintf("VDI:%s VM:%s", vdiUUID, vmUUID)
+	notFoundErr := errors.New("API error: 404 Not Found")
+
+	t.Run("UnplugsThenDestroys", func(t *testing.T) {
+		c, mockVBD, mockTask := newClientWithMockVBDAndTask(t)
+
+		vbd := &payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID, Attached: true}
+		mockVBD.EXPECT().GetAll(gomock.Any(), 0, vbdFilter).Return([]*payloads.VBD{vbd}, nil)
+		gomock.InOrder(
+			mockVBD.EXPECT().Disconnect(gomock.Any(), vbd.ID).Return(taskID, nil),
+			mockTask.EXPECT().Wait(gomock.Any(), taskID).Return(&payloads.Task{Status: payloads.Success}, nil),
+			mockVBD.EXPECT().Delete(gomock.Any(), vbd.ID).Return(nil),
+		)
+
+		require.NoError(t, c.DetachVDIFromVM(context.Background(), vdiTest, vmUUID))
+	})
+
+	t.Run("DestroysAlreadyUnpluggedVBD", func(t *testing.T) {
+		c, mockVBD, _ := newClientWithMockVBDAndTask(t)
+
+		vbd := &payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID}
+		mockVBD.EXPECT().GetAll(gomock.Any(), 0, vbdFilter).Return([]*payloads.VBD{vbd}, nil)
+		mockVBD.EXPECT().Delete(gomock.Any(), vbd.ID).Return(nil)
+
+		require.NoError(t, c.DetachVDIFromVM(context.Background(), vdiTest, vmUUID))
+	})
+
+	t.Run("NoVBDReturnsErrVBDNotFound", func(t *testing.T) {
+		c, mockVBD, _ := newClientWithMockVBDAndTask(t)
+
+		mockVBD.EXPECT().GetAll(gomock.Any(), 0, vbdFilter).Return([]*payloads.VBD{}, nil)
+
+		err := c.DetachVDIFromVM(context.Background(), vdiTest, vmUUID)
+		assert.ErrorIs(t, err, ErrVBDNotFound)
+	})
+
+	t.Run("VBDVanishingIsNotAnError", func(t *testing.T) {
+		c, mockVBD, mockTask := newClientWithMockVBDAndTask(t)
+
+		vbd := &payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID, Attached: true}
+		mockVBD.EXPECT().GetAll(gomock.Any(), 0, vbdFilter).Return([]*payloads.VBD{vbd}, nil)
+		mockVBD.EXPECT().Disconnect(gomock.Any(), vbd.ID).Return(taskID, nil)
+		mockTask.EXPECT().Wait(gomock.Any(), taskID).Return(&payloads.Task{Status: payloads.Success}, nil)
+		mockVBD.EXPECT().Delete(gomock.Any(), vbd.ID).Return(notFoundErr)
+
+		require.NoError(t, c.DetachVDIFromVM(context.Background(), vdiTest, vmUUID))
+	})
+
+	t.Run("UnplugFailureKeepsVBD", func(t *testing.T) {
+		c, mockVBD, mockTask := newClientWithMockVBDAndTask(t)
+
+		vbd := &payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID, Attached: true}
+		mockVBD.EXPECT().GetAll(gomock.Any(), 0, vbdFilter).Return([]*payloads.VBD{vbd}, nil)
+		mockVBD.EXPECT().Disconnect(gomock.Any(), vbd.ID).Return(taskID, nil)
+		mockTask.EXPECT().Wait(gomock.Any(), taskID).Return(&payloads.Task{
+			Status: payloads.Failure,
+			Result: payloads.Result{Message: "device busy"},
+		}, nil)
+
+		err := c.DetachVDIFromVM(context.Background(), vdiTest, vmUUID)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "device busy")
+	})
+
+	t.Run("DeleteError", func(t *testing.T) {
+		c, mockVBD, _ := newClientWithMockVBDAndTask(t)
+
+		vbd := &payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID}
+		apiErr := errors.New("connection refused")
+		mockVBD.EXPECT().GetAll(gomock.Any(), 0, vbdFilter).Return([]*payloads.VBD{vbd}, nil)
+		mockVBD.EXPECT().Delete(gomock.Any(), vbd.ID).Return(apiErr)
+
+		err := c.DetachVDIFromVM(context.Background(), vdiTest, vmUUID)
+		assert.ErrorIs(t, err, apiErr)
+	})
+}
+
+func TestForceDestroyVBD(t *testing.T) {
+	vmUUID := uuid.Must(uuid.FromString("ffffffff-0000-0000-0000-000000000006"))
+
+	newClient := func(t *testing.T, unplugErr error) (*xoClient, *xoLibMock.MockVBD, *fakeV1Client) {
+		t.Helper()
+		mockVBD := xoLibMock.NewMockVBD(gomock.NewController(t))
+		fakeV1 := &fakeV1Client{err: unplugErr}
+		return &xoClient{Library: stubLibrary{vbd: mockVBD, v1: fakeV1}}, mockVBD, fakeV1
+	}
+
+	t.Run("ForceUnplugsThenDestroys", func(t *testing.T) {
+		c, mockVBD, fakeV1 := newClient(t, nil)
+		vbd := payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID, Attached: true}
+		mockVBD.EXPECT().Delete(gomock.Any(), vbd.ID).Return(nil)
+
+		require.NoError(t, c.ForceDestroyVBD(context.Background(), vbd))
+		assert.Equal(t, []string{vbd.ID.String()}, fakeV1.disconnected)
+	})
+
+	t.Run("DestroysUnpluggedVBD", func(t *testing.T) {
+		c, mockVBD, fakeV1 := newClient(t, nil)
+		vbd := payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID}
+		mockVBD.EXPECT().Delete(gomock.Any(), vbd.ID).Return(nil)
+
+		require.NoError(t, c.ForceDestroyVBD(context.Background(), vbd))
+		assert.Empty(t, fakeV1.disconnected)
+	})
+
+	t.Run("DeleteFailureOfAttachedVBD", func(t *testing.T) {
+		c, mockVBD, _ := newClient(t, nil)
+		vbd := payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID, Attached: true}
+		mockVBD.EXPECT().Delete(gomock.Any(), vbd.ID).Return(errors.New("VBD is currently attached"))
+
+		err := c.ForceDestroyVBD(context.Background(), vbd)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "currently attached")
+	})
+
+	t.Run("UnplugFailureKeepsAttachedVBD", func(t *testing.T) {
+		c, mockVBD, _ := newClient(t, errors.New("VM_BAD_POWER_STATE"))
+		vbd := payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID, Attached: true}
+		mockVBD.EXPECT().Get(gomock.Any(), vbd.ID).Return(&vbd, nil)
+
+		err := c.ForceDestroyVBD(context.Background(), vbd)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "VM_BAD_POWER_STATE")
+	})
+
+	t.Run("UnplugFailureOfVBDUnpluggedMeanwhile", func(t *testing.T) {
+		c, mockVBD, _ := newClient(t, errors.New("DEVICE_ALREADY_DETACHED"))
+		vbd := payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID, Attached: true}
+		mockVBD.EXPECT().Get(gomock.Any(), vbd.ID).Return(&payloads.VBD{ID: vbd.ID, VM: vmUUID}, nil)
+		mockVBD.EXPECT().Delete(gomock.Any(), vbd.ID).Return(nil)
+
+		require.NoError(t, c.ForceDestroyVBD(context.Background(), vbd))
+	})
+
+	t.Run("VBDVanishingIsNotAnError", func(t *testing.T) {
+		c, mockVBD, _ := newClient(t, errors.New("no such VBD"))
+		vbd := payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: vmUUID, Attached: true}
+		mockVBD.EXPECT().Get(gomock.Any(), vbd.ID).Return(nil, errors.New("API error: 404 Not Found"))
+
+		require.NoError(t, c.ForceDestroyVBD(context.Background(), vbd))
+	})
+}
+
+// ---------------------------------------------------------------------------
+// WaitForVDIToBeFullyAttached / waitForTask
+// ---------------------------------------------------------------------------
+
+// fakeEventSource is an EventSource whose notifications are sent by the test.
+type fakeEventSource struct {
+	changed   chan struct{}
+	connected bool
+	waitTask  func(ctx context.Context, taskID string) (*payloads.Task, error)
+}
+
+func (f *fakeEventSource) Subscribe(uuid.UUID) (<-chan struct{}, func()) { return f.changed, func() {} }
+func (f *fakeEventSource) OnChange(func(uuid.UUID, string))              {}
+func (f *fakeEventSource) Connected() bool                               { return f.connected }
+func (f *fakeEventSource) WaitTask(ctx context.Context, taskID string) (*payloads.Task, error) {
+	return f.waitTask(ctx, taskID)
+}
+
+func TestWaitForVDIToBeFullyAttached(t *testing.T) {
+	vbdID := uuid.Must(uuid.NewV4())
+	device := "xvdb"
+	pending := &payloads.VBD{ID: vbdID, Attached: true}
+	attached := &payloads.VBD{ID: vbdID, Attached: true, Device: &device}
+
+	t.Run("WakesUpOnEvent", func(t *testing.T) {
+		c, mockVBD, _ := newClientWithMockVBDAndTask(t)
+		events := &fakeEventSource{changed: make(chan struct{}, 1), connected: true}
+		// Polling alone would never get there before the test times out.
+		c.options = XoClientOptions{PollInterval: time.Hour, Events: events}
+
+		gomock.InOrder(
+			mockVBD.EXPECT().Get(gomock.Any(), vbdID).DoAndReturn(func(context.Context, uuid.UUID) (*payloads.VBD, error) {
+				events.changed <- struct{}{}
+				return pending, nil
+			}),
+			mockVBD.EXPECT().Get(gomock.Any(), vbdID).Return(attached, nil),
+		)
+
+		vbd, err := c.WaitForVDIToBeFullyAttached(context.Background(), vbdID)
+		require.NoError(t, err)
+		assert.Equal(t, &device, vbd.Device)
+	})
+
+	t.Run("PollsWithoutEventSource", func(t *testing.T) {
+		c, mockVBD, _ := newClientWithMockVBDAndTask(t)
+		c.options = XoClientOptions{PollInterval: time.Millisecond}
+
+		gomock.InOrder(
+			mockVBD.EXPECT().Get(gomock.Any(), vbdID).Return(nil, errors.New("transient")),
+			mockVBD.EXPECT().Get(gomock.Any(), vbdID).Return(pending, nil),
+			mockVBD.EXPECT().Get(gomock.Any(), vbdID).Return(attached, nil),
+		)
+
+		_, err := c.WaitForVDIToBeFullyAttached(context.Background(), vbdID)
+		require.NoError(t, err)
+	})
+
+	t.Run("HonoursContextDeadline", func(t *testing.T) {
+		c, mockVBD, _ := newClientWithMockVBDAndTask(t)
+		c.options = XoClientOptions{AttachTimeout: time.Hour, PollInterval: time.Millisecond}
+		mockVBD.EXPECT().Get(gomock.Any(), vbdID).Return(pending, nil).MinTimes(1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := c.WaitForVDIToBeFullyAttached(ctx, vbdID)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("AttachTimeout", func(t *testing.T) {
+		c, mockVBD, _ := newClientWithMockVBDAndTask(t)
+		c.options = XoClientOptions{AttachTimeout: 20 * time.Millisecond, PollInterval: time.Millisecond}
+		mockVBD.EXPECT().Get(gomock.Any(), vbdID).Return(pending, nil).MinTimes(1)
+
+		_, err := c.WaitForVDIToBeFullyAttached(context.Background(), vbdID)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestWaitForTask(t *testing.T) {
+	t.Run("UsesEventSource", func(t *testing.T) {
+		c, _, _ := newClientWithMockVBDAndTask(t)
+		c.options.Events = &fakeEventSource{waitTask: func(_ context.Context, id string) (*payloads.Task, error) {
+			assert.Equal(t, taskID, id)
+			return &payloads.Task{Status: payloads.Success}, nil
+		}}
+
+		task, err := c.waitForTask(context.Background(), taskID)
+		require.NoError(t, err)
+		assert.Equal(t, payloads.Success, task.Status)
+	})
+
+	t.Run("FallsBackToPolling", func(t *testing.T) {
+		c, _, mockTask := newClientWithMockVBDAndTask(t)
+		c.options.Events = &fakeEventSource{waitTask: func(context.Context, string) (*payloads.Task, error) {
+			return nil, ErrEventsUnavailable
+		}}
+		mockTask.EXPECT().Wait(gomock.Any(), taskID).Return(&payloads.Task{Status: payloads.Failure}, nil)
+
+		task, err := c.waitForTask(context.Background(), taskID)
+		require.NoError(t, err)
+		assert.Equal(t, payloads.Failure, task.Status)
+	})
+
+	t.Run("TaskTimeout", func(t *testing.T) {
+		c, _, mockTask := newClientWithMockVBDAndTask(t)
+		c.options.TaskTimeout = 10 * time.Millisecond
+		mockTask.EXPECT().Wait(gomock.Any(), taskID).DoAndReturn(func(ctx context.Context, _ string) (*payloads.Task, error) {
+			<-ctx.Done()
+			return nil, errors.New("task wait timed out")
+		})
+
+		_, err := c.waitForTask(context.Background(), taskID)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Ping
+// ---------------------------------------------------------------------------
+
+func TestPing(t *testing.T) {
+	newClient := func(t *testing.T) (XoClient, *xoLibMock.MockVM) {
+		ctrl := gomock.NewController(t)
+		mockVM := xoLibMock.NewMockVM(ctrl)
+		mockPool := xoLibMock.NewMockPool(ctrl)
+		// The cache is enabled: Ping must reach XO anyway.
+		return NewXoClientWithOptions(stubLibrary{vm: mockVM, pool: mockPool}, XoClientOptions{CacheTTL: time.Minute}), mockVM
+	}
+
+	t.Run("ListsOneVM", func(t *testing.T) {
+		c, mockVM := newClient(t)
+		mockVM.EXPECT().GetAll(gomock.Any(), 1, "").Return(nil, nil).Times(2)
+
+		require.NoError(t, c.Ping(context.Background()))
+		require.NoError(t, c.Ping(context.Background()))
+	})
+
+	t.Run("ReturnsAPIError", func(t *testing.T) {
+		c, mockVM := newClient(t)
+		mockVM.EXPECT().GetAll(gomock.Any(), 1, "").Return(nil, errors.New("invalid token"))
+
+		err := c.Ping(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid token")
+	})
+}
@@ -0,0 +1,71 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+)
+
+// XoInstance is the client of a Xen Orchestra instance of the configuration
+// file.
+type XoInstance struct {
+	// Name is empty for the single instance of a configuration file without
+	// an instances list.
+	Name   string
+	Client XoClient
+}
+
+// FindVolume returns the VDI of the volume of handle and the instance storing
+// it: the instance named in the handle, or else the only instance finding it.
+func FindVolume(ctx context.Context, instances []XoInstance, handle string) (XoInstance, *payloads.VDI, error) {
+	name, _ := SplitVolumeHandle(handle)
+	if name != "" {
+		i := slices.IndexFunc(instances, func(instance XoInstance) bool { return instance.Name == name })
+		if i < 0 {
+			return XoInstance{}, nil, fmt.Errorf("volume %s belongs to Xen Orchestra instance %q, which is not configured", handle, name)
+		}
+		instances = instances[i : i+1]
+	}
+
+	var found []XoInstance
+	var vdi *payloads.VDI
+	for _, instance := range instances {
+		v, err := instance.Client.GetVDIByVolumeId(ctx, handle)
+		if errors.Is(err, ErrVolumeNotFound) {
+			continue
+		}
+		if err != nil {
+			if instance.Name != "" {
+				err = fmt.Errorf("Xen Orchestra instance %q: %w", instance.Name, err)
+			}
+			return XoInstance{}, nil, fmt.Errorf("failed to look up volume %s: %w", handle, err)
+		}
+		found, vdi = append(found, instance), v
+	}
+	switch len(found) {
+	case 0:
+		return XoInstance{}, nil, fmt.Errorf("volume %s: %w", handle, ErrVolumeNotFound)
+	case 1:
+		return found[0], vdi, nil
+	}
+	return XoInstance{}, nil, fmt.Errorf("volume %s is stored by several Xen Orchestra instances, select one with --xo-instance", handle)
+}
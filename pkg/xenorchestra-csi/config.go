@@ -17,16 +17,192 @@ limitations under the License.
 package xenorchestracsi
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"regexp"
 
+	"github.com/gofrs/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
 	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	"k8s.io/client-go/dynamic"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
 )
 
-// LoadXOConfigFromFile loads the XO configuration from the mounted secret file.
-func LoadXOConfigFromFile(configFile string) (xok8s.XoConfig, error) {
+// XoInstanceConfig is a Xen Orchestra instance of the configuration file.
+type XoInstanceConfig struct {
+	// Name identifies the instance in the volume handles. It is empty when
+	// the configuration file holds a single unnamed instance.
+	Name           string `yaml:"name"`
+	xok8s.XoConfig `yaml:",inline"`
+	// Pools lists the pools managed by the instance. When empty, the instance
+	// is asked whether it knows a pool.
+	Pools []uuid.UUID `yaml:"pools,omitempty"`
+}
+
+// xoConfigFile is a configuration file listing several Xen Orchestra
+// instances.
+type xoConfigFile struct {
+	Instances []XoInstanceConfig `yaml:"instances"`
+}
+
+var xoInstanceNameRe = regexp.MustCompile(`^[a-z0-9](?:[-a-z0-9]{0,30}[a-z0-9])?$`)
+
+// LoadXoInstancesFromFile loads the Xen Orchestra instances from the mounted
+// secret file. A file without an instances list holds a single unnamed
+// instance.
+func LoadXoInstancesFromFile(configFile string) ([]XoInstanceConfig, error) {
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		return xok8s.XoConfig{}, fmt.Errorf("config file %s does not exist", configFile)
+		return nil, fmt.Errorf("config file %s does not exist", configFile)
+	}
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", configFile, err)
+	}
+	return parseXoInstances(content)
+}
+
+// LoadXoInstances loads the Xen Orchestra instances from configFile, falling
+// back to a single instance configured by the XOA_* environment variables
+// when the file cannot be read.
+func LoadXoInstances(configFile string) ([]XoInstanceConfig, error) {
+	instances, err := LoadXoInstancesFromFile(configFile)
+	if err == nil {
+		return instances, nil
+	}
+	klog.Warningf("Failed to load config from file %s: %v, falling back to environment variables", configFile, err)
+	xoConfig, err := xok8s.LoadXOConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config from environment variables: %w. "+
+			"Please ensure either a valid config file is mounted or the required environment variables (XOA_URL and XOA_TOKEN) are set", err)
+	}
+	return []XoInstanceConfig{{XoConfig: xoConfig}}, nil
+}
+
+func parseXoInstances(content []byte) ([]XoInstanceConfig, error) {
+	var file xoConfigFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Instances) == 0 {
+		xoConfig, err := xok8s.ReadCloudConfig(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		return []XoInstanceConfig{{XoConfig: xoConfig}}, nil
+	}
+
+	names := map[string]bool{}
+	pools := map[uuid.UUID]string{}
+	for i := range file.Instances {
+		instance := &file.Instances[i]
+		if !xoInstanceNameRe.MatchString(instance.Name) {
+			return nil, fmt.Errorf("instance %d: name %q must be 1 to 32 lowercase alphanumeric characters or '-'", i, instance.Name)
+		}
+		if names[instance.Name] {
+			return nil, fmt.Errorf("instance %q is listed twice", instance.Name)
+		}
+		names[instance.Name] = true
+		// Validate the connection settings the same way as a single instance.
+		section, err := yaml.Marshal(instance.XoConfig)
+		if err != nil {
+			return nil, err
+		}
+		if instance.XoConfig, err = xok8s.ReadCloudConfig(bytes.NewReader(section)); err != nil {
+			return nil, fmt.Errorf("instance %q: %w", instance.Name, err)
+		}
+		for _, pool := range instance.Pools {
+			if other, found := pools[pool]; found {
+				return nil, fmt.Errorf("pool %s is listed by instances %q and %q", pool, other, instance.Name)
+			}
+			pools[pool] = instance.Name
+		}
+	}
+	return file.Instances, nil
+}
+
+// NewKubeClient builds a Kubernetes client from kubeconfig, or from the
+// in-cluster configuration when kubeconfig is empty.
+func NewKubeClient(kubeconfig string) (kube.Interface, error) {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+	kclient, err := kube.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return kclient, nil
+}
+
+// NewDynamicClient returns a client of the custom resources, configured the
+// same way as NewKubeClient.
+func NewDynamicClient(kubeconfig string) (dynamic.Interface, error) {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+	client, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes dynamic client: %w", err)
+	}
+	return client, nil
+}
+
+// NewXoClientFromConfig loads the XO configuration the same way NewDriver does
+// and returns a client for the instance named instance. instance may be empty
+// when the configuration holds a single instance. It is used by the
+// administrative subcommands.
+func NewXoClientFromConfig(configFile, instance string) (clients.XoClient, error) {
+	xoClients, err := NewXoClientsFromConfig(configFile, instance)
+	if err != nil {
+		return nil, err
+	}
+	if len(xoClients) > 1 {
+		return nil, fmt.Errorf("%s lists several Xen Orchestra instances, select one with --xo-instance", configFile)
+	}
+	return xoClients[0], nil
+}
+
+// NewXoClientsFromConfig is NewXoClientFromConfig returning the clients of all
+// the instances when instance is empty.
+func NewXoClientsFromConfig(configFile, instance string) ([]clients.XoClient, error) {
+	instances, err := NewXoInstanceClientsFromConfig(configFile, instance)
+	if err != nil {
+		return nil, err
+	}
+	xoClients := make([]clients.XoClient, 0, len(instances))
+	for _, i := range instances {
+		xoClients = append(xoClients, i.Client)
+	}
+	return xoClients, nil
+}
+
+// NewXoInstanceClientsFromConfig is NewXoClientsFromConfig returning the
+// names of the instances along with their clients.
+func NewXoInstanceClientsFromConfig(configFile, instance string) ([]clients.XoInstance, error) {
+	instances, err := LoadXoInstances(configFile)
+	if err != nil {
+		return nil, err
+	}
+	var xoClients []clients.XoInstance
+	for _, config := range instances {
+		if instance != "" && config.Name != instance {
+			continue
+		}
+		xoSDKClient, err := xok8s.NewXOClient(&config.XoConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Xen Orchestra client: %w", err)
+		}
+		xoClients = append(xoClients, clients.XoInstance{Name: config.Name, Client: clients.NewXoClient(xoSDKClient.Client)})
+	}
+	if len(xoClients) == 0 {
+		return nil, fmt.Errorf("no Xen Orchestra instance named %q in %s", instance, configFile)
 	}
-	return xok8s.ReadCloudConfigFromFile(configFile)
+	return xoClients, nil
 }
@@ -0,0 +1,89 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+)
+
+func TestParseXoInstances(t *testing.T) {
+	poolA := uuid.Must(uuid.FromString("6f0b0c6e-3b2f-4f3c-9a53-6f4b2c0e1a01"))
+
+	t.Run("SingleInstance", func(t *testing.T) {
+		instances, err := parseXoInstances([]byte("url: https://xo.example.com\ntoken: a\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []XoInstanceConfig{{XoConfig: xok8s.XoConfig{URL: "https://xo.example.com", Token: "a"}}}, instances)
+	})
+
+	t.Run("SeveralInstances", func(t *testing.T) {
+		instances, err := parseXoInstances([]byte(`instances:
+  - name: paris
+    url: https://xo.paris.example.com
+    token: a
+    pools: [` + poolA.String() + `]
+  - name: lyon
+    url: https://xo.lyon.example.com
+    username: admin
+    password: p
+`))
+		require.NoError(t, err)
+		assert.Equal(t, []XoInstanceConfig{
+			{Name: "paris", XoConfig: xok8s.XoConfig{URL: "https://xo.paris.example.com", Token: "a"}, Pools: []uuid.UUID{poolA}},
+			{Name: "lyon", XoConfig: xok8s.XoConfig{URL: "https://xo.lyon.example.com", Username: "admin", Password: "p"}},
+		}, instances)
+	})
+
+	errorCases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "InvalidName",
+			content: "instances:\n  - name: Paris/1\n    url: https://xo\n    token: a\n",
+			wantErr: "must be 1 to 32 lowercase",
+		},
+		{
+			name:    "DuplicateName",
+			content: "instances:\n  - name: paris\n    url: https://xo\n    token: a\n  - name: paris\n    url: https://xo2\n    token: b\n",
+			wantErr: "listed twice",
+		},
+		{
+			name:    "InvalidInstance",
+			content: "instances:\n  - name: paris\n    token: a\n",
+			wantErr: `instance "paris"`,
+		},
+		{
+			name: "PoolInTwoInstances",
+			content: "instances:\n  - name: paris\n    url: https://xo\n    token: a\n    pools: [" + poolA.String() + "]\n" +
+				"  - name: lyon\n    url: https://xo2\n    token: b\n    pools: [" + poolA.String() + "]\n",
+			wantErr: "listed by instances",
+		},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseXoInstances([]byte(tt.content))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -15,6 +15,8 @@ limitations under the License.
 */
 package xenorchestracsi
 
+import "time"
+
 const (
 	DriverName = "csi.xenorchestra.vates.tech"
 
@@ -73,4 +75,46 @@ const (
 	// VolumeContextKeyStorageType carries the storageType value through the CSI
 	// lifecycle (CreateVolume → ControllerPublishVolume).
 	VolumeContextKeyStorageType = "storageType"
+
+	// ParameterReplicaPoolID is an optional StorageClass parameter naming the
+	// Xen Orchestra pool the volumes are replicated to for disaster recovery.
+	// The replicas are copied to its DefaultSR every --replication-interval.
+	ParameterReplicaPoolID = "replicaPoolId"
+
+	// ParameterStripes is an optional StorageClass parameter: the number of
+	// VDIs a volume is striped over, from 1 (default, a regular volume) to
+	// MaxStripes. The node assembles them into an md RAID 0 array, for volumes
+	// larger than the size limit of a VDI.
+	ParameterStripes = "stripes"
+
+	// MaxStripes is the highest value of the stripes parameter.
+	MaxStripes = 16
+
+	// DefaultOrphanGCGracePeriod is the default time a VDI must stay unreferenced
+	// by any PersistentVolume before the garbage collector deletes it.
+	// Override with --orphan-gc-grace-period at driver startup.
+	DefaultOrphanGCGracePeriod = 24 * time.Hour
+
+	// DefaultVolumePopulatorMaxConcurrency is the default number of images the
+	// volume populator imports at once. Override with
+	// --volume-populator-max-concurrency.
+	DefaultVolumePopulatorMaxConcurrency = 2
+
+	// DefaultLeaderElectionNamespace is the default namespace of the Leases used
+	// for leader election. Override with --leader-election-namespace.
+	DefaultLeaderElectionNamespace = "kube-system"
+
+	// DefaultShutdownDrainTimeout is how long in-flight CSI calls may run after
+	// SIGTERM before they are cancelled. It stays below the default Kubernetes
+	// termination grace period of 30s. Override with --shutdown-drain-timeout.
+	DefaultShutdownDrainTimeout = 25 * time.Second
+
+	// DefaultProbeCacheTTL is how long the outcome of the readiness checks run
+	// by Probe is reused. Override with --probe-cache-ttl.
+	DefaultProbeCacheTTL = 30 * time.Second
+
+	// DefaultXoConfigReloadInterval is how often the Xen Orchestra
+	// configuration file is checked for changes. Override with
+	// --xo-config-reload-interval.
+	DefaultXoConfigReloadInterval = 30 * time.Second
 )
@@ -41,8 +41,6 @@ func (driver *xenorchestraCSIDriver) ControllerExpandVolume(context.Context, *cs
 
 // ControllerGetCapabilities implements Driver.
 func (driver *xenorchestraCSIDriver) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
-	klog.V(5).Infof("ControllerGetCapabilities called, request: %v", req)
-
 	return &csi.ControllerGetCapabilitiesResponse{
 		Capabilities: []*csi.ControllerServiceCapability{
 			{
@@ -91,8 +89,6 @@ func (driver *xenorchestraCSIDriver) ControllerModifyVolume(context.Context, *cs
 
 // ControllerPublishVolume implements Driver.
 func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
-	klog.V(5).Infof("ControllerPublishVolume called, request: %v", req)
-
 	vmUUID, err := uuid.FromString(req.GetNodeId())
 	if err != nil || vmUUID == uuid.Nil {
 		return nil, status.Errorf(codes.InvalidArgument, "node ID is required")
@@ -107,7 +103,13 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 		return nil, status.Errorf(codes.InvalidArgument, "volume ID is required")
 	}
 
-	vdi, err := driver.xoClient.GetVDIByVolumeId(ctx, volumeId)
+	release, err := driver.lockVolume(volumeId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	vdi, members, err := driver.lookupVolume(ctx, volumeId)
 	if err != nil {
 		if errors.Is(err, clients.ErrVolumeNotFound) {
 			klog.V(2).InfoS("Volume handle not found during ControllerPublishVolume", "volumeID", volumeId)
@@ -115,12 +117,15 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 		}
 		return nil, status.Errorf(codes.Internal, "failed to look up volume %s: %v", volumeId, err)
 	}
+	if members != nil {
+		return driver.publishStripedVolume(ctx, volumeId, vmUUID, members)
+	}
 
 	// Adopt the VDI into this cluster's tag set if the tag is not already present.
 	// This ensures static (pre-existing) VDIs are visible without requiring manual
 	// re-tagging.
 	if driver.clusterTag != "" && !slices.Contains(vdi.Tags, driver.clusterTag) {
-		if err := driver.xoClient.VDI().AddTag(ctx, vdi.ID, driver.clusterTag); err != nil {
+		if err := driver.xo(ctx).VDI().AddTag(ctx, vdi.ID, driver.clusterTag); err != nil {
 			klog.ErrorS(err, "Failed to add cluster tag to VDI", "vdiID", vdi.ID, "tag", driver.clusterTag)
 			return nil, status.Errorf(codes.Internal, "failed to add cluster tag to VDI %s: %v", vdi.ID, err)
 		}
@@ -128,7 +133,7 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 	}
 
 	// Get Node/VM
-	nodeVM, err := driver.xoClient.VM().GetByID(ctx, vmUUID)
+	nodeVM, err := driver.xo(ctx).VM().GetByID(ctx, vmUUID)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "failed to get VM by ID %s: %v", vmUUID, err)
 	}
@@ -139,7 +144,7 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 
 	// For local storage, migrate the VDI to the host's local SR if needed.
 	if req.GetVolumeContext()[VolumeContextKeyStorageType] == StorageTypeLocal {
-		localSR, err := driver.xoClient.FindLocalSRForHost(ctx, nodeVM.Container)
+		localSR, err := driver.xo(ctx).FindLocalSRForHost(ctx, nodeVM.Container)
 		if err != nil {
 			return nil, status.Errorf(codes.FailedPrecondition,
 				"no local SR found for host %s: %v", nodeVM.Container, err)
@@ -147,13 +152,13 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 		if vdi.SR != localSR.ID {
 			klog.V(2).InfoS("Migrating VDI to local SR",
 				"vdiID", vdi.ID, "fromSR", vdi.SR, "toSR", localSR.ID)
-			newVDIUUID, err := driver.xoClient.MigrateVDIAndWait(ctx, *vdi, localSR.ID)
+			newVDIUUID, err := driver.xo(ctx).MigrateVDIAndWait(ctx, *vdi, localSR.ID)
 			if err != nil {
 				klog.ErrorS(err, "Failed to migrate VDI to local SR", "vdiID", vdi.ID, "localSRID", localSR.ID)
 				return nil, status.Errorf(codes.Internal,
 					"failed to migrate VDI %s to local SR %s: %v", vdi.ID, localSR.ID, err)
 			}
-			vdi, err = driver.xoClient.VDI().Get(ctx, newVDIUUID)
+			vdi, err = driver.xo(ctx).VDI().Get(ctx, newVDIUUID)
 			if err != nil {
 				klog.ErrorS(err, "Failed to fetch VDI after migration", "newVDIID", newVDIUUID)
 				return nil, status.Errorf(codes.Internal,
@@ -168,13 +173,28 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 
 	// Verify the SR is reachable from the host where the VM is running before attempting
 	// to attach or connect any VBD.
-	if err := driver.xoClient.IsSRAttachedToHost(ctx, vdi.SR, nodeVM.Container); err != nil {
+	if err := driver.xo(ctx).IsSRAttachedToHost(ctx, vdi.SR, nodeVM.Container); err != nil {
 		klog.ErrorS(err, "SR is not attached to VM host", "srID", vdi.SR, "hostID", nodeVM.Container, "vmUUID", vmUUID)
 		return nil, status.Errorf(codes.FailedPrecondition, "SR is not attached to the VM host: %v", err)
 	}
 
+	vbd, err := driver.attachVDIToNode(ctx, vdi, volumeId, vmUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the publish context with the VBD ID and device name
+	return &csi.ControllerPublishVolumeResponse{
+		PublishContext: publishContextFromVBD(*vbd),
+	}, nil
+}
+
+// attachVDIToNode attaches vdi to the node VM vmUUID, unless it already is,
+// and returns its VBD once it has a device name. The VBDs of VMs that are not
+// running are detached first.
+func (driver *xenorchestraCSIDriver) attachVDIToNode(ctx context.Context, vdi *payloads.VDI, volumeId string, vmUUID uuid.UUID) (*payloads.VBD, error) {
 	// Check the VDI is not already attached to another VM
-	vbds, err := driver.xoClient.IsVDIUsedAnywhere(ctx, vdi)
+	vbds, err := driver.xo(ctx).IsVDIUsedAnywhere(ctx, vdi)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to check if VDI is already attached: %v", err)
 	}
@@ -183,8 +203,11 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 		var vbdToAttach *payloads.VBD
 		for _, vbd := range vbds {
 			if vbd.Attached && vbd.VM != vmUUID {
-				klog.ErrorS(err, "VDI is already attached to another VM", "vdi", vdi.ID, "vmID", vbd.VM)
-				return nil, status.Errorf(codes.FailedPrecondition, "VDI %s is already attached to another VM %s", vdi.ID, vbd.VM)
+				// Only a VM that is not running may lose the VDI, e.g. after a hypervisor crash.
+				if err := driver.forceDetachStaleVBD(ctx, vdi, volumeId, vbd, vmUUID); err != nil {
+					return nil, err
+				}
+				continue
 			} else if vbd.VM == vmUUID {
 				vbdToAttach = vbd
 				// Continue to check all VDB to be sure the VDI ins't connected to any VM
@@ -195,28 +218,28 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 			// The VDI is already added to this VM; connect it if not yet hot-plugged.
 			if !vbdToAttach.Attached {
 				klog.V(5).InfoS("Connecting existing VBD to VM", "vbd", *vbdToAttach, "vmUUID", vmUUID)
-				vbdConnected, err := driver.xoClient.ConnectVBDToVM(ctx, *vbdToAttach)
+				var vbdConnected *payloads.VBD
+				err := driver.vmQueue.Do(ctx, vmUUID, vmOperationConnect, func() (err error) {
+					vbdConnected, err = driver.xo(ctx).ConnectVBDToVM(ctx, *vbdToAttach)
+					return err
+				})
 				if err != nil {
 					klog.ErrorS(err, "Failed to connect VBD to VM", "vbd", *vbdToAttach, "vmUUID", vmUUID)
-					return nil, status.Errorf(codes.Internal, "Failed to connect VBD to VM: %v", err)
+					return nil, status.Errorf(waitErrorCode(err), "Failed to connect VBD to VM: %v", err)
 				}
-				return &csi.ControllerPublishVolumeResponse{
-					PublishContext: publishContextFromVBD(*vbdConnected),
-				}, nil
+				return vbdConnected, nil
 			}
 			klog.V(2).InfoS("VDI already attached to the node", "vbd", vbdToAttach)
 			if vbdToAttach.Device == nil {
 				klog.ErrorS(nil, "Device name is not yet assigned to the VBD, waiting...", "vbd", vbdToAttach)
-				vbdToAttach, err = driver.xoClient.WaitForVDIToBeFullyAttached(ctx, vbdToAttach.ID)
+				vbdToAttach, err = driver.xo(ctx).WaitForVDIToBeFullyAttached(ctx, vbdToAttach.ID)
 				if err != nil {
 					klog.ErrorS(err, "Failed to wait for VBD to be fully attached", "vbd", vbdToAttach)
-					return nil, status.Errorf(codes.Internal, "Failed to wait for VBD to be fully attached: %v", err)
+					return nil, status.Errorf(waitErrorCode(err), "Failed to wait for VBD to be fully attached: %v", err)
 				}
 				klog.V(5).InfoS("VBD is now fully attached with device name assigned", "vbd", vbdToAttach)
 			}
-			return &csi.ControllerPublishVolumeResponse{
-				PublishContext: publishContextFromVBD(*vbdToAttach),
-			}, nil
+			return vbdToAttach, nil
 		} else {
 			// Else, it means the VDI is added to a VM (= has VBD) but is not attached (connected) to it
 			// We can continue to attach it to the node
@@ -225,23 +248,22 @@ func (driver *xenorchestraCSIDriver) ControllerPublishVolume(ctx context.Context
 	}
 
 	klog.V(5).InfoS("Attaching VDI to VM", "vdi", vdi, "vmUUID", vmUUID)
-	vbd, err := driver.xoClient.AttachVDIToVM(ctx, *vdi, vmUUID)
+	var vbd *payloads.VBD
+	err = driver.vmQueue.Do(ctx, vmUUID, vmOperationAttach, func() (err error) {
+		vbd, err = driver.xo(ctx).AttachVDIToVM(ctx, *vdi, vmUUID)
+		return err
+	})
 	if err != nil {
 		klog.ErrorS(err, "Failed to attach VDI to VM", "vdi", vdi, "vmUUID", vmUUID)
-		return nil, status.Errorf(codes.Internal, "Failed to attach VDI to VM: %v", err)
+		return nil, status.Errorf(waitErrorCode(err), "Failed to attach VDI to VM: %v", err)
 	}
 	klog.V(5).InfoS("VDI attached to VM", "vmUUID", vmUUID, "vbd", vbd)
 
-	// Return the publish context with the VBD ID and device name
-	return &csi.ControllerPublishVolumeResponse{
-		PublishContext: publishContextFromVBD(*vbd),
-	}, nil
+	return vbd, nil
 }
 
 // ControllerUnpublishVolume implements Driver.
 func (driver *xenorchestraCSIDriver) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
-	klog.V(5).Infof("ControllerUnpublishVolume called, request: %v", req)
-
 	vmUUID, err := uuid.FromString(req.GetNodeId())
 	if err != nil || vmUUID == uuid.Nil {
 		return nil, status.Errorf(codes.InvalidArgument, "node ID is required")
@@ -252,7 +274,13 @@ func (driver *xenorchestraCSIDriver) ControllerUnpublishVolume(ctx context.Conte
 		return nil, status.Errorf(codes.InvalidArgument, "volume ID is required")
 	}
 
-	vdi, err := driver.xoClient.GetVDIByVolumeId(ctx, volumeId)
+	release, err := driver.lockVolume(volumeId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	vdi, members, err := driver.lookupVolume(ctx, volumeId)
 	if err != nil {
 		if errors.Is(err, clients.ErrVolumeNotFound) {
 			// VDI is already gone; idempotent success.
@@ -261,17 +289,27 @@ func (driver *xenorchestraCSIDriver) ControllerUnpublishVolume(ctx context.Conte
 		}
 		return nil, status.Errorf(codes.Internal, "failed to look up volume %s: %v", volumeId, err)
 	}
+	if members != nil {
+		if err := driver.unpublishStripedVolume(ctx, vmUUID, members); err != nil {
+			return nil, err
+		}
+		return &csi.ControllerUnpublishVolumeResponse{}, nil
+	}
 
-	err = driver.xoClient.DisconnectVBDFromVM(ctx, *vdi, vmUUID)
+	// Destroy the VBD rather than only unplugging it, otherwise every node VM
+	// keeps a dead VBD record for each volume it ever mounted.
+	err = driver.vmQueue.Do(ctx, vmUUID, vmOperationDetach, func() error {
+		return driver.xo(ctx).DetachVDIFromVM(ctx, *vdi, vmUUID)
+	})
 	if err != nil {
-		// Ignore not found errors as the VBD may have already been detached
+		// Ignore not found errors as the VBD may have already been destroyed
 		if !errors.Is(err, clients.ErrVBDNotFound) {
 			klog.ErrorS(err, "Failed to detach VDI from VM", "vdiID", vdi.ID, "vmUUID", vmUUID)
 			return nil, status.Errorf(codes.Internal, "Failed to detach VDI from VM: %v", err)
 		}
 		klog.V(5).InfoS("VBD not found, already detached", "vdiID", vdi.ID, "vmUUID", vmUUID)
 	}
-	klog.V(5).InfoS("VBD disconnected from VM", "vdiID", vdi.ID, "vmUUID", vmUUID)
+	klog.V(5).InfoS("VBD detached from VM", "vdiID", vdi.ID, "vmUUID", vmUUID)
 
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
@@ -284,13 +322,17 @@ func (driver *xenorchestraCSIDriver) CreateSnapshot(context.Context, *csi.Create
 
 // CreateVolume implements Driver.
 func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
-	klog.V(5).Infof("CreateVolume called, request: %v", req)
-
 	volumeName := req.GetName()
 	if volumeName == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "disk name is required")
 	}
 
+	release, err := driver.lockVolume(volumeName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	if req.VolumeContentSource != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "volume content source is not supported")
 	}
@@ -337,6 +379,30 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 			"invalid storageType %q: must be %q or %q", storageType, StorageTypeShared, StorageTypeLocal)
 	}
 
+	var replicaPoolID uuid.UUID
+	if value := params[ParameterReplicaPoolID]; value != "" {
+		replicaPoolID, err = uuid.FromString(value)
+		if err != nil || replicaPoolID == uuid.Nil {
+			return nil, status.Errorf(codes.InvalidArgument, "parameter %q must be a valid UUID, got %q", ParameterReplicaPoolID, value)
+		}
+	}
+
+	stripes, err := parseStripes(params)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if stripes > 1 {
+		switch {
+		case capacityBytes == 0:
+			return nil, status.Errorf(codes.InvalidArgument, "a capacity is required for a volume striped over %d VDIs", stripes)
+		case storageType == StorageTypeLocal:
+			return nil, status.Errorf(codes.InvalidArgument, "parameter %q is not supported with storageType %q", ParameterStripes, StorageTypeLocal)
+		case replicaPoolID != uuid.Nil:
+			return nil, status.Errorf(codes.InvalidArgument, "parameter %q is not supported with parameter %q", ParameterStripes, ParameterReplicaPoolID)
+		}
+	}
+
+	var instance *xoInstance
 	var pool *payloads.Pool
 	var sr *payloads.StorageRepository
 
@@ -349,7 +415,7 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 		if err := topology.ValidatePoolIDAgainstRequisite(ar, poolUUID); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
-		pool, sr, err = topology.SelectPoolAndStorage(ctx, driver.xoClient.SR(), driver.xoClient.Pool(), []uuid.UUID{poolUUID})
+		instance, pool, sr, err = driver.selectPoolAndStorage(ctx, []uuid.UUID{poolUUID})
 		if err != nil {
 			klog.ErrorS(err, "Pool or SR not viable", "poolID", poolUUID)
 			return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
@@ -368,7 +434,7 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 			klog.V(2).InfoS("No pool topology found in accessibility_requirements, falling back to tag-based pool discovery",
 				"kubernetesPoolTag", driver.kubernetesPoolTag)
 
-			orderedPoolIDs, err = topology.TaggedPoolIDs(ctx, driver.xoClient.Pool(), driver.kubernetesPoolTag)
+			orderedPoolIDs, err = driver.taggedPoolIDs(ctx)
 			if err != nil {
 				return nil, status.Errorf(codes.Internal,
 					"failed to list pools for tag-based fallback: %v", err)
@@ -379,7 +445,7 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 					driver.kubernetesPoolTag)
 			}
 		}
-		pool, sr, err = topology.SelectPoolAndStorage(ctx, driver.xoClient.SR(), driver.xoClient.Pool(), orderedPoolIDs)
+		instance, pool, sr, err = driver.selectPoolAndStorage(ctx, orderedPoolIDs)
 		if err != nil {
 			klog.ErrorS(err, "No viable pool found in accessibility_requirements")
 			return nil, status.Errorf(codes.ResourceExhausted, "%v", err)
@@ -389,12 +455,26 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 
 	klog.V(5).InfoS("Using pool and SR", "poolID", pool.ID, "srID", sr.ID)
 
+	// The rest of the call uses the Xen Orchestra instance of the pool.
+	if instance != nil {
+		client, release := instance.client().Pin()
+		defer release()
+		ctx = context.WithValue(ctx, xoClientKey{}, client)
+		klog.V(5).InfoS("Using Xen Orchestra instance of the pool", "instance", instance.name, "poolID", pool.ID)
+	}
+
+	if replicaPoolID != uuid.Nil {
+		if err := driver.validateReplicaPool(ctx, pool, replicaPoolID); err != nil {
+			return nil, err
+		}
+	}
+
 	// For local storage, override the SR with one of the pool's local SRs so
 	// the VDI lands on local storage from the start rather than on the shared
 	// DefaultSR. That will help avoid an extra migration step in the common case
 	// where the volume is created and attached to the same node.
 	if storageType == StorageTypeLocal {
-		localSRs, err := driver.xoClient.FindLocalSRsForPool(ctx, pool.ID)
+		localSRs, err := driver.xo(ctx).FindLocalSRsForPool(ctx, pool.ID)
 		if err != nil {
 			return nil, status.Errorf(codes.FailedPrecondition, "no local SR available in pool %s: %v", pool.ID, err)
 		}
@@ -402,8 +482,27 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 		klog.V(4).InfoS("Local storageType: using local SR for initial VDI creation", "poolID", pool.ID, "srID", sr.ID)
 	}
 
+	if stripes > 1 {
+		if err := validateStripeMemberSize(capacityBytes, stripes, sr); err != nil {
+			return nil, err
+		}
+		volumeID, err := driver.createStripedVolume(ctx, sr, volumeName, capacityBytes, stripes)
+		if err != nil {
+			return nil, err
+		}
+		klog.V(5).InfoS("Striped volume created", "volumeID", volumeID, "volumeName", volumeName, "stripes", stripes)
+		return &csi.CreateVolumeResponse{
+			Volume: &csi.Volume{
+				VolumeId:           instance.volumeHandle(volumeID),
+				CapacityBytes:      capacityBytes,
+				AccessibleTopology: driver.buildAccessibleTopology(pool),
+				VolumeContext:      buildVolumeContext(pool, sr, storageType),
+			},
+		}, nil
+	}
+
 	// Idempotency check: return the existing VDI if one was already created for this PV name.
-	existingVDI, existingId, err := driver.xoClient.FindVDIByVolumeName(ctx, volumeName)
+	existingVDI, existingId, err := driver.xo(ctx).FindVDIByVolumeName(ctx, volumeName)
 	if err != nil {
 		if errors.Is(err, clients.ErrVolumeNotFound) {
 			existingVDI = nil
@@ -420,10 +519,13 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 		if existingId == "" {
 			return nil, status.Errorf(codes.Internal, "existing VDI %s is missing volume ID in tags", existingVDI.ID)
 		}
+		if err := driver.tagReplicaPool(ctx, existingVDI.ID, existingVDI.Tags, replicaPoolID); err != nil {
+			return nil, err
+		}
 		klog.V(5).InfoS("Volume already exists, returning existing VDI", "vdiID", existingVDI.ID, "volumeId", existingId)
 		return &csi.CreateVolumeResponse{
 			Volume: &csi.Volume{
-				VolumeId:           existingId,
+				VolumeId:           instance.volumeHandle(existingId),
 				CapacityBytes:      capacityBytes,
 				AccessibleTopology: driver.buildAccessibleTopology(pool),
 				VolumeContext:      buildVolumeContext(pool, sr, storageType),
@@ -431,16 +533,22 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 		}, nil
 	}
 
-	vdiID, volumeID, err := driver.xoClient.CreateNewVolume(ctx, sr.ID, driver.vdiNamePrefix, capacityBytes, volumeName, driver.Name+"@"+driver.Version, driver.clusterTag)
+	vdiID, volumeID, err := driver.xo(ctx).CreateNewVolume(ctx, sr.ID, driver.vdiNamePrefix, capacityBytes, volumeName, driver.Name+"@"+driver.Version, driver.clusterTag)
 	if err != nil {
 		klog.ErrorS(err, "Failed to create VDI", "volumeName", volumeName, "capacityBytes", capacityBytes)
+		// The SR may have changed since it was cached: read it again on retry.
+		driver.xo(ctx).InvalidateCache(sr.ID, pool.ID)
 		return nil, status.Errorf(codes.Internal, "Failed to create VDI: %v", err)
 	}
 	klog.V(5).InfoS("VDI created", "vdiID", vdiID, "volumeID", volumeID, "volumeName", volumeName)
+	// A failure leaves the VDI untagged: the retried call finds it and tags it.
+	if err := driver.tagReplicaPool(ctx, vdiID, nil, replicaPoolID); err != nil {
+		return nil, err
+	}
 
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
-			VolumeId:           volumeID.String(),
+			VolumeId:           instance.volumeHandle(volumeID.String()),
 			CapacityBytes:      capacityBytes,
 			AccessibleTopology: driver.buildAccessibleTopology(pool),
 			VolumeContext:      buildVolumeContext(pool, sr, storageType),
@@ -448,6 +556,40 @@ func (driver *xenorchestraCSIDriver) CreateVolume(ctx context.Context, req *csi.
 	}, nil
 }
 
+// validateReplicaPool checks that the volumes of pool can be replicated to
+// the pool replicaPoolID: another pool of the same Xen Orchestra instance,
+// with a default SR to store the replicas.
+func (driver *xenorchestraCSIDriver) validateReplicaPool(ctx context.Context, pool *payloads.Pool, replicaPoolID uuid.UUID) error {
+	if replicaPoolID == pool.ID {
+		return status.Errorf(codes.InvalidArgument, "parameter %q must name another pool than the volume pool %s", ParameterReplicaPoolID, pool.ID)
+	}
+	replicaPool, err := driver.xo(ctx).Pool().Get(ctx, replicaPoolID)
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "replica pool %s not found in the Xen Orchestra instance of pool %s: %v", replicaPoolID, pool.ID, err)
+	}
+	if replicaPool.DefaultSR == uuid.Nil {
+		return status.Errorf(codes.FailedPrecondition, "replica pool %s has no default SR", replicaPoolID)
+	}
+	return nil
+}
+
+// tagReplicaPool tags the VDI vdiID of a volume with the pool it is
+// replicated to, unless tags already holds that tag.
+func (driver *xenorchestraCSIDriver) tagReplicaPool(ctx context.Context, vdiID uuid.UUID, tags []string, replicaPoolID uuid.UUID) error {
+	if replicaPoolID == uuid.Nil {
+		return nil
+	}
+	tag := clients.BuildTag(clients.VDITagKeyReplicaPoolID, replicaPoolID.String())
+	if slices.Contains(tags, tag) {
+		return nil
+	}
+	if err := driver.xo(ctx).VDI().AddTag(ctx, vdiID, tag); err != nil {
+		klog.ErrorS(err, "Failed to tag VDI with its replica pool", "vdiID", vdiID, "replicaPoolID", replicaPoolID)
+		return status.Errorf(codes.Internal, "failed to tag VDI %s with replica pool %s: %v", vdiID, replicaPoolID, err)
+	}
+	return nil
+}
+
 // DeleteSnapshot implements Driver.
 func (driver *xenorchestraCSIDriver) DeleteSnapshot(context.Context, *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
 	klog.Error("DeleteSnapshot is not implemented")
@@ -456,14 +598,18 @@ func (driver *xenorchestraCSIDriver) DeleteSnapshot(context.Context, *csi.Delete
 
 // DeleteVolume implements Driver.
 func (driver *xenorchestraCSIDriver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
-	klog.V(5).Infof("DeleteVolume called, request: %v", req)
-
 	volumeID := req.GetVolumeId()
 	if volumeID == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "volume ID is required")
 	}
 
-	vdi, err := driver.xoClient.GetVDIByVolumeId(ctx, volumeID)
+	release, err := driver.lockVolume(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	vdi, members, err := driver.lookupVolume(ctx, volumeID)
 	if err != nil {
 		if errors.Is(err, clients.ErrVolumeNotFound) {
 			klog.V(5).InfoS("VDI not found, treating as already deleted", "volumeID", volumeID)
@@ -477,8 +623,16 @@ func (driver *xenorchestraCSIDriver) DeleteVolume(ctx context.Context, req *csi.
 		return nil, status.Errorf(codes.Internal, "failed to look up volume %s: %v", volumeID, err)
 	}
 
+	if members != nil {
+		if err := driver.deleteStripedVolume(ctx, volumeID, members); err != nil {
+			return nil, err
+		}
+		klog.V(5).InfoS("Striped volume deleted successfully", "volumeID", volumeID, "members", len(members))
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
 	// Refuse to delete a VDI that is still attached to a VM.
-	vbds, err := driver.xoClient.IsVDIUsedAnywhere(ctx, vdi)
+	vbds, err := driver.xo(ctx).IsVDIUsedAnywhere(ctx, vdi)
 	if err != nil {
 		klog.ErrorS(err, "Failed to check VDI attachments", "vdiID", vdi.ID)
 		return nil, status.Errorf(codes.Internal, "failed to check VDI attachments for %s: %v", vdi.ID, err)
@@ -490,7 +644,7 @@ func (driver *xenorchestraCSIDriver) DeleteVolume(ctx context.Context, req *csi.
 		}
 	}
 
-	if err := driver.xoClient.VDI().Delete(ctx, vdi.ID); err != nil {
+	if err := driver.xo(ctx).VDI().Delete(ctx, vdi.ID); err != nil {
 		if clients.IsNotFoundError(err) {
 			// Deleted by a concurrent call between our lookup and Delete
 			klog.V(4).InfoS("VDI not found during delete call, already deleted by concurrent call", "volumeID", volumeID, "vdiID", vdi.ID)
@@ -524,8 +678,6 @@ func (driver *xenorchestraCSIDriver) ListVolumes(context.Context, *csi.ListVolum
 
 // ValidateVolumeCapabilities implements Driver.
 func (driver *xenorchestraCSIDriver) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
-	klog.V(5).Info("ValidateVolumeCapabilities called", "request", req)
-
 	volumeID := req.GetVolumeId()
 	if volumeID == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "Volume ID is required")
@@ -535,8 +687,8 @@ func (driver *xenorchestraCSIDriver) ValidateVolumeCapabilities(ctx context.Cont
 		return nil, status.Errorf(codes.InvalidArgument, "At least one volume capability is required")
 	}
 
-	_, err := driver.xoClient.GetVDIByVolumeId(ctx, volumeID)
-	if err != nil {
+	_, err := driver.xo(ctx).GetVDIByVolumeId(ctx, volumeID)
+	if err != nil && !errors.Is(err, clients.ErrVolumeStriped) {
 		if errors.Is(err, clients.ErrVolumeNotFound) {
 			klog.V(2).InfoS("VDI not found during ValidateVolumeCapabilities", "volumeID", volumeID)
 			return nil, status.Errorf(codes.NotFound, "Volume %s not found", volumeID)
@@ -582,9 +734,77 @@ func buildVolumeContext(pool *payloads.Pool, sr *payloads.StorageRepository, sto
 	}
 }
 
+// selectPoolAndStorage runs topology.SelectPoolAndStorage on the cached pools
+// and SRs, and again on fresh ones if no candidate is viable. When the call is
+// routed between several Xen Orchestra instances, each pool is checked, in
+// order, with the client of its instance, and the instance of the selected
+// pool is returned. It is nil when the call is not routed.
+func (driver *xenorchestraCSIDriver) selectPoolAndStorage(ctx context.Context, orderedPoolIDs []uuid.UUID) (*xoInstance, *payloads.Pool, *payloads.StorageRepository, error) {
+	instances := driver.routedInstances(ctx)
+	switch {
+	case instances == nil:
+		pool, sr, err := selectPoolAndStorageWith(ctx, driver.xo(ctx), orderedPoolIDs)
+		return nil, pool, sr, err
+	case len(instances.list) == 1:
+		pool, sr, err := selectPoolAndStorageWith(ctx, instances.list[0].client(), orderedPoolIDs)
+		return instances.list[0], pool, sr, err
+	}
+
+	var errs []error
+	for _, poolID := range orderedPoolIDs {
+		instance, err := instances.forPool(ctx, poolID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pool, sr, err := selectPoolAndStorageWith(ctx, instance.client(), []uuid.UUID{poolID})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return instance, pool, sr, nil
+	}
+	if len(errs) == 0 {
+		errs = append(errs, errors.New("no candidate pool"))
+	}
+	return nil, nil, nil, errors.Join(errs...)
+}
+
+func selectPoolAndStorageWith(ctx context.Context, xoClient clients.XoClient, orderedPoolIDs []uuid.UUID) (*payloads.Pool, *payloads.StorageRepository, error) {
+	pool, sr, err := topology.SelectPoolAndStorage(ctx, xoClient.SR(), xoClient.Pool(), orderedPoolIDs)
+	if err != nil {
+		klog.V(4).InfoS("No viable pool in cached XO objects, refreshing", "err", err)
+		pool, sr, err = topology.SelectPoolAndStorage(clients.WithoutCache(ctx), xoClient.SR(), xoClient.Pool(), orderedPoolIDs)
+	}
+	return pool, sr, err
+}
+
+// taggedPoolIDs returns the pools tagged with --kubernetes-pool-tag, in every
+// Xen Orchestra instance when the call is routed between several.
+func (driver *xenorchestraCSIDriver) taggedPoolIDs(ctx context.Context) ([]uuid.UUID, error) {
+	if instances := driver.routedInstances(ctx); instances != nil {
+		return instances.taggedPoolIDs(ctx, driver.kubernetesPoolTag)
+	}
+	return topology.TaggedPoolIDs(ctx, driver.xo(ctx).Pool(), driver.kubernetesPoolTag)
+}
+
 func publishContextFromVBD(vbd payloads.VBD) map[string]string {
 	return map[string]string{
 		"device": *vbd.Device,
 		"vbd":    vbd.ID.String(),
 	}
 }
+
+// waitErrorCode returns the gRPC code of an error returned while waiting for
+// Xen Orchestra: DeadlineExceeded or Canceled when the wait was cut short, so
+// that the CO retries, and Internal otherwise.
+func waitErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,397 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package doctor checks that Xen Orchestra and Kubernetes are set up the way
+// the driver expects, and explains how to fix what is not.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/topology"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+)
+
+// Names of the checks.
+const (
+	CheckXoAPI       = "xo-api"
+	CheckTaggedPools = "tagged-pools"
+	CheckDefaultSR   = "default-sr"
+	CheckSRPBDs      = "sr-pbds"
+	CheckKubernetes  = "kubernetes"
+	CheckProviderID  = "provider-id"
+	CheckNodeVM      = "node-vm"
+	CheckLocalSR     = "local-sr"
+	CheckPVDrivers   = "pv-drivers"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	// StatusWarn reports something that only breaks some setups.
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Check is the outcome of a check on a subject, such as a pool, an SR or a
+// node.
+type Check struct {
+	Instance string `json:"instance,omitempty"`
+	Name     string `json:"name"`
+	Subject  string `json:"subject"`
+	Status   Status `json:"status"`
+	Message  string `json:"message"`
+	// Hint explains how to fix a failure or a warning.
+	Hint string `json:"hint,omitempty"`
+}
+
+// Report lists the checks run.
+type Report struct {
+	Checks []Check `json:"checks"`
+}
+
+// Count returns the number of checks of the report with status.
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Options configures Run.
+type Options struct {
+	// DriverName is the CSI driver name StorageClasses must reference.
+	DriverName string
+	// KubernetesPoolTag is the --kubernetes-pool-tag of the driver.
+	KubernetesPoolTag string
+	// NodeMetadataFromXoAPI is set when the node plugin runs with
+	// --node-metadata-source=xo-api: the nodes need no providerID, their VM is
+	// found by their SystemUUID.
+	NodeMetadataFromXoAPI bool
+}
+
+// Run checks every instance, then every node of the cluster when kubeClient
+// is not nil. Only the errors listing the Kubernetes objects are returned:
+// the failures of Xen Orchestra are reported as failed checks.
+func Run(ctx context.Context, instances []clients.XoInstance, kubeClient kube.Interface, opts Options) (*Report, error) {
+	report := &Report{Checks: []Check{}}
+	reachable := make([]clients.XoInstance, 0, len(instances))
+	for _, instance := range instances {
+		if checkInstance(ctx, report, instance, opts) {
+			reachable = append(reachable, instance)
+		}
+	}
+
+	if kubeClient == nil {
+		report.add(Check{
+			Name:    CheckKubernetes,
+			Subject: "cluster",
+			Status:  StatusWarn,
+			Message: "no kubeconfig available, the nodes were not checked",
+			Hint:    "Pass --kubeconfig, or run the command in a pod of the cluster.",
+		})
+		return report, nil
+	}
+	localStorage, err := usesLocalStorage(ctx, kubeClient, opts.DriverName)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		checkNode(ctx, report, reachable, &node, opts.NodeMetadataFromXoAPI, localStorage)
+	}
+	return report, nil
+}
+
+func (r *Report) add(check Check) {
+	r.Checks = append(r.Checks, check)
+}
+
+// checkInstance checks the credentials and the pools of instance. It returns
+// false when the instance cannot be queried.
+func checkInstance(ctx context.Context, report *Report, instance clients.XoInstance, opts Options) bool {
+	xoClient := instance.Client
+	subject := "Xen Orchestra"
+	if err := xoClient.Ping(ctx); err != nil {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckXoAPI, Subject: subject, Status: StatusFail,
+			Message: err.Error(),
+			Hint:    "Check the URL and the token of the configuration file. The token user needs read access to the pools, hosts, SRs and VMs, and write access to the VDIs and VBDs.",
+		})
+		return false
+	}
+	report.add(Check{Instance: instance.Name, Name: CheckXoAPI, Subject: subject, Status: StatusPass, Message: "the API answers with the configured token"})
+
+	if opts.KubernetesPoolTag == "" {
+		return true
+	}
+	poolIDs, err := topology.TaggedPoolIDs(ctx, xoClient.Pool(), opts.KubernetesPoolTag)
+	if err != nil {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckTaggedPools, Subject: subject, Status: StatusFail,
+			Message: err.Error(),
+			Hint:    "The token user needs read access to the pools.",
+		})
+		return true
+	}
+	if len(poolIDs) == 0 {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckTaggedPools, Subject: subject, Status: StatusFail,
+			Message: fmt.Sprintf("no pool is tagged %q", opts.KubernetesPoolTag),
+			Hint:    fmt.Sprintf("Tag the pools volumes may be created in with %q in Xen Orchestra, or set a poolId parameter on every StorageClass.", opts.KubernetesPoolTag),
+		})
+		return true
+	}
+	report.add(Check{
+		Instance: instance.Name, Name: CheckTaggedPools, Subject: subject, Status: StatusPass,
+		Message: fmt.Sprintf("%d pool(s) tagged %q", len(poolIDs), opts.KubernetesPoolTag),
+	})
+	for _, poolID := range poolIDs {
+		checkPool(ctx, report, instance, poolID)
+	}
+	return true
+}
+
+// checkPool checks the default SR of a tagged pool and the PBDs of its user
+// SRs.
+func checkPool(ctx context.Context, report *Report, instance clients.XoInstance, poolID uuid.UUID) {
+	xoClient := instance.Client
+	pool, err := xoClient.Pool().Get(ctx, poolID)
+	if err != nil {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckDefaultSR, Subject: "pool " + poolID.String(), Status: StatusFail,
+			Message: fmt.Sprintf("failed to get the pool: %v", err),
+			Hint:    "The token user needs read access to the pool.",
+		})
+		return
+	}
+	subject := "pool " + pool.NameLabel
+	if sr, err := defaultSR(ctx, xoClient, pool); err != nil {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckDefaultSR, Subject: subject, Status: StatusFail,
+			Message: err.Error(),
+			Hint:    "Set a default SR on the pool in Xen Orchestra, outside of maintenance mode. Volumes with the shared storage type are created on it.",
+		})
+	} else {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckDefaultSR, Subject: subject, Status: StatusPass,
+			Message: "default SR " + sr.NameLabel,
+		})
+	}
+
+	srs, err := xoClient.SR().GetAll(ctx, 0, fmt.Sprintf("content_type:user $pool:%s", pool.ID))
+	if err != nil {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckSRPBDs, Subject: subject, Status: StatusFail,
+			Message: fmt.Sprintf("failed to list the SRs: %v", err),
+			Hint:    "The token user needs read access to the SRs of the pool.",
+		})
+		return
+	}
+	for _, sr := range srs {
+		checkSRPBDs(ctx, report, instance, sr)
+	}
+}
+
+func defaultSR(ctx context.Context, xoClient clients.XoClient, pool *payloads.Pool) (*payloads.StorageRepository, error) {
+	if pool.DefaultSR == uuid.Nil {
+		return nil, errors.New("the pool has no default SR")
+	}
+	sr, err := xoClient.SR().Get(ctx, pool.DefaultSR)
+	if err != nil {
+		return nil, fmt.Errorf("default SR %s not found or inaccessible: %w", pool.DefaultSR, err)
+	}
+	if sr.InMaintenanceMode {
+		return nil, fmt.Errorf("default SR %s is in maintenance mode", sr.NameLabel)
+	}
+	return sr, nil
+}
+
+// checkSRPBDs fails when no host is connected to sr, and warns when only some
+// hosts are: the volumes of sr cannot be attached to the VMs of the others.
+func checkSRPBDs(ctx context.Context, report *Report, instance clients.XoInstance, sr *payloads.StorageRepository) {
+	subject := "SR " + sr.NameLabel
+	pbds, err := instance.Client.PBD().GetAll(ctx, 0, fmt.Sprintf("SR:%s", sr.ID))
+	if err != nil {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckSRPBDs, Subject: subject, Status: StatusFail,
+			Message: fmt.Sprintf("failed to list the PBDs: %v", err),
+			Hint:    "The token user needs read access to the PBDs of the SR.",
+		})
+		return
+	}
+	var unplugged []string
+	for _, pbd := range pbds {
+		if !pbd.Attached {
+			unplugged = append(unplugged, pbd.Host.String())
+		}
+	}
+	check := Check{Instance: instance.Name, Name: CheckSRPBDs, Subject: subject}
+	switch {
+	case len(pbds) == 0 || len(unplugged) == len(pbds):
+		check.Status, check.Message = StatusFail, "no host is connected to the SR"
+		check.Hint = "Repair the SR in Xen Orchestra to plug its PBDs, or remove it if it is no longer used."
+	case len(unplugged) > 0:
+		check.Status, check.Message = StatusWarn, fmt.Sprintf("the SR is not connected to host(s) %v", unplugged)
+		check.Hint = "Repair the SR in Xen Orchestra to plug its PBD on every host: its volumes cannot be attached to the VMs of the other hosts."
+	default:
+		check.Status, check.Message = StatusPass, fmt.Sprintf("connected to %d host(s)", len(pbds))
+	}
+	report.add(check)
+}
+
+// checkNode checks that the VM of a node can be found from its providerID,
+// has a local SR on its host and can hot-plug disks. A missing local SR only
+// fails when a StorageClass uses local storage.
+func checkNode(ctx context.Context, report *Report, instances []clients.XoInstance, node *corev1.Node, fromXoAPI, localStorage bool) {
+	subject := "node " + node.Name
+	vmID, ok := nodeVMID(report, node, fromXoAPI)
+	if !ok {
+		return
+	}
+
+	var (
+		instance clients.XoInstance
+		vm       *payloads.VM
+	)
+	for _, i := range instances {
+		if found, err := i.Client.VM().GetByID(ctx, vmID); err == nil {
+			vm = found
+			instance = i
+			break
+		}
+	}
+	if vm == nil {
+		report.add(Check{
+			Name: CheckNodeVM, Subject: subject, Status: StatusFail,
+			Message: fmt.Sprintf("VM %s not found in Xen Orchestra", vmID),
+			Hint:    "Check that the providerID of the node references its VM, and that the configuration file lists the Xen Orchestra instance managing it.",
+		})
+		return
+	}
+	report.add(Check{Instance: instance.Name, Name: CheckNodeVM, Subject: subject, Status: StatusPass, Message: "VM " + vm.NameLabel})
+
+	if vm.PowerState != payloads.PowerStateRunning {
+		report.add(Check{
+			Instance: instance.Name, Name: CheckLocalSR, Subject: subject, Status: StatusWarn,
+			Message: fmt.Sprintf("the VM is %s, its host is unknown", vm.PowerState),
+		})
+	} else if sr, err := instance.Client.FindLocalSRForHost(ctx, vm.Container); err != nil {
+		check := Check{
+			Instance: instance.Name, Name: CheckLocalSR, Subject: subject, Status: StatusWarn,
+			Message: err.Error(),
+			Hint:    "Volumes with the local storage type cannot be attached to this node. Create a local SR on its host, or keep the node out of their topology.",
+		}
+		if localStorage {
+			check.Status = StatusFail
+		}
+		report.add(check)
+	} else {
+		report.add(Check{Instance: instance.Name, Name: CheckLocalSR, Subject: subject, Status: StatusPass, Message: "local SR " + sr.NameLabel})
+	}
+
+	detected, err := instance.Client.ArePVDriversDetected(ctx, vm.ID)
+	switch {
+	case err != nil:
+		report.add(Check{
+			Instance: instance.Name, Name: CheckPVDrivers, Subject: subject, Status: StatusFail,
+			Message: err.Error(),
+			Hint:    "The token user needs read access to the VM.",
+		})
+	case !detected:
+		report.add(Check{
+			Instance: instance.Name, Name: CheckPVDrivers, Subject: subject, Status: StatusFail,
+			Message: "Xen Orchestra detects no PV drivers in the VM, volumes cannot be hot-plugged",
+			Hint:    "Install the Xen guest agent (xe-guest-utilities or xen-guest-agent) in the node VM and check it is running.",
+		})
+	default:
+		report.add(Check{Instance: instance.Name, Name: CheckPVDrivers, Subject: subject, Status: StatusPass, Message: "PV drivers detected"})
+	}
+}
+
+// nodeVMID checks the providerID of node and returns the UUID of its VM. With
+// fromXoAPI, a node without a providerID is identified by its SystemUUID.
+func nodeVMID(report *Report, node *corev1.Node, fromXoAPI bool) (uuid.UUID, bool) {
+	subject := "node " + node.Name
+	parsed, _, err := xok8s.ParseProviderID(node.Spec.ProviderID)
+	if err == nil {
+		report.add(Check{Name: CheckProviderID, Subject: subject, Status: StatusPass, Message: node.Spec.ProviderID})
+		return parsed.ID, true
+	}
+	if !fromXoAPI {
+		report.add(Check{
+			Name: CheckProviderID, Subject: subject, Status: StatusFail,
+			Message: err.Error(),
+			Hint:    "Install the Xen Orchestra cloud controller manager, which sets the providerID of the nodes, or run the node plugin with --node-metadata-source=xo-api.",
+		})
+		return uuid.Nil, false
+	}
+	report.add(Check{
+		Name: CheckProviderID, Subject: subject, Status: StatusWarn,
+		Message: err.Error(),
+		Hint:    "The node plugin finds the VM by the SystemUUID of the node. Install the Xen Orchestra cloud controller manager to also get the pool topology labels.",
+	})
+	vmID, err := uuid.FromString(node.Status.NodeInfo.SystemUUID)
+	if err != nil {
+		report.add(Check{
+			Name: CheckNodeVM, Subject: subject, Status: StatusFail,
+			Message: fmt.Sprintf("invalid SystemUUID %q: %v", node.Status.NodeInfo.SystemUUID, err),
+			Hint:    "Install the Xen Orchestra cloud controller manager, which sets the providerID of the nodes.",
+		})
+		return uuid.Nil, false
+	}
+	return vmID, true
+}
+
+// usesLocalStorage reports whether a StorageClass of the driver creates
+// volumes on local SRs.
+func usesLocalStorage(ctx context.Context, kubeClient kube.Interface, driverName string) (bool, error) {
+	classes, err := kubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list StorageClasses: %w", err)
+	}
+	for _, class := range classes.Items {
+		if class.Provisioner == driverName && class.Parameters[parameterStorageType] == storageTypeLocal {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StorageClass parameter selecting local SRs, as in xenorchestracsi.ParameterStorageType
+// and StorageTypeLocal: the driver subpackages do not import the driver package.
+const (
+	parameterStorageType = "storageType"
+	storageTypeLocal     = "local"
+)
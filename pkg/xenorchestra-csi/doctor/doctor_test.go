@@ -0,0 +1,249 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testDriverName = "csi.xenorchestra.vates.tech"
+	testPoolTag    = "k8s-pool"
+)
+
+var (
+	poolID   = uuid.Must(uuid.FromString("11111111-0000-0000-0000-000000000001"))
+	sharedID = uuid.Must(uuid.FromString("22222222-0000-0000-0000-000000000001"))
+	localID  = uuid.Must(uuid.FromString("22222222-0000-0000-0000-000000000002"))
+	hostA    = uuid.Must(uuid.FromString("44444444-0000-0000-0000-000000000001"))
+	hostB    = uuid.Must(uuid.FromString("44444444-0000-0000-0000-000000000002"))
+	vmA      = uuid.Must(uuid.FromString("33333333-0000-0000-0000-000000000001"))
+	vmB      = uuid.Must(uuid.FromString("33333333-0000-0000-0000-000000000002"))
+)
+
+type testInstance struct {
+	xo   *clientsMock.MockXoClient
+	pool *xoLibMock.MockPool
+	sr   *xoLibMock.MockSR
+	pbd  *xoLibMock.MockPBD
+	vm   *xoLibMock.MockVM
+}
+
+func newTestInstance(t *testing.T) *testInstance {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	i := &testInstance{
+		xo:   clientsMock.NewMockXoClient(ctrl),
+		pool: xoLibMock.NewMockPool(ctrl),
+		sr:   xoLibMock.NewMockSR(ctrl),
+		pbd:  xoLibMock.NewMockPBD(ctrl),
+		vm:   xoLibMock.NewMockVM(ctrl),
+	}
+	i.xo.EXPECT().Pool().Return(i.pool).AnyTimes()
+	i.xo.EXPECT().SR().Return(i.sr).AnyTimes()
+	i.xo.EXPECT().PBD().Return(i.pbd).AnyTimes()
+	i.xo.EXPECT().VM().Return(i.vm).AnyTimes()
+	return i
+}
+
+// expectPool sets up a tagged pool whose default SR is shared and connected to
+// both hosts, with a local SR connected to hostA only.
+func (i *testInstance) expectPool() {
+	i.xo.EXPECT().Ping(gomock.Any()).Return(nil)
+	i.pool.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("tags:/^%s$/", testPoolTag)).Return([]*payloads.Pool{{ID: poolID}}, nil)
+	i.pool.EXPECT().Get(gomock.Any(), poolID).Return(&payloads.Pool{ID: poolID, NameLabel: "pool-a", DefaultSR: sharedID}, nil)
+	shared := &payloads.StorageRepository{ID: sharedID, NameLabel: "nfs"}
+	i.sr.EXPECT().Get(gomock.Any(), sharedID).Return(shared, nil)
+	i.sr.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("content_type:user $pool:%s", poolID)).Return([]*payloads.StorageRepository{
+		shared,
+		{ID: localID, NameLabel: "local-a"},
+	}, nil)
+	i.pbd.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("SR:%s", sharedID)).Return([]*payloads.PBD{
+		{Host: hostA, Attached: true},
+		{Host: hostB, Attached: true},
+	}, nil)
+	i.pbd.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("SR:%s", localID)).Return([]*payloads.PBD{
+		{Host: hostA, Attached: true},
+	}, nil)
+}
+
+func newNode(name, providerID string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.NodeSpec{ProviderID: providerID}}
+}
+
+func providerID(vmID uuid.UUID) string {
+	return fmt.Sprintf("xenorchestra://%s/%s", poolID, vmID)
+}
+
+func findCheck(t *testing.T, report *Report, name, subject string) Check {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Name == name && check.Subject == subject {
+			return check
+		}
+	}
+	require.Failf(t, "check not found", "%s on %s", name, subject)
+	return Check{}
+}
+
+func TestRun(t *testing.T) {
+	opts := Options{DriverName: testDriverName, KubernetesPoolTag: testPoolTag}
+
+	t.Run("Healthy", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.expectPool()
+		i.vm.EXPECT().GetByID(gomock.Any(), vmA).Return(&payloads.VM{ID: vmA, NameLabel: "worker-a", PowerState: payloads.PowerStateRunning, Container: hostA}, nil)
+		i.xo.EXPECT().FindLocalSRForHost(gomock.Any(), hostA).Return(&payloads.StorageRepository{ID: localID, NameLabel: "local-a"}, nil)
+		i.xo.EXPECT().ArePVDriversDetected(gomock.Any(), vmA).Return(true, nil)
+		kubeClient := fake.NewClientset(newNode("worker-a", providerID(vmA)))
+
+		report, err := Run(context.Background(), []clients.XoInstance{{Name: "paris", Client: i.xo}}, kubeClient, opts)
+		require.NoError(t, err)
+		assert.Zero(t, report.Count(StatusFail))
+		assert.Zero(t, report.Count(StatusWarn))
+		assert.Len(t, report.Checks, 9)
+		assert.Equal(t, "paris", findCheck(t, report, CheckPVDrivers, "node worker-a").Instance)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.expectPool()
+		i.vm.EXPECT().GetByID(gomock.Any(), vmB).Return(&payloads.VM{ID: vmB, NameLabel: "worker-b", PowerState: payloads.PowerStateRunning, Container: hostB}, nil)
+		i.xo.EXPECT().FindLocalSRForHost(gomock.Any(), hostB).Return(nil, errors.New("no local SR with a connected PBD found on host"))
+		i.xo.EXPECT().ArePVDriversDetected(gomock.Any(), vmB).Return(false, nil)
+		kubeClient := fake.NewClientset(
+			newNode("worker-b", providerID(vmB)),
+			newNode("no-ccm", ""),
+			&storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: "local"},
+				Provisioner: testDriverName,
+				Parameters:  map[string]string{"storageType": "local"},
+			},
+		)
+
+		report, err := Run(context.Background(), []clients.XoInstance{{Client: i.xo}}, kubeClient, opts)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFail, findCheck(t, report, CheckProviderID, "node no-ccm").Status)
+		assert.Equal(t, StatusFail, findCheck(t, report, CheckLocalSR, "node worker-b").Status, "a StorageClass uses local storage")
+		pvDrivers := findCheck(t, report, CheckPVDrivers, "node worker-b")
+		assert.Equal(t, StatusFail, pvDrivers.Status)
+		assert.NotEmpty(t, pvDrivers.Hint)
+		assert.Equal(t, 3, report.Count(StatusFail))
+	})
+
+	t.Run("LocalSRWithoutLocalStorageClass", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.expectPool()
+		i.vm.EXPECT().GetByID(gomock.Any(), vmB).Return(&payloads.VM{ID: vmB, PowerState: payloads.PowerStateRunning, Container: hostB}, nil)
+		i.xo.EXPECT().FindLocalSRForHost(gomock.Any(), hostB).Return(nil, errors.New("no local SR"))
+		i.xo.EXPECT().ArePVDriversDetected(gomock.Any(), vmB).Return(true, nil)
+
+		report, err := Run(context.Background(), []clients.XoInstance{{Client: i.xo}}, fake.NewClientset(newNode("worker-b", providerID(vmB))), opts)
+		require.NoError(t, err)
+		assert.Equal(t, StatusWarn, findCheck(t, report, CheckLocalSR, "node worker-b").Status)
+		assert.Zero(t, report.Count(StatusFail))
+	})
+
+	t.Run("PoolProblems", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.xo.EXPECT().Ping(gomock.Any()).Return(nil)
+		i.pool.EXPECT().GetAll(gomock.Any(), 0, gomock.Any()).Return([]*payloads.Pool{{ID: poolID}}, nil)
+		i.pool.EXPECT().Get(gomock.Any(), poolID).Return(&payloads.Pool{ID: poolID, NameLabel: "pool-a"}, nil)
+		i.sr.EXPECT().GetAll(gomock.Any(), 0, gomock.Any()).Return([]*payloads.StorageRepository{
+			{ID: sharedID, NameLabel: "nfs"},
+			{ID: localID, NameLabel: "iscsi"},
+		}, nil)
+		i.pbd.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("SR:%s", sharedID)).Return([]*payloads.PBD{
+			{Host: hostA, Attached: true},
+			{Host: hostB, Attached: false},
+		}, nil)
+		i.pbd.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("SR:%s", localID)).Return([]*payloads.PBD{
+			{Host: hostA, Attached: false},
+		}, nil)
+
+		report, err := Run(context.Background(), []clients.XoInstance{{Client: i.xo}}, nil, opts)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFail, findCheck(t, report, CheckDefaultSR, "pool pool-a").Status)
+		assert.Equal(t, StatusWarn, findCheck(t, report, CheckSRPBDs, "SR nfs").Status)
+		assert.Equal(t, StatusFail, findCheck(t, report, CheckSRPBDs, "SR iscsi").Status)
+		assert.Equal(t, StatusWarn, findCheck(t, report, CheckKubernetes, "cluster").Status)
+	})
+
+	t.Run("NoTaggedPool", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.xo.EXPECT().Ping(gomock.Any()).Return(nil)
+		i.pool.EXPECT().GetAll(gomock.Any(), 0, gomock.Any()).Return([]*payloads.Pool{}, nil)
+
+		report, err := Run(context.Background(), []clients.XoInstance{{Client: i.xo}}, nil, opts)
+		require.NoError(t, err)
+		check := findCheck(t, report, CheckTaggedPools, "Xen Orchestra")
+		assert.Equal(t, StatusFail, check.Status)
+		assert.Contains(t, check.Hint, testPoolTag)
+	})
+
+	t.Run("NodeMetadataFromXoAPI", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.expectPool()
+		i.vm.EXPECT().GetByID(gomock.Any(), vmA).Return(&payloads.VM{ID: vmA, PowerState: payloads.PowerStateRunning, Container: hostA}, nil)
+		i.xo.EXPECT().FindLocalSRForHost(gomock.Any(), hostA).Return(&payloads.StorageRepository{ID: localID}, nil)
+		i.xo.EXPECT().ArePVDriversDetected(gomock.Any(), vmA).Return(true, nil)
+		node := newNode("worker-a", "")
+		node.Status.NodeInfo.SystemUUID = vmA.String()
+
+		xoAPIOpts := opts
+		xoAPIOpts.NodeMetadataFromXoAPI = true
+		report, err := Run(context.Background(), []clients.XoInstance{{Client: i.xo}}, fake.NewClientset(node), xoAPIOpts)
+		require.NoError(t, err)
+		assert.Equal(t, StatusWarn, findCheck(t, report, CheckProviderID, "node worker-a").Status)
+		assert.Equal(t, StatusPass, findCheck(t, report, CheckNodeVM, "node worker-a").Status)
+		assert.Zero(t, report.Count(StatusFail))
+	})
+
+	t.Run("UnreachableInstance", func(t *testing.T) {
+		down, up := newTestInstance(t), newTestInstance(t)
+		down.xo.EXPECT().Ping(gomock.Any()).Return(errors.New("401 Unauthorized"))
+		up.expectPool()
+		up.vm.EXPECT().GetByID(gomock.Any(), vmA).Return(&payloads.VM{ID: vmA, PowerState: payloads.PowerStateHalted}, nil)
+		up.xo.EXPECT().ArePVDriversDetected(gomock.Any(), vmA).Return(true, nil)
+
+		report, err := Run(context.Background(), []clients.XoInstance{{Name: "down", Client: down.xo}, {Name: "up", Client: up.xo}},
+			fake.NewClientset(newNode("worker-a", providerID(vmA))), opts)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFail, report.Checks[0].Status)
+		assert.Equal(t, "down", report.Checks[0].Instance)
+		assert.Equal(t, "up", findCheck(t, report, CheckNodeVM, "node worker-a").Instance)
+		assert.Equal(t, StatusWarn, findCheck(t, report, CheckLocalSR, "node worker-a").Status, "the host of a halted VM is unknown")
+	})
+}
@@ -18,6 +18,9 @@ package xenorchestracsi
 import (
 	"flag"
 	"fmt"
+	"time"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
 )
 
 // NodeMetadataSource controls how the CSI node plugin resolves pool ID and VM
@@ -40,9 +43,20 @@ type DriverOptions struct {
 	// Common options
 	NodeName   string
 	DriverName string
-	Endpoint   string
+	// Endpoint is the unix://path or tcp://host:port the CSI services are
+	// served on.
+	Endpoint string
+	// TLSCertFile, TLSKeyFile and TLSClientCAFile enable TLS, and client
+	// certificate verification, on a tcp:// Endpoint. See TLSOptions.
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
 	// XO Configuration
 	ConfigFile string
+	// XoConfigReloadInterval is how often ConfigFile is checked for changes,
+	// which are applied without restarting. Zero disables the reloads.
+	// Defaults to DefaultXoConfigReloadInterval.
+	XoConfigReloadInterval time.Duration
 	// NodeMetadataSource selects how the node plugin resolves pool ID and VM identity.
 	NodeMetadataSource NodeMetadataSource
 	// VDINamePrefix is prepended to the VDI name label in Xen Orchestra.
@@ -57,6 +71,73 @@ type DriverOptions struct {
 	// automatic VDI placement when no poolId or topology constraints are provided.
 	// Defaults to DefaultKubernetesPoolTag ("k8s-pool").
 	KubernetesPoolTag string
+	// ForceDetachPausedSuspended lets ControllerPublishVolume take a volume
+	// over from a Paused or Suspended VM. Volumes attached to a Halted VM are
+	// always taken over; volumes attached to a Running VM never are.
+	ForceDetachPausedSuspended bool
+	// OrphanGCInterval is the period of the orphaned VDI garbage collector.
+	// Zero (the default) disables it. Only enable it on the controller plugin.
+	OrphanGCInterval time.Duration
+	// OrphanGCGracePeriod is how long a VDI must remain unreferenced by any
+	// PersistentVolume before the garbage collector deletes it.
+	OrphanGCGracePeriod time.Duration
+	// OrphanGCDelete enables deletion of orphaned VDIs. When false (the default)
+	// the garbage collector only reports orphans through logs, metrics and events.
+	OrphanGCDelete bool
+	// ReplicationInterval is the time between two copies of a volume with a
+	// replicaPoolId to its replica pool. Zero (the default) disables the
+	// replication. Only enable it on the controller plugin.
+	ReplicationInterval time.Duration
+	// VolumePopulatorInterval is how often the volume populator looks for
+	// claims of a VolumeImage. Zero (the default) disables it. Only enable it
+	// on the controller plugin.
+	VolumePopulatorInterval time.Duration
+	// VolumePopulatorMaxConcurrency bounds the images imported at once.
+	// Defaults to DefaultVolumePopulatorMaxConcurrency.
+	VolumePopulatorMaxConcurrency int
+	// XoAttachTimeout bounds the wait for a hot-plugged disk to show up in its
+	// VM. Defaults to clients.DefaultAttachTimeout.
+	XoAttachTimeout time.Duration
+	// XoTaskTimeout bounds the wait for an XO task such as a plug, an unplug
+	// or a VDI migration. Defaults to clients.DefaultTaskTimeout.
+	XoTaskTimeout time.Duration
+	// XoPollInterval is the polling period used when the XO event feed is
+	// disabled or disconnected. Defaults to clients.DefaultPollInterval.
+	XoPollInterval time.Duration
+	// XoCacheTTL is how long pools, SRs, PBDs, hosts and VM placements are
+	// cached. Zero disables the cache. Defaults to clients.DefaultCacheTTL.
+	XoCacheTTL time.Duration
+	// XoEventFeed makes waits for XO objects and tasks event-driven, falling
+	// back to polling when the feed is unavailable. Defaults to true.
+	XoEventFeed bool
+	// MetricsAddress is the TCP address on which Prometheus metrics are served
+	// at /metrics. Empty (the default) disables the metrics endpoint.
+	MetricsAddress string
+	// ManagedVolumesMetric reports the PersistentVolumes of the driver in the
+	// managed volumes metric, from the replica holding its Lease. It requires
+	// MetricsAddress. Only enable it on the controller plugin.
+	ManagedVolumesMetric bool
+	// TracingEndpoint is the host:port of the OTLP gRPC collector receiving
+	// the traces. Empty (the default) disables tracing.
+	TracingEndpoint string
+	// TracingInsecure disables TLS towards the OTLP collector.
+	TracingInsecure bool
+	// TracingSampleRatio is the fraction of the traces started by the driver
+	// that are sampled. Traces propagated by the caller keep its decision.
+	TracingSampleRatio float64
+	// ShutdownDrainTimeout is how long in-flight CSI calls may run after the
+	// driver is asked to stop, before they are cancelled. Zero cancels them
+	// right away. Defaults to DefaultShutdownDrainTimeout.
+	ShutdownDrainTimeout time.Duration
+	// ProbeNodeMetadata makes Probe also check that the node metadata can be
+	// resolved. Only enable it on the node plugin.
+	ProbeNodeMetadata bool
+	// ProbeCacheTTL is how long the outcome of the readiness checks run by
+	// Probe is reused. Defaults to DefaultProbeCacheTTL.
+	ProbeCacheTTL time.Duration
+	// LeaderElectionNamespace is the namespace of the Leases used to elect the
+	// replica running background workers such as the garbage collector.
+	LeaderElectionNamespace string
 }
 
 func (o *DriverOptions) AddFlags() *flag.FlagSet {
@@ -70,10 +151,29 @@ func (o *DriverOptions) AddFlags() *flag.FlagSet {
 	o.VDINamePrefix = DefaultVDINamePrefix
 	o.ClusterTag = DefaultClusterTag
 	o.KubernetesPoolTag = DefaultKubernetesPoolTag
+	o.OrphanGCGracePeriod = DefaultOrphanGCGracePeriod
+	o.LeaderElectionNamespace = DefaultLeaderElectionNamespace
+	o.VolumePopulatorMaxConcurrency = DefaultVolumePopulatorMaxConcurrency
+	o.XoAttachTimeout = clients.DefaultAttachTimeout
+	o.XoTaskTimeout = clients.DefaultTaskTimeout
+	o.XoPollInterval = clients.DefaultPollInterval
+	o.XoCacheTTL = clients.DefaultCacheTTL
+	o.XoEventFeed = true
+	o.TracingSampleRatio = 1
+	o.ShutdownDrainTimeout = DefaultShutdownDrainTimeout
+	o.ProbeCacheTTL = DefaultProbeCacheTTL
+	o.XoConfigReloadInterval = DefaultXoConfigReloadInterval
 	fs.StringVar(&o.NodeName, "node-name", "", "Node name")
 	fs.StringVar(&o.DriverName, "driver-name", DriverName, "Driver name")
-	fs.StringVar(&o.Endpoint, "endpoint", "unix://tmp/csi.sock", "CSI endpoint")
+	fs.StringVar(&o.Endpoint, "endpoint", "unix://tmp/csi.sock", "CSI endpoint, unix://path or tcp://host:port")
+	fs.StringVar(&o.TLSCertFile, "tls-cert-file", "",
+		"PEM certificate served on a tcp:// endpoint. Enables TLS together with --tls-key-file. Reloaded when modified.")
+	fs.StringVar(&o.TLSKeyFile, "tls-key-file", "", "PEM private key of --tls-cert-file.")
+	fs.StringVar(&o.TLSClientCAFile, "tls-client-ca-file", "",
+		"PEM CA certificates that client certificates must be signed by. Clients without a valid certificate are rejected.")
 	fs.StringVar(&o.ConfigFile, "config-file", "/etc/xenorchestra/config.yaml", "Path to XO configuration file")
+	fs.DurationVar(&o.XoConfigReloadInterval, "xo-config-reload-interval", DefaultXoConfigReloadInterval,
+		"How often the XO configuration file is checked for changes, such as a rotated token, which are applied without restarting. 0 disables the reloads.")
 	fs.StringVar(&o.VDINamePrefix, "vdi-name-prefix", DefaultVDINamePrefix,
 		"Prefix prepended to the Kubernetes volume name when naming VDIs in Xen Orchestra (default: \"csi-\")")
 	fs.StringVar(&o.ClusterTag, "cluster-tag", DefaultClusterTag,
@@ -83,6 +183,61 @@ func (o *DriverOptions) AddFlags() *flag.FlagSet {
 	fs.StringVar(&o.KubernetesPoolTag, "kubernetes-pool-tag", DefaultKubernetesPoolTag,
 		"Tag added to Xen Orchestra pools eligible for automatic volume placement. "+
 			"Used when no poolId or topology constraints are provided.")
+	fs.BoolVar(&o.ForceDetachPausedSuspended, "force-detach-paused-suspended", false,
+		"Allow taking a volume over from a Paused or Suspended VM. "+
+			"Volumes attached to a Halted VM are always taken over, never from a Running VM. "+
+			"A Suspended VM resumed afterwards loses the disk.")
+	fs.DurationVar(&o.OrphanGCInterval, "orphan-gc-interval", 0,
+		"Interval between two passes of the orphaned VDI garbage collector. "+
+			"0 disables it. Only enable it on the controller plugin.")
+	fs.DurationVar(&o.OrphanGCGracePeriod, "orphan-gc-grace-period", DefaultOrphanGCGracePeriod,
+		"Minimum time a VDI must stay unreferenced by any PersistentVolume before it is deleted.")
+	fs.BoolVar(&o.OrphanGCDelete, "orphan-gc-delete", false,
+		"Delete orphaned VDIs once their grace period has elapsed. "+
+			"When false (dry-run), orphans are only reported through logs, metrics and events.")
+	fs.DurationVar(&o.ReplicationInterval, "replication-interval", 0,
+		"Time between two copies of a volume whose StorageClass has a replicaPoolId to that pool. "+
+			"0 disables the replication. Only enable it on the controller plugin.")
+	fs.DurationVar(&o.VolumePopulatorInterval, "volume-populator-interval", 0,
+		"Interval between two looks of the volume populator for PersistentVolumeClaims whose dataSourceRef is a VolumeImage. "+
+			"0 disables it. Only enable it on the controller plugin.")
+	fs.IntVar(&o.VolumePopulatorMaxConcurrency, "volume-populator-max-concurrency", DefaultVolumePopulatorMaxConcurrency,
+		"Maximum number of images the volume populator imports at once.")
+	fs.DurationVar(&o.XoAttachTimeout, "xo-attach-timeout", clients.DefaultAttachTimeout,
+		"Maximum time to wait for a hot-plugged disk to get a device name in its VM. "+
+			"Waits never outlive the deadline of the CSI call.")
+	fs.DurationVar(&o.XoTaskTimeout, "xo-task-timeout", clients.DefaultTaskTimeout,
+		"Maximum time to wait for a Xen Orchestra task, such as a VBD plug or a VDI migration.")
+	fs.DurationVar(&o.XoPollInterval, "xo-poll-interval", clients.DefaultPollInterval,
+		"Polling period used when the Xen Orchestra event feed is disabled or disconnected.")
+	fs.DurationVar(&o.XoCacheTTL, "xo-cache-ttl", clients.DefaultCacheTTL,
+		"How long pools, SRs, PBDs, hosts and VM placements are cached. "+
+			"Changes reported by the event feed invalidate entries earlier. 0 disables the cache.")
+	fs.BoolVar(&o.XoEventFeed, "xo-event-feed", true,
+		"Wait for Xen Orchestra objects and tasks using its event feed instead of polling. "+
+			"Polling is still used when the feed is unavailable.")
+	fs.StringVar(&o.MetricsAddress, "metrics-address", "",
+		"TCP address, such as \":29604\", on which Prometheus metrics are served at /metrics. "+
+			"Empty disables the metrics endpoint.")
+	fs.BoolVar(&o.ManagedVolumesMetric, "managed-volumes-metric", false,
+		"Report the PersistentVolumes of the driver by pool and storage type on the metrics endpoint, "+
+			"from the replica holding the Lease of the metric. Only enable it on the controller plugin.")
+	fs.StringVar(&o.TracingEndpoint, "tracing-endpoint", "",
+		"host:port of the OpenTelemetry (OTLP gRPC) collector receiving the traces. Empty disables tracing.")
+	fs.BoolVar(&o.TracingInsecure, "tracing-insecure", false,
+		"Connect to the OpenTelemetry collector without TLS.")
+	fs.Float64Var(&o.TracingSampleRatio, "tracing-sample-ratio", 1,
+		"Fraction of the traces started by the driver that are sampled. "+
+			"Traces propagated by the CSI sidecars follow their sampling decision.")
+	fs.DurationVar(&o.ShutdownDrainTimeout, "shutdown-drain-timeout", DefaultShutdownDrainTimeout,
+		"On SIGTERM or SIGINT, how long in-flight CSI calls, such as VDI migrations, may run before they are cancelled. "+
+			"Keep it below the terminationGracePeriodSeconds of the pod.")
+	fs.BoolVar(&o.ProbeNodeMetadata, "probe-node-metadata", false,
+		"Make the CSI Probe also check that the pool ID and VM of the node can be resolved. Enable it on the node plugin.")
+	fs.DurationVar(&o.ProbeCacheTTL, "probe-cache-ttl", DefaultProbeCacheTTL,
+		"How long the outcome of the Xen Orchestra and node metadata checks run by the CSI Probe is reused.")
+	fs.StringVar(&o.LeaderElectionNamespace, "leader-election-namespace", DefaultLeaderElectionNamespace,
+		"Namespace of the Leases used to elect the controller replica running background workers.")
 	fs.Func("node-metadata-source",
 		`Source used by the node plugin to resolve pool ID and VM identity.
 Allowed values:
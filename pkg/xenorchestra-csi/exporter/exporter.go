@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package exporter streams the content of a volume, or of a snapshot of its
+// VDI, from the VDI export endpoint of Xen Orchestra.
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Options configures Run.
+type Options struct {
+	// VolumeHandle is the volume handle of the volume to export.
+	VolumeHandle string
+	// Snapshot is the UUID of a snapshot of the VDI of the volume to export
+	// instead of the VDI itself. uuid.Nil exports the VDI, which must not be
+	// attached read-write.
+	Snapshot uuid.UUID
+	Format   payloads.VDIFormat
+}
+
+// Result describes an export.
+type Result struct {
+	Instance string
+	// VDI is the VDI of the volume.
+	VDI *payloads.VDI
+	// Exported is the VDI exported: VDI, or its snapshot.
+	Exported *payloads.VDI
+	Format   payloads.VDIFormat
+	// Bytes is the size of the exported file.
+	Bytes int64
+}
+
+// ErrAttachedReadWrite is returned by Run when the VDI of the volume is
+// attached read-write to a VM, whose writes would corrupt the export.
+var ErrAttachedReadWrite = errors.New("the volume is attached read-write")
+
+// PVVolumeHandle returns the volume handle of the PersistentVolume pvName of
+// the driver.
+func PVVolumeHandle(ctx context.Context, kubeClient kube.Interface, driverName, pvName string) (string, error) {
+	pv, err := kubeClient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get PersistentVolume %s: %w", pvName, err)
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName {
+		return "", fmt.Errorf("PersistentVolume %s is not a volume of driver %s", pvName, driverName)
+	}
+	return pv.Spec.CSI.VolumeHandle, nil
+}
+
+// Run writes the content of the volume, or of its snapshot, to w in
+// opts.Format. Nothing is written when the volume cannot be exported.
+func Run(ctx context.Context, instances []clients.XoInstance, opts Options, w io.Writer) (*Result, error) {
+	if opts.Format != payloads.VDIFormatRaw && opts.Format != payloads.VDIFormatVHD {
+		return nil, fmt.Errorf("unknown format %q, expected %q or %q", opts.Format, payloads.VDIFormatVHD, payloads.VDIFormatRaw)
+	}
+	instance, vdi, err := clients.FindVolume(ctx, instances, opts.VolumeHandle)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Instance: instance.Name, VDI: vdi, Exported: vdi, Format: opts.Format}
+
+	if opts.Snapshot != uuid.Nil {
+		if !slices.Contains(vdi.Snapshots, opts.Snapshot) {
+			return nil, fmt.Errorf("VDI %s is not a snapshot of VDI %s of volume %s", opts.Snapshot, vdi.ID, opts.VolumeHandle)
+		}
+		if result.Exported, err = instance.Client.VDI().Get(ctx, opts.Snapshot); err != nil {
+			return nil, fmt.Errorf("failed to get snapshot %s: %w", opts.Snapshot, err)
+		}
+	} else if err := checkNotAttachedReadWrite(ctx, instance.Client, vdi); err != nil {
+		return nil, err
+	}
+
+	klog.InfoS("Exporting volume", "volumeHandle", opts.VolumeHandle, "vdiID", vdi.ID, "exportedVDIID", result.Exported.ID, "format", opts.Format)
+	err = instance.Client.VDI().Export(ctx, result.Exported.ID, opts.Format, func(content io.Reader) error {
+		n, copyErr := io.Copy(w, content)
+		result.Bytes = n
+		return copyErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export VDI %s: %w", result.Exported.ID, err)
+	}
+	return result, nil
+}
+
+// checkNotAttachedReadWrite returns ErrAttachedReadWrite when a VBD plugs vdi
+// read-write into a VM.
+func checkNotAttachedReadWrite(ctx context.Context, xoClient clients.XoClient, vdi *payloads.VDI) error {
+	vbds, err := xoClient.IsVDIUsedAnywhere(ctx, vdi)
+	if err != nil {
+		return fmt.Errorf("failed to get the VBDs of VDI %s: %w", vdi.ID, err)
+	}
+	if vbd := clients.ReadWriteVBD(vbds); vbd != nil {
+		return fmt.Errorf("%w to VM %s: export one of its snapshots, or detach it first", ErrAttachedReadWrite, vbd.VM)
+	}
+	return nil
+}
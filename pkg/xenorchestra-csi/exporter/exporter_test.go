@@ -0,0 +1,161 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var (
+	vdiID      = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
+	snapshotID = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000002"))
+	vmID       = uuid.Must(uuid.FromString("cccccccc-0000-0000-0000-000000000003"))
+)
+
+func expectExport(mockVDI *xoLibMock.MockVDI, id uuid.UUID, format payloads.VDIFormat, content string) {
+	mockVDI.EXPECT().Export(gomock.Any(), id, format, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, _ payloads.VDIFormat, fn func(io.Reader) error) error {
+			return fn(strings.NewReader(content))
+		})
+}
+
+func TestRun(t *testing.T) {
+	vdi := &payloads.VDI{ID: vdiID, Snapshots: []uuid.UUID{snapshotID}}
+
+	t.Run("ExportsDetachedVolume", func(t *testing.T) {
+		instance, mockXo, mockVDI := stub.NewXoInstance(gomock.NewController(t), "")
+		mockXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(vdi, nil)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), vdi).Return([]*payloads.VBD{{VM: vmID}}, nil)
+		expectExport(mockVDI, vdiID, payloads.VDIFormatVHD, "conectix")
+
+		var out bytes.Buffer
+		result, err := Run(context.Background(), []clients.XoInstance{instance}, Options{VolumeHandle: "vol-1", Format: payloads.VDIFormatVHD}, &out)
+		require.NoError(t, err)
+		assert.Equal(t, "conectix", out.String())
+		assert.Equal(t, int64(8), result.Bytes)
+		assert.Same(t, vdi, result.Exported)
+	})
+
+	t.Run("RefusesVolumeAttachedReadWrite", func(t *testing.T) {
+		instance, mockXo, _ := stub.NewXoInstance(gomock.NewController(t), "")
+		mockXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(vdi, nil)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), vdi).Return([]*payloads.VBD{{VM: vmID, Attached: true}}, nil)
+
+		var out bytes.Buffer
+		_, err := Run(context.Background(), []clients.XoInstance{instance}, Options{VolumeHandle: "vol-1", Format: payloads.VDIFormatRaw}, &out)
+		require.ErrorIs(t, err, ErrAttachedReadWrite)
+		assert.Contains(t, err.Error(), vmID.String())
+		assert.Zero(t, out.Len())
+	})
+
+	t.Run("ExportsVolumeAttachedReadOnly", func(t *testing.T) {
+		instance, mockXo, mockVDI := stub.NewXoInstance(gomock.NewController(t), "")
+		mockXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(vdi, nil)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), vdi).Return([]*payloads.VBD{{VM: vmID, Attached: true, ReadOnly: true}}, nil)
+		expectExport(mockVDI, vdiID, payloads.VDIFormatRaw, "disk")
+
+		_, err := Run(context.Background(), []clients.XoInstance{instance}, Options{VolumeHandle: "vol-1", Format: payloads.VDIFormatRaw}, io.Discard)
+		require.NoError(t, err)
+	})
+
+	t.Run("ExportsSnapshotOfAttachedVolume", func(t *testing.T) {
+		instance, mockXo, mockVDI := stub.NewXoInstance(gomock.NewController(t), "")
+		mockXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(vdi, nil)
+		mockVDI.EXPECT().Get(gomock.Any(), snapshotID).Return(&payloads.VDI{ID: snapshotID}, nil)
+		expectExport(mockVDI, snapshotID, payloads.VDIFormatVHD, "snapshot")
+
+		result, err := Run(context.Background(), []clients.XoInstance{instance}, Options{VolumeHandle: "vol-1", Snapshot: snapshotID, Format: payloads.VDIFormatVHD}, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, snapshotID, result.Exported.ID)
+		assert.Equal(t, vdiID, result.VDI.ID)
+	})
+
+	t.Run("RefusesSnapshotOfAnotherVDI", func(t *testing.T) {
+		instance, mockXo, _ := stub.NewXoInstance(gomock.NewController(t), "")
+		mockXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(vdi, nil)
+
+		_, err := Run(context.Background(), []clients.XoInstance{instance}, Options{VolumeHandle: "vol-1", Snapshot: vmID, Format: payloads.VDIFormatVHD}, io.Discard)
+		require.ErrorContains(t, err, "is not a snapshot")
+	})
+
+	t.Run("RoutesByInstance", func(t *testing.T) {
+		paris, _, _ := stub.NewXoInstance(gomock.NewController(t), "paris")
+		lyon, lyonXo, lyonVDI := stub.NewXoInstance(gomock.NewController(t), "lyon")
+		lyonXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "lyon/vol-1").Return(vdi, nil)
+		lyonXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), vdi).Return(nil, nil)
+		expectExport(lyonVDI, vdiID, payloads.VDIFormatVHD, "disk")
+
+		result, err := Run(context.Background(), []clients.XoInstance{paris, lyon}, Options{VolumeHandle: "lyon/vol-1", Format: payloads.VDIFormatVHD}, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, "lyon", result.Instance)
+
+		_, err = Run(context.Background(), []clients.XoInstance{paris, lyon}, Options{VolumeHandle: "marseille/vol-1", Format: payloads.VDIFormatVHD}, io.Discard)
+		require.ErrorContains(t, err, "not configured")
+	})
+
+	t.Run("SearchesInstancesWithoutOneInTheHandle", func(t *testing.T) {
+		paris, parisXo, _ := stub.NewXoInstance(gomock.NewController(t), "paris")
+		lyon, lyonXo, _ := stub.NewXoInstance(gomock.NewController(t), "lyon")
+		parisXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(nil, clients.ErrVolumeNotFound)
+		lyonXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(nil, clients.ErrVolumeNotFound)
+
+		_, err := Run(context.Background(), []clients.XoInstance{paris, lyon}, Options{VolumeHandle: "vol-1", Format: payloads.VDIFormatVHD}, io.Discard)
+		require.ErrorIs(t, err, clients.ErrVolumeNotFound)
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		_, err := Run(context.Background(), nil, Options{VolumeHandle: "vol-1", Format: "qcow2"}, io.Discard)
+		require.Error(t, err)
+	})
+}
+
+func TestPVVolumeHandle(t *testing.T) {
+	kubeClient := fake.NewClientset(
+		&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: "csi.xenorchestra.vates.tech", VolumeHandle: "paris/vol-1"},
+			}},
+		},
+		&corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "nfs"}},
+	)
+
+	handle, err := PVVolumeHandle(context.Background(), kubeClient, "csi.xenorchestra.vates.tech", "pvc-1")
+	require.NoError(t, err)
+	assert.Equal(t, "paris/vol-1", handle)
+
+	_, err = PVVolumeHandle(context.Background(), kubeClient, "csi.xenorchestra.vates.tech", "nfs")
+	require.ErrorContains(t, err, "not a volume of driver")
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// EventReasonVolumeForceDetached is the event reason used when a volume is
+// taken over from a VM that is not running.
+const EventReasonVolumeForceDetached = "VolumeForceDetached"
+
+// canForceDetachFrom reports whether a VBD may be taken away from a VM in the
+// given power state. A Running VM may still be writing to the disk and is
+// never eligible.
+func (driver *xenorchestraCSIDriver) canForceDetachFrom(powerState string) bool {
+	switch powerState {
+	case payloads.PowerStateHalted:
+		return true
+	case payloads.PowerStatePaused, payloads.PowerStateSuspended:
+		return driver.forceDetachPausedSuspended
+	default:
+		return false
+	}
+}
+
+// forceDetachStaleVBD force-unplugs and destroys vbd, which still attaches vdi
+// to another VM, so that the volume can be published to targetVM. This
+// typically happens after a hypervisor crash left the previous node VM halted
+// with the VBD attached.
+// It returns FailedPrecondition when the owning VM is not in a power state
+// that allows it.
+func (driver *xenorchestraCSIDriver) forceDetachStaleVBD(ctx context.Context, vdi *payloads.VDI, volumeId string, vbd *payloads.VBD, targetVM uuid.UUID) error {
+	ownerVM, err := driver.xo(ctx).VM().GetByID(ctx, vbd.VM)
+	if err != nil {
+		klog.ErrorS(err, "Failed to get VM holding the VDI", "vdiID", vdi.ID, "vmID", vbd.VM)
+		return status.Errorf(codes.Internal, "failed to get VM %s holding VDI %s: %v", vbd.VM, vdi.ID, err)
+	}
+	if !driver.canForceDetachFrom(ownerVM.PowerState) {
+		klog.ErrorS(nil, "VDI is already attached to another VM", "vdi", vdi.ID, "vmID", vbd.VM, "powerState", ownerVM.PowerState)
+		return status.Errorf(codes.FailedPrecondition, "VDI %s is already attached to another VM %s (power state %s)", vdi.ID, vbd.VM, ownerVM.PowerState)
+	}
+
+	// The VM is not running, so the VBD cannot be hot-unplugged, and XAPI
+	// refuses to delete it while attached: force-unplug it first.
+	klog.InfoS("Force-detaching VDI from VM that is not running",
+		"vdiID", vdi.ID, "vbdID", vbd.ID, "fromVM", vbd.VM, "powerState", ownerVM.PowerState, "toVM", targetVM)
+	err = driver.vmQueue.Do(ctx, vbd.VM, vmOperationForceDetach, func() error {
+		return driver.xo(ctx).ForceDestroyVBD(ctx, *vbd)
+	})
+	if err != nil {
+		klog.ErrorS(err, "Failed to force-detach VDI", "vdiID", vdi.ID, "vbdID", vbd.ID, "fromVM", vbd.VM)
+		return status.Errorf(codes.Internal, "failed to detach VDI %s from %s VM %s: %v", vdi.ID, ownerVM.PowerState, vbd.VM, err)
+	}
+
+	driver.recorder.Eventf(driver.persistentVolumeRef(ctx, vdi, volumeId), corev1.EventTypeWarning, EventReasonVolumeForceDetached,
+		"Detached VDI %s from %s VM %s (%s) to attach it to VM %s", vdi.ID, ownerVM.PowerState, ownerVM.NameLabel, vbd.VM, targetVM)
+	return nil
+}
+
+// persistentVolumePageSize is the number of PersistentVolumes listed at once
+// when looking for the one of a static volume.
+const persistentVolumePageSize = 500
+
+// persistentVolumeRef returns a reference to the PersistentVolume backed by
+// vdi, for use as an event subject. The name comes from the pvName tag of
+// dynamically provisioned volumes, without any API call. Only static volumes,
+// which lack the tag, are searched for among the PersistentVolumes, page by
+// page, by their volumeId handle. The name falls back to volumeId.
+func (driver *xenorchestraCSIDriver) persistentVolumeRef(ctx context.Context, vdi *payloads.VDI, volumeId string) *corev1.ObjectReference {
+	ref := &corev1.ObjectReference{Kind: "PersistentVolume", APIVersion: "v1", Name: clients.ParseTagValue(vdi.Tags, clients.VDITagKeyPVName)}
+	if ref.Name != "" {
+		return ref
+	}
+	if driver.kubeClient == nil {
+		ref.Name = volumeId
+		return ref
+	}
+	opts := metav1.ListOptions{Limit: persistentVolumePageSize}
+	for {
+		pvs, err := driver.kubeClient.CoreV1().PersistentVolumes().List(ctx, opts)
+		if err != nil {
+			klog.V(4).InfoS("Failed to list PersistentVolumes to reference in event", "err", err)
+			break
+		}
+		for _, pv := range pvs.Items {
+			if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == driver.Name && pv.Spec.CSI.VolumeHandle == volumeId {
+				ref.Name, ref.UID = pv.Name, pv.UID
+				return ref
+			}
+		}
+		if opts.Continue = pvs.Continue; opts.Continue == "" {
+			break
+		}
+	}
+	ref.Name = volumeId
+	return ref
+}
@@ -0,0 +1,142 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+var (
+	staleVMID  = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
+	targetVMID = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000002"))
+	staleVDIID = uuid.Must(uuid.FromString("cccccccc-0000-0000-0000-000000000003"))
+)
+
+func newForceDetachDriver(t *testing.T, powerState string, forceDetachPausedSuspended bool) (*xenorchestraCSIDriver, *clientsMock.MockXoClient, *record.FakeRecorder) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockXo := clientsMock.NewMockXoClient(ctrl)
+	mockVM := xoLibMock.NewMockVM(ctrl)
+	mockXo.EXPECT().VM().Return(mockVM).AnyTimes()
+	mockVM.EXPECT().GetByID(gomock.Any(), staleVMID).
+		Return(&payloads.VM{ID: staleVMID, NameLabel: "worker-1", PowerState: powerState}, nil)
+
+	recorder := record.NewFakeRecorder(10)
+	driver := &xenorchestraCSIDriver{
+		Name:                       DriverName,
+		xoClient:                   mockXo,
+		recorder:                   recorder,
+		vmQueue:                    NewVMOperationQueue(),
+		forceDetachPausedSuspended: forceDetachPausedSuspended,
+	}
+	return driver, mockXo, recorder
+}
+
+func TestForceDetachStaleVBD(t *testing.T) {
+	vdi := &payloads.VDI{ID: staleVDIID, Tags: []string{clients.BuildTag(clients.VDITagKeyPVName, "pv-1")}}
+	vbd := &payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: staleVMID, VDI: &staleVDIID, Attached: true}
+
+	t.Run("HaltedVMIsDetached", func(t *testing.T) {
+		driver, mockXo, recorder := newForceDetachDriver(t, payloads.PowerStateHalted, false)
+		mockXo.EXPECT().ForceDestroyVBD(gomock.Any(), *vbd).Return(nil)
+
+		require.NoError(t, driver.forceDetachStaleVBD(context.Background(), vdi, "vol-1", vbd, targetVMID))
+		require.Len(t, recorder.Events, 1)
+		assert.Contains(t, <-recorder.Events, EventReasonVolumeForceDetached)
+	})
+
+	t.Run("RunningVMIsNeverTouched", func(t *testing.T) {
+		driver, _, recorder := newForceDetachDriver(t, payloads.PowerStateRunning, true)
+
+		err := driver.forceDetachStaleVBD(context.Background(), vdi, "vol-1", vbd, targetVMID)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("SuspendedVMRequiresOptIn", func(t *testing.T) {
+		driver, _, _ := newForceDetachDriver(t, payloads.PowerStateSuspended, false)
+
+		err := driver.forceDetachStaleVBD(context.Background(), vdi, "vol-1", vbd, targetVMID)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("PausedVMWithOptInIsDetached", func(t *testing.T) {
+		driver, mockXo, _ := newForceDetachDriver(t, payloads.PowerStatePaused, true)
+		mockXo.EXPECT().ForceDestroyVBD(gomock.Any(), *vbd).Return(nil)
+
+		require.NoError(t, driver.forceDetachStaleVBD(context.Background(), vdi, "vol-1", vbd, targetVMID))
+	})
+
+	t.Run("DestroyFailureIsInternal", func(t *testing.T) {
+		driver, mockXo, recorder := newForceDetachDriver(t, payloads.PowerStateHalted, false)
+		mockXo.EXPECT().ForceDestroyVBD(gomock.Any(), *vbd).Return(errors.New("failed to destroy VBD: VBD is currently attached"))
+
+		err := driver.forceDetachStaleVBD(context.Background(), vdi, "vol-1", vbd, targetVMID)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Empty(t, recorder.Events)
+	})
+}
+
+func TestPersistentVolumeRef(t *testing.T) {
+	t.Run("FromPVNameTag", func(t *testing.T) {
+		kubeClient := fake.NewClientset()
+		driver := &xenorchestraCSIDriver{Name: DriverName, kubeClient: kubeClient}
+		vdi := &payloads.VDI{Tags: []string{clients.BuildTag(clients.VDITagKeyPVName, "pv-1")}}
+
+		assert.Equal(t, "pv-1", driver.persistentVolumeRef(context.Background(), vdi, "vol-1").Name)
+		assert.Empty(t, kubeClient.Actions(), "the PersistentVolumes are only listed for static volumes")
+	})
+
+	t.Run("StaticVolumeFromHandle", func(t *testing.T) {
+		driver := &xenorchestraCSIDriver{Name: DriverName, kubeClient: fake.NewClientset(&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "static-pv"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: DriverName, VolumeHandle: staleVDIID.String()},
+				},
+			},
+		})}
+
+		ref := driver.persistentVolumeRef(context.Background(), &payloads.VDI{ID: staleVDIID}, staleVDIID.String())
+		assert.Equal(t, "static-pv", ref.Name)
+	})
+
+	t.Run("FallsBackToVolumeID", func(t *testing.T) {
+		driver := &xenorchestraCSIDriver{Name: DriverName}
+
+		assert.Equal(t, "vol-1", driver.persistentVolumeRef(context.Background(), &payloads.VDI{}, "vol-1").Name)
+	})
+}
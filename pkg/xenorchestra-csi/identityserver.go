@@ -65,6 +65,13 @@ func (driver *xenorchestraCSIDriver) GetPluginInfo(context.Context, *csi.GetPlug
 	}, nil
 }
 
-func (driver *xenorchestraCSIDriver) Probe(context.Context, *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+// Probe reports the driver as ready when Xen Orchestra answers an
+// authenticated call and, on node plugins, the node metadata can be resolved.
+// It fails with Unavailable otherwise, so that the livenessprobe sidecar
+// restarts the plugin.
+func (driver *xenorchestraCSIDriver) Probe(ctx context.Context, _ *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+	if err := driver.readiness.Check(ctx); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "driver is not ready: %v", err)
+	}
 	return &csi.ProbeResponse{Ready: &wrappers.BoolValue{Value: true}}, nil
 }
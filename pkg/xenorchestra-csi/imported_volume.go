@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/importer"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// provisionedByAnnotation marks the PersistentVolumes of a provisioner, which
+// deletes their volume when their reclaim policy is Delete.
+const provisionedByAnnotation = "pv.kubernetes.io/provisioned-by"
+
+// ImportedVolumeOptions configures the manifests of the imported volumes.
+type ImportedVolumeOptions struct {
+	DriverName       string
+	StorageClassName string
+	ReclaimPolicy    corev1.PersistentVolumeReclaimPolicy
+	VolumeMode       corev1.PersistentVolumeMode
+	// ClaimNamespace is the namespace of the PersistentVolumeClaim bound to
+	// the PersistentVolume. Empty generates no claim.
+	ClaimNamespace string
+}
+
+// ImportedVolumeManifests returns the PersistentVolume of an imported volume,
+// with the volume attributes and pool topology CreateVolume would have set,
+// and the PersistentVolumeClaim bound to it, nil without opts.ClaimNamespace.
+func ImportedVolumeManifests(volume *importer.Volume, opts ImportedVolumeOptions) (*corev1.PersistentVolume, *corev1.PersistentVolumeClaim) {
+	storageType := StorageTypeShared
+	if !volume.SR.Shared {
+		storageType = StorageTypeLocal
+	}
+	capacity := corev1.ResourceList{corev1.ResourceStorage: *resource.NewQuantity(volume.VDI.Size, resource.BinarySI)}
+	accessModes := []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+
+	pv := &corev1.PersistentVolume{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        volume.PVName,
+			Annotations: map[string]string{provisionedByAnnotation: opts.DriverName},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:                      capacity,
+			AccessModes:                   accessModes,
+			PersistentVolumeReclaimPolicy: opts.ReclaimPolicy,
+			StorageClassName:              opts.StorageClassName,
+			VolumeMode:                    &opts.VolumeMode,
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:           opts.DriverName,
+					VolumeHandle:     volume.VolumeHandle(),
+					VolumeAttributes: buildVolumeContext(volume.Pool, volume.SR, storageType),
+				},
+			},
+			NodeAffinity: poolNodeAffinity(volume.Pool),
+		},
+	}
+	if opts.ClaimNamespace == "" {
+		return pv, nil
+	}
+
+	// Both sides are bound so that no other claim takes the volume.
+	pv.Spec.ClaimRef = &corev1.ObjectReference{Namespace: opts.ClaimNamespace, Name: volume.PVName}
+	pvc := &corev1.PersistentVolumeClaim{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: opts.ClaimNamespace, Name: volume.PVName},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			Resources:        corev1.VolumeResourceRequirements{Requests: capacity},
+			StorageClassName: &opts.StorageClassName,
+			VolumeMode:       &opts.VolumeMode,
+			VolumeName:       volume.PVName,
+		},
+	}
+	return pv, pvc
+}
+
+// poolNodeAffinity restricts a PersistentVolume to the nodes of pool, like the
+// accessible topology CreateVolume returns.
+func poolNodeAffinity(pool *payloads.Pool) *corev1.VolumeNodeAffinity {
+	return &corev1.VolumeNodeAffinity{
+		Required: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key:      xok8s.XOLabelTopologyPoolID,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{pool.ID.String()},
+				}},
+			}},
+		},
+	}
+}
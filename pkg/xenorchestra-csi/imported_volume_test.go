@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/importer"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestImportedVolumeManifests(t *testing.T) {
+	pool := &payloads.Pool{ID: uuid.Must(uuid.NewV4()), NameLabel: "pool-a"}
+	volume := &importer.Volume{
+		Instance: "paris",
+		VDI:      &payloads.VDI{ID: uuid.Must(uuid.NewV4()), Size: 10 << 30},
+		Pool:     pool,
+		SR:       &payloads.StorageRepository{ID: uuid.Must(uuid.NewV4()), NameLabel: "local-a"},
+		VolumeID: "vol-1",
+		PVName:   "xo-data",
+	}
+	opts := ImportedVolumeOptions{
+		DriverName:       DriverName,
+		StorageClassName: "xo",
+		ReclaimPolicy:    corev1.PersistentVolumeReclaimRetain,
+		VolumeMode:       corev1.PersistentVolumeFilesystem,
+	}
+
+	t.Run("PersistentVolume", func(t *testing.T) {
+		pv, pvc := ImportedVolumeManifests(volume, opts)
+		assert.Nil(t, pvc)
+		assert.Equal(t, "xo-data", pv.Name)
+		assert.Equal(t, "10Gi", pv.Spec.Capacity.Storage().String())
+		assert.Equal(t, "xo", pv.Spec.StorageClassName)
+		require.NotNil(t, pv.Spec.CSI)
+		assert.Equal(t, "paris/vol-1", pv.Spec.CSI.VolumeHandle)
+		assert.Equal(t, StorageTypeLocal, pv.Spec.CSI.VolumeAttributes[VolumeContextKeyStorageType], "the VDI is on a local SR")
+		assert.Equal(t, "pool-a", pv.Spec.CSI.VolumeAttributes[VolumeContextKeyPoolName])
+		require.NotNil(t, pv.Spec.NodeAffinity)
+		term := pv.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions[0]
+		assert.Equal(t, xok8s.XOLabelTopologyPoolID, term.Key)
+		assert.Equal(t, []string{pool.ID.String()}, term.Values)
+		assert.Nil(t, pv.Spec.ClaimRef)
+	})
+
+	t.Run("BoundClaim", func(t *testing.T) {
+		claimOpts := opts
+		claimOpts.ClaimNamespace = "legacy"
+		pv, pvc := ImportedVolumeManifests(volume, claimOpts)
+		require.NotNil(t, pvc)
+		assert.Equal(t, "legacy", pvc.Namespace)
+		assert.Equal(t, "xo-data", pvc.Spec.VolumeName)
+		assert.Equal(t, "xo", *pvc.Spec.StorageClassName)
+		assert.Equal(t, pv.Spec.Capacity, pvc.Spec.Resources.Requests)
+		require.NotNil(t, pv.Spec.ClaimRef)
+		assert.Equal(t, "legacy", pv.Spec.ClaimRef.Namespace)
+		assert.Equal(t, pvc.Name, pv.Spec.ClaimRef.Name)
+	})
+}
@@ -0,0 +1,317 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package importer turns existing VDIs into volumes of the driver: it tags
+// them the way CreateVolume tags the VDIs it creates, so that static
+// PersistentVolumes can reference them by volume ID.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Status describes the outcome of the import of a VDI.
+type Status string
+
+const (
+	// StatusImported means the VDI carries the tags of a volume.
+	StatusImported Status = "imported"
+	// StatusPlanned means the tags were only computed (dry-run).
+	StatusPlanned Status = "planned"
+	// StatusSkipped means the VDI cannot be imported, see Reason.
+	StatusSkipped Status = "skipped"
+	// StatusFailed means tagging the VDI failed, see Reason. Running the
+	// import again resumes it with the same volume ID.
+	StatusFailed Status = "failed"
+)
+
+// Selector selects the VDIs to import. Exactly one of its fields is set.
+type Selector struct {
+	VDIIDs []uuid.UUID
+	// SRID selects the VDIs of an SR.
+	SRID uuid.UUID
+	// Tag selects the VDIs carrying a tag.
+	Tag string
+}
+
+// Options configures Run.
+type Options struct {
+	Selector
+	// DriverName is the CSI driver name PersistentVolumes must reference.
+	DriverName string
+	// ClusterTag is the --cluster-tag of the driver, added to the VDIs.
+	ClusterTag string
+	// ManagedBy is the value of the managedBy tag of the VDIs without one.
+	ManagedBy string
+	// PVNamePrefix is prepended to the VDI UUID to name the PersistentVolume
+	// of a VDI without a PV name tag.
+	PVNamePrefix string
+	// Force imports the VDIs linked to a VM by a VBD, which are skipped
+	// otherwise: they may be the disks of VMs outside the cluster.
+	Force bool
+	// DryRun computes the tags without adding them.
+	DryRun bool
+}
+
+// Volume is the import of a VDI.
+type Volume struct {
+	Instance string
+	VDI      *payloads.VDI
+	Pool     *payloads.Pool
+	SR       *payloads.StorageRepository
+	VolumeID string
+	PVName   string
+	// Tags are the tags added, or to be added, to the VDI.
+	Tags   []string
+	Status Status
+	Reason string
+}
+
+// VolumeHandle returns the handle PersistentVolumes reference the volume by.
+func (v *Volume) VolumeHandle() string {
+	return clients.JoinVolumeHandle(v.Instance, v.VolumeID)
+}
+
+// Run imports the selected VDIs. It is idempotent: a VDI already tagged keeps
+// its volume ID and PV name. When kubeClient is not nil, the VDIs already
+// referenced by a PersistentVolume are skipped.
+func Run(ctx context.Context, instances []clients.XoInstance, kubeClient kube.Interface, opts Options) ([]Volume, error) {
+	if err := opts.Selector.validate(); err != nil {
+		return nil, err
+	}
+	var handles map[string]string
+	if kubeClient != nil {
+		var err error
+		if handles, err = listVolumeHandles(ctx, kubeClient, opts.DriverName); err != nil {
+			return nil, err
+		}
+	}
+
+	volumes := []Volume{}
+	found := map[uuid.UUID]bool{}
+	for _, instance := range instances {
+		vdis, err := selectVDIs(ctx, instance.Client, opts.Selector)
+		if err != nil {
+			if instance.Name != "" {
+				err = fmt.Errorf("Xen Orchestra instance %q: %w", instance.Name, err)
+			}
+			return nil, err
+		}
+		resolver := newResolver(instance.Client)
+		for _, vdi := range vdis {
+			found[vdi.ID] = true
+			volume, err := importVDI(ctx, instance, resolver, vdi, handles, opts)
+			if err != nil {
+				return nil, err
+			}
+			volumes = append(volumes, volume)
+		}
+	}
+	for _, id := range opts.VDIIDs {
+		if !found[id] {
+			return nil, fmt.Errorf("VDI %s not found in Xen Orchestra", id)
+		}
+	}
+	return volumes, nil
+}
+
+func (s *Selector) validate() error {
+	selectors := 0
+	if len(s.VDIIDs) > 0 {
+		selectors++
+	}
+	if s.SRID != uuid.Nil {
+		selectors++
+	}
+	if s.Tag != "" {
+		selectors++
+	}
+	if selectors != 1 {
+		return errors.New("select the VDIs to import by UUID, SR or tag, and by only one of them")
+	}
+	return nil
+}
+
+// selectVDIs returns the selected VDIs of an instance. The VDIs selected by
+// UUID that the instance does not store are left out.
+func selectVDIs(ctx context.Context, xoClient clients.XoClient, selector Selector) ([]*payloads.VDI, error) {
+	switch {
+	case selector.SRID != uuid.Nil:
+		vdis, err := xoClient.VDI().GetAll(ctx, 0, fmt.Sprintf("$SR:%s", selector.SRID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the VDIs of SR %s: %w", selector.SRID, err)
+		}
+		return vdis, nil
+	case selector.Tag != "":
+		vdis, err := xoClient.VDI().GetAll(ctx, 0, clients.BuildClusterTagFilter(selector.Tag))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list VDIs with tag %q: %w", selector.Tag, err)
+		}
+		return vdis, nil
+	}
+	vdis := make([]*payloads.VDI, 0, len(selector.VDIIDs))
+	for _, id := range selector.VDIIDs {
+		found, err := xoClient.VDI().GetAll(ctx, 1, fmt.Sprintf("id:%s", id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get VDI %s: %w", id, err)
+		}
+		vdis = append(vdis, found...)
+	}
+	return vdis, nil
+}
+
+func importVDI(ctx context.Context, instance clients.XoInstance, resolver *resolver, vdi *payloads.VDI, handles map[string]string, opts Options) (Volume, error) {
+	volume := Volume{
+		Instance: instance.Name,
+		VDI:      vdi,
+		VolumeID: clients.ParseTagValue(vdi.Tags, clients.VDITagKeyVolumeId),
+		PVName:   clients.ParseTagValue(vdi.Tags, clients.VDITagKeyPVName),
+		Tags:     []string{},
+	}
+	switch {
+	case vdi.Missing:
+		volume.Status, volume.Reason = StatusSkipped, "the VDI is missing from its SR"
+		return volume, nil
+	case vdi.VDIType != "" && vdi.VDIType != payloads.VDITypeUser:
+		volume.Status, volume.Reason = StatusSkipped, fmt.Sprintf("%s VDI", vdi.VDIType)
+		return volume, nil
+	}
+	for _, handle := range []string{volume.VolumeID, vdi.ID.String()} {
+		if pv, found := handles[handle]; found && handle != "" {
+			volume.Status, volume.Reason = StatusSkipped, fmt.Sprintf("already referenced by PersistentVolume %s", pv)
+			return volume, nil
+		}
+	}
+	if len(vdi.VBDs) > 0 && !opts.Force {
+		volume.Status, volume.Reason = StatusSkipped, fmt.Sprintf("linked to a VM by %d VBD(s), detach it or use --force", len(vdi.VBDs))
+		return volume, nil
+	}
+
+	var err error
+	if volume.SR, err = resolver.sr(ctx, vdi.SR); err != nil {
+		return volume, err
+	}
+	if volume.Pool, err = resolver.pool(ctx, vdi.PoolID); err != nil {
+		return volume, err
+	}
+
+	if volume.VolumeID == "" {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return volume, fmt.Errorf("failed to generate volume ID UUID: %w", err)
+		}
+		volume.VolumeID = id.String()
+		volume.Tags = append(volume.Tags, clients.BuildTag(clients.VDITagKeyVolumeId, volume.VolumeID))
+	}
+	if volume.PVName == "" {
+		volume.PVName = opts.PVNamePrefix + vdi.ID.String()
+		volume.Tags = append(volume.Tags, clients.BuildTag(clients.VDITagKeyPVName, volume.PVName))
+	}
+	if opts.ClusterTag != "" && !slices.Contains(vdi.Tags, opts.ClusterTag) {
+		volume.Tags = append(volume.Tags, opts.ClusterTag)
+	}
+	if opts.ManagedBy != "" && clients.ParseTagValue(vdi.Tags, clients.VDITagKeyManagedBy) == "" {
+		volume.Tags = append(volume.Tags, clients.BuildTag(clients.VDITagKeyManagedBy, opts.ManagedBy))
+	}
+	if len(vdi.VBDs) > 0 {
+		klog.Warningf("Importing VDI %s, which has %d VBD(s): the driver takes it over from a halted VM only, shut the VM down or detach the VDI first", vdi.ID, len(vdi.VBDs))
+	}
+
+	if opts.DryRun {
+		volume.Status = StatusPlanned
+		return volume, nil
+	}
+	// The volume ID tag comes first, so that a retry after a failure keeps
+	// the same volume ID.
+	for _, tag := range volume.Tags {
+		if err := instance.Client.VDI().AddTag(ctx, vdi.ID, tag); err != nil {
+			klog.ErrorS(err, "Failed to tag VDI", "vdiID", vdi.ID, "tag", tag)
+			volume.Status, volume.Reason = StatusFailed, err.Error()
+			return volume, nil
+		}
+	}
+	klog.InfoS("Imported VDI", "vdiID", vdi.ID, "volumeID", volume.VolumeID, "pvName", volume.PVName)
+	volume.Status = StatusImported
+	return volume, nil
+}
+
+// listVolumeHandles maps the volume IDs in the handles of the
+// PersistentVolumes of the driver to the name of the PersistentVolume.
+func listVolumeHandles(ctx context.Context, kubeClient kube.Interface, driverName string) (map[string]string, error) {
+	list, err := kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumes: %w", err)
+	}
+	handles := make(map[string]string, len(list.Items))
+	for _, pv := range list.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == driverName {
+			_, volumeID := clients.SplitVolumeHandle(pv.Spec.CSI.VolumeHandle)
+			handles[volumeID] = pv.Name
+		}
+	}
+	return handles, nil
+}
+
+// resolver looks up the SRs and pools of an instance once each.
+type resolver struct {
+	xoClient clients.XoClient
+	srs      map[uuid.UUID]*payloads.StorageRepository
+	pools    map[uuid.UUID]*payloads.Pool
+}
+
+func newResolver(xoClient clients.XoClient) *resolver {
+	return &resolver{
+		xoClient: xoClient,
+		srs:      map[uuid.UUID]*payloads.StorageRepository{},
+		pools:    map[uuid.UUID]*payloads.Pool{},
+	}
+}
+
+func (r *resolver) sr(ctx context.Context, id uuid.UUID) (*payloads.StorageRepository, error) {
+	if sr, found := r.srs[id]; found {
+		return sr, nil
+	}
+	sr, err := r.xoClient.SR().Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SR %s: %w", id, err)
+	}
+	r.srs[id] = sr
+	return sr, nil
+}
+
+func (r *resolver) pool(ctx context.Context, id uuid.UUID) (*payloads.Pool, error) {
+	if pool, found := r.pools[id]; found {
+		return pool, nil
+	}
+	pool, err := r.xoClient.Pool().Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool %s: %w", id, err)
+	}
+	r.pools[id] = pool
+	return pool, nil
+}
@@ -0,0 +1,224 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testDriverName = "csi.xenorchestra.vates.tech"
+	testClusterTag = "k8s-test"
+	testManagedBy  = testDriverName + "@dev"
+)
+
+var (
+	poolID = uuid.Must(uuid.FromString("11111111-0000-0000-0000-000000000001"))
+	srID   = uuid.Must(uuid.FromString("22222222-0000-0000-0000-000000000002"))
+
+	legacyID   = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
+	taggedID   = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000002"))
+	snapshotID = uuid.Must(uuid.FromString("cccccccc-0000-0000-0000-000000000003"))
+	boundID    = uuid.Must(uuid.FromString("dddddddd-0000-0000-0000-000000000004"))
+)
+
+func newTestInstance(t *testing.T) (clients.XoInstance, *xoLibMock.MockVDI) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	instance, mockXo, mockVDI := stub.NewXoInstance(ctrl, "")
+	mockSR := xoLibMock.NewMockSR(ctrl)
+	mockPool := xoLibMock.NewMockPool(ctrl)
+	mockXo.EXPECT().SR().Return(mockSR).AnyTimes()
+	mockXo.EXPECT().Pool().Return(mockPool).AnyTimes()
+	// SRs and pools are looked up once each.
+	mockSR.EXPECT().Get(gomock.Any(), srID).Return(&payloads.StorageRepository{ID: srID, NameLabel: "nfs", Shared: true}, nil).MaxTimes(1)
+	mockPool.EXPECT().Get(gomock.Any(), poolID).Return(&payloads.Pool{ID: poolID, NameLabel: "pool-a"}, nil).MaxTimes(1)
+	return instance, mockVDI
+}
+
+func newVDI(id uuid.UUID, tags ...string) *payloads.VDI {
+	vdi := stub.NewVolumeVDI(id, "", "", tags...)
+	vdi.NameLabel, vdi.SR, vdi.PoolID, vdi.VDIType = "data", srID, poolID, payloads.VDITypeUser
+	return vdi
+}
+
+func byVDI(volumes []Volume) map[uuid.UUID]Volume {
+	m := map[uuid.UUID]Volume{}
+	for _, v := range volumes {
+		m[v.VDI.ID] = v
+	}
+	return m
+}
+
+func TestRun(t *testing.T) {
+	opts := Options{DriverName: testDriverName, ClusterTag: testClusterTag, ManagedBy: testManagedBy, PVNamePrefix: "xo-"}
+
+	t.Run("BySR", func(t *testing.T) {
+		instance, mockVDI := newTestInstance(t)
+		tagged := newVDI(taggedID,
+			clients.BuildTag(clients.VDITagKeyVolumeId, "vol-2"),
+			clients.BuildTag(clients.VDITagKeyPVName, "pv-2"),
+			testClusterTag,
+			clients.BuildTag(clients.VDITagKeyManagedBy, testManagedBy),
+		)
+		snapshot := newVDI(snapshotID)
+		snapshot.VDIType = payloads.VDITypeSuspend
+		bound := newVDI(boundID)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("$SR:%s", srID)).Return([]*payloads.VDI{newVDI(legacyID), tagged, snapshot, bound}, nil)
+
+		var added []string
+		mockVDI.EXPECT().AddTag(gomock.Any(), legacyID, gomock.Any()).DoAndReturn(func(_ context.Context, _ uuid.UUID, tag string) error {
+			added = append(added, tag)
+			return nil
+		}).Times(4)
+		kubeClient := fake.NewClientset(&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "static"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: testDriverName, VolumeHandle: boundID.String()},
+			}},
+		})
+
+		srOpts := opts
+		srOpts.SRID = srID
+		volumes, err := Run(context.Background(), []clients.XoInstance{instance}, kubeClient, srOpts)
+		require.NoError(t, err)
+		require.Len(t, volumes, 4)
+		got := byVDI(volumes)
+
+		legacy := got[legacyID]
+		assert.Equal(t, StatusImported, legacy.Status)
+		assert.Equal(t, "xo-"+legacyID.String(), legacy.PVName)
+		require.NotEmpty(t, legacy.VolumeID)
+		assert.Equal(t, legacy.VolumeID, legacy.VolumeHandle())
+		assert.Equal(t, []string{
+			clients.BuildTag(clients.VDITagKeyVolumeId, legacy.VolumeID),
+			clients.BuildTag(clients.VDITagKeyPVName, legacy.PVName),
+			testClusterTag,
+			clients.BuildTag(clients.VDITagKeyManagedBy, testManagedBy),
+		}, added, "the volume ID tag is added first")
+		assert.Equal(t, "pool-a", legacy.Pool.NameLabel)
+		assert.Equal(t, "nfs", legacy.SR.NameLabel)
+
+		assert.Equal(t, StatusImported, got[taggedID].Status, "a VDI already tagged is imported again")
+		assert.Equal(t, "vol-2", got[taggedID].VolumeID)
+		assert.Equal(t, "pv-2", got[taggedID].PVName)
+		assert.Empty(t, got[taggedID].Tags)
+
+		assert.Equal(t, StatusSkipped, got[snapshotID].Status)
+		assert.Equal(t, StatusSkipped, got[boundID].Status)
+		assert.Contains(t, got[boundID].Reason, "static")
+	})
+
+	t.Run("SkipsVDIsLinkedToVMs", func(t *testing.T) {
+		instance, mockVDI := newTestInstance(t)
+		attached := newVDI(legacyID)
+		attached.VBDs = []uuid.UUID{uuid.Must(uuid.NewV4())}
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("$SR:%s", srID)).Return([]*payloads.VDI{attached}, nil)
+
+		srOpts := opts
+		srOpts.SRID = srID
+		volumes, err := Run(context.Background(), []clients.XoInstance{instance}, nil, srOpts)
+		require.NoError(t, err)
+		require.Len(t, volumes, 1)
+		assert.Equal(t, StatusSkipped, volumes[0].Status)
+		assert.Contains(t, volumes[0].Reason, "--force")
+		assert.Empty(t, volumes[0].Tags)
+	})
+
+	t.Run("ForceImportsVDIsLinkedToVMs", func(t *testing.T) {
+		instance, mockVDI := newTestInstance(t)
+		attached := newVDI(legacyID)
+		attached.VBDs = []uuid.UUID{uuid.Must(uuid.NewV4())}
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("$SR:%s", srID)).Return([]*payloads.VDI{attached}, nil)
+
+		srOpts := opts
+		srOpts.SRID, srOpts.Force, srOpts.DryRun = srID, true, true
+		volumes, err := Run(context.Background(), []clients.XoInstance{instance}, nil, srOpts)
+		require.NoError(t, err)
+		require.Len(t, volumes, 1)
+		assert.Equal(t, StatusPlanned, volumes[0].Status)
+	})
+
+	t.Run("ByUUIDAcrossInstances", func(t *testing.T) {
+		paris, parisVDI := newTestInstance(t)
+		paris.Name = "paris"
+		lyon, lyonVDI := newTestInstance(t)
+		lyon.Name = "lyon"
+		parisVDI.EXPECT().GetAll(gomock.Any(), 1, fmt.Sprintf("id:%s", legacyID)).Return(nil, nil)
+		lyonVDI.EXPECT().GetAll(gomock.Any(), 1, fmt.Sprintf("id:%s", legacyID)).Return([]*payloads.VDI{newVDI(legacyID)}, nil)
+
+		uuidOpts := opts
+		uuidOpts.VDIIDs = []uuid.UUID{legacyID}
+		uuidOpts.DryRun = true
+		volumes, err := Run(context.Background(), []clients.XoInstance{paris, lyon}, nil, uuidOpts)
+		require.NoError(t, err)
+		require.Len(t, volumes, 1)
+		assert.Equal(t, StatusPlanned, volumes[0].Status)
+		assert.Equal(t, "lyon/"+volumes[0].VolumeID, volumes[0].VolumeHandle())
+		assert.Len(t, volumes[0].Tags, 4)
+	})
+
+	t.Run("UnknownUUID", func(t *testing.T) {
+		instance, mockVDI := newTestInstance(t)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 1, gomock.Any()).Return(nil, nil)
+
+		uuidOpts := opts
+		uuidOpts.VDIIDs = []uuid.UUID{legacyID}
+		_, err := Run(context.Background(), []clients.XoInstance{instance}, nil, uuidOpts)
+		require.ErrorContains(t, err, legacyID.String())
+	})
+
+	t.Run("TagFailure", func(t *testing.T) {
+		instance, mockVDI := newTestInstance(t)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, clients.BuildClusterTagFilter("legacy")).Return([]*payloads.VDI{newVDI(legacyID, "legacy")}, nil)
+		mockVDI.EXPECT().AddTag(gomock.Any(), legacyID, gomock.Any()).Return(errors.New("forbidden"))
+
+		tagOpts := opts
+		tagOpts.Tag = "legacy"
+		volumes, err := Run(context.Background(), []clients.XoInstance{instance}, nil, tagOpts)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, volumes[0].Status)
+		assert.Equal(t, "forbidden", volumes[0].Reason)
+	})
+
+	t.Run("RequiresOneSelector", func(t *testing.T) {
+		_, err := Run(context.Background(), nil, nil, opts)
+		require.Error(t, err)
+
+		both := opts
+		both.Tag, both.SRID = "legacy", srID
+		_, err = Run(context.Background(), nil, nil, both)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,198 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"path"
+	"runtime/debug"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/tracing"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// RequestIDHeader is the gRPC response header carrying the request ID.
+	RequestIDHeader = "x-request-id"
+	// strippedSecret replaces the values of the fields marked as secrets.
+	strippedSecret = "***stripped***"
+)
+
+// unaryInterceptors returns the interceptors run around every CSI call, from
+// the outermost to the innermost. Panics are recovered right inside the
+// logging, so that a panic of any other interceptor, such as the selection of
+// the Xen Orchestra client, is logged as an Internal error. The panics of the
+// handler are recovered innermost too, so that the span and the metrics also
+// see the resulting error.
+func unaryInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		loggingInterceptor,
+		recoveryInterceptor,
+		tracingInterceptor,
+		metricsInterceptor,
+		xoClientInterceptor,
+		recoveryInterceptor,
+	}
+}
+
+type requestIDKey struct{}
+
+// RequestID returns the ID assigned to the CSI call of ctx, or "" outside of one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// loggingInterceptor assigns a request ID to the call and logs it once it
+// ends, with its method, duration and code. The request and response are
+// added, without their secrets, at verbosity 5. The ID is returned to the
+// caller in the x-request-id header, recorded on the RPC span and available
+// to the handler through RequestID and klog.FromContext.
+func loggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	requestID := newRequestID()
+	method := path.Base(info.FullMethod)
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+	ctx = klog.NewContext(ctx, klog.FromContext(ctx).WithValues("requestID", requestID, "method", method))
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrRequestID.String(requestID))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	keysAndValues := []any{"method", method, "requestID", requestID, "code", status.Code(err), "duration", time.Since(start)}
+	if klog.V(5).Enabled() {
+		keysAndValues = append(keysAndValues, "request", stripSecrets(req), "response", stripSecrets(resp))
+	}
+	switch {
+	case err != nil && isServerError(status.Code(err)):
+		klog.ErrorS(err, "CSI call failed", keysAndValues...)
+	case err != nil:
+		klog.V(2).InfoS("CSI call rejected", append(keysAndValues, "err", err)...)
+	default:
+		klog.V(3).InfoS("CSI call", keysAndValues...)
+	}
+	return resp, err
+}
+
+// isServerError reports whether code denotes a failure of the driver or of
+// Xen Orchestra, rather than a request the driver legitimately refused.
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DeadlineExceeded, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// recoveryInterceptor turns a panic of the handler into an Internal error
+// instead of crashing the plugin.
+func recoveryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			klog.ErrorS(nil, "Panic in CSI handler", "method", path.Base(info.FullMethod), "requestID", RequestID(ctx), "panic", r, "stack", string(debug.Stack()))
+			resp, err = nil, status.Errorf(codes.Internal, "internal error in %s (request %s): %v", path.Base(info.FullMethod), RequestID(ctx), r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// stripSecrets returns the text form of a CSI message whose fields marked
+// with the csi_secret option are replaced by a placeholder. Values that are
+// not protobuf messages are returned unchanged.
+func stripSecrets(v any) any {
+	msg, ok := v.(proto.Message)
+	if !ok || msg == nil || !msg.ProtoReflect().IsValid() {
+		return v
+	}
+	clone := proto.Clone(msg)
+	stripMessageSecrets(clone.ProtoReflect())
+	return prototext.MarshalOptions{}.Format(clone)
+}
+
+func stripMessageSecrets(m protoreflect.Message) {
+	var fields []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+
+	for _, fd := range fields {
+		value := m.Get(fd)
+		switch {
+		case isSecretField(fd):
+			stripSecretField(m, fd, value)
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			value.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				stripMessageSecrets(v.Message())
+				return true
+			})
+		case fd.IsList() && fd.Message() != nil:
+			list := value.List()
+			for i := range list.Len() {
+				stripMessageSecrets(list.Get(i).Message())
+			}
+		case fd.Message() != nil:
+			stripMessageSecrets(value.Message())
+		}
+	}
+}
+
+func isSecretField(fd protoreflect.FieldDescriptor) bool {
+	options := fd.Options()
+	if options == nil {
+		return false
+	}
+	secret, _ := proto.GetExtension(options, csi.E_CsiSecret).(bool)
+	return secret
+}
+
+// stripSecretField keeps the keys of secret maps, which help debugging, and
+// replaces every value.
+func stripSecretField(m protoreflect.Message, fd protoreflect.FieldDescriptor, value protoreflect.Value) {
+	switch {
+	case fd.IsMap() && fd.MapValue().Kind() == protoreflect.StringKind:
+		secrets := value.Map()
+		secrets.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			secrets.Set(k, protoreflect.ValueOfString(strippedSecret))
+			return true
+		})
+	case fd.Kind() == protoreflect.StringKind && !fd.IsList():
+		m.Set(fd, protoreflect.ValueOfString(strippedSecret))
+	default:
+		m.Clear(fd)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStripSecrets(t *testing.T) {
+	t.Run("NodeStageVolume", func(t *testing.T) {
+		req := &csi.NodeStageVolumeRequest{
+			VolumeId:          "volume-1",
+			StagingTargetPath: "/staging",
+			Secrets:           map[string]string{"password": "hunter2"},
+		}
+		out := fmt.Sprint(stripSecrets(req))
+		assert.NotContains(t, out, "hunter2")
+		assert.Contains(t, out, "password")
+		assert.Contains(t, out, strippedSecret)
+		assert.Contains(t, out, "volume-1")
+		assert.Equal(t, "hunter2", req.Secrets["password"], "the request itself must not be modified")
+	})
+
+	t.Run("NestedSecrets", func(t *testing.T) {
+		req := &csi.CreateVolumeRequest{
+			Name:    "pvc-1",
+			Secrets: map[string]string{"token": "s3cr3t"},
+			VolumeContentSource: &csi.VolumeContentSource{Type: &csi.VolumeContentSource_Volume{
+				Volume: &csi.VolumeContentSource_VolumeSource{VolumeId: "source"},
+			}},
+		}
+		out := fmt.Sprint(stripSecrets(req))
+		assert.NotContains(t, out, "s3cr3t")
+		assert.Contains(t, out, "pvc-1")
+		assert.Contains(t, out, "source")
+	})
+
+	t.Run("NonMessagesAreUnchanged", func(t *testing.T) {
+		assert.Nil(t, stripSecrets(nil))
+		assert.Equal(t, "text", stripSecrets("text"))
+		var resp *csi.NodeStageVolumeResponse
+		assert.Equal(t, resp, stripSecrets(resp))
+	})
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Node/NodeStageVolume"}
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "0123abcd")
+
+	resp, err := recoveryInterceptor(ctx, &csi.NodeStageVolumeRequest{}, info, func(context.Context, any) (any, error) {
+		var vbd *struct{ ID string }
+		return vbd.ID, nil
+	})
+	assert.Nil(t, resp)
+	require.Error(t, err)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Contains(t, err.Error(), "0123abcd")
+}
+
+func TestLoggingInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Identity/Probe"}
+	var seen []string
+	handler := func(ctx context.Context, _ any) (any, error) {
+		seen = append(seen, RequestID(ctx))
+		return &csi.ProbeResponse{}, nil
+	}
+
+	for range 2 {
+		_, err := loggingInterceptor(context.Background(), &csi.ProbeRequest{}, info, handler)
+		require.NoError(t, err)
+	}
+	require.Len(t, seen, 2)
+	assert.Len(t, seen[0], 16)
+	assert.NotEqual(t, seen[0], seen[1], "every call gets its own request ID")
+}
+
+func TestUnaryInterceptorsChain(t *testing.T) {
+	// A panic surfaces as Internal through the whole chain.
+	interceptors := unaryInterceptors()
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/CreateVolume"}
+	handler := grpc.UnaryHandler(func(context.Context, any) (any, error) { panic("boom") })
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req any) (any, error) { return interceptor(ctx, req, info, next) }
+	}
+	_, err := handler(context.Background(), &csi.CreateVolumeRequest{Name: "pvc-1"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	// So does a panic of an interceptor, such as while selecting the client.
+	panicking := func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) { panic("boom") }
+	handler = func(context.Context, any) (any, error) { return &csi.CreateVolumeResponse{}, nil }
+	interceptors = append(interceptors[:2:2], panicking)
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req any) (any, error) { return interceptor(ctx, req, info, next) }
+	}
+	_, err = handler(context.Background(), &csi.CreateVolumeRequest{Name: "pvc-1"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
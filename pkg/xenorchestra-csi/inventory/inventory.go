@@ -0,0 +1,342 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory lists the VDIs owned by the cluster together with the
+// Kubernetes PersistentVolumes referencing them.
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+)
+
+// Problem is an inconsistency found on a volume.
+type Problem string
+
+const (
+	// ProblemOrphan means no PersistentVolume references the VDI.
+	ProblemOrphan Problem = "orphan"
+	// ProblemAmbiguousID means several VDIs of the instance carry the volume ID,
+	// other than the members of a striped volume.
+	ProblemAmbiguousID Problem = "ambiguous-id"
+	// ProblemMissingTags means the VDI lacks the volume ID or PV name tag.
+	ProblemMissingTags Problem = "missing-tags"
+)
+
+// Options configures Collect.
+type Options struct {
+	// DriverName is the CSI driver name PersistentVolumes must reference.
+	DriverName string
+	// ClusterTag selects the VDIs owned by this cluster. It must not be empty.
+	ClusterTag string
+}
+
+// VM is a VM a volume is attached to.
+type VM struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// Plugged is false for a VBD left unplugged on the VM.
+	Plugged bool `json:"plugged"`
+}
+
+// Volume is a VDI owned by the cluster.
+type Volume struct {
+	Instance string    `json:"instance,omitempty"`
+	VDIID    uuid.UUID `json:"vdiId"`
+	VDIName  string    `json:"vdiName"`
+	// VolumeID and PVName are read from the VDI tags.
+	VolumeID string `json:"volumeId"`
+	PVName   string `json:"pvName"`
+	// ReplicaOf is the ID of the volume the VDI is a replica of, complete or
+	// being copied. PVName is then the PersistentVolume of that volume.
+	ReplicaOf string    `json:"replicaOf,omitempty"`
+	SRID      uuid.UUID `json:"srId"`
+	SRName    string    `json:"srName"`
+	PoolID    uuid.UUID `json:"poolId"`
+	PoolName  string    `json:"poolName"`
+	Size      int64     `json:"size"`
+	VMs       []VM      `json:"vms"`
+	// PersistentVolume and Claim are the PersistentVolume referencing the
+	// VDI, and its claim as namespace/name. They are empty when Kubernetes
+	// was not checked.
+	PersistentVolume string    `json:"persistentVolume,omitempty"`
+	Claim            string    `json:"claim,omitempty"`
+	Problems         []Problem `json:"problems"`
+}
+
+// Inventory lists the volumes of the cluster.
+type Inventory struct {
+	// Kubernetes is false when the volumes were not cross-referenced against
+	// the PersistentVolumes, in which case no orphan is reported.
+	Kubernetes bool     `json:"kubernetes"`
+	Volumes    []Volume `json:"volumes"`
+}
+
+// Collect lists the VDIs carrying the cluster tag in every instance. When
+// kubeClient is not nil, they are cross-referenced against the
+// PersistentVolumes of the driver.
+func Collect(ctx context.Context, instances []clients.XoInstance, kubeClient kube.Interface, opts Options) (*Inventory, error) {
+	if opts.ClusterTag == "" {
+		return nil, errors.New("a cluster tag is required to identify the VDIs owned by this cluster")
+	}
+
+	inventory := &Inventory{Kubernetes: kubeClient != nil, Volumes: []Volume{}}
+	var pvs *persistentVolumes
+	if kubeClient != nil {
+		var err error
+		if pvs, err = listPersistentVolumes(ctx, kubeClient, opts.DriverName); err != nil {
+			return nil, err
+		}
+	}
+	for _, instance := range instances {
+		volumes, err := collectInstance(ctx, instance, pvs, opts)
+		if err != nil {
+			if instance.Name != "" {
+				err = fmt.Errorf("Xen Orchestra instance %q: %w", instance.Name, err)
+			}
+			return nil, err
+		}
+		inventory.Volumes = append(inventory.Volumes, volumes...)
+	}
+	return inventory, nil
+}
+
+func collectInstance(ctx context.Context, instance clients.XoInstance, pvs *persistentVolumes, opts Options) ([]Volume, error) {
+	xoClient := instance.Client
+	vdis, err := xoClient.VDI().GetAll(ctx, 0, clients.BuildClusterTagFilter(opts.ClusterTag))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VDIs with cluster tag %q: %w", opts.ClusterTag, err)
+	}
+	volumeIDs := map[string]int{}
+	for _, vdi := range vdis {
+		if volumeID := clients.ParseTagValue(vdi.Tags, clients.VDITagKeyVolumeId); volumeID != "" {
+			volumeIDs[volumeID]++
+		}
+	}
+
+	names := newNameResolver(xoClient)
+	volumes := make([]Volume, 0, len(vdis))
+	for _, vdi := range vdis {
+		volume := Volume{
+			Instance: instance.Name,
+			VDIID:    vdi.ID,
+			VDIName:  vdi.NameLabel,
+			VolumeID: clients.ParseTagValue(vdi.Tags, clients.VDITagKeyVolumeId),
+			PVName:   clients.ParseTagValue(vdi.Tags, clients.VDITagKeyPVName),
+			SRID:     vdi.SR,
+			PoolID:   vdi.PoolID,
+			Size:     vdi.Size,
+			VMs:      []VM{},
+			Problems: []Problem{},
+		}
+		if volume.SRName, err = names.sr(ctx, vdi.SR); err != nil {
+			return nil, err
+		}
+		if volume.PoolName, err = names.pool(ctx, vdi.PoolID); err != nil {
+			return nil, err
+		}
+		if len(vdi.VBDs) > 0 {
+			vbds, err := xoClient.IsVDIUsedAnywhere(ctx, vdi)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list the VBDs of VDI %s: %w", vdi.ID, err)
+			}
+			for _, vbd := range vbds {
+				name, err := names.vm(ctx, vbd.VM)
+				if err != nil {
+					return nil, err
+				}
+				volume.VMs = append(volume.VMs, VM{ID: vbd.VM, Name: name, Plugged: vbd.Attached})
+			}
+		}
+
+		if replicaOf := replicaVolumeID(vdi); replicaOf != "" {
+			// Replicas are owned by the replicator: they carry neither the
+			// volume ID nor the PV name tag of their volume, and are
+			// referenced through it.
+			volume.ReplicaOf = replicaOf
+			volume.PVName = clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaPVName)
+			if pvs != nil {
+				if pv := pvs.referencing(vdi, replicaOf, volume.PVName); pv != nil {
+					volume.PersistentVolume, volume.Claim = pv.name, pv.claim
+				}
+			}
+			volumes = append(volumes, volume)
+			continue
+		}
+
+		static := false
+		if pvs != nil {
+			pv := pvs.referencing(vdi, volume.VolumeID, volume.PVName)
+			if pv == nil {
+				volume.Problems = append(volume.Problems, ProblemOrphan)
+			} else {
+				volume.PersistentVolume, volume.Claim = pv.name, pv.claim
+				// Static volumes adopted at publish time are referenced by the
+				// VDI UUID and carry no volume ID or PV name tag.
+				static = pv.byVDIID
+			}
+		}
+		if volume.VolumeID != "" && volumeIDs[volume.VolumeID] > 1 && !clients.IsStripeMember(vdi) {
+			volume.Problems = append(volume.Problems, ProblemAmbiguousID)
+		}
+		if !static && (volume.VolumeID == "" || volume.PVName == "") {
+			volume.Problems = append(volume.Problems, ProblemMissingTags)
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+// replicaVolumeID returns the ID of the volume vdi is a replica of, or ""
+// when it is not a replica.
+func replicaVolumeID(vdi *payloads.VDI) string {
+	if volumeID := clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaOf); volumeID != "" {
+		return volumeID
+	}
+	return clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaPending)
+}
+
+// HasProblem reports whether the volume has one of problems.
+func (v Volume) HasProblem(problems ...Problem) bool {
+	for _, problem := range problems {
+		if slices.Contains(v.Problems, problem) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameResolver looks up the names of the SRs, pools and VMs of an instance
+// once each.
+type nameResolver struct {
+	xoClient clients.XoClient
+	names    map[uuid.UUID]string
+}
+
+func newNameResolver(xoClient clients.XoClient) *nameResolver {
+	return &nameResolver{xoClient: xoClient, names: map[uuid.UUID]string{}}
+}
+
+func (r *nameResolver) sr(ctx context.Context, id uuid.UUID) (string, error) {
+	return r.resolve(id, func() (string, error) {
+		sr, err := r.xoClient.SR().Get(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to get SR %s: %w", id, err)
+		}
+		return sr.NameLabel, nil
+	})
+}
+
+func (r *nameResolver) pool(ctx context.Context, id uuid.UUID) (string, error) {
+	return r.resolve(id, func() (string, error) {
+		pool, err := r.xoClient.Pool().Get(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to get pool %s: %w", id, err)
+		}
+		return pool.NameLabel, nil
+	})
+}
+
+func (r *nameResolver) vm(ctx context.Context, id uuid.UUID) (string, error) {
+	return r.resolve(id, func() (string, error) {
+		vm, err := r.xoClient.VM().GetByID(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to get VM %s: %w", id, err)
+		}
+		return vm.NameLabel, nil
+	})
+}
+
+func (r *nameResolver) resolve(id uuid.UUID, lookup func() (string, error)) (string, error) {
+	if id == uuid.Nil {
+		return "", nil
+	}
+	if name, found := r.names[id]; found {
+		return name, nil
+	}
+	name, err := lookup()
+	if err != nil {
+		return "", err
+	}
+	r.names[id] = name
+	return name, nil
+}
+
+// persistentVolume is a PersistentVolume that may reference a VDI.
+type persistentVolume struct {
+	name  string
+	claim string
+	// byVDIID is set by referencing when the PersistentVolume references the
+	// VDI by its UUID.
+	byVDIID bool
+}
+
+// persistentVolumes indexes the PersistentVolumes the way the orphaned VDI
+// garbage collector does: a VDI is referenced by a PersistentVolume of the
+// driver whose handle holds its volume ID or UUID, or by any PersistentVolume
+// named after its PV name tag.
+type persistentVolumes struct {
+	byHandle map[string]*persistentVolume
+	byName   map[string]*persistentVolume
+}
+
+func listPersistentVolumes(ctx context.Context, kubeClient kube.Interface, driverName string) (*persistentVolumes, error) {
+	list, err := kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumes: %w", err)
+	}
+	pvs := &persistentVolumes{
+		byHandle: make(map[string]*persistentVolume, len(list.Items)),
+		byName:   make(map[string]*persistentVolume, len(list.Items)),
+	}
+	for _, item := range list.Items {
+		pv := &persistentVolume{name: item.Name}
+		if ref := item.Spec.ClaimRef; ref != nil {
+			pv.claim = ref.Namespace + "/" + ref.Name
+		}
+		pvs.byName[item.Name] = pv
+		if item.Spec.CSI != nil && item.Spec.CSI.Driver == driverName {
+			_, volumeID := clients.SplitVolumeHandle(item.Spec.CSI.VolumeHandle)
+			pvs.byHandle[volumeID] = pv
+		}
+	}
+	return pvs, nil
+}
+
+func (p *persistentVolumes) referencing(vdi *payloads.VDI, volumeID, pvName string) *persistentVolume {
+	if volumeID != "" {
+		if pv, found := p.byHandle[volumeID]; found {
+			return pv
+		}
+	}
+	if pv, found := p.byHandle[vdi.ID.String()]; found {
+		return &persistentVolume{name: pv.name, claim: pv.claim, byVDIID: true}
+	}
+	if pvName != "" {
+		return p.byName[pvName]
+	}
+	return nil
+}
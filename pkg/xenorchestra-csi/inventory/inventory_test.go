@@ -0,0 +1,175 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testDriverName = "csi.xenorchestra.vates.tech"
+	testClusterTag = "k8s-test"
+)
+
+var (
+	poolID = uuid.Must(uuid.FromString("11111111-0000-0000-0000-000000000001"))
+	srID   = uuid.Must(uuid.FromString("22222222-0000-0000-0000-000000000002"))
+	vmID   = uuid.Must(uuid.FromString("33333333-0000-0000-0000-000000000003"))
+
+	boundID     = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
+	orphanID    = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000002"))
+	staticID    = uuid.Must(uuid.FromString("cccccccc-0000-0000-0000-000000000003"))
+	duplicateID = uuid.Must(uuid.FromString("dddddddd-0000-0000-0000-000000000004"))
+	untaggedID  = uuid.Must(uuid.FromString("eeeeeeee-0000-0000-0000-000000000005"))
+)
+
+func newVDI(id uuid.UUID, volumeID, pvName string) *payloads.VDI {
+	vdi := stub.NewVolumeVDI(id, volumeID, pvName, testClusterTag)
+	vdi.NameLabel, vdi.SR, vdi.PoolID = "csi-"+pvName, srID, poolID
+	return vdi
+}
+
+func newPV(name, handle, claim string) *corev1.PersistentVolume {
+	pv := stub.NewPersistentVolume(name, testDriverName, handle)
+	pv.Spec.ClaimRef = &corev1.ObjectReference{Namespace: "default", Name: claim}
+	return pv
+}
+
+func newTestInstance(t *testing.T, vdis []*payloads.VDI) (clients.XoInstance, *clientsMock.MockXoClient) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	instance, mockXo, mockVDI := stub.NewXoInstance(ctrl, "paris")
+	mockSR := xoLibMock.NewMockSR(ctrl)
+	mockPool := xoLibMock.NewMockPool(ctrl)
+	mockVM := xoLibMock.NewMockVM(ctrl)
+	mockXo.EXPECT().SR().Return(mockSR).AnyTimes()
+	mockXo.EXPECT().Pool().Return(mockPool).AnyTimes()
+	mockXo.EXPECT().VM().Return(mockVM).AnyTimes()
+	mockVDI.EXPECT().GetAll(gomock.Any(), 0, clients.BuildClusterTagFilter(testClusterTag)).Return(vdis, nil)
+	// Names are looked up once each.
+	mockSR.EXPECT().Get(gomock.Any(), srID).Return(&payloads.StorageRepository{ID: srID, NameLabel: "nfs"}, nil).MaxTimes(1)
+	mockPool.EXPECT().Get(gomock.Any(), poolID).Return(&payloads.Pool{ID: poolID, NameLabel: "pool-a"}, nil).MaxTimes(1)
+	mockVM.EXPECT().GetByID(gomock.Any(), vmID).Return(&payloads.VM{ID: vmID, NameLabel: "worker-1"}, nil).MaxTimes(1)
+	return instance, mockXo
+}
+
+func TestCollect(t *testing.T) {
+	attached := newVDI(boundID, "vol-1", "pv-1")
+	attached.VBDs = []uuid.UUID{uuid.Must(uuid.NewV4())}
+	vdis := []*payloads.VDI{
+		attached,
+		newVDI(orphanID, "vol-2", "pv-2"),
+		newVDI(staticID, "", ""),
+		newVDI(duplicateID, "vol-1", "pv-1-copy"),
+		newVDI(untaggedID, "vol-5", ""),
+	}
+	byVDI := func(inv *Inventory) map[uuid.UUID]Volume {
+		volumes := map[uuid.UUID]Volume{}
+		for _, v := range inv.Volumes {
+			volumes[v.VDIID] = v
+		}
+		return volumes
+	}
+
+	t.Run("WithKubernetes", func(t *testing.T) {
+		instance, mockXo := newTestInstance(t, vdis)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), attached).Return([]*payloads.VBD{{VM: vmID, Attached: true}}, nil)
+		kubeClient := fake.NewClientset(
+			newPV("pv-1", "paris/vol-1", "data"),
+			newPV("pv-static", staticID.String(), "static"),
+			newPV("pv-5", "vol-5", "logs"),
+		)
+
+		inv, err := Collect(context.Background(), []clients.XoInstance{instance}, kubeClient, Options{DriverName: testDriverName, ClusterTag: testClusterTag})
+		require.NoError(t, err)
+		assert.True(t, inv.Kubernetes)
+		require.Len(t, inv.Volumes, 5)
+		volumes := byVDI(inv)
+
+		bound := volumes[boundID]
+		assert.Equal(t, "paris", bound.Instance)
+		assert.Equal(t, "nfs", bound.SRName)
+		assert.Equal(t, "pool-a", bound.PoolName)
+		assert.Equal(t, []VM{{ID: vmID, Name: "worker-1", Plugged: true}}, bound.VMs)
+		assert.Equal(t, "pv-1", bound.PersistentVolume)
+		assert.Equal(t, "default/data", bound.Claim)
+		assert.Equal(t, []Problem{ProblemAmbiguousID}, bound.Problems)
+
+		assert.Equal(t, []Problem{ProblemOrphan}, volumes[orphanID].Problems)
+		assert.Empty(t, volumes[staticID].Problems, "static volumes carry no volume ID or PV name tag")
+		assert.Equal(t, "pv-static", volumes[staticID].PersistentVolume)
+		assert.Equal(t, []Problem{ProblemAmbiguousID}, volumes[duplicateID].Problems, "the handle holding its volume ID references it too")
+		assert.Equal(t, []Problem{ProblemMissingTags}, volumes[untaggedID].Problems)
+	})
+
+	t.Run("WithoutKubernetes", func(t *testing.T) {
+		instance, mockXo := newTestInstance(t, vdis)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), attached).Return(nil, nil)
+
+		inv, err := Collect(context.Background(), []clients.XoInstance{instance}, nil, Options{DriverName: testDriverName, ClusterTag: testClusterTag})
+		require.NoError(t, err)
+		assert.False(t, inv.Kubernetes)
+		volumes := byVDI(inv)
+		assert.Empty(t, volumes[orphanID].Problems, "orphans are not reported without Kubernetes")
+		assert.Equal(t, []Problem{ProblemMissingTags}, volumes[staticID].Problems)
+		assert.True(t, volumes[duplicateID].HasProblem(ProblemOrphan, ProblemAmbiguousID))
+	})
+
+	t.Run("Replicas", func(t *testing.T) {
+		replicaID := uuid.Must(uuid.NewV4())
+		pendingID := uuid.Must(uuid.NewV4())
+		replica := newVDI(replicaID, "", "")
+		replica.Tags = append(replica.Tags,
+			clients.BuildTag(clients.VDITagKeyReplicaOf, "vol-1"),
+			clients.BuildTag(clients.VDITagKeyReplicaPVName, "pv-1"))
+		pending := newVDI(pendingID, "", "")
+		pending.Tags = append(pending.Tags, clients.BuildTag(clients.VDITagKeyReplicaPending, "vol-1"))
+		instance, _ := newTestInstance(t, []*payloads.VDI{replica, pending})
+		kubeClient := fake.NewClientset(newPV("pv-1", "paris/vol-1", "data"))
+
+		inv, err := Collect(context.Background(), []clients.XoInstance{instance}, kubeClient, Options{DriverName: testDriverName, ClusterTag: testClusterTag})
+		require.NoError(t, err)
+		volumes := byVDI(inv)
+		for _, id := range []uuid.UUID{replicaID, pendingID} {
+			assert.Equal(t, "vol-1", volumes[id].ReplicaOf)
+			assert.Empty(t, volumes[id].VolumeID)
+			assert.Equal(t, "pv-1", volumes[id].PersistentVolume)
+			assert.Empty(t, volumes[id].Problems, "replicas are neither orphans nor missing tags")
+		}
+		assert.Equal(t, "pv-1", volumes[replicaID].PVName)
+	})
+
+	t.Run("RequiresClusterTag", func(t *testing.T) {
+		_, err := Collect(context.Background(), nil, nil, Options{})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,82 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	leaderElectionLeaseDuration = 15 * time.Second
+	leaderElectionRenewDeadline = 10 * time.Second
+	leaderElectionRetryPeriod   = 2 * time.Second
+)
+
+// leaseName builds a Lease name for a background worker from the driver name,
+// e.g. "csi-xenorchestra-vates-tech-orphan-gc".
+func leaseName(driverName, worker string) string {
+	return strings.ReplaceAll(driverName, ".", "-") + "-" + worker
+}
+
+// runLeaderElected runs fn while this process holds the given Lease, so that
+// only one controller replica runs a background worker at a time. The context
+// passed to fn is cancelled when leadership is lost; the election is then
+// retried until ctx is done.
+func runLeaderElected(ctx context.Context, kubeClient kube.Interface, namespace, name string, fn func(ctx context.Context)) {
+	identity, err := os.Hostname()
+	if err != nil || identity == "" {
+		identity = name
+	}
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Client: kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			Name:            name,
+			ReleaseOnCancel: true,
+			LeaseDuration:   leaderElectionLeaseDuration,
+			RenewDeadline:   leaderElectionRenewDeadline,
+			RetryPeriod:     leaderElectionRetryPeriod,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					klog.InfoS("Acquired leadership", "lease", name, "identity", identity)
+					fn(ctx)
+				},
+				OnStoppedLeading: func() {
+					klog.InfoS("Lost or released leadership", "lease", name, "identity", identity)
+				},
+			},
+		})
+	}
+}
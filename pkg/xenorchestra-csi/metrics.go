@@ -0,0 +1,136 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"context"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	kube "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// metricsInterceptor counts and times every CSI RPC. The pool and storage type
+// labels are read from the volume context of the response, then of the
+// request, then from the StorageClass parameters; they are empty for RPCs
+// that carry none of them.
+func metricsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	method := path.Base(info.FullMethod)
+	pool, storageType := rpcVolumeLabels(req, resp)
+	metrics.RPCRequests.WithLabelValues(method, status.Code(err).String(), pool, storageType).Inc()
+	metrics.RPCDurationSeconds.WithLabelValues(method, pool, storageType).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// rpcVolumeLabels returns the pool and storage type labels of an RPC.
+func rpcVolumeLabels(req, resp any) (string, string) {
+	var attributes map[string]string
+	if r, ok := resp.(interface{ GetVolume() *csi.Volume }); ok {
+		attributes = r.GetVolume().GetVolumeContext()
+	}
+	if len(attributes) == 0 {
+		if r, ok := req.(interface{ GetVolumeContext() map[string]string }); ok {
+			attributes = r.GetVolumeContext()
+		}
+	}
+	if len(attributes) == 0 {
+		if r, ok := req.(interface{ GetParameters() map[string]string }); ok {
+			attributes = r.GetParameters()
+		}
+	}
+	if len(attributes) == 0 {
+		return "", ""
+	}
+	return attributes[VolumeContextKeyPoolID], storageTypeOf(attributes)
+}
+
+// storageTypeOf returns the storage type recorded in a volume context or in
+// StorageClass parameters, which defaults to shared.
+func storageTypeOf(attributes map[string]string) string {
+	if storageType := attributes[VolumeContextKeyStorageType]; storageType != "" {
+		return storageType
+	}
+	return StorageTypeShared
+}
+
+// managedVolumesCollector reports the PersistentVolumes of the driver, read
+// from the cache of an informer while Run runs.
+type managedVolumesCollector struct {
+	driverName string
+	// lister is nil until the informer of Run has synced, and after it stops.
+	lister atomic.Pointer[corelisters.PersistentVolumeLister]
+}
+
+// Run watches the PersistentVolumes until ctx is done.
+func (c *managedVolumesCollector) Run(ctx context.Context, kubeClient kube.Interface) {
+	factory := informers.NewSharedInformerFactory(kubeClient, 0)
+	lister := factory.Core().V1().PersistentVolumes().Lister()
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	for informer, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			klog.InfoS("Stopped before the PersistentVolumes of the managed volumes metric were listed", "informer", informer)
+			return
+		}
+	}
+	c.lister.Store(&lister)
+	defer c.lister.Store(nil)
+	<-ctx.Done()
+}
+
+func (c *managedVolumesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metrics.ManagedVolumes
+}
+
+func (c *managedVolumesCollector) Collect(ch chan<- prometheus.Metric) {
+	lister := c.lister.Load()
+	if lister == nil {
+		return
+	}
+	pvs, err := (*lister).List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list PersistentVolumes for the managed volumes metric")
+		return
+	}
+
+	type volumeLabels struct{ pool, storageType string }
+	counts := make(map[volumeLabels]int)
+	for _, pv := range pvs {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != c.driverName {
+			continue
+		}
+		attributes := pv.Spec.CSI.VolumeAttributes
+		counts[volumeLabels{attributes[VolumeContextKeyPoolID], storageTypeOf(attributes)}]++
+	}
+	for l, count := range counts {
+		ch <- prometheus.MustNewConstMetric(metrics.ManagedVolumes, prometheus.GaugeValue, float64(count), l.pool, l.storageType)
+	}
+}
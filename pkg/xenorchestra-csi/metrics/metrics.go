@@ -0,0 +1,234 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the Prometheus collectors exposed by the driver.
+// All collectors are registered on Registry rather than on the global
+// Prometheus registry so tests and subcommands can inspect them in isolation.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "xenorchestra_csi"
+
+// Registry is the Prometheus registry holding every driver metric.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// OrphanedVolumes is the number of cluster-tagged VDIs that no
+	// PersistentVolume references, as seen by the last garbage collector pass.
+	OrphanedVolumes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_gc",
+		Name:      "orphaned_volumes",
+		Help:      "Number of cluster-tagged VDIs not referenced by any PersistentVolume.",
+	}, []string{"pool"})
+
+	// OrphanedVolumeBytes is the virtual size of the orphaned VDIs.
+	OrphanedVolumeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_gc",
+		Name:      "orphaned_volume_bytes",
+		Help:      "Total virtual size in bytes of cluster-tagged VDIs not referenced by any PersistentVolume.",
+	}, []string{"pool"})
+
+	// OrphanedVolumesDeleted counts the orphaned VDIs deleted by the garbage collector.
+	OrphanedVolumesDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_gc",
+		Name:      "deleted_volumes_total",
+		Help:      "Number of orphaned VDIs deleted by the garbage collector.",
+	}, []string{"pool"})
+
+	// OrphanGCRuns counts garbage collector passes by result ("success" or "error").
+	OrphanGCRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_gc",
+		Name:      "runs_total",
+		Help:      "Number of orphaned VDI garbage collector passes by result.",
+	}, []string{"result"})
+
+	// VMQueueDepth is the number of VBD operations queued or running per VM.
+	VMQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "vm_queue",
+		Name:      "depth",
+		Help:      "Number of VBD operations queued or running on a VM.",
+	}, []string{"vm"})
+
+	// VMQueueWaitSeconds is the time a VBD operation waited for its turn on its VM.
+	VMQueueWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "vm_queue",
+		Name:      "wait_seconds",
+		Help:      "Time VBD operations waited in the per-VM queue before running.",
+		Buckets:   []float64{0.01, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"operation"})
+
+	// RPCRequests counts CSI RPCs by method, gRPC code, pool and storage type.
+	RPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "requests_total",
+		Help:      "Number of CSI RPCs handled, by method, gRPC code, pool and storage type.",
+	}, []string{"method", "code", "pool", "storage_type"})
+
+	// RPCDurationSeconds is the latency of the CSI RPCs.
+	RPCDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "duration_seconds",
+		Help:      "Latency of the CSI RPCs, by method, pool and storage type.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"method", "pool", "storage_type"})
+
+	// XORequestDurationSeconds is the latency of the Xen Orchestra API calls.
+	XORequestDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "xo",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the Xen Orchestra API calls, by service and operation.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"service", "operation"})
+
+	// XORequestErrors counts the failed Xen Orchestra API calls.
+	XORequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "xo",
+		Name:      "request_errors_total",
+		Help:      "Number of failed Xen Orchestra API calls, by service and operation.",
+	}, []string{"service", "operation"})
+
+	// VDIMigrationDurationSeconds is the duration of the VDI migrations to a
+	// local SR, by result ("success" or "error").
+	VDIMigrationDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "vdi",
+		Name:      "migration_duration_seconds",
+		Help:      "Duration of VDI migrations, by pool and result.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	}, []string{"pool", "result"})
+
+	// AttachWaitSeconds is the time spent waiting for a plugged VBD to get a
+	// device name, by result ("success" or "error").
+	AttachWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "vbd",
+		Name:      "attach_wait_seconds",
+		Help:      "Time spent waiting for a plugged VBD to get a device name, by result.",
+		Buckets:   []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120},
+	}, []string{"result"})
+
+	// ReplicaLagSeconds is the age of the latest replica of each replicated
+	// volume, by PersistentVolume and replica pool, as of the last replicator
+	// pass. It is +Inf until the volume has a replica in its replica pool.
+	ReplicaLagSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "replication",
+		Name:      "lag_seconds",
+		Help:      "Age of the latest replica of each replicated volume, +Inf before its first replica.",
+	}, []string{"pv", "pool"})
+
+	// ReplicaCopies counts the copies of volumes to their replica pool, by
+	// replica pool and result ("success", "error", or "skipped" for volumes
+	// attached read-write).
+	ReplicaCopies = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "replication",
+		Name:      "copies_total",
+		Help:      "Number of copies of volumes to their replica pool, by replica pool and result.",
+	}, []string{"pool", "result"})
+
+	// ReplicationRuns counts replicator passes by result ("success" or "error").
+	ReplicationRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "replication",
+		Name:      "runs_total",
+		Help:      "Number of volume replicator passes, by result.",
+	}, []string{"result"})
+
+	// XOConfigReloads counts the reloads of the Xen Orchestra configuration
+	// file, by instance (empty for a single unnamed one) and result
+	// ("success" or "error").
+	XOConfigReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "xo",
+		Name:      "config_reloads_total",
+		Help:      "Number of reloads of the Xen Orchestra configuration file, by instance and result.",
+	}, []string{"instance", "result"})
+
+	// XOConfigLastReloadSuccessful is 1 when the configuration of a Xen
+	// Orchestra instance in the file has been applied, and 0 when its last
+	// change was rejected.
+	XOConfigLastReloadSuccessful = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "xo",
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last change of the Xen Orchestra configuration file was applied, by instance.",
+	}, []string{"instance"})
+)
+
+// ManagedVolumes describes the number of PersistentVolumes provisioned by the
+// driver. It is computed at scrape time by a collector of the driver package,
+// which has access to the Kubernetes API.
+var ManagedVolumes = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "managed_volumes"),
+	"Number of PersistentVolumes provisioned by the driver, by pool and storage type.",
+	[]string{"pool", "storage_type"}, nil,
+)
+
+// Label values shared by the collectors.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+	// ResultSkipped is the result of the copies of volumes that cannot be
+	// replicated consistently.
+	ResultSkipped = "skipped"
+)
+
+// Result returns the "result" label value for err.
+func Result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+func init() {
+	Registry.MustRegister(
+		OrphanedVolumes,
+		OrphanedVolumeBytes,
+		OrphanedVolumesDeleted,
+		OrphanGCRuns,
+		VMQueueDepth,
+		VMQueueWaitSeconds,
+		RPCRequests,
+		RPCDurationSeconds,
+		XORequestDurationSeconds,
+		XORequestErrors,
+		VDIMigrationDurationSeconds,
+		AttachWaitSeconds,
+		XOConfigReloads,
+		XOConfigLastReloadSuccessful,
+		ReplicaLagSeconds,
+		ReplicaCopies,
+		ReplicationRuns,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
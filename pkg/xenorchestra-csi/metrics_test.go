@@ -0,0 +1,136 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMetricsInterceptor(t *testing.T) {
+	call := func(method string, req any, resp any, err error) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/" + method}
+		_, _ = metricsInterceptor(context.Background(), req, info, func(context.Context, any) (any, error) {
+			return resp, err
+		})
+	}
+
+	t.Run("LabelsFromResponseVolumeContext", func(t *testing.T) {
+		req := &csi.CreateVolumeRequest{Parameters: map[string]string{ParameterStorageType: StorageTypeLocal}}
+		resp := &csi.CreateVolumeResponse{Volume: &csi.Volume{VolumeContext: map[string]string{
+			VolumeContextKeyPoolID:      "pool-a",
+			VolumeContextKeyStorageType: StorageTypeLocal,
+		}}}
+		counter := metrics.RPCRequests.WithLabelValues("CreateVolume", "OK", "pool-a", StorageTypeLocal)
+		before := testutil.ToFloat64(counter)
+
+		call("CreateVolume", req, resp, nil)
+		assert.Equal(t, before+1, testutil.ToFloat64(counter))
+	})
+
+	t.Run("FailedCallUsesRequestAndCode", func(t *testing.T) {
+		req := &csi.ControllerPublishVolumeRequest{VolumeContext: map[string]string{VolumeContextKeyPoolID: "pool-b"}}
+		counter := metrics.RPCRequests.WithLabelValues("ControllerPublishVolume", "DeadlineExceeded", "pool-b", StorageTypeShared)
+		before := testutil.ToFloat64(counter)
+
+		call("ControllerPublishVolume", req, (*csi.ControllerPublishVolumeResponse)(nil), status.Error(codes.DeadlineExceeded, "timeout"))
+		assert.Equal(t, before+1, testutil.ToFloat64(counter))
+	})
+
+	t.Run("CallsWithoutVolumeHaveEmptyLabels", func(t *testing.T) {
+		counter := metrics.RPCRequests.WithLabelValues("ControllerUnpublishVolume", "OK", "", "")
+		before := testutil.ToFloat64(counter)
+
+		call("ControllerUnpublishVolume", &csi.ControllerUnpublishVolumeRequest{VolumeId: "vol"}, &csi.ControllerUnpublishVolumeResponse{}, nil)
+		assert.Equal(t, before+1, testutil.ToFloat64(counter))
+	})
+}
+
+func TestManagedVolumesCollector(t *testing.T) {
+	pv := func(name, driver string, attributes map[string]string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: name, VolumeAttributes: attributes},
+			}},
+		}
+	}
+	kubeClient := fake.NewClientset(
+		pv("pv-1", DriverName, map[string]string{VolumeContextKeyPoolID: "pool-a"}),
+		pv("pv-2", DriverName, map[string]string{VolumeContextKeyPoolID: "pool-a", VolumeContextKeyStorageType: StorageTypeShared}),
+		pv("pv-3", DriverName, map[string]string{VolumeContextKeyPoolID: "pool-a", VolumeContextKeyStorageType: StorageTypeLocal}),
+		pv("pv-4", "other.csi.driver", map[string]string{VolumeContextKeyPoolID: "pool-a"}),
+	)
+
+	collector := &managedVolumesCollector{driverName: DriverName}
+
+	t.Run("NothingReportedBeforeRun", func(t *testing.T) {
+		assert.Equal(t, 0, testutil.CollectAndCount(collector))
+	})
+
+	t.Run("ReportsVolumesOfTheDriver", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			collector.Run(ctx, kubeClient)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-stopped
+		})
+		require.Eventually(t, func() bool { return collector.lister.Load() != nil }, 5*time.Second, 10*time.Millisecond)
+
+		expected := `
+# HELP xenorchestra_csi_managed_volumes Number of PersistentVolumes provisioned by the driver, by pool and storage type.
+# TYPE xenorchestra_csi_managed_volumes gauge
+xenorchestra_csi_managed_volumes{pool="pool-a",storage_type="local"} 1
+xenorchestra_csi_managed_volumes{pool="pool-a",storage_type="shared"} 2
+`
+		require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+	})
+
+	t.Run("NothingReportedAfterRun", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			collector.Run(ctx, kubeClient)
+		}()
+		require.Eventually(t, func() bool { return collector.lister.Load() != nil }, 5*time.Second, 10*time.Millisecond)
+		cancel()
+		<-stopped
+
+		assert.Equal(t, 0, testutil.CollectAndCount(collector))
+	})
+}
@@ -0,0 +1,279 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migrate converts the metadata of the VDIs created by driver versions
+// before v0.4.0, stored in other_config, to the k8s: tags the driver looks
+// volumes up by.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Keys of the VDI other_config written by the driver before v0.4.0.
+const (
+	// OtherConfigKeyVolumeID holds the CSI volume ID since v0.3.0.
+	OtherConfigKeyVolumeID = "kubernetes_volume_id"
+	// OtherConfigKeyPVName holds the PersistentVolume name since v0.3.0.
+	OtherConfigKeyPVName = "kubernetes_pv_name"
+	// OtherConfigKeyLegacyPVName holds the PersistentVolume name in v0.2.0.
+	OtherConfigKeyLegacyPVName = "kubernetesPVName"
+)
+
+// Versions of the driver a VDI was created or last migrated by.
+const (
+	VersionV020 = "v0.2.0"
+	VersionV030 = "v0.3.0"
+)
+
+// Status describes the outcome of the migration of a VDI.
+type Status string
+
+const (
+	// StatusUpToDate means the VDI already carries the tags and name label.
+	StatusUpToDate Status = "up-to-date"
+	// StatusPlanned means the changes were only computed (dry-run).
+	StatusPlanned Status = "planned"
+	// StatusMigrated means the changes were applied.
+	StatusMigrated Status = "migrated"
+	// StatusSkipped means the VDI was left alone, see Reason.
+	StatusSkipped Status = "skipped"
+	// StatusFailed means applying a change failed, see Reason. Running the
+	// migration again resumes it.
+	StatusFailed Status = "failed"
+)
+
+// ChangeKind is the kind of a change made to a VDI.
+type ChangeKind string
+
+const (
+	// ChangeRename sets the name label of the VDI.
+	ChangeRename ChangeKind = "rename"
+	// ChangeAddTag adds a tag to the VDI.
+	ChangeAddTag ChangeKind = "add-tag"
+)
+
+// Change is a change made, or to be made, to a VDI.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// From is the previous name label of a rename.
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	// Applied is set once the change is made.
+	Applied bool `json:"applied"`
+}
+
+// Options configures Run.
+type Options struct {
+	// DriverName is the CSI driver name PersistentVolumes must reference.
+	DriverName string
+	// VDINamePrefix is the --vdi-name-prefix of the driver.
+	VDINamePrefix string
+	// Apply makes the changes. When false, they are only reported.
+	Apply bool
+}
+
+// VDI is the migration of a VDI.
+type VDI struct {
+	Instance string    `json:"instance,omitempty"`
+	VDIID    uuid.UUID `json:"vdiId"`
+	// Version is the driver version the metadata of the VDI comes from.
+	Version  string   `json:"version"`
+	PVName   string   `json:"pvName"`
+	VolumeID string   `json:"volumeId"`
+	Changes  []Change `json:"changes"`
+	Status   Status   `json:"status"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+// Report lists the VDIs found with legacy metadata and what was done to them.
+type Report struct {
+	DryRun bool  `json:"dryRun"`
+	VDIs   []VDI `json:"vdis"`
+}
+
+// Count returns the number of VDIs of the report with status.
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, vdi := range r.VDIs {
+		if vdi.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Run migrates the VDIs carrying legacy other_config metadata in every
+// instance. It is idempotent: the VDIs already migrated are reported as up to
+// date. When kubeClient is not nil, a VDI whose PersistentVolume references
+// another volume ID is skipped.
+func Run(ctx context.Context, instances []clients.XoInstance, kubeClient kube.Interface, opts Options) (*Report, error) {
+	report := &Report{DryRun: !opts.Apply, VDIs: []VDI{}}
+	for _, instance := range instances {
+		vdis, err := listLegacyVDIs(ctx, instance.Client)
+		if err != nil {
+			if instance.Name != "" {
+				err = fmt.Errorf("Xen Orchestra instance %q: %w", instance.Name, err)
+			}
+			return nil, err
+		}
+		for _, vdi := range vdis {
+			migration := plan(vdi, opts.VDINamePrefix)
+			migration.Instance = instance.Name
+			if migration.Status == "" && kubeClient != nil {
+				if err := checkPersistentVolume(ctx, kubeClient, opts.DriverName, &migration); err != nil {
+					return nil, err
+				}
+			}
+			if migration.Status == "" {
+				apply(ctx, instance.Client, vdi, &migration, opts.Apply)
+			}
+			report.VDIs = append(report.VDIs, migration)
+		}
+	}
+	return report, nil
+}
+
+// listLegacyVDIs returns the VDIs with a PersistentVolume name in their
+// other_config, written by v0.2.0 or v0.3.0.
+func listLegacyVDIs(ctx context.Context, xoClient clients.XoClient) ([]*payloads.VDI, error) {
+	var vdis []*payloads.VDI
+	seen := map[uuid.UUID]bool{}
+	for _, key := range []string{OtherConfigKeyPVName, OtherConfigKeyLegacyPVName} {
+		found, err := xoClient.VDI().GetAll(ctx, 0, fmt.Sprintf("other_config:%s?", key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list VDIs with other_config key %q: %w", key, err)
+		}
+		for _, vdi := range found {
+			if !seen[vdi.ID] {
+				seen[vdi.ID] = true
+				vdis = append(vdis, vdi)
+			}
+		}
+	}
+	return vdis, nil
+}
+
+// plan computes the changes bringing vdi to the v0.4.0 metadata. The status
+// of the returned migration is only set when the VDI is up to date or must be
+// skipped.
+func plan(vdi *payloads.VDI, prefix string) VDI {
+	migration := VDI{
+		VDIID:    vdi.ID,
+		Version:  VersionV030,
+		PVName:   vdi.OtherConfig[OtherConfigKeyPVName],
+		VolumeID: vdi.OtherConfig[OtherConfigKeyVolumeID],
+		Changes:  []Change{},
+	}
+	if migration.PVName == "" {
+		migration.PVName = vdi.OtherConfig[OtherConfigKeyLegacyPVName]
+	}
+	if migration.VolumeID == "" {
+		// v0.2.0 volumes are identified by the UUID of their VDI, which the
+		// v0.2.0 to v0.3.0 migration copied to kubernetes_volume_id.
+		migration.Version = VersionV020
+		migration.VolumeID = vdi.ID.String()
+	}
+	if migration.PVName == "" {
+		migration.Status, migration.Reason = StatusSkipped, "empty PersistentVolume name in other_config"
+		return migration
+	}
+
+	for _, tag := range []struct{ key, value string }{
+		{clients.VDITagKeyVolumeId, migration.VolumeID},
+		{clients.VDITagKeyPVName, migration.PVName},
+	} {
+		if current := clients.ParseTagValue(vdi.Tags, tag.key); current != "" && current != tag.value {
+			migration.Status = StatusSkipped
+			migration.Reason = fmt.Sprintf("already tagged %q, expected %q", clients.BuildTag(tag.key, current), tag.value)
+			return migration
+		}
+	}
+
+	if nameLabel := clients.BuildVDINameLabel(prefix, migration.VolumeID, migration.PVName); vdi.NameLabel != nameLabel {
+		migration.Changes = append(migration.Changes, Change{Kind: ChangeRename, From: vdi.NameLabel, To: nameLabel})
+	}
+	for _, tag := range []string{
+		clients.BuildTag(clients.VDITagKeyVolumeId, migration.VolumeID),
+		clients.BuildTag(clients.VDITagKeyPVName, migration.PVName),
+	} {
+		if !slices.Contains(vdi.Tags, tag) {
+			migration.Changes = append(migration.Changes, Change{Kind: ChangeAddTag, To: tag})
+		}
+	}
+	if len(migration.Changes) == 0 {
+		migration.Status = StatusUpToDate
+	}
+	return migration
+}
+
+// checkPersistentVolume skips the migration when the PersistentVolume of the
+// VDI references another volume, e.g. a v0.2.0 VDI whose UUID changed when it
+// was migrated to another SR.
+func checkPersistentVolume(ctx context.Context, kubeClient kube.Interface, driverName string, migration *VDI) error {
+	pv, err := kubeClient.CoreV1().PersistentVolumes().Get(ctx, migration.PVName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get PersistentVolume %s: %w", migration.PVName, err)
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName {
+		return nil
+	}
+	if _, volumeID := clients.SplitVolumeHandle(pv.Spec.CSI.VolumeHandle); volumeID != migration.VolumeID {
+		migration.Status = StatusSkipped
+		migration.Reason = fmt.Sprintf("PersistentVolume %s references volume %s", pv.Name, pv.Spec.CSI.VolumeHandle)
+	}
+	return nil
+}
+
+func apply(ctx context.Context, xoClient clients.XoClient, vdi *payloads.VDI, migration *VDI, doApply bool) {
+	if !doApply {
+		migration.Status = StatusPlanned
+		return
+	}
+	for i := range migration.Changes {
+		change := &migration.Changes[i]
+		var err error
+		switch change.Kind {
+		case ChangeRename:
+			err = xoClient.SetVDINameLabel(ctx, *vdi, change.To)
+		case ChangeAddTag:
+			err = xoClient.VDI().AddTag(ctx, vdi.ID, change.To)
+		}
+		if err != nil {
+			klog.ErrorS(err, "Failed to migrate VDI", "vdiID", vdi.ID, "change", change.Kind, "to", change.To)
+			migration.Status, migration.Reason = StatusFailed, err.Error()
+			return
+		}
+		change.Applied = true
+	}
+	klog.InfoS("Migrated VDI", "vdiID", vdi.ID, "version", migration.Version, "volumeID", migration.VolumeID, "pvName", migration.PVName)
+	migration.Status = StatusMigrated
+}
@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testDriverName = "csi.xenorchestra.vates.tech"
+
+var (
+	v030ID   = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
+	v020ID   = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000002"))
+	volumeID = "11111111-2222-3333-4444-555555555555"
+)
+
+func newV030VDI() *payloads.VDI {
+	return &payloads.VDI{
+		ID:          v030ID,
+		NameLabel:   "csi-pvc-a",
+		OtherConfig: map[string]string{OtherConfigKeyPVName: "pvc-a", OtherConfigKeyVolumeID: volumeID},
+	}
+}
+
+func newV020VDI() *payloads.VDI {
+	return &payloads.VDI{
+		ID:          v020ID,
+		NameLabel:   "csi-pvc-b",
+		OtherConfig: map[string]string{OtherConfigKeyLegacyPVName: "pvc-b"},
+	}
+}
+
+func newTestInstance(t *testing.T, v030, v020 []*payloads.VDI) (clients.XoInstance, *clientsMock.MockXoClient, *xoLibMock.MockVDI) {
+	t.Helper()
+	instance, mockXo, mockVDI := stub.NewXoInstance(gomock.NewController(t), "")
+	mockVDI.EXPECT().GetAll(gomock.Any(), 0, "other_config:kubernetes_pv_name?").Return(v030, nil)
+	mockVDI.EXPECT().GetAll(gomock.Any(), 0, "other_config:kubernetesPVName?").Return(v020, nil)
+	return instance, mockXo, mockVDI
+}
+
+func TestRun(t *testing.T) {
+	opts := Options{DriverName: testDriverName, VDINamePrefix: "csi-"}
+
+	t.Run("DryRunPlansChanges", func(t *testing.T) {
+		instance, _, _ := newTestInstance(t, []*payloads.VDI{newV030VDI()}, []*payloads.VDI{newV020VDI()})
+
+		report, err := Run(context.Background(), []clients.XoInstance{instance}, nil, opts)
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		require.Len(t, report.VDIs, 2)
+
+		v030 := report.VDIs[0]
+		assert.Equal(t, VersionV030, v030.Version)
+		assert.Equal(t, StatusPlanned, v030.Status)
+		assert.Equal(t, []Change{
+			{Kind: ChangeRename, From: "csi-pvc-a", To: "csi-" + volumeID + "-pvc-a"},
+			{Kind: ChangeAddTag, To: "k8s:volumeId:" + volumeID},
+			{Kind: ChangeAddTag, To: "k8s:pvName:pvc-a"},
+		}, v030.Changes)
+
+		v020 := report.VDIs[1]
+		assert.Equal(t, VersionV020, v020.Version)
+		assert.Equal(t, v020ID.String(), v020.VolumeID, "v0.2.0 volumes are identified by their VDI UUID")
+		assert.Equal(t, "pvc-b", v020.PVName)
+		assert.Equal(t, StatusPlanned, v020.Status)
+	})
+
+	t.Run("ApplyMakesChanges", func(t *testing.T) {
+		vdi := newV030VDI()
+		instance, mockXo, mockVDI := newTestInstance(t, []*payloads.VDI{vdi}, nil)
+		gomock.InOrder(
+			mockXo.EXPECT().SetVDINameLabel(gomock.Any(), *vdi, "csi-"+volumeID+"-pvc-a").Return(nil),
+			mockVDI.EXPECT().AddTag(gomock.Any(), v030ID, "k8s:volumeId:"+volumeID).Return(nil),
+			mockVDI.EXPECT().AddTag(gomock.Any(), v030ID, "k8s:pvName:pvc-a").Return(nil),
+		)
+
+		report, err := Run(context.Background(), []clients.XoInstance{instance}, nil, Options{DriverName: testDriverName, VDINamePrefix: "csi-", Apply: true})
+		require.NoError(t, err)
+		assert.False(t, report.DryRun)
+		assert.Equal(t, StatusMigrated, report.VDIs[0].Status)
+		assert.Equal(t, 1, report.Count(StatusMigrated))
+		for _, change := range report.VDIs[0].Changes {
+			assert.True(t, change.Applied)
+		}
+	})
+
+	t.Run("MigratedVDIIsUpToDate", func(t *testing.T) {
+		vdi := newV030VDI()
+		vdi.NameLabel = "csi-" + volumeID + "-pvc-a"
+		vdi.Tags = []string{"k8s:volumeId:" + volumeID, "k8s:pvName:pvc-a"}
+		instance, _, _ := newTestInstance(t, []*payloads.VDI{vdi}, []*payloads.VDI{vdi})
+
+		report, err := Run(context.Background(), []clients.XoInstance{instance}, nil, Options{VDINamePrefix: "csi-", Apply: true})
+		require.NoError(t, err)
+		require.Len(t, report.VDIs, 1, "a VDI listed by both keys is migrated once")
+		assert.Equal(t, StatusUpToDate, report.VDIs[0].Status)
+		assert.Empty(t, report.VDIs[0].Changes)
+	})
+
+	t.Run("ConflictingTagIsSkipped", func(t *testing.T) {
+		vdi := newV030VDI()
+		vdi.Tags = []string{"k8s:volumeId:other"}
+		instance, _, _ := newTestInstance(t, []*payloads.VDI{vdi}, nil)
+
+		report, err := Run(context.Background(), []clients.XoInstance{instance}, nil, Options{VDINamePrefix: "csi-", Apply: true})
+		require.NoError(t, err)
+		assert.Equal(t, StatusSkipped, report.VDIs[0].Status)
+		assert.Contains(t, report.VDIs[0].Reason, "k8s:volumeId:other")
+	})
+
+	t.Run("PersistentVolumeOfAnotherVolumeIsSkipped", func(t *testing.T) {
+		instance, _, _ := newTestInstance(t, nil, []*payloads.VDI{newV020VDI()})
+		kubeClient := fake.NewClientset(&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-b"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: testDriverName, VolumeHandle: "cccccccc-0000-0000-0000-000000000003"},
+			}},
+		})
+
+		report, err := Run(context.Background(), []clients.XoInstance{instance}, kubeClient, Options{DriverName: testDriverName, VDINamePrefix: "csi-", Apply: true})
+		require.NoError(t, err)
+		assert.Equal(t, StatusSkipped, report.VDIs[0].Status)
+		assert.Contains(t, report.VDIs[0].Reason, "references volume")
+	})
+
+	t.Run("FailureIsReported", func(t *testing.T) {
+		vdi := newV030VDI()
+		vdi.NameLabel = "csi-" + volumeID + "-pvc-a"
+		instance, _, mockVDI := newTestInstance(t, []*payloads.VDI{vdi}, nil)
+		mockVDI.EXPECT().AddTag(gomock.Any(), v030ID, "k8s:volumeId:"+volumeID).Return(errors.New("unavailable"))
+
+		report, err := Run(context.Background(), []clients.XoInstance{instance}, nil, Options{VDINamePrefix: "csi-", Apply: true})
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, report.VDIs[0].Status)
+		assert.Equal(t, "unavailable", report.VDIs[0].Reason)
+		assert.False(t, report.VDIs[0].Changes[0].Applied)
+	})
+}
+
+func TestPlanUsesPrefix(t *testing.T) {
+	migration := plan(newV030VDI(), "team-a-")
+	assert.Equal(t, clients.BuildVDINameLabel("team-a-", volumeID, "pvc-a"), migration.Changes[0].To)
+}
@@ -18,6 +18,7 @@ package xenorchestracsi
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
@@ -32,8 +33,6 @@ import (
 
 // NodeGetCapabilities implements Driver.
 func (driver *xenorchestraCSIDriver) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
-	klog.V(5).InfoS("NodeGetCapabilities called", "request", req)
-
 	return &csi.NodeGetCapabilitiesResponse{
 		Capabilities: []*csi.NodeServiceCapability{
 			{
@@ -50,11 +49,11 @@ func (driver *xenorchestraCSIDriver) NodeGetCapabilities(ctx context.Context, re
 
 // NodeGetInfo implements Driver.
 func (driver *xenorchestraCSIDriver) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
-	klog.V(5).InfoS("NodeGetInfo called", "request", req)
 	metadata, err := driver.nodeMetadata.GetNodeMetadata()
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to fetch node metadata: %v", err)
 	}
+	klog.V(4).InfoS("Resolved node metadata", "nodeID", metadata.NodeId, "poolID", metadata.PoolId, "instance", metadata.Instance)
 	return &csi.NodeGetInfoResponse{
 		NodeId: metadata.NodeId,
 		// According to Xen Orchestra documentation, 241 is the maximum number of VDIs that can be attached to a single VM,
@@ -70,7 +69,6 @@ func (driver *xenorchestraCSIDriver) NodeGetInfo(ctx context.Context, req *csi.N
 
 // NodePublishVolume implements Driver.
 func (driver *xenorchestraCSIDriver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
-	klog.V(5).InfoS("NodePublishVolume called", "request", req)
 	// Check arguments
 	volCap := req.GetVolumeCapability()
 	if volCap == nil {
@@ -83,6 +81,13 @@ func (driver *xenorchestraCSIDriver) NodePublishVolume(ctx context.Context, req
 	if len(req.GetVolumeId()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "volume ID missing in request")
 	}
+
+	release, err := driver.lockVolume(req.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	if len(req.GetTargetPath()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "target path missing in request")
 	}
@@ -156,18 +161,23 @@ func (driver *xenorchestraCSIDriver) NodePublishVolume(ctx context.Context, req
 
 // NodeUnpublishVolume implements Driver.
 func (driver *xenorchestraCSIDriver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
-	klog.V(5).Info("NodeUnpublishVolume called", "request", req)
-
 	// Check arguments
 	if len(req.GetVolumeId()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
 	}
+
+	release, err := driver.lockVolume(req.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	if len(req.GetTargetPath()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Target path missing in request")
 	}
 	targetPath := req.GetTargetPath()
 
-	err := driver.mounter.Unmount(targetPath)
+	err = driver.mounter.Unmount(targetPath)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to unmount target path: %v", err)
 	}
@@ -191,13 +201,17 @@ func (driver *xenorchestraCSIDriver) NodeExpandVolume(context.Context, *csi.Node
 
 // NodeStageVolume implements Driver.
 func (driver *xenorchestraCSIDriver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
-	klog.V(5).Info("NodeStageVolume called", "request", req)
-
 	// Check arguments
 	if len(req.GetVolumeId()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
 	}
 
+	release, err := driver.lockVolume(req.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	stagingTarget := req.GetStagingTargetPath()
 	if len(stagingTarget) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Staging target path missing in request")
@@ -218,6 +232,14 @@ func (driver *xenorchestraCSIDriver) NodeStageVolume(ctx context.Context, req *c
 		fsType = DefaultFsType
 	}
 
+	if req.GetPublishContext()[publishContextKeyDevices] != "" {
+		if err := driver.stageStripedVolume(ctx, req.GetVolumeId(), stagingTarget, fsType, req.GetPublishContext()); err != nil {
+			return nil, err
+		}
+		klog.V(2).Info("NodeStageVolume: successfully staged striped volume", "target", stagingTarget, "fstype", fsType)
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
 	device := req.GetPublishContext()["device"]
 	if device == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "device is not set")
@@ -247,7 +269,7 @@ func (driver *xenorchestraCSIDriver) NodeStageVolume(ctx context.Context, req *c
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "vbd in publish context is not a valid UUID: %v", err)
 	}
-	if err := driver.xoClient.IsSRAttachedToVMHost(ctx, vbdID); err != nil {
+	if err := driver.xo(ctx).IsSRAttachedToVMHost(ctx, vbdID); err != nil {
 		return nil, status.Errorf(codes.Internal, "SR connectivity check failed for VBD %s: %v", vbdIDStr, err)
 	}
 
@@ -269,12 +291,17 @@ func (driver *xenorchestraCSIDriver) NodeStageVolume(ctx context.Context, req *c
 
 // NodeUnstageVolume implements Driver.
 func (driver *xenorchestraCSIDriver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
-	klog.V(5).Info("NodeUnstageVolume called", "request", req)
 	// Check arguments
 	if len(req.GetVolumeId()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
 	}
 
+	release, err := driver.lockVolume(req.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	stagingTarget := req.GetStagingTargetPath()
 	if len(stagingTarget) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Staging target path missing in request")
@@ -286,7 +313,20 @@ func (driver *xenorchestraCSIDriver) NodeUnstageVolume(ctx context.Context, req
 		return nil, status.Errorf(codes.Internal, "failed to check if device is already mounted: %v", err)
 	}
 
+	// The md array of a striped volume, named after the volume, holds its
+	// members until it is stopped.
+	arrayPath, err := driver.mounter.StripedDevicePath(stripedDeviceName(req.GetVolumeId()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to look up striped device: %v", err)
+	}
+
 	if refCount < 1 {
+		// A previous call may have unmounted the array but failed to stop it.
+		if arrayPath != "" {
+			if err := driver.mounter.StopStripedDevice(arrayPath); err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to stop striped device %s: %v", arrayPath, err)
+			}
+		}
 		klog.V(2).Info("NodeUnstageVolume: target is not mounted, nothing to do", "stagingTarget", stagingTarget)
 		return &csi.NodeUnstageVolumeResponse{}, nil
 	}
@@ -301,6 +341,12 @@ func (driver *xenorchestraCSIDriver) NodeUnstageVolume(ctx context.Context, req
 		return nil, status.Errorf(codes.Internal, "failed to unstage device at %s: %v", stagingTarget, err)
 	}
 
+	if arrayPath != "" && filepath.Base(currentDevice) == filepath.Base(arrayPath) {
+		if err := driver.mounter.StopStripedDevice(arrayPath); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to stop striped device %s: %v", arrayPath, err)
+		}
+	}
+
 	klog.V(4).Info("NodeUnstageVolume: successfully unstaged device", "stagingTarget", stagingTarget)
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
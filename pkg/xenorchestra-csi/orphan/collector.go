@@ -0,0 +1,340 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package orphan finds VDIs created by the driver that no Kubernetes
+// PersistentVolume references any more, reports them and optionally deletes
+// them once a grace period has elapsed.
+package orphan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// EventReasonOrphanedVolume is the event reason used when an orphaned VDI is found.
+	EventReasonOrphanedVolume = "OrphanedVolume"
+	// EventReasonOrphanedVolumeDeleted is the event reason used when an orphaned VDI is deleted.
+	EventReasonOrphanedVolumeDeleted = "OrphanedVolumeDeleted"
+	// EventReasonOrphanedVolumeDeleteFailed is the event reason used when deleting an orphaned VDI fails.
+	EventReasonOrphanedVolumeDeleteFailed = "OrphanedVolumeDeleteFailed"
+)
+
+// Action describes what the collector did with an orphaned VDI.
+type Action string
+
+const (
+	// ActionReported means the orphan was only reported (dry-run).
+	ActionReported Action = "reported"
+	// ActionMarked means the orphan was tagged with the time it was first seen.
+	ActionMarked Action = "marked"
+	// ActionPending means the orphan is still within its grace period.
+	ActionPending Action = "pending"
+	// ActionSkippedAttached means the orphan is still plugged into a VM and was left alone.
+	ActionSkippedAttached Action = "skipped-attached"
+	// ActionDeleted means the orphan was deleted.
+	ActionDeleted Action = "deleted"
+	// ActionFailed means an error occurred while handling the orphan.
+	ActionFailed Action = "failed"
+)
+
+// Options configures a Collector.
+type Options struct {
+	// DriverName is the CSI driver name PersistentVolumes must reference.
+	DriverName string
+	// ClusterTag selects the VDIs owned by this cluster. It must not be empty.
+	ClusterTag string
+	// GracePeriod is the minimum time a VDI must stay orphaned before deletion.
+	GracePeriod time.Duration
+	// Delete enables deletion of orphans whose grace period has elapsed.
+	// When false the collector runs in dry-run mode and never modifies a VDI.
+	Delete bool
+}
+
+// Orphan is a VDI owned by the cluster that no PersistentVolume references.
+type Orphan struct {
+	VDI      *payloads.VDI
+	VolumeID string
+	PVName   string
+	// OrphanSince is when the VDI was first found orphaned; zero in dry-run mode
+	// if the VDI was never marked.
+	OrphanSince time.Time
+	Action      Action
+	Err         error
+}
+
+// Report summarises a single collector pass.
+type Report struct {
+	// Scanned is the number of cluster-tagged VDIs inspected.
+	Scanned int
+	Orphans []Orphan
+}
+
+// Collector cross-checks cluster-tagged VDIs against Kubernetes PersistentVolumes.
+type Collector struct {
+	// xoClients are the clients of the Xen Orchestra instances storing the
+	// volumes of the cluster.
+	xoClients  []clients.XoClient
+	kubeClient kube.Interface
+	recorder   record.EventRecorder
+	opts       Options
+	now        func() time.Time
+}
+
+// NewCollector returns a Collector of the VDIs of xoClients. recorder may be
+// nil, in which case no Kubernetes events are emitted.
+func NewCollector(xoClients []clients.XoClient, kubeClient kube.Interface, recorder record.EventRecorder, opts Options) *Collector {
+	if recorder == nil {
+		recorder = &record.FakeRecorder{}
+	}
+	return &Collector{
+		xoClients:  xoClients,
+		kubeClient: kubeClient,
+		recorder:   recorder,
+		opts:       opts,
+		now:        time.Now,
+	}
+}
+
+// Run executes a collector pass every interval until ctx is cancelled.
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	klog.InfoS("Starting orphaned VDI garbage collector", "interval", interval, "gracePeriod", c.opts.GracePeriod, "delete", c.opts.Delete)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			klog.ErrorS(err, "Orphaned VDI garbage collector pass failed")
+		}
+		select {
+		case <-ctx.Done():
+			klog.InfoS("Stopping orphaned VDI garbage collector")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs a single collector pass and returns what it found and did.
+func (c *Collector) RunOnce(ctx context.Context) (*Report, error) {
+	report, err := c.runOnce(ctx)
+	if err != nil {
+		metrics.OrphanGCRuns.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.OrphanGCRuns.WithLabelValues("success").Inc()
+	return report, nil
+}
+
+func (c *Collector) runOnce(ctx context.Context) (*Report, error) {
+	if c.opts.ClusterTag == "" {
+		return nil, errors.New("a cluster tag is required to identify the VDIs owned by this cluster")
+	}
+
+	vdis := make([][]*payloads.VDI, len(c.xoClients))
+	for i, xoClient := range c.xoClients {
+		var err error
+		vdis[i], err = xoClient.VDI().GetAll(ctx, 0, clients.BuildClusterTagFilter(c.opts.ClusterTag))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list VDIs with cluster tag %q: %w", c.opts.ClusterTag, err)
+		}
+	}
+
+	refs, err := c.listVolumeReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	now := c.now()
+	for i, xoClient := range c.xoClients {
+		report.Scanned += len(vdis[i])
+		for _, vdi := range vdis[i] {
+			c.check(ctx, xoClient, vdi, refs, now, report)
+		}
+	}
+
+	c.updateMetrics(report)
+	return report, nil
+}
+
+// check adds vdi to report if it is orphaned, and handles it.
+func (c *Collector) check(ctx context.Context, xoClient clients.XoClient, vdi *payloads.VDI, refs *volumeReferences, now time.Time, report *Report) {
+	pvName := clients.ParseTagValue(vdi.Tags, clients.VDITagKeyPVName)
+	if pvName == "" {
+		// Only dynamically provisioned VDIs carry a pvName tag; static VDIs
+		// adopted at publish time are never considered orphans.
+		return
+	}
+	volumeID := clients.ParseTagValue(vdi.Tags, clients.VDITagKeyVolumeId)
+	orphanSince := parseOrphanSince(vdi)
+
+	if refs.references(vdi, volumeID, pvName) {
+		if !orphanSince.IsZero() && c.opts.Delete {
+			// The volume is referenced again (e.g. a PV was restored); clear the mark.
+			tag := clients.BuildTag(clients.VDITagKeyOrphanSince, clients.ParseTagValue(vdi.Tags, clients.VDITagKeyOrphanSince))
+			if err := xoClient.VDI().RemoveTag(ctx, vdi.ID, tag); err != nil {
+				klog.ErrorS(err, "Failed to remove orphan mark from referenced VDI", "vdiID", vdi.ID)
+			}
+		}
+		return
+	}
+
+	orphan := Orphan{VDI: vdi, VolumeID: volumeID, PVName: pvName, OrphanSince: orphanSince}
+	c.handleOrphan(ctx, xoClient, &orphan, now)
+	report.Orphans = append(report.Orphans, orphan)
+}
+
+func (c *Collector) handleOrphan(ctx context.Context, xoClient clients.XoClient, orphan *Orphan, now time.Time) {
+	vdi := orphan.VDI
+	pvRef := &corev1.ObjectReference{Kind: "PersistentVolume", APIVersion: "v1", Name: orphan.PVName}
+
+	switch {
+	case !c.opts.Delete:
+		orphan.Action = ActionReported
+		klog.InfoS("Found orphaned VDI (dry-run)", "vdiID", vdi.ID, "volumeID", orphan.VolumeID, "pvName", orphan.PVName)
+		c.recorder.Eventf(pvRef, corev1.EventTypeWarning, EventReasonOrphanedVolume,
+			"VDI %s (volume %s) is not referenced by any PersistentVolume", vdi.ID, orphan.VolumeID)
+		return
+
+	case orphan.OrphanSince.IsZero():
+		tag := clients.BuildTag(clients.VDITagKeyOrphanSince, now.UTC().Format(time.RFC3339))
+		if err := xoClient.VDI().AddTag(ctx, vdi.ID, tag); err != nil {
+			orphan.Action, orphan.Err = ActionFailed, fmt.Errorf("failed to mark VDI %s as orphaned: %w", vdi.ID, err)
+			klog.ErrorS(err, "Failed to mark orphaned VDI", "vdiID", vdi.ID)
+			return
+		}
+		orphan.OrphanSince = now
+		orphan.Action = ActionMarked
+		klog.InfoS("Marked orphaned VDI, deletion after grace period", "vdiID", vdi.ID, "pvName", orphan.PVName, "gracePeriod", c.opts.GracePeriod)
+		c.recorder.Eventf(pvRef, corev1.EventTypeWarning, EventReasonOrphanedVolume,
+			"VDI %s (volume %s) is not referenced by any PersistentVolume; it will be deleted after %s", vdi.ID, orphan.VolumeID, c.opts.GracePeriod)
+		return
+
+	case now.Sub(orphan.OrphanSince) < c.opts.GracePeriod:
+		orphan.Action = ActionPending
+		klog.V(4).InfoS("Orphaned VDI still within grace period", "vdiID", vdi.ID, "orphanSince", orphan.OrphanSince)
+		return
+	}
+
+	// Never delete a VDI that is still plugged into a VM, whatever the PVs say.
+	vbds, err := xoClient.IsVDIUsedAnywhere(ctx, vdi)
+	if err != nil {
+		orphan.Action, orphan.Err = ActionFailed, fmt.Errorf("failed to check attachments of VDI %s: %w", vdi.ID, err)
+		klog.ErrorS(err, "Failed to check orphaned VDI attachments", "vdiID", vdi.ID)
+		return
+	}
+	for _, vbd := range vbds {
+		if vbd.Attached {
+			orphan.Action = ActionSkippedAttached
+			klog.InfoS("Orphaned VDI is still attached to a VM, skipping deletion", "vdiID", vdi.ID, "vmID", vbd.VM)
+			return
+		}
+	}
+
+	if err := xoClient.VDI().Delete(ctx, vdi.ID); err != nil && !clients.IsNotFoundError(err) {
+		orphan.Action, orphan.Err = ActionFailed, fmt.Errorf("failed to delete VDI %s: %w", vdi.ID, err)
+		klog.ErrorS(err, "Failed to delete orphaned VDI", "vdiID", vdi.ID)
+		c.recorder.Eventf(pvRef, corev1.EventTypeWarning, EventReasonOrphanedVolumeDeleteFailed,
+			"Failed to delete orphaned VDI %s: %v", vdi.ID, err)
+		return
+	}
+	orphan.Action = ActionDeleted
+	metrics.OrphanedVolumesDeleted.WithLabelValues(vdi.PoolID.String()).Inc()
+	klog.InfoS("Deleted orphaned VDI", "vdiID", vdi.ID, "volumeID", orphan.VolumeID, "pvName", orphan.PVName, "orphanSince", orphan.OrphanSince)
+	c.recorder.Eventf(pvRef, corev1.EventTypeNormal, EventReasonOrphanedVolumeDeleted,
+		"Deleted orphaned VDI %s (volume %s), unreferenced since %s", vdi.ID, orphan.VolumeID, orphan.OrphanSince.UTC().Format(time.RFC3339))
+}
+
+func (c *Collector) updateMetrics(report *Report) {
+	metrics.OrphanedVolumes.Reset()
+	metrics.OrphanedVolumeBytes.Reset()
+	for _, orphan := range report.Orphans {
+		if orphan.Action == ActionDeleted {
+			continue
+		}
+		pool := orphan.VDI.PoolID.String()
+		metrics.OrphanedVolumes.WithLabelValues(pool).Inc()
+		metrics.OrphanedVolumeBytes.WithLabelValues(pool).Add(float64(orphan.VDI.Size))
+	}
+}
+
+// volumeReferences indexes the PersistentVolumes that may reference a VDI.
+type volumeReferences struct {
+	handles map[string]struct{}
+	pvNames map[string]struct{}
+}
+
+func (c *Collector) listVolumeReferences(ctx context.Context) (*volumeReferences, error) {
+	pvs, err := c.kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumes: %w", err)
+	}
+	refs := &volumeReferences{
+		handles: make(map[string]struct{}, len(pvs.Items)),
+		pvNames: make(map[string]struct{}, len(pvs.Items)),
+	}
+	for _, pv := range pvs.Items {
+		// Any PV with the same name keeps the VDI alive, even if it belongs to
+		// another driver: deleting data is never worth the ambiguity.
+		refs.pvNames[pv.Name] = struct{}{}
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == c.opts.DriverName {
+			// VDIs store the volume ID without the Xen Orchestra instance of
+			// the handle.
+			_, volumeID := clients.SplitVolumeHandle(pv.Spec.CSI.VolumeHandle)
+			refs.handles[volumeID] = struct{}{}
+		}
+	}
+	return refs, nil
+}
+
+func (r *volumeReferences) references(vdi *payloads.VDI, volumeID, pvName string) bool {
+	if _, ok := r.pvNames[pvName]; ok {
+		return true
+	}
+	if _, ok := r.handles[vdi.ID.String()]; ok {
+		return true
+	}
+	if volumeID == "" {
+		return false
+	}
+	_, ok := r.handles[volumeID]
+	return ok
+}
+
+func parseOrphanSince(vdi *payloads.VDI) time.Time {
+	value := clients.ParseTagValue(vdi.Tags, clients.VDITagKeyOrphanSince)
+	if value == "" {
+		return time.Time{}
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		klog.V(2).InfoS("Ignoring malformed orphan mark on VDI", "vdiID", vdi.ID, "value", value)
+		return time.Time{}
+	}
+	return since
+}
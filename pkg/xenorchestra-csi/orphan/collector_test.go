@@ -0,0 +1,189 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	testDriverName = "csi.xenorchestra.vates.tech"
+	testClusterTag = "k8s-test"
+)
+
+var (
+	now          = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	referencedID = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
+	orphanID     = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000002"))
+	staticID     = uuid.Must(uuid.FromString("cccccccc-0000-0000-0000-000000000003"))
+)
+
+func newVDI(id uuid.UUID, volumeID, pvName string, extraTags ...string) *payloads.VDI {
+	return stub.NewVolumeVDI(id, volumeID, pvName, append([]string{testClusterTag}, extraTags...)...)
+}
+
+func orphanSinceTag(t time.Time) string {
+	return clients.BuildTag(clients.VDITagKeyOrphanSince, t.Format(time.RFC3339))
+}
+
+func newTestCollector(t *testing.T, opts Options, vdis []*payloads.VDI, pvs ...*corev1.PersistentVolume) (*Collector, *clientsMock.MockXoClient, *xoLibMock.MockVDI, *record.FakeRecorder) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockXo := clientsMock.NewMockXoClient(ctrl)
+	mockVDI := xoLibMock.NewMockVDI(ctrl)
+	mockXo.EXPECT().VDI().Return(mockVDI).AnyTimes()
+	mockVDI.EXPECT().GetAll(gomock.Any(), 0, clients.BuildClusterTagFilter(testClusterTag)).Return(vdis, nil)
+
+	kubeClient := fake.NewClientset()
+	for _, pv := range pvs {
+		_, err := kubeClient.CoreV1().PersistentVolumes().Create(context.Background(), pv, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	opts.DriverName = testDriverName
+	opts.ClusterTag = testClusterTag
+	c := NewCollector([]clients.XoClient{mockXo}, kubeClient, recorder, opts)
+	c.now = func() time.Time { return now }
+	return c, mockXo, mockVDI, recorder
+}
+
+func TestRunOnce(t *testing.T) {
+	t.Run("DryRunReportsWithoutModifying", func(t *testing.T) {
+		vdis := []*payloads.VDI{
+			newVDI(referencedID, "vol-1", "pv-1"),
+			newVDI(orphanID, "vol-2", "pv-2"),
+			newVDI(staticID, "", ""), // adopted static VDI, never an orphan
+		}
+		c, _, _, recorder := newTestCollector(t, Options{GracePeriod: time.Hour}, vdis, stub.NewPersistentVolume("pv-1", testDriverName, "vol-1"))
+
+		report, err := c.RunOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Scanned)
+		require.Len(t, report.Orphans, 1)
+		assert.Equal(t, orphanID, report.Orphans[0].VDI.ID)
+		assert.Equal(t, ActionReported, report.Orphans[0].Action)
+		assert.Contains(t, <-recorder.Events, EventReasonOrphanedVolume)
+	})
+
+	t.Run("ReferencedByHandleOrName", func(t *testing.T) {
+		vdis := []*payloads.VDI{
+			newVDI(referencedID, "vol-1", "renamed-pv"),
+			newVDI(orphanID, "vol-2", "pv-2"),
+			newVDI(staticID, "vol-3", "pv-3"),
+		}
+		c, _, _, _ := newTestCollector(t, Options{}, vdis,
+			stub.NewPersistentVolume("other", testDriverName, "vol-1"),            // by volume ID tag
+			stub.NewPersistentVolume("pv-2", testDriverName, "unrelated"),         // by PV name
+			stub.NewPersistentVolume("static", testDriverName, staticID.String()), // by raw VDI UUID
+		)
+
+		report, err := c.RunOnce(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, report.Orphans)
+	})
+
+	t.Run("ReferencedByHandleWithInstance", func(t *testing.T) {
+		vdis := []*payloads.VDI{newVDI(referencedID, "vol-1", "renamed-pv")}
+		c, _, _, _ := newTestCollector(t, Options{}, vdis, stub.NewPersistentVolume("other", testDriverName, "paris/vol-1"))
+
+		report, err := c.RunOnce(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, report.Orphans)
+	})
+
+	t.Run("MarksNewOrphan", func(t *testing.T) {
+		vdis := []*payloads.VDI{newVDI(orphanID, "vol-2", "pv-2")}
+		c, _, mockVDI, _ := newTestCollector(t, Options{Delete: true, GracePeriod: time.Hour}, vdis)
+		mockVDI.EXPECT().AddTag(gomock.Any(), orphanID, orphanSinceTag(now)).Return(nil)
+
+		report, err := c.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.Len(t, report.Orphans, 1)
+		assert.Equal(t, ActionMarked, report.Orphans[0].Action)
+		assert.Equal(t, now, report.Orphans[0].OrphanSince)
+	})
+
+	t.Run("KeepsOrphanWithinGracePeriod", func(t *testing.T) {
+		vdis := []*payloads.VDI{newVDI(orphanID, "vol-2", "pv-2", orphanSinceTag(now.Add(-30*time.Minute)))}
+		c, _, _, _ := newTestCollector(t, Options{Delete: true, GracePeriod: time.Hour}, vdis)
+
+		report, err := c.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.Len(t, report.Orphans, 1)
+		assert.Equal(t, ActionPending, report.Orphans[0].Action)
+	})
+
+	t.Run("DeletesOrphanAfterGracePeriod", func(t *testing.T) {
+		vdis := []*payloads.VDI{newVDI(orphanID, "vol-2", "pv-2", orphanSinceTag(now.Add(-2*time.Hour)))}
+		c, mockXo, mockVDI, recorder := newTestCollector(t, Options{Delete: true, GracePeriod: time.Hour}, vdis)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), vdis[0]).Return(nil, nil)
+		mockVDI.EXPECT().Delete(gomock.Any(), orphanID).Return(nil)
+
+		report, err := c.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.Len(t, report.Orphans, 1)
+		assert.Equal(t, ActionDeleted, report.Orphans[0].Action)
+		assert.Contains(t, <-recorder.Events, EventReasonOrphanedVolumeDeleted)
+	})
+
+	t.Run("NeverDeletesAttachedOrphan", func(t *testing.T) {
+		vdis := []*payloads.VDI{newVDI(orphanID, "vol-2", "pv-2", orphanSinceTag(now.Add(-2*time.Hour)))}
+		c, mockXo, _, _ := newTestCollector(t, Options{Delete: true, GracePeriod: time.Hour}, vdis)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), vdis[0]).Return([]*payloads.VBD{{Attached: true}}, nil)
+
+		report, err := c.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.Len(t, report.Orphans, 1)
+		assert.Equal(t, ActionSkippedAttached, report.Orphans[0].Action)
+	})
+
+	t.Run("UnmarksReferencedVDI", func(t *testing.T) {
+		mark := orphanSinceTag(now.Add(-2 * time.Hour))
+		vdis := []*payloads.VDI{newVDI(referencedID, "vol-1", "pv-1", mark)}
+		c, _, mockVDI, _ := newTestCollector(t, Options{Delete: true, GracePeriod: time.Hour}, vdis, stub.NewPersistentVolume("pv-1", testDriverName, "vol-1"))
+		mockVDI.EXPECT().RemoveTag(gomock.Any(), referencedID, mark).Return(nil)
+
+		report, err := c.RunOnce(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, report.Orphans)
+	})
+}
+
+func TestRunOnceRequiresClusterTag(t *testing.T) {
+	c := NewCollector(nil, nil, nil, Options{})
+	_, err := c.RunOnce(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,208 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// ActionDestroyed means the stale VBD was destroyed.
+const ActionDestroyed Action = "destroyed"
+
+// StaleVBD is an unplugged VBD linking a cluster VDI to a node VM. Earlier
+// driver versions only unplugged VBDs on unpublish, leaving one behind for
+// every volume a node ever mounted.
+type StaleVBD struct {
+	VBD      *payloads.VBD
+	NodeName string
+	VDIID    uuid.UUID
+	VolumeID string
+	PVName   string
+	Action   Action
+	Err      error
+}
+
+// VBDCleanupReport summarises a stale VBD cleanup.
+type VBDCleanupReport struct {
+	// Nodes is the number of node VMs inspected.
+	Nodes int
+	// SkippedNodes lists the nodes whose VM could not be resolved from their
+	// providerID, or is not running.
+	SkippedNodes []string
+	StaleVBDs    []StaleVBD
+}
+
+// CleanupStaleVBDs finds the unplugged VBDs linking a cluster-tagged VDI to one
+// of the cluster's running node VMs. Unless destroy is true, they are only
+// reported. Node VMs are resolved from the providerID set by the Xen
+// Orchestra CCM.
+//
+// Every VBD of a halted or rebooting VM is unplugged, so the VBDs of VMs that
+// are not running are never stale, nor are the VBDs of the volumes a
+// VolumeAttachment attaches, or is attaching, to the node. A VBD is briefly
+// unplugged while ControllerPublishVolume creates it, so this is meant to be
+// run once after upgrading, while no volume is being attached.
+func CleanupStaleVBDs(ctx context.Context, xoClient clients.XoClient, kubeClient kube.Interface, clusterTag string, destroy bool) (*VBDCleanupReport, error) {
+	if clusterTag == "" {
+		return nil, errors.New("a cluster tag is required to identify the cluster VDIs")
+	}
+
+	vdis, err := xoClient.VDI().GetAll(ctx, 0, clients.BuildClusterTagFilter(clusterTag))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VDIs with tag %q: %w", clusterTag, err)
+	}
+	clusterVDIs := make(map[uuid.UUID]*payloads.VDI, len(vdis))
+	for _, vdi := range vdis {
+		clusterVDIs[vdi.ID] = vdi
+	}
+
+	nodes, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	attached, err := attachedVolumes(ctx, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VBDCleanupReport{}
+	for _, node := range nodes.Items {
+		vm, _, err := xok8s.ParseProviderID(node.Spec.ProviderID)
+		if err != nil {
+			klog.InfoS("Skipping node without a Xen Orchestra providerID", "node", node.Name, "err", err)
+			report.SkippedNodes = append(report.SkippedNodes, node.Name)
+			continue
+		}
+		nodeVM, err := xoClient.VM().GetByID(ctx, vm.ID)
+		if err != nil {
+			return report, fmt.Errorf("failed to get VM %s of node %s: %w", vm.ID, node.Name, err)
+		}
+		if nodeVM.PowerState != payloads.PowerStateRunning {
+			klog.InfoS("Skipping node whose VM is not running", "node", node.Name, "vm", vm.ID, "powerState", nodeVM.PowerState)
+			report.SkippedNodes = append(report.SkippedNodes, node.Name)
+			continue
+		}
+		report.Nodes++
+
+		vbds, err := xoClient.VBD().GetAll(ctx, 0, fmt.Sprintf("VM:%s !attached?", vm.ID))
+		if err != nil {
+			return report, fmt.Errorf("failed to list unplugged VBDs of node %s (VM %s): %w", node.Name, vm.ID, err)
+		}
+		for _, vbd := range vbds {
+			if vbd.Attached || vbd.VDI == nil {
+				continue
+			}
+			vdi, ok := clusterVDIs[*vbd.VDI]
+			if !ok {
+				continue
+			}
+			if attached.has(node.Name, vdi) {
+				klog.V(2).InfoS("Skipping VBD of a volume attached to the node", "vbd", vbd.ID, "vdi", vdi.ID, "node", node.Name)
+				continue
+			}
+
+			stale := StaleVBD{
+				VBD:      vbd,
+				NodeName: node.Name,
+				VDIID:    vdi.ID,
+				VolumeID: clients.ParseTagValue(vdi.Tags, clients.VDITagKeyVolumeId),
+				PVName:   clients.ParseTagValue(vdi.Tags, clients.VDITagKeyPVName),
+				Action:   ActionReported,
+			}
+			if destroy {
+				if err := xoClient.DestroyVBD(ctx, *vbd); err != nil {
+					klog.ErrorS(err, "Failed to destroy stale VBD", "vbd", vbd.ID, "vdi", vdi.ID, "node", node.Name)
+					stale.Action, stale.Err = ActionFailed, err
+				} else {
+					klog.InfoS("Destroyed stale VBD", "vbd", vbd.ID, "vdi", vdi.ID, "node", node.Name)
+					stale.Action = ActionDestroyed
+				}
+			}
+			report.StaleVBDs = append(report.StaleVBDs, stale)
+		}
+	}
+	return report, nil
+}
+
+// nodeVolumes are the volumes VolumeAttachments attach, or are attaching, to
+// each node, by PersistentVolume name and volume ID.
+type nodeVolumes map[string]map[string]bool
+
+// attachedVolumes returns the volumes of the VolumeAttachments that are
+// attached, or not being detached.
+func attachedVolumes(ctx context.Context, kubeClient kube.Interface) (nodeVolumes, error) {
+	vas, err := kubeClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeAttachments: %w", err)
+	}
+	pvs, err := kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumes: %w", err)
+	}
+	handles := make(map[string]string, len(pvs.Items))
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil {
+			handles[pv.Name] = pv.Spec.CSI.VolumeHandle
+		}
+	}
+
+	volumes := nodeVolumes{}
+	for _, va := range vas.Items {
+		if va.DeletionTimestamp != nil && !va.Status.Attached {
+			continue
+		}
+		if volumes[va.Spec.NodeName] == nil {
+			volumes[va.Spec.NodeName] = map[string]bool{}
+		}
+		var handle string
+		if name := va.Spec.Source.PersistentVolumeName; name != nil {
+			volumes[va.Spec.NodeName][*name] = true
+			handle = handles[*name]
+		} else if spec := va.Spec.Source.InlineVolumeSpec; spec != nil && spec.CSI != nil {
+			handle = spec.CSI.VolumeHandle
+		}
+		if handle != "" {
+			_, volumeID := clients.SplitVolumeHandle(handle)
+			volumes[va.Spec.NodeName][volumeID] = true
+		}
+	}
+	return volumes, nil
+}
+
+// has returns whether a VolumeAttachment attaches the volume of vdi to node,
+// matched by volume ID or PersistentVolume name tag.
+func (v nodeVolumes) has(node string, vdi *payloads.VDI) bool {
+	for _, key := range []string{clients.VDITagKeyVolumeId, clients.VDITagKeyPVName} {
+		if value := clients.ParseTagValue(vdi.Tags, key); value != "" && v[node][value] {
+			return true
+		}
+	}
+	return false
+}
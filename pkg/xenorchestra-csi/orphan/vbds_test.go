@@ -0,0 +1,204 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var (
+	nodeVMID   = uuid.Must(uuid.FromString("dddddddd-0000-0000-0000-000000000004"))
+	nodePoolID = uuid.Must(uuid.FromString("eeeeeeee-0000-0000-0000-000000000005"))
+	foreignID  = uuid.Must(uuid.FromString("ffffffff-0000-0000-0000-000000000006"))
+)
+
+func newNode(name, providerID string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{ProviderID: providerID},
+	}
+}
+
+func newVBD(vdiID uuid.UUID, attached bool) *payloads.VBD {
+	return &payloads.VBD{ID: uuid.Must(uuid.NewV4()), VM: nodeVMID, VDI: &vdiID, Attached: attached}
+}
+
+// newVBDCleanupMocks returns the clients of a cluster with the node VM of
+// node-1 in powerState, holding vbds when running, the node node-2 without a
+// providerID, and objects.
+func newVBDCleanupMocks(t *testing.T, powerState string, vbds []*payloads.VBD, objects ...runtime.Object) (*clientsMock.MockXoClient, *fake.Clientset) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockXo := clientsMock.NewMockXoClient(ctrl)
+	mockVDI := xoLibMock.NewMockVDI(ctrl)
+	mockVBD := xoLibMock.NewMockVBD(ctrl)
+	mockVM := xoLibMock.NewMockVM(ctrl)
+	mockXo.EXPECT().VDI().Return(mockVDI).AnyTimes()
+	mockXo.EXPECT().VBD().Return(mockVBD).AnyTimes()
+	mockXo.EXPECT().VM().Return(mockVM).AnyTimes()
+	mockVDI.EXPECT().GetAll(gomock.Any(), 0, clients.BuildClusterTagFilter(testClusterTag)).
+		Return([]*payloads.VDI{newVDI(orphanID, "vol-1", "pv-1")}, nil)
+	mockVM.EXPECT().GetByID(gomock.Any(), nodeVMID).Return(&payloads.VM{ID: nodeVMID, PowerState: powerState}, nil)
+	if powerState == payloads.PowerStateRunning {
+		mockVBD.EXPECT().GetAll(gomock.Any(), 0, fmt.Sprintf("VM:%s !attached?", nodeVMID)).Return(vbds, nil)
+	}
+
+	kubeClient := fake.NewClientset(append(objects,
+		newNode("node-1", fmt.Sprintf("xenorchestra://%s/%s", nodePoolID, nodeVMID)),
+		newNode("node-2", ""),
+	)...)
+	return mockXo, kubeClient
+}
+
+func newVolumeAttachment(name, pvName, nodeName string, attached bool) *storagev1.VolumeAttachment {
+	return &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: testDriverName,
+			NodeName: nodeName,
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+		},
+		Status: storagev1.VolumeAttachmentStatus{Attached: attached},
+	}
+}
+
+func TestCleanupStaleVBDs(t *testing.T) {
+	t.Run("DryRunOnlyReportsClusterVBDs", func(t *testing.T) {
+		stale := newVBD(orphanID, false)
+		mockXo, kubeClient := newVBDCleanupMocks(t, payloads.PowerStateRunning, []*payloads.VBD{stale, newVBD(foreignID, false)})
+
+		report, err := CleanupStaleVBDs(context.Background(), mockXo, kubeClient, testClusterTag, false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Nodes)
+		assert.Equal(t, []string{"node-2"}, report.SkippedNodes)
+		require.Len(t, report.StaleVBDs, 1)
+		assert.Equal(t, stale, report.StaleVBDs[0].VBD)
+		assert.Equal(t, "node-1", report.StaleVBDs[0].NodeName)
+		assert.Equal(t, "vol-1", report.StaleVBDs[0].VolumeID)
+		assert.Equal(t, ActionReported, report.StaleVBDs[0].Action)
+	})
+
+	t.Run("DestroysStaleVBDs", func(t *testing.T) {
+		stale := newVBD(orphanID, false)
+		mockXo, kubeClient := newVBDCleanupMocks(t, payloads.PowerStateRunning, []*payloads.VBD{stale})
+		mockXo.EXPECT().DestroyVBD(gomock.Any(), *stale).Return(nil)
+
+		report, err := CleanupStaleVBDs(context.Background(), mockXo, kubeClient, testClusterTag, true)
+		require.NoError(t, err)
+		require.Len(t, report.StaleVBDs, 1)
+		assert.Equal(t, ActionDestroyed, report.StaleVBDs[0].Action)
+	})
+
+	t.Run("ReportsDestroyFailure", func(t *testing.T) {
+		stale := newVBD(orphanID, false)
+		mockXo, kubeClient := newVBDCleanupMocks(t, payloads.PowerStateRunning, []*payloads.VBD{stale})
+		destroyErr := errors.New("VBD is in use")
+		mockXo.EXPECT().DestroyVBD(gomock.Any(), *stale).Return(destroyErr)
+
+		report, err := CleanupStaleVBDs(context.Background(), mockXo, kubeClient, testClusterTag, true)
+		require.NoError(t, err)
+		require.Len(t, report.StaleVBDs, 1)
+		assert.Equal(t, ActionFailed, report.StaleVBDs[0].Action)
+		assert.ErrorIs(t, report.StaleVBDs[0].Err, destroyErr)
+	})
+
+	t.Run("NeverTouchesPluggedVBDs", func(t *testing.T) {
+		mockXo, kubeClient := newVBDCleanupMocks(t, payloads.PowerStateRunning, []*payloads.VBD{newVBD(orphanID, true)})
+
+		report, err := CleanupStaleVBDs(context.Background(), mockXo, kubeClient, testClusterTag, true)
+		require.NoError(t, err)
+		assert.Empty(t, report.StaleVBDs)
+	})
+
+	t.Run("SkipsNodesNotRunning", func(t *testing.T) {
+		for _, powerState := range []string{payloads.PowerStateHalted, payloads.PowerStatePaused, payloads.PowerStateSuspended} {
+			t.Run(powerState, func(t *testing.T) {
+				mockXo, kubeClient := newVBDCleanupMocks(t, powerState, nil)
+
+				report, err := CleanupStaleVBDs(context.Background(), mockXo, kubeClient, testClusterTag, true)
+				require.NoError(t, err)
+				assert.Zero(t, report.Nodes)
+				assert.ElementsMatch(t, []string{"node-1", "node-2"}, report.SkippedNodes)
+				assert.Empty(t, report.StaleVBDs)
+			})
+		}
+	})
+
+	t.Run("SkipsVolumesAttachedToTheNode", func(t *testing.T) {
+		for _, attached := range []bool{true, false} {
+			t.Run(fmt.Sprintf("Attached=%t", attached), func(t *testing.T) {
+				mockXo, kubeClient := newVBDCleanupMocks(t, payloads.PowerStateRunning, []*payloads.VBD{newVBD(orphanID, false)},
+					newVolumeAttachment("va-1", "pv-1", "node-1", attached))
+
+				report, err := CleanupStaleVBDs(context.Background(), mockXo, kubeClient, testClusterTag, true)
+				require.NoError(t, err)
+				assert.Empty(t, report.StaleVBDs)
+			})
+		}
+	})
+
+	t.Run("SkipsVolumesAttachedByHandle", func(t *testing.T) {
+		mockXo, kubeClient := newVBDCleanupMocks(t, payloads.PowerStateRunning, []*payloads.VBD{newVBD(orphanID, false)},
+			newVolumeAttachment("va-1", "renamed-pv", "node-1", true),
+			stub.NewPersistentVolume("renamed-pv", testDriverName, "paris/vol-1"))
+
+		report, err := CleanupStaleVBDs(context.Background(), mockXo, kubeClient, testClusterTag, true)
+		require.NoError(t, err)
+		assert.Empty(t, report.StaleVBDs)
+	})
+
+	t.Run("DestroysVBDsOfVolumesAttachedToOtherNodes", func(t *testing.T) {
+		stale := newVBD(orphanID, false)
+		detaching := newVolumeAttachment("va-2", "pv-1", "node-1", false)
+		detaching.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		detaching.Finalizers = []string{"external-attacher/" + testDriverName}
+		mockXo, kubeClient := newVBDCleanupMocks(t, payloads.PowerStateRunning, []*payloads.VBD{stale},
+			newVolumeAttachment("va-1", "pv-1", "node-3", true), detaching)
+		mockXo.EXPECT().DestroyVBD(gomock.Any(), *stale).Return(nil)
+
+		report, err := CleanupStaleVBDs(context.Background(), mockXo, kubeClient, testClusterTag, true)
+		require.NoError(t, err)
+		require.Len(t, report.StaleVBDs, 1)
+		assert.Equal(t, ActionDestroyed, report.StaleVBDs[0].Action)
+	})
+
+	t.Run("RequiresClusterTag", func(t *testing.T) {
+		_, err := CleanupStaleVBDs(context.Background(), nil, nil, "", false)
+		require.Error(t, err)
+	})
+}
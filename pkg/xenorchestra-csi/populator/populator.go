@@ -0,0 +1,634 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package populator provisions the PersistentVolumeClaims whose dataSourceRef
+// is a VolumeImage: it creates their volume through the CSI controller of the
+// driver, imports the image into its VDI and binds a PersistentVolume to the
+// claim. The progress and the failures are reported as events of the claim.
+package populator
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// EventReasonImportStarted is the event reason used when the import of an image starts.
+	EventReasonImportStarted = "ImportStarted"
+	// EventReasonImportProgress is the event reason used every tenth of an import.
+	EventReasonImportProgress = "ImportProgress"
+	// EventReasonVolumePopulated is the event reason used once the volume of a claim holds its image.
+	EventReasonVolumePopulated = "VolumePopulated"
+	// EventReasonImportFailed is the event reason used when populating a volume fails.
+	EventReasonImportFailed = "ImportFailed"
+	// EventReasonChecksumMismatch is the event reason used when the image does not match its checksum.
+	EventReasonChecksumMismatch = "ChecksumMismatch"
+	// EventReasonVolumeCleanedUp is the event reason used when the volume of a failed import is deleted.
+	EventReasonVolumeCleanedUp = "VolumeCleanedUp"
+	// EventReasonCleanupFailed is the event reason used when deleting the volume of a failed import fails.
+	EventReasonCleanupFailed = "CleanupFailed"
+)
+
+const (
+	// selectedNodeAnnotation is set on the claims of a WaitForFirstConsumer
+	// StorageClass once the scheduler picked the node of their first pod.
+	selectedNodeAnnotation = "volume.kubernetes.io/selected-node"
+	// provisionedByAnnotation makes the external-provisioner delete the
+	// volume of the PersistentVolume when its reclaim policy is Delete.
+	provisionedByAnnotation = "pv.kubernetes.io/provisioned-by"
+
+	// The StorageClass parameters with this prefix are read by the
+	// external-provisioner, which does not pass them to CreateVolume.
+	csiParameterPrefix  = "csi.storage.k8s.io/"
+	parameterFSType     = csiParameterPrefix + "fstype"
+	parameterSecretName = csiParameterPrefix + "provisioner-secret-name"
+	parameterSecretNS   = csiParameterPrefix + "provisioner-secret-namespace"
+)
+
+// parameterStripes is the StorageClass parameter of the driver striping the
+// volumes over several VDIs, which an image cannot be imported into.
+const parameterStripes = "stripes"
+
+const (
+	// progressSteps is the number of progress events of an import, plus one.
+	progressSteps         = 10
+	maxRetryDelay         = 15 * time.Minute
+	defaultMaxConcurrency = 2
+)
+
+// Provisioner creates, fills and deletes the volumes, on behalf of the CSI
+// controller of the driver.
+type Provisioner interface {
+	CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error)
+	DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error)
+	// ImportVolume writes the size bytes of content into the VDI of the
+	// volume volumeID.
+	ImportVolume(ctx context.Context, volumeID string, secrets map[string]string, format payloads.VDIFormat, content io.Reader, size int64) error
+}
+
+// Options configures a Populator.
+type Options struct {
+	// DriverName is the provisioner of the StorageClasses whose claims are
+	// populated.
+	DriverName string
+	// MaxConcurrency bounds the imports running at once. Defaults to 2.
+	MaxConcurrency int
+	// HTTPClient downloads the images. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Populator populates the claims referencing a VolumeImage.
+type Populator struct {
+	kubeClient    kube.Interface
+	dynamicClient dynamic.Interface
+	provisioner   Provisioner
+	recorder      record.EventRecorder
+	opts          Options
+	now           func() time.Time
+
+	mu sync.Mutex
+	// running are the claims being populated.
+	running map[types.UID]bool
+	// retries are the claims whose last population failed.
+	retries map[types.UID]retry
+	jobs    sync.WaitGroup
+}
+
+// retry delays the next population of a claim after a failure.
+type retry struct {
+	failures int
+	next     time.Time
+}
+
+// NewPopulator returns a Populator. recorder may be nil, in which case no
+// Kubernetes events are emitted.
+func NewPopulator(kubeClient kube.Interface, dynamicClient dynamic.Interface, provisioner Provisioner, recorder record.EventRecorder, opts Options) *Populator {
+	if recorder == nil {
+		recorder = &record.FakeRecorder{}
+	}
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = defaultMaxConcurrency
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	return &Populator{
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		provisioner:   provisioner,
+		recorder:      recorder,
+		opts:          opts,
+		now:           time.Now,
+		running:       map[types.UID]bool{},
+		retries:       map[types.UID]retry{},
+	}
+}
+
+// Run looks for claims to populate every interval until ctx is cancelled,
+// then waits for the imports in progress, which ctx cancels too.
+func (p *Populator) Run(ctx context.Context, interval time.Duration) {
+	klog.InfoS("Starting volume populator", "interval", interval, "maxConcurrency", p.opts.MaxConcurrency)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.RunOnce(ctx, interval); err != nil && !errors.Is(err, context.Canceled) {
+			klog.ErrorS(err, "Volume populator pass failed")
+		}
+		select {
+		case <-ctx.Done():
+			klog.InfoS("Stopping volume populator")
+			p.jobs.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce starts populating the pending claims, as long as fewer than
+// MaxConcurrency imports run. A claim whose population failed is retried
+// after retryDelay, doubled on each failure.
+func (p *Populator) RunOnce(ctx context.Context, retryDelay time.Duration) error {
+	claims, err := p.kubeClient.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list PersistentVolumeClaims: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending := map[types.UID]bool{}
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if !p.references(claim) {
+			continue
+		}
+		pending[claim.UID] = true
+		if p.running[claim.UID] || p.now().Before(p.retries[claim.UID].next) || len(p.running) >= p.opts.MaxConcurrency {
+			continue
+		}
+		p.running[claim.UID] = true
+		p.jobs.Go(func() {
+			err := p.Populate(ctx, claim)
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			delete(p.running, claim.UID)
+			if err == nil {
+				delete(p.retries, claim.UID)
+				return
+			}
+			klog.ErrorS(err, "Failed to populate volume", "pvc", klog.KObj(claim))
+			r := p.retries[claim.UID]
+			r.failures++
+			delay := retryDelay
+			for i := 1; i < r.failures && delay < maxRetryDelay; i++ {
+				delay *= 2
+			}
+			r.next = p.now().Add(min(delay, maxRetryDelay))
+			p.retries[claim.UID] = r
+		})
+	}
+	// Forget the claims deleted or bound since.
+	for uid := range p.retries {
+		if !pending[uid] {
+			delete(p.retries, uid)
+		}
+	}
+	return nil
+}
+
+// references tells whether claim is an unbound claim of a VolumeImage.
+func (p *Populator) references(claim *corev1.PersistentVolumeClaim) bool {
+	ref := claim.Spec.DataSourceRef
+	return ref != nil && ref.APIGroup != nil && *ref.APIGroup == APIGroup && ref.Kind == Kind &&
+		claim.Spec.VolumeName == "" && claim.DeletionTimestamp == nil
+}
+
+// Populate creates the volume of claim, imports its image and binds a
+// PersistentVolume to claim. It does nothing when the claim belongs to
+// another provisioner, or waits for the scheduler to pick a node. The volume
+// of a failed population is deleted, and populating the claim again starts
+// over.
+func (p *Populator) Populate(ctx context.Context, claim *corev1.PersistentVolumeClaim) error {
+	pvName := "pvc-" + string(claim.UID)
+	if _, err := p.kubeClient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{}); err == nil {
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get PersistentVolume %s: %w", pvName, err)
+	}
+
+	if claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName == "" {
+		return nil
+	}
+	sc, err := p.kubeClient.StorageV1().StorageClasses().Get(ctx, *claim.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get StorageClass %s: %w", *claim.Spec.StorageClassName, err)
+	}
+	if sc.Provisioner != p.opts.DriverName {
+		return nil
+	}
+	// The import writes a single VDI: the member of a striped volume would
+	// only get a slice of the image.
+	if stripes, err := strconv.Atoi(sc.Parameters[parameterStripes]); err == nil && stripes > 1 {
+		return p.fail(claim, fmt.Errorf("StorageClass %s stripes volumes over %d VDIs: images cannot be imported into striped volumes", sc.Name, stripes))
+	}
+	var node *corev1.Node
+	if sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+		nodeName := claim.Annotations[selectedNodeAnnotation]
+		if nodeName == "" {
+			klog.V(4).InfoS("Waiting for the scheduler to select a node", "pvc", klog.KObj(claim))
+			return nil
+		}
+		if node, err = p.kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{}); err != nil {
+			return p.fail(claim, fmt.Errorf("failed to get selected node %s: %w", nodeName, err))
+		}
+	}
+
+	ref := claim.Spec.DataSourceRef
+	if ref.Namespace != nil && *ref.Namespace != "" && *ref.Namespace != claim.Namespace {
+		return p.fail(claim, fmt.Errorf("%s %s/%s is in another namespace than the claim", Kind, *ref.Namespace, ref.Name))
+	}
+	image, err := getImage(ctx, p.dynamicClient, claim.Namespace, ref.Name)
+	if err != nil {
+		return p.fail(claim, err)
+	}
+	secrets, err := p.provisionerSecrets(ctx, sc, claim)
+	if err != nil {
+		return p.fail(claim, err)
+	}
+
+	return p.populate(ctx, claim, sc, node, pvName, image, secrets)
+}
+
+func (p *Populator) populate(ctx context.Context, claim *corev1.PersistentVolumeClaim, sc *storagev1.StorageClass,
+	node *corev1.Node, pvName string, image *Image, secrets map[string]string) error {
+	content, err := p.download(ctx, image)
+	if err != nil {
+		return p.fail(claim, err)
+	}
+	defer content.Close()
+
+	capacity := claim.Spec.Resources.Requests.Storage().Value()
+	if content.virtualSize > capacity {
+		klog.InfoS("Raising the capacity of the volume to the size of its image", "pvc", klog.KObj(claim), "requestedBytes", capacity, "imageBytes", content.virtualSize)
+		capacity = content.virtualSize
+	}
+	req := &csi.CreateVolumeRequest{
+		Name:                      pvName,
+		CapacityRange:             &csi.CapacityRange{RequiredBytes: capacity},
+		VolumeCapabilities:        volumeCapabilities(claim, sc),
+		Parameters:                volumeParameters(sc.Parameters),
+		Secrets:                   secrets,
+		AccessibilityRequirements: accessibilityRequirements(sc, node),
+	}
+	p.recorder.Eventf(claim, corev1.EventTypeNormal, EventReasonImportStarted, "Importing %s image %s (%s) into volume %s", image.Format, image.URL, formatBytes(content.size), pvName)
+	resp, err := p.provisioner.CreateVolume(ctx, req)
+	if err != nil {
+		return p.fail(claim, fmt.Errorf("failed to create volume %s: %w", pvName, err))
+	}
+	volume := resp.GetVolume()
+
+	progress := &progressReader{reader: content, size: content.size, report: func(percent int, done int64) {
+		p.recorder.Eventf(claim, corev1.EventTypeNormal, EventReasonImportProgress, "Imported %d%% (%s of %s)", percent, formatBytes(done), formatBytes(content.size))
+	}}
+	if err := p.provisioner.ImportVolume(ctx, volume.GetVolumeId(), secrets, image.vdiFormat(), progress, content.size); err != nil {
+		return p.cleanup(ctx, claim, volume, secrets, EventReasonImportFailed, fmt.Errorf("failed to import image %s: %w", image.URL, err))
+	}
+	if err := content.verify(); err != nil {
+		reason := EventReasonImportFailed
+		if errors.Is(err, errChecksumMismatch) {
+			reason = EventReasonChecksumMismatch
+		}
+		return p.cleanup(ctx, claim, volume, secrets, reason, err)
+	}
+
+	pv := persistentVolume(claim, sc, pvName, volume, req, p.opts.DriverName)
+	if _, err := p.kubeClient.CoreV1().PersistentVolumes().Create(ctx, pv, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return p.cleanup(ctx, claim, volume, secrets, EventReasonImportFailed, fmt.Errorf("failed to create PersistentVolume %s: %w", pvName, err))
+	}
+	klog.InfoS("Populated volume", "pvc", klog.KObj(claim), "pv", pvName, "volumeID", volume.GetVolumeId(), "image", image.URL)
+	p.recorder.Eventf(claim, corev1.EventTypeNormal, EventReasonVolumePopulated, "Volume %s holds image %s", pvName, image.URL)
+	return nil
+}
+
+// fail reports err on claim and returns it.
+func (p *Populator) fail(claim *corev1.PersistentVolumeClaim, err error) error {
+	p.recorder.Event(claim, corev1.EventTypeWarning, EventReasonImportFailed, err.Error())
+	return err
+}
+
+// cleanup reports err on claim with reason and deletes volume, so that the
+// next attempt starts from an empty volume.
+func (p *Populator) cleanup(ctx context.Context, claim *corev1.PersistentVolumeClaim, volume *csi.Volume, secrets map[string]string, reason string, err error) error {
+	p.recorder.Event(claim, corev1.EventTypeWarning, reason, err.Error())
+	// The volume is deleted even when the import was cancelled.
+	ctx = context.WithoutCancel(ctx)
+	if _, deleteErr := p.provisioner.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volume.GetVolumeId(), Secrets: secrets}); deleteErr != nil {
+		klog.ErrorS(deleteErr, "Failed to delete the volume of a failed import", "pvc", klog.KObj(claim), "volumeID", volume.GetVolumeId())
+		p.recorder.Eventf(claim, corev1.EventTypeWarning, EventReasonCleanupFailed, "Failed to delete volume %s: %v", volume.GetVolumeId(), deleteErr)
+		return err
+	}
+	p.recorder.Eventf(claim, corev1.EventTypeNormal, EventReasonVolumeCleanedUp, "Deleted volume %s of the failed import", volume.GetVolumeId())
+	return err
+}
+
+// provisionerSecrets returns the secret referenced by the
+// csi.storage.k8s.io/provisioner-secret-* parameters of sc, if any.
+func (p *Populator) provisionerSecrets(ctx context.Context, sc *storagev1.StorageClass, claim *corev1.PersistentVolumeClaim) (map[string]string, error) {
+	name, namespace := sc.Parameters[parameterSecretName], sc.Parameters[parameterSecretNS]
+	if name == "" || namespace == "" {
+		return nil, nil
+	}
+	expand := strings.NewReplacer("${pvc.namespace}", claim.Namespace, "${pvc.name}", claim.Name)
+	name, namespace = expand.Replace(name), expand.Replace(namespace)
+	secret, err := p.kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provisioner secret %s/%s: %w", namespace, name, err)
+	}
+	secrets := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		secrets[key] = string(value)
+	}
+	return secrets, nil
+}
+
+// volumeParameters returns the parameters of sc passed to CreateVolume.
+func volumeParameters(parameters map[string]string) map[string]string {
+	params := make(map[string]string, len(parameters))
+	for key, value := range parameters {
+		if !strings.HasPrefix(key, csiParameterPrefix) {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+func volumeCapabilities(claim *corev1.PersistentVolumeClaim, sc *storagev1.StorageClass) []*csi.VolumeCapability {
+	capability := &csi.VolumeCapability{}
+	if claim.Spec.VolumeMode != nil && *claim.Spec.VolumeMode == corev1.PersistentVolumeBlock {
+		capability.AccessType = &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}
+	} else {
+		capability.AccessType = &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{
+			FsType:     sc.Parameters[parameterFSType],
+			MountFlags: sc.MountOptions,
+		}}
+	}
+	capabilities := make([]*csi.VolumeCapability, 0, len(claim.Spec.AccessModes))
+	for _, mode := range claim.Spec.AccessModes {
+		c := &csi.VolumeCapability{AccessType: capability.AccessType, AccessMode: &csi.VolumeCapability_AccessMode{}}
+		switch mode {
+		case corev1.ReadWriteOnce:
+			c.AccessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER
+		case corev1.ReadWriteOncePod:
+			c.AccessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER
+		case corev1.ReadOnlyMany:
+			c.AccessMode.Mode = csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY
+		case corev1.ReadWriteMany:
+			c.AccessMode.Mode = csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER
+		}
+		capabilities = append(capabilities, c)
+	}
+	return capabilities
+}
+
+// accessibilityRequirements restricts the volume to the pool of the selected
+// node, if any, or else to the allowed topologies of sc.
+func accessibilityRequirements(sc *storagev1.StorageClass, node *corev1.Node) *csi.TopologyRequirement {
+	if node != nil {
+		poolID := node.Labels[xok8s.XOLabelTopologyPoolID]
+		if poolID == "" {
+			return nil
+		}
+		topology := &csi.Topology{Segments: map[string]string{xok8s.XOLabelTopologyPoolID: poolID}}
+		return &csi.TopologyRequirement{Requisite: []*csi.Topology{topology}, Preferred: []*csi.Topology{topology}}
+	}
+	var requisite []*csi.Topology
+	for _, term := range sc.AllowedTopologies {
+		for _, expression := range term.MatchLabelExpressions {
+			for _, value := range expression.Values {
+				requisite = append(requisite, &csi.Topology{Segments: map[string]string{expression.Key: value}})
+			}
+		}
+	}
+	if len(requisite) == 0 {
+		return nil
+	}
+	return &csi.TopologyRequirement{Requisite: requisite}
+}
+
+// persistentVolume returns the PersistentVolume of volume, bound to claim the
+// way the external-provisioner binds the volumes it provisions.
+func persistentVolume(claim *corev1.PersistentVolumeClaim, sc *storagev1.StorageClass, pvName string,
+	volume *csi.Volume, req *csi.CreateVolumeRequest, driverName string) *corev1.PersistentVolume {
+	reclaimPolicy := corev1.PersistentVolumeReclaimDelete
+	if sc.ReclaimPolicy != nil {
+		reclaimPolicy = *sc.ReclaimPolicy
+	}
+	volumeMode := corev1.PersistentVolumeFilesystem
+	if claim.Spec.VolumeMode != nil {
+		volumeMode = *claim.Spec.VolumeMode
+	}
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pvName,
+			Annotations: map[string]string{provisionedByAnnotation: driverName},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: *resource.NewQuantity(max(volume.GetCapacityBytes(), req.GetCapacityRange().GetRequiredBytes()), resource.BinarySI),
+			},
+			AccessModes:                   claim.Spec.AccessModes,
+			PersistentVolumeReclaimPolicy: reclaimPolicy,
+			StorageClassName:              sc.Name,
+			MountOptions:                  sc.MountOptions,
+			VolumeMode:                    &volumeMode,
+			ClaimRef: &corev1.ObjectReference{
+				Kind:            "PersistentVolumeClaim",
+				APIVersion:      "v1",
+				Namespace:       claim.Namespace,
+				Name:            claim.Name,
+				UID:             claim.UID,
+				ResourceVersion: claim.ResourceVersion,
+			},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:           driverName,
+					VolumeHandle:     volume.GetVolumeId(),
+					VolumeAttributes: volume.GetVolumeContext(),
+				},
+			},
+		},
+	}
+	if volumeMode == corev1.PersistentVolumeFilesystem {
+		pv.Spec.CSI.FSType = sc.Parameters[parameterFSType]
+	}
+	if secretName, secretNS := sc.Parameters[parameterSecretName], sc.Parameters[parameterSecretNS]; secretName != "" && secretNS != "" {
+		// The external-provisioner deletes the volume with the same secret.
+		pv.Annotations["volume.kubernetes.io/provisioner-deletion-secret-name"] = secretName
+		pv.Annotations["volume.kubernetes.io/provisioner-deletion-secret-namespace"] = secretNS
+	}
+	var terms []corev1.NodeSelectorTerm
+	for _, topology := range volume.GetAccessibleTopology() {
+		term := corev1.NodeSelectorTerm{}
+		for key, value := range topology.GetSegments() {
+			term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{
+				Key: key, Operator: corev1.NodeSelectorOpIn, Values: []string{value},
+			})
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) > 0 {
+		pv.Spec.NodeAffinity = &corev1.VolumeNodeAffinity{Required: &corev1.NodeSelector{NodeSelectorTerms: terms}}
+	}
+	return pv
+}
+
+// imageContent is the body of the download of an image, whose digest is
+// computed as it is read.
+type imageContent struct {
+	reader      io.Reader
+	body        io.Closer
+	image       *Image
+	size        int64
+	virtualSize int64
+	read        int64
+	sum         hash.Hash
+	expected    string
+}
+
+// errChecksumMismatch is returned by imageContent.verify when the image does
+// not match its checksum.
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// download starts downloading image. The server must report the size of the
+// image, which the VDI import endpoint requires.
+func (p *Populator) download(ctx context.Context, image *Image) (*imageContent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, image.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image URL %q: %w", image.URL, err)
+	}
+	resp, err := p.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download image %s: %s", image.URL, resp.Status)
+	}
+	if resp.ContentLength <= 0 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("the server of image %s does not report its size (Content-Length)", image.URL)
+	}
+
+	content := &imageContent{body: resp.Body, image: image, size: resp.ContentLength}
+	buffered := bufio.NewReaderSize(resp.Body, vhdFooterSize)
+	header, err := buffered.Peek(vhdFooterSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download image %s: %w", image.URL, err)
+	}
+	if content.virtualSize, err = image.virtualSize(header, content.size); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	content.reader = buffered
+	if image.Checksum != "" {
+		h, expected, _ := image.digest()
+		content.reader, content.sum, content.expected = io.TeeReader(buffered, h), h, expected
+	}
+	return content, nil
+}
+
+func (c *imageContent) Read(b []byte) (int, error) {
+	n, err := c.reader.Read(b)
+	c.read += int64(n)
+	return n, err
+}
+
+func (c *imageContent) Close() error {
+	return c.body.Close()
+}
+
+// verify checks that the whole image was read and matches its checksum.
+func (c *imageContent) verify() error {
+	if c.read != c.size {
+		return fmt.Errorf("only %d of the %d bytes of image %s were imported", c.read, c.size, c.image.URL)
+	}
+	if c.sum == nil {
+		return nil
+	}
+	if actual := hex.EncodeToString(c.sum.Sum(nil)); actual != c.expected {
+		return fmt.Errorf("%w: image %s has digest %s, expected %s", errChecksumMismatch, c.image.URL, actual, c.expected)
+	}
+	return nil
+}
+
+// progressReader calls report every 1/progressSteps of the size bytes read.
+type progressReader struct {
+	reader   io.Reader
+	size     int64
+	read     int64
+	reported int
+	report   func(percent int, done int64)
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.read += int64(n)
+	if step := int(r.read * progressSteps / r.size); step > r.reported && step < progressSteps {
+		r.reported = step
+		r.report(step*100/progressSteps, r.read)
+	}
+	return n, err
+}
+
+// formatBytes returns n in binary units, such as 1.5Gi.
+func formatBytes(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	value, unit := float64(n)/1024, 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f%ci", value, units[unit])
+}
@@ -0,0 +1,379 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package populator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+const testDriverName = "csi.xenorchestra.vates.tech"
+
+// fakeProvisioner records the calls of the populator.
+type fakeProvisioner struct {
+	created   []*csi.CreateVolumeRequest
+	deleted   []string
+	imported  []byte
+	format    payloads.VDIFormat
+	importErr error
+}
+
+func (f *fakeProvisioner) CreateVolume(_ context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	f.created = append(f.created, req)
+	return &csi.CreateVolumeResponse{Volume: &csi.Volume{
+		VolumeId:           "paris/vol-1",
+		CapacityBytes:      req.GetCapacityRange().GetRequiredBytes(),
+		VolumeContext:      map[string]string{"poolName": "pool-a"},
+		AccessibleTopology: []*csi.Topology{{Segments: map[string]string{xok8s.XOLabelTopologyPoolID: "pool-a"}}},
+	}}, nil
+}
+
+func (f *fakeProvisioner) DeleteVolume(_ context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	f.deleted = append(f.deleted, req.GetVolumeId())
+	return &csi.DeleteVolumeResponse{}, nil
+}
+
+func (f *fakeProvisioner) ImportVolume(_ context.Context, _ string, _ map[string]string, format payloads.VDIFormat, content io.Reader, size int64) error {
+	if f.importErr != nil {
+		return f.importErr
+	}
+	f.format = format
+	data, err := io.ReadAll(io.LimitReader(content, size))
+	f.imported = data
+	return err
+}
+
+func newImage(name, url, format, checksum string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": APIGroup + "/v1alpha1",
+		"kind":       Kind,
+		"metadata":   map[string]any{"name": name, "namespace": "apps"},
+		"spec":       map[string]any{"url": url, "format": format, "checksum": checksum},
+	}}
+}
+
+func newClaim(image string, requested string) *corev1.PersistentVolumeClaim {
+	group := APIGroup
+	sc := "xo"
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "apps", UID: "1234"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: &sc,
+			Resources:        corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(requested)}},
+			DataSourceRef:    &corev1.TypedObjectReference{APIGroup: &group, Kind: Kind, Name: image},
+		},
+	}
+}
+
+func newStorageClass(mode storagev1.VolumeBindingMode) *storagev1.StorageClass {
+	reclaim := corev1.PersistentVolumeReclaimRetain
+	return &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "xo"},
+		Provisioner:       testDriverName,
+		Parameters:        map[string]string{"storageType": "local", parameterFSType: "xfs"},
+		ReclaimPolicy:     &reclaim,
+		VolumeBindingMode: &mode,
+	}
+}
+
+type testEnv struct {
+	populator   *Populator
+	provisioner *fakeProvisioner
+	kubeClient  *fake.Clientset
+	recorder    *record.FakeRecorder
+}
+
+func newTestEnv(t *testing.T, content []byte, objects ...runtime.Object) (*testEnv, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.img" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	var images []runtime.Object
+	var kubeObjects []runtime.Object
+	for _, object := range objects {
+		if _, ok := object.(*unstructured.Unstructured); ok {
+			images = append(images, object)
+		} else {
+			kubeObjects = append(kubeObjects, object)
+		}
+	}
+	env := &testEnv{
+		provisioner: &fakeProvisioner{},
+		kubeClient:  fake.NewClientset(kubeObjects...),
+		recorder:    record.NewFakeRecorder(100),
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), images...)
+	env.populator = NewPopulator(env.kubeClient, dynamicClient, env.provisioner, env.recorder, Options{DriverName: testDriverName, HTTPClient: server.Client()})
+	return env, server.URL
+}
+
+func (e *testEnv) events() []string {
+	var events []string
+	for {
+		select {
+		case event := <-e.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func reasons(events []string) []string {
+	var r []string
+	for _, event := range events {
+		r = append(r, strings.Fields(event)[1])
+	}
+	return r
+}
+
+func TestPopulate(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1<<10)
+	digest := sha256.Sum256(content)
+	checksum := "sha256:" + hex.EncodeToString(digest[:])
+
+	t.Run("Populates", func(t *testing.T) {
+		claim := newClaim("debian", "1Mi")
+		env, url := newTestEnv(t, content, claim, newStorageClass(storagev1.VolumeBindingImmediate))
+		_, err := env.populator.dynamicClient.Resource(VolumeImageResource).Namespace("apps").
+			Create(context.Background(), newImage("debian", url+"/debian.img", "raw", checksum), metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, env.populator.Populate(context.Background(), claim))
+		assert.Equal(t, content, env.provisioner.imported)
+		assert.Equal(t, payloads.VDIFormatRaw, env.provisioner.format)
+		require.Len(t, env.provisioner.created, 1)
+		req := env.provisioner.created[0]
+		assert.Equal(t, "pvc-1234", req.Name)
+		assert.Equal(t, int64(1<<20), req.CapacityRange.RequiredBytes)
+		assert.Equal(t, map[string]string{"storageType": "local"}, req.Parameters, "the external-provisioner parameters are left out")
+		assert.Equal(t, "xfs", req.VolumeCapabilities[0].GetMount().FsType)
+		assert.Empty(t, env.provisioner.deleted)
+
+		pv, err := env.kubeClient.CoreV1().PersistentVolumes().Get(context.Background(), "pvc-1234", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "paris/vol-1", pv.Spec.CSI.VolumeHandle)
+		assert.Equal(t, "xfs", pv.Spec.CSI.FSType)
+		assert.Equal(t, claim.UID, pv.Spec.ClaimRef.UID)
+		assert.Equal(t, corev1.PersistentVolumeReclaimRetain, pv.Spec.PersistentVolumeReclaimPolicy)
+		assert.Equal(t, testDriverName, pv.Annotations[provisionedByAnnotation])
+		assert.Equal(t, xok8s.XOLabelTopologyPoolID, pv.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions[0].Key)
+
+		events := reasons(env.events())
+		assert.Equal(t, EventReasonImportStarted, events[0])
+		assert.Contains(t, events, EventReasonImportProgress)
+		assert.Equal(t, EventReasonVolumePopulated, events[len(events)-1])
+
+		// The PersistentVolume marks the claim as populated.
+		require.NoError(t, env.populator.Populate(context.Background(), claim))
+		assert.Len(t, env.provisioner.created, 1)
+	})
+
+	t.Run("ChecksumMismatch", func(t *testing.T) {
+		claim := newClaim("debian", "1Mi")
+		env, url := newTestEnv(t, content, claim, newStorageClass(storagev1.VolumeBindingImmediate))
+		_, err := env.populator.dynamicClient.Resource(VolumeImageResource).Namespace("apps").
+			Create(context.Background(), newImage("debian", url+"/debian.img", "raw", "sha256:"+strings.Repeat("0", 64)), metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		err = env.populator.Populate(context.Background(), claim)
+		require.ErrorIs(t, err, errChecksumMismatch)
+		assert.Equal(t, []string{"paris/vol-1"}, env.provisioner.deleted)
+		_, err = env.kubeClient.CoreV1().PersistentVolumes().Get(context.Background(), "pvc-1234", metav1.GetOptions{})
+		assert.Error(t, err)
+		events := reasons(env.events())
+		assert.Contains(t, events, EventReasonChecksumMismatch)
+		assert.Equal(t, EventReasonVolumeCleanedUp, events[len(events)-1])
+	})
+
+	t.Run("ImportFailure", func(t *testing.T) {
+		claim := newClaim("debian", "1Mi")
+		env, url := newTestEnv(t, content, claim, newStorageClass(storagev1.VolumeBindingImmediate))
+		_, err := env.populator.dynamicClient.Resource(VolumeImageResource).Namespace("apps").
+			Create(context.Background(), newImage("debian", url+"/debian.img", "raw", ""), metav1.CreateOptions{})
+		require.NoError(t, err)
+		env.provisioner.importErr = errors.New("SR full")
+
+		err = env.populator.Populate(context.Background(), claim)
+		require.ErrorContains(t, err, "SR full")
+		assert.Equal(t, []string{"paris/vol-1"}, env.provisioner.deleted)
+		assert.Contains(t, reasons(env.events()), EventReasonImportFailed)
+	})
+
+	t.Run("DownloadFailure", func(t *testing.T) {
+		claim := newClaim("debian", "1Mi")
+		env, url := newTestEnv(t, content, claim, newStorageClass(storagev1.VolumeBindingImmediate))
+		_, err := env.populator.dynamicClient.Resource(VolumeImageResource).Namespace("apps").
+			Create(context.Background(), newImage("debian", url+"/missing.img", "raw", ""), metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		require.ErrorContains(t, env.populator.Populate(context.Background(), claim), "404")
+		assert.Empty(t, env.provisioner.created, "no volume is created before the download starts")
+		assert.Equal(t, []string{EventReasonImportFailed}, reasons(env.events()))
+	})
+
+	t.Run("WaitsForTheSelectedNode", func(t *testing.T) {
+		claim := newClaim("debian", "1Mi")
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: map[string]string{xok8s.XOLabelTopologyPoolID: "pool-b"}}}
+		env, url := newTestEnv(t, content, claim, node, newStorageClass(storagev1.VolumeBindingWaitForFirstConsumer))
+		_, err := env.populator.dynamicClient.Resource(VolumeImageResource).Namespace("apps").
+			Create(context.Background(), newImage("debian", url+"/debian.img", "raw", ""), metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, env.populator.Populate(context.Background(), claim))
+		assert.Empty(t, env.provisioner.created)
+
+		claim.Annotations = map[string]string{selectedNodeAnnotation: "worker-1"}
+		require.NoError(t, env.populator.Populate(context.Background(), claim))
+		require.Len(t, env.provisioner.created, 1)
+		assert.Equal(t, "pool-b", env.provisioner.created[0].AccessibilityRequirements.Requisite[0].Segments[xok8s.XOLabelTopologyPoolID])
+	})
+
+	t.Run("GrowsToTheImageSize", func(t *testing.T) {
+		claim := newClaim("debian", "4Ki")
+		env, url := newTestEnv(t, content, claim, newStorageClass(storagev1.VolumeBindingImmediate))
+		_, err := env.populator.dynamicClient.Resource(VolumeImageResource).Namespace("apps").
+			Create(context.Background(), newImage("debian", url+"/debian.img", "raw", ""), metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, env.populator.Populate(context.Background(), claim))
+		assert.Equal(t, int64(len(content)), env.provisioner.created[0].CapacityRange.RequiredBytes)
+	})
+
+	t.Run("RejectsStripedVolumes", func(t *testing.T) {
+		claim := newClaim("debian", "1Mi")
+		sc := newStorageClass(storagev1.VolumeBindingImmediate)
+		sc.Parameters[parameterStripes] = "4"
+		env, _ := newTestEnv(t, content, claim, sc)
+
+		require.ErrorContains(t, env.populator.Populate(context.Background(), claim), "striped volumes")
+		assert.Empty(t, env.provisioner.created)
+		assert.Equal(t, []string{EventReasonImportFailed}, reasons(env.events()))
+	})
+
+	t.Run("OtherProvisioner", func(t *testing.T) {
+		claim := newClaim("debian", "1Mi")
+		sc := newStorageClass(storagev1.VolumeBindingImmediate)
+		sc.Provisioner = "other.csi.example.com"
+		env, _ := newTestEnv(t, content, claim, sc)
+
+		require.NoError(t, env.populator.Populate(context.Background(), claim))
+		assert.Empty(t, env.provisioner.created)
+		assert.Empty(t, env.events())
+	})
+}
+
+func TestRunOnce(t *testing.T) {
+	claim := newClaim("debian", "1Mi")
+	other := newClaim("", "1Mi")
+	other.Name, other.UID, other.Spec.DataSourceRef = "plain", "5678", nil
+	env, url := newTestEnv(t, []byte("disk"), claim, other, newStorageClass(storagev1.VolumeBindingImmediate))
+	_, err := env.populator.dynamicClient.Resource(VolumeImageResource).Namespace("apps").
+		Create(context.Background(), newImage("debian", url+"/missing.img", "raw", ""), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, env.populator.RunOnce(context.Background(), time.Hour))
+	env.populator.jobs.Wait()
+	assert.Equal(t, 1, env.populator.retries[claim.UID].failures)
+	assert.NotContains(t, env.populator.retries, other.UID)
+
+	// The failed claim waits for its retry delay.
+	require.NoError(t, env.populator.RunOnce(context.Background(), time.Hour))
+	env.populator.jobs.Wait()
+	assert.Equal(t, 1, env.populator.retries[claim.UID].failures)
+
+	// Deleted claims are forgotten.
+	require.NoError(t, env.kubeClient.CoreV1().PersistentVolumeClaims("apps").Delete(context.Background(), claim.Name, metav1.DeleteOptions{}))
+	require.NoError(t, env.populator.RunOnce(context.Background(), time.Hour))
+	assert.Empty(t, env.populator.retries)
+}
+
+func TestImage(t *testing.T) {
+	t.Run("Validate", func(t *testing.T) {
+		valid := Image{URL: "https://images.example.com/debian.vhd", Format: FormatVHD, Checksum: "sha512:" + strings.Repeat("ab", 64)}
+		require.NoError(t, valid.validate())
+
+		for name, image := range map[string]Image{
+			"Scheme":    {URL: "ftp://images.example.com/debian.img", Format: FormatRaw},
+			"Format":    {URL: "https://images.example.com/debian.img", Format: "vmdk"},
+			"Qcow2":     {URL: "https://images.example.com/debian.qcow2", Format: FormatQcow2},
+			"Algorithm": {URL: "https://images.example.com/debian.img", Format: FormatRaw, Checksum: "md5:abcd"},
+			"Digest":    {URL: "https://images.example.com/debian.img", Format: FormatRaw, Checksum: "sha256:xyz"},
+		} {
+			assert.Error(t, image.validate(), name)
+		}
+		assert.ErrorContains(t, (&Image{URL: "https://x/y", Format: FormatQcow2}).validate(), "qemu-img convert")
+	})
+
+	t.Run("VirtualSize", func(t *testing.T) {
+		vhd := Image{Format: FormatVHD}
+		header := make([]byte, vhdFooterSize)
+		copy(header, vhdCookie)
+		binary.BigEndian.PutUint64(header[48:], 10<<30)
+		binary.BigEndian.PutUint32(header[60:], 3)
+		size, err := vhd.virtualSize(header, 1<<20)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10<<30), size, "dynamic VHD images carry their size")
+
+		size, err = vhd.virtualSize(make([]byte, vhdFooterSize), 1<<20+vhdFooterSize)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1<<20), size, "fixed VHD images end with their footer")
+
+		binary.BigEndian.PutUint32(header[60:], vhdDiskTypeDifferencing)
+		_, err = vhd.virtualSize(header, 1<<20)
+		assert.ErrorContains(t, err, "parent")
+
+		_, err = (&Image{Format: FormatRaw}).virtualSize(append([]byte{'Q', 'F', 'I', 0xfb}, header...), 1<<20)
+		assert.ErrorContains(t, err, "qcow2")
+	})
+}
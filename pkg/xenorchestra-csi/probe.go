@@ -0,0 +1,105 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+
+	"k8s.io/klog/v2"
+)
+
+// readinessCheck is one of the checks run by Probe.
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// readinessProbe runs the readiness checks and caches their outcome for ttl,
+// so that frequent probes do not load Xen Orchestra. Concurrent probes share
+// the same run.
+type readinessProbe struct {
+	checks []readinessCheck
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// newReadinessProbe returns a probe checking that Xen Orchestra answers an
+// authenticated call and, if nodeMetadata is set, that the node metadata can
+// be resolved.
+func newReadinessProbe(xoClient clients.XoClient, nodeMetadata clients.NodeMetadataGetter, ttl time.Duration) *readinessProbe {
+	p := &readinessProbe{ttl: ttl, now: time.Now}
+	if xoClient != nil {
+		p.checks = append(p.checks, readinessCheck{name: "Xen Orchestra API", check: xoClient.Ping})
+	}
+	if nodeMetadata != nil {
+		p.checks = append(p.checks, readinessCheck{name: "node metadata", check: func(context.Context) error {
+			_, err := nodeMetadata.GetNodeMetadata()
+			return err
+		}})
+	}
+	return p
+}
+
+// add appends a check run after the existing ones.
+func (p *readinessProbe) add(name string, check func(ctx context.Context) error) {
+	p.checks = append(p.checks, readinessCheck{name: name, check: check})
+}
+
+// Check returns nil when every check passed within the last ttl, or the
+// error of the first failing check.
+func (p *readinessProbe) Check(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.checkedAt.IsZero() && p.now().Sub(p.checkedAt) < p.ttl {
+		return p.err
+	}
+
+	err := p.run(ctx)
+	// A probe cancelled by its caller says nothing about the driver.
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	if err != nil && p.err == nil {
+		klog.ErrorS(err, "Driver is not ready")
+	} else if err == nil && p.err != nil {
+		klog.InfoS("Driver is ready again")
+	}
+	p.checkedAt, p.err = p.now(), err
+	return err
+}
+
+func (p *readinessProbe) run(ctx context.Context) error {
+	for _, c := range p.checks {
+		if err := c.check(ctx); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("%s check timed out: %w", c.name, err)
+			}
+			return fmt.Errorf("%s check failed: %w", c.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+)
+
+type nodeMetadataFunc func() (*clients.NodeMetadata, error)
+
+func (f nodeMetadataFunc) GetNodeMetadata() (*clients.NodeMetadata, error) { return f() }
+
+func TestProbe(t *testing.T) {
+	newDriver := func(t *testing.T, nodeMetadata clients.NodeMetadataGetter) (*xenorchestraCSIDriver, *clientsMock.MockXoClient, *time.Time) {
+		mockXo := clientsMock.NewMockXoClient(gomock.NewController(t))
+		now := time.Now()
+		readiness := newReadinessProbe(mockXo, nodeMetadata, 30*time.Second)
+		readiness.now = func() time.Time { return now }
+		return &xenorchestraCSIDriver{xoClient: mockXo, readiness: readiness}, mockXo, &now
+	}
+
+	t.Run("ReadyWhenXOAnswers", func(t *testing.T) {
+		driver, mockXo, _ := newDriver(t, nil)
+		mockXo.EXPECT().Ping(gomock.Any()).Return(nil)
+
+		resp, err := driver.Probe(context.Background(), &csi.ProbeRequest{})
+		require.NoError(t, err)
+		assert.True(t, resp.GetReady().GetValue())
+	})
+
+	t.Run("UnavailableWhenTokenIsRejected", func(t *testing.T) {
+		driver, mockXo, _ := newDriver(t, nil)
+		mockXo.EXPECT().Ping(gomock.Any()).Return(errors.New("401 Unauthorized"))
+
+		_, err := driver.Probe(context.Background(), &csi.ProbeRequest{})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Contains(t, err.Error(), "Xen Orchestra API check failed")
+		assert.Contains(t, err.Error(), "401 Unauthorized")
+	})
+
+	t.Run("OutcomeIsCachedUntilTTL", func(t *testing.T) {
+		driver, mockXo, now := newDriver(t, nil)
+		gomock.InOrder(
+			mockXo.EXPECT().Ping(gomock.Any()).Return(errors.New("connection refused")),
+			mockXo.EXPECT().Ping(gomock.Any()).Return(nil),
+		)
+
+		for range 3 {
+			_, err := driver.Probe(context.Background(), &csi.ProbeRequest{})
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+		}
+		*now = now.Add(30 * time.Second)
+		_, err := driver.Probe(context.Background(), &csi.ProbeRequest{})
+		require.NoError(t, err)
+	})
+
+	t.Run("CancelledProbeIsNotCached", func(t *testing.T) {
+		driver, mockXo, _ := newDriver(t, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		gomock.InOrder(
+			mockXo.EXPECT().Ping(gomock.Any()).DoAndReturn(func(context.Context) error {
+				cancel()
+				return context.Canceled
+			}),
+			mockXo.EXPECT().Ping(gomock.Any()).Return(nil),
+		)
+
+		_, err := driver.Probe(ctx, &csi.ProbeRequest{})
+		require.Error(t, err)
+		_, err = driver.Probe(context.Background(), &csi.ProbeRequest{})
+		require.NoError(t, err)
+	})
+
+	t.Run("UnavailableWhenNodeMetadataFails", func(t *testing.T) {
+		driver, mockXo, _ := newDriver(t, nodeMetadataFunc(func() (*clients.NodeMetadata, error) {
+			return nil, errors.New(`node "worker-1" has no pool ID label`)
+		}))
+		mockXo.EXPECT().Ping(gomock.Any()).Return(nil)
+
+		_, err := driver.Probe(context.Background(), &csi.ProbeRequest{})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Contains(t, err.Error(), "node metadata check failed")
+	})
+}
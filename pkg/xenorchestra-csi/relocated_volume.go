@@ -0,0 +1,159 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// pvDeletionTimeout bounds the wait for a PersistentVolume to be gone
+	// before it is created again.
+	pvDeletionTimeout = time.Minute
+	pvDeletionPoll    = time.Second
+)
+
+// DetachedPersistentVolume returns the PersistentVolume pvName of the driver.
+// It fails when a VolumeAttachment references it, that is while a pod may use
+// the volume.
+func DetachedPersistentVolume(ctx context.Context, kubeClient kube.Interface, driverName, pvName string) (*corev1.PersistentVolume, error) {
+	pv, err := kubeClient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PersistentVolume %s: %w", pvName, err)
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName {
+		return nil, fmt.Errorf("PersistentVolume %s is not a volume of driver %s", pvName, driverName)
+	}
+	attachments, err := kubeClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeAttachments: %w", err)
+	}
+	for _, attachment := range attachments.Items {
+		if name := attachment.Spec.Source.PersistentVolumeName; name != nil && *name == pvName {
+			return nil, fmt.Errorf("PersistentVolume %s is attached to node %s, stop the pods using it first", pvName, attachment.Spec.NodeName)
+		}
+	}
+	return pv, nil
+}
+
+// RelocatePersistentVolume makes pv reference the volume in sr of pool: its
+// volume attributes and node affinity become the ones CreateVolume would have
+// set. As both are immutable, the PersistentVolume is deleted and created
+// again with the same name and claim reference, and Kubernetes binds it to
+// its claim again. When it could not be created again, the error comes with
+// the PersistentVolume to create.
+func RelocatePersistentVolume(ctx context.Context, kubeClient kube.Interface, pv *corev1.PersistentVolume, pool *payloads.Pool, sr *payloads.StorageRepository) (*corev1.PersistentVolume, error) {
+	relocated := RelocatedPersistentVolume(pv, pool, sr)
+	if equality.Semantic.DeepEqual(relocated.Spec.CSI.VolumeAttributes, pv.Spec.CSI.VolumeAttributes) &&
+		equality.Semantic.DeepEqual(relocated.Spec.NodeAffinity, pv.Spec.NodeAffinity) {
+		return pv, nil
+	}
+	pvs := kubeClient.CoreV1().PersistentVolumes()
+
+	// Retain, so that the provisioner does not delete the volume along with
+	// the PersistentVolume.
+	if pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+		patch := fmt.Appendf(nil, `{"spec":{"persistentVolumeReclaimPolicy":%q}}`, corev1.PersistentVolumeReclaimRetain)
+		if _, err := pvs.Patch(ctx, pv.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to retain PersistentVolume %s: %w", pv.Name, err)
+		}
+	}
+	err := pvs.Delete(ctx, pv.Name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &pv.UID}})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete PersistentVolume %s: %w", pv.Name, err)
+	}
+	// The protection finalizers keep a bound PersistentVolume until its claim
+	// is deleted.
+	_, err = pvs.Patch(ctx, pv.Name, types.MergePatchType, []byte(`{"metadata":{"finalizers":null}}`), metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return relocated, fmt.Errorf("failed to remove the finalizers of PersistentVolume %s: %w", pv.Name, err)
+	}
+	if err := waitForPersistentVolumeDeletion(ctx, kubeClient, pv); err != nil {
+		return relocated, err
+	}
+
+	created, err := pvs.Create(ctx, relocated, metav1.CreateOptions{})
+	if err != nil {
+		return relocated, fmt.Errorf("PersistentVolume %s was deleted but could not be created again: %w", pv.Name, err)
+	}
+	klog.InfoS("Relocated PersistentVolume", "pv", pv.Name, "poolID", pool.ID, "srID", sr.ID)
+	return created, nil
+}
+
+// RelocatedPersistentVolume returns a PersistentVolume to create instead of
+// pv, referencing the volume in sr of pool.
+func RelocatedPersistentVolume(pv *corev1.PersistentVolume, pool *payloads.Pool, sr *payloads.StorageRepository) *corev1.PersistentVolume {
+	storageType := StorageTypeShared
+	if !sr.Shared {
+		storageType = StorageTypeLocal
+	}
+	relocated := &corev1.PersistentVolume{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pv.Name,
+			Labels:      pv.Labels,
+			Annotations: pv.Annotations,
+			Finalizers:  pv.Finalizers,
+		},
+		Spec: *pv.Spec.DeepCopy(),
+	}
+	attributes := maps.Clone(pv.Spec.CSI.VolumeAttributes)
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+	maps.Copy(attributes, buildVolumeContext(pool, sr, storageType))
+	relocated.Spec.CSI.VolumeAttributes = attributes
+	relocated.Spec.NodeAffinity = poolNodeAffinity(pool)
+	if relocated.Spec.ClaimRef != nil {
+		relocated.Spec.ClaimRef.ResourceVersion = ""
+	}
+	return relocated
+}
+
+func waitForPersistentVolumeDeletion(ctx context.Context, kubeClient kube.Interface, pv *corev1.PersistentVolume) error {
+	ctx, cancel := context.WithTimeout(ctx, pvDeletionTimeout)
+	defer cancel()
+	ticker := time.NewTicker(pvDeletionPoll)
+	defer ticker.Stop()
+	for {
+		current, err := kubeClient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			return nil
+		case err == nil && current.UID != pv.UID:
+			return fmt.Errorf("PersistentVolume %s was created again by someone else", pv.Name)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("PersistentVolume %s is still being deleted: %w", pv.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newRelocatedTestPV() *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pvc-1",
+			UID:         "pv-uid",
+			Annotations: map[string]string{provisionedByAnnotation: DriverName},
+			Finalizers:  []string{"kubernetes.io/pv-protection"},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+			ClaimRef:                      &corev1.ObjectReference{Namespace: "apps", Name: "data", UID: "claim-uid", ResourceVersion: "12"},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{
+				Driver:       DriverName,
+				VolumeHandle: "vol-1",
+				VolumeAttributes: map[string]string{
+					VolumeContextKeyPoolID:                 "old-pool",
+					VolumeContextKeyStorageType:            StorageTypeShared,
+					"csi.storage.k8s.io/pv/name":           "pvc-1",
+					"storage.kubernetes.io/csiProvisioner": DriverName,
+				},
+			}},
+			NodeAffinity: poolNodeAffinity(&payloads.Pool{ID: uuid.Must(uuid.NewV4())}),
+		},
+	}
+}
+
+func TestDetachedPersistentVolume(t *testing.T) {
+	pvName := "pvc-1"
+	kubeClient := fake.NewClientset(newRelocatedTestPV())
+
+	pv, err := DetachedPersistentVolume(context.Background(), kubeClient, DriverName, pvName)
+	require.NoError(t, err)
+	assert.Equal(t, "vol-1", pv.Spec.CSI.VolumeHandle)
+
+	_, err = DetachedPersistentVolume(context.Background(), kubeClient, "other.csi.driver", pvName)
+	require.ErrorContains(t, err, "not a volume of driver")
+
+	_, err = kubeClient.StorageV1().VolumeAttachments().Create(context.Background(), &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: "csi-1"},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: DriverName,
+			NodeName: "node-1",
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = DetachedPersistentVolume(context.Background(), kubeClient, DriverName, pvName)
+	require.ErrorContains(t, err, "attached to node node-1")
+}
+
+func TestRelocatePersistentVolume(t *testing.T) {
+	pool := &payloads.Pool{ID: uuid.Must(uuid.NewV4()), NameLabel: "lyon"}
+	sr := &payloads.StorageRepository{ID: uuid.Must(uuid.NewV4()), NameLabel: "nfs-lyon", Shared: true}
+
+	t.Run("RecreatesThePersistentVolume", func(t *testing.T) {
+		pv := newRelocatedTestPV()
+		kubeClient := fake.NewClientset(pv)
+
+		_, err := RelocatePersistentVolume(context.Background(), kubeClient, pv, pool, sr)
+		require.NoError(t, err)
+
+		relocated, err := kubeClient.CoreV1().PersistentVolumes().Get(context.Background(), "pvc-1", metav1.GetOptions{})
+		require.NoError(t, err)
+		attributes := relocated.Spec.CSI.VolumeAttributes
+		assert.Equal(t, pool.ID.String(), attributes[VolumeContextKeyPoolID])
+		assert.Equal(t, "lyon", attributes[VolumeContextKeyPoolName])
+		assert.Equal(t, sr.ID.String(), attributes[VolumeContextKeySRID])
+		assert.Equal(t, "pvc-1", attributes["csi.storage.k8s.io/pv/name"], "the other attributes are kept")
+		assert.Equal(t, []string{pool.ID.String()}, relocated.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions[0].Values)
+		assert.Equal(t, "vol-1", relocated.Spec.CSI.VolumeHandle)
+		assert.Equal(t, corev1.PersistentVolumeReclaimDelete, relocated.Spec.PersistentVolumeReclaimPolicy)
+		require.NotNil(t, relocated.Spec.ClaimRef)
+		assert.Equal(t, "claim-uid", string(relocated.Spec.ClaimRef.UID), "the claim binds the new PersistentVolume")
+		assert.Equal(t, DriverName, relocated.Annotations[provisionedByAnnotation])
+	})
+
+	t.Run("KeepsARelocatedPersistentVolume", func(t *testing.T) {
+		pv := RelocatedPersistentVolume(newRelocatedTestPV(), pool, sr)
+		kubeClient := fake.NewClientset(pv)
+
+		_, err := RelocatePersistentVolume(context.Background(), kubeClient, pv, pool, sr)
+		require.NoError(t, err)
+		for _, action := range kubeClient.Actions() {
+			assert.Equal(t, "get", action.GetVerb(), "nothing is changed")
+		}
+	})
+}
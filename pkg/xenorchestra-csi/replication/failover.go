@@ -0,0 +1,138 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replication
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	"k8s.io/klog/v2"
+)
+
+// ErrVolumeAvailable is returned by Promote when the volume of the replica is
+// still found: promoting the replica would give the volume two VDIs.
+var ErrVolumeAvailable = errors.New("the volume is still available")
+
+// Replica is a complete copy of a volume.
+type Replica struct {
+	VDI      *payloads.VDI
+	VolumeID string
+	PVName   string
+	// ReplicatedAt is when the copy started: the replica holds the content
+	// of the volume at about that time.
+	ReplicatedAt time.Time
+}
+
+func newReplica(vdi *payloads.VDI) *Replica {
+	// A replica without a valid time is older than any other.
+	replicatedAt, _ := time.Parse(time.RFC3339, clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicatedAt))
+	return &Replica{
+		VDI:          vdi,
+		VolumeID:     clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaOf),
+		PVName:       clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaPVName),
+		ReplicatedAt: replicatedAt,
+	}
+}
+
+// LatestReplicas returns the latest complete replica of every volume of the
+// cluster tagged with clusterTag, sorted by PersistentVolume name.
+func LatestReplicas(ctx context.Context, xoClient clients.XoClient, clusterTag string) ([]*Replica, error) {
+	vdis, err := xoClient.VDI().GetAll(ctx, 0, clients.BuildClusterTagFilter(clusterTag))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VDIs with cluster tag %q: %w", clusterTag, err)
+	}
+	latest := map[string]*Replica{}
+	for _, vdi := range vdis {
+		if clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaOf) == "" {
+			continue
+		}
+		replica := newReplica(vdi)
+		if current, found := latest[replica.VolumeID]; !found || replica.ReplicatedAt.After(current.ReplicatedAt) {
+			latest[replica.VolumeID] = replica
+		}
+	}
+	replicas := make([]*Replica, 0, len(latest))
+	for _, replica := range latest {
+		replicas = append(replicas, replica)
+	}
+	slices.SortFunc(replicas, func(a, b *Replica) int {
+		return cmp.Or(cmp.Compare(a.PVName, b.PVName), cmp.Compare(a.VolumeID, b.VolumeID))
+	})
+	return replicas, nil
+}
+
+// PromoteOptions configures Promote.
+type PromoteOptions struct {
+	// VDINamePrefix prefixes the name label of the promoted VDI, as the
+	// driver --vdi-name-prefix does.
+	VDINamePrefix string
+	// ManagedBy is the value of the managedBy tag of the promoted VDI.
+	ManagedBy string
+}
+
+// Promote turns replica into the VDI of its volume: it gets the volume ID
+// and PersistentVolume name tags and the name label of a volume, and loses
+// the tags of a replica. The volume handle is unchanged. It fails with
+// ErrVolumeAvailable when the driver still finds the volume.
+func Promote(ctx context.Context, xoClient clients.XoClient, replica *Replica, opts PromoteOptions) error {
+	vdi, err := xoClient.GetVDIByVolumeId(ctx, replica.VolumeID)
+	switch {
+	case err == nil && vdi.ID == replica.VDI.ID:
+		// An interrupted promotion, resumed.
+	case err == nil:
+		return fmt.Errorf("%w in VDI %s: only the replicas of lost volumes are promoted", ErrVolumeAvailable, vdi.ID)
+	case !errors.Is(err, clients.ErrVolumeNotFound):
+		return fmt.Errorf("failed to look up volume %s: %w", replica.VolumeID, err)
+	}
+
+	// The volume tags come first: once they are set, the volume is found in
+	// the replica, and promoting it again resumes the promotion.
+	tags := []string{
+		clients.BuildTag(clients.VDITagKeyPVName, replica.PVName),
+		clients.BuildTag(clients.VDITagKeyVolumeId, replica.VolumeID),
+	}
+	if opts.ManagedBy != "" && clients.ParseTagValue(replica.VDI.Tags, clients.VDITagKeyManagedBy) == "" {
+		tags = append(tags, clients.BuildTag(clients.VDITagKeyManagedBy, opts.ManagedBy))
+	}
+	for _, tag := range tags {
+		if err := xoClient.VDI().AddTag(ctx, replica.VDI.ID, tag); err != nil {
+			return fmt.Errorf("failed to tag VDI %s with %s: %w", replica.VDI.ID, tag, err)
+		}
+	}
+	for _, key := range []string{clients.VDITagKeyReplicaOf, clients.VDITagKeyReplicaPVName, clients.VDITagKeyReplicatedAt} {
+		value := clients.ParseTagValue(replica.VDI.Tags, key)
+		if value == "" {
+			continue
+		}
+		if err := xoClient.VDI().RemoveTag(ctx, replica.VDI.ID, clients.BuildTag(key, value)); err != nil {
+			klog.ErrorS(err, "Failed to remove a replica tag from a promoted VDI", "vdiID", replica.VDI.ID, "key", key)
+		}
+	}
+	nameLabel := clients.BuildVDINameLabel(opts.VDINamePrefix, replica.VolumeID, replica.PVName)
+	if err := xoClient.SetVDINameLabel(ctx, *replica.VDI, nameLabel); err != nil {
+		klog.ErrorS(err, "Failed to rename a promoted VDI", "vdiID", replica.VDI.ID)
+	}
+	klog.InfoS("Promoted replica", "vdiID", replica.VDI.ID, "volumeID", replica.VolumeID, "pvName", replica.PVName, "replicatedAt", replica.ReplicatedAt)
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replication
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+)
+
+func TestLatestReplicas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	xo := clientsMock.NewMockXoClient(ctrl)
+	vdi := xoLibMock.NewMockVDI(ctrl)
+	xo.EXPECT().VDI().Return(vdi).AnyTimes()
+
+	latest := newReplicaVDI(replicaID, "vol-1", now)
+	vdi.EXPECT().GetAll(gomock.Any(), 0, clients.BuildClusterTagFilter(testClusterTag)).Return([]*payloads.VDI{
+		newSourceVDI(),
+		newReplicaVDI(oldReplica, "vol-1", now.Add(-time.Hour)),
+		latest,
+	}, nil)
+
+	replicas, err := LatestReplicas(context.Background(), xo, testClusterTag)
+	require.NoError(t, err)
+	require.Len(t, replicas, 1)
+	assert.Equal(t, &Replica{VDI: latest, VolumeID: "vol-1", PVName: "pvc-1", ReplicatedAt: now}, replicas[0])
+}
+
+func TestPromote(t *testing.T) {
+	opts := PromoteOptions{VDINamePrefix: "csi-", ManagedBy: "cluster-a"}
+
+	setup := func(t *testing.T) (*clientsMock.MockXoClient, *xoLibMock.MockVDI, *Replica) {
+		ctrl := gomock.NewController(t)
+		xo := clientsMock.NewMockXoClient(ctrl)
+		vdi := xoLibMock.NewMockVDI(ctrl)
+		xo.EXPECT().VDI().Return(vdi).AnyTimes()
+		return xo, vdi, newReplica(newReplicaVDI(replicaID, "vol-1", now))
+	}
+	expectPromotion := func(xo *clientsMock.MockXoClient, vdi *xoLibMock.MockVDI, replica *Replica) {
+		gomock.InOrder(
+			vdi.EXPECT().AddTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyPVName, "pvc-1")).Return(nil),
+			vdi.EXPECT().AddTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyVolumeId, "vol-1")).Return(nil),
+			vdi.EXPECT().AddTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyManagedBy, "cluster-a")).Return(nil),
+		)
+		vdi.EXPECT().RemoveTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyReplicaOf, "vol-1")).Return(nil)
+		vdi.EXPECT().RemoveTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyReplicaPVName, "pvc-1")).Return(nil)
+		vdi.EXPECT().RemoveTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyReplicatedAt, now.Format(time.RFC3339))).Return(nil)
+		xo.EXPECT().SetVDINameLabel(gomock.Any(), *replica.VDI, "csi-vol-1-pvc-1").Return(nil)
+	}
+
+	t.Run("PromotesReplicaOfLostVolume", func(t *testing.T) {
+		xo, vdi, replica := setup(t)
+		xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(nil, fmt.Errorf("lookup: %w", clients.ErrVolumeNotFound))
+		expectPromotion(xo, vdi, replica)
+
+		require.NoError(t, Promote(context.Background(), xo, replica, opts))
+	})
+
+	t.Run("ResumesPromotion", func(t *testing.T) {
+		xo, vdi, replica := setup(t)
+		xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(replica.VDI, nil)
+		expectPromotion(xo, vdi, replica)
+
+		require.NoError(t, Promote(context.Background(), xo, replica, opts))
+	})
+
+	t.Run("RefusesAvailableVolume", func(t *testing.T) {
+		xo, _, replica := setup(t)
+		xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(&payloads.VDI{ID: uuid.Must(uuid.NewV4())}, nil)
+
+		require.ErrorIs(t, Promote(context.Background(), xo, replica, opts), ErrVolumeAvailable)
+	})
+}
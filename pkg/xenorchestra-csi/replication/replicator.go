@@ -0,0 +1,352 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replication keeps disaster recovery replicas of the volumes whose
+// StorageClass has a replicaPoolId parameter: copies of their VDI in an SR of
+// another pool, which failover promotes to volumes when the pool is lost.
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// EventReasonReplicationSkipped is the reason of the events of the volumes
+// whose copy is skipped because they are attached read-write.
+const EventReasonReplicationSkipped = "ReplicationSkipped"
+
+// maxCheckPeriod bounds the time between two passes of the replicator, so
+// that the lag metric stays current with long intervals.
+const maxCheckPeriod = time.Minute
+
+// Options configures a Replicator.
+type Options struct {
+	// DriverName is the CSI driver name PersistentVolumes must reference.
+	DriverName string
+	// ClusterTag selects the VDIs owned by this cluster. It must not be empty.
+	ClusterTag string
+	// VDINamePrefix prefixes the name label of the replicas.
+	VDINamePrefix string
+	// Interval is the time between two copies of a volume.
+	Interval time.Duration
+}
+
+// Replicator copies the replicated volumes to their replica pool.
+type Replicator struct {
+	// xoClients are the clients of the Xen Orchestra instances storing the
+	// volumes of the cluster.
+	xoClients  []clients.XoClient
+	kubeClient kube.Interface
+	recorder   record.EventRecorder
+	opts       Options
+	now        func() time.Time
+}
+
+// NewReplicator returns a Replicator of the volumes of xoClients. recorder may
+// be nil, in which case no Kubernetes events are emitted.
+func NewReplicator(xoClients []clients.XoClient, kubeClient kube.Interface, recorder record.EventRecorder, opts Options) *Replicator {
+	if recorder == nil {
+		recorder = &record.FakeRecorder{}
+	}
+	return &Replicator{
+		xoClients:  xoClients,
+		kubeClient: kubeClient,
+		recorder:   recorder,
+		opts:       opts,
+		now:        time.Now,
+	}
+}
+
+// Run executes a replicator pass every Interval, and at least every minute,
+// until ctx is cancelled.
+func (r *Replicator) Run(ctx context.Context) {
+	klog.InfoS("Starting volume replicator", "interval", r.opts.Interval)
+	ticker := time.NewTicker(min(r.opts.Interval, maxCheckPeriod))
+	defer ticker.Stop()
+	for {
+		if err := r.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			klog.ErrorS(err, "Volume replicator pass failed")
+		}
+		select {
+		case <-ctx.Done():
+			klog.InfoS("Stopping volume replicator")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce copies the replicated volumes whose latest replica is older than
+// Interval, deletes their previous replicas, the incomplete ones, and the
+// replicas of the volumes no PersistentVolume references any more.
+func (r *Replicator) RunOnce(ctx context.Context) error {
+	err := r.runOnce(ctx)
+	metrics.ReplicationRuns.WithLabelValues(metrics.Result(err)).Inc()
+	return err
+}
+
+func (r *Replicator) runOnce(ctx context.Context) error {
+	if r.opts.ClusterTag == "" {
+		return errors.New("a cluster tag is required to identify the VDIs owned by this cluster")
+	}
+	vdis := make([][]*payloads.VDI, len(r.xoClients))
+	for i, xoClient := range r.xoClients {
+		var err error
+		vdis[i], err = xoClient.VDI().GetAll(ctx, 0, clients.BuildClusterTagFilter(r.opts.ClusterTag))
+		if err != nil {
+			return fmt.Errorf("failed to list VDIs with cluster tag %q: %w", r.opts.ClusterTag, err)
+		}
+	}
+	volumeIDs, err := r.listVolumeIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	metrics.ReplicaLagSeconds.Reset()
+	var errs []error
+	for i, xoClient := range r.xoClients {
+		errs = append(errs, r.replicate(ctx, xoClient, vdis[i], volumeIDs))
+	}
+	return errors.Join(errs...)
+}
+
+// replicate handles the volumes and replicas among the VDIs of xoClient.
+func (r *Replicator) replicate(ctx context.Context, xoClient clients.XoClient, vdis []*payloads.VDI, volumeIDs map[string]struct{}) error {
+	var sources []*payloads.VDI
+	replicas := map[string][]*Replica{}
+	for _, vdi := range vdis {
+		switch {
+		case clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaOf) != "":
+			replica := newReplica(vdi)
+			replicas[replica.VolumeID] = append(replicas[replica.VolumeID], replica)
+		case clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaPending) != "":
+			// Only the leader copies replicas, one pass at a time: this copy
+			// was interrupted.
+			r.deleteReplica(ctx, xoClient, vdi, "incomplete")
+		case clients.IsStripeMember(vdi):
+			// A member alone is not a copy of its volume.
+			if clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaPoolID) != "" {
+				klog.V(2).InfoS("Skipping member of a striped volume, which cannot be replicated", "vdiID", vdi.ID)
+			}
+		case clients.ParseTagValue(vdi.Tags, clients.VDITagKeyReplicaPoolID) != "" &&
+			clients.ParseTagValue(vdi.Tags, clients.VDITagKeyVolumeId) != "":
+			sources = append(sources, vdi)
+		}
+	}
+	for _, list := range replicas {
+		slices.SortFunc(list, func(a, b *Replica) int { return b.ReplicatedAt.Compare(a.ReplicatedAt) })
+	}
+
+	var errs []error
+	for _, source := range sources {
+		volumeID := clients.ParseTagValue(source.Tags, clients.VDITagKeyVolumeId)
+		if err := r.replicateVolume(ctx, xoClient, source, replicas[volumeID]); err != nil {
+			errs = append(errs, err)
+		}
+		delete(replicas, volumeID)
+	}
+
+	// The replicas of a lost volume are kept as long as its PersistentVolume
+	// exists: they are what failover promotes.
+	for volumeID, list := range replicas {
+		if _, found := volumeIDs[volumeID]; found {
+			latest := list[0]
+			metrics.ReplicaLagSeconds.WithLabelValues(latest.PVName, latest.VDI.PoolID.String()).Set(r.now().Sub(latest.ReplicatedAt).Seconds())
+			continue
+		}
+		for _, replica := range list {
+			r.deleteReplica(ctx, xoClient, replica.VDI, "volume deleted")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// replicateVolume copies source to its replica pool when its latest replica,
+// first in replicas, is older than Interval or in another pool, then deletes
+// the previous replicas. A source attached read-write is not copied: the
+// skipped copy is counted, and reported by a warning event on its
+// PersistentVolume.
+func (r *Replicator) replicateVolume(ctx context.Context, xoClient clients.XoClient, source *payloads.VDI, replicas []*Replica) error {
+	pvName := clients.ParseTagValue(source.Tags, clients.VDITagKeyPVName)
+	poolTag := clients.ParseTagValue(source.Tags, clients.VDITagKeyReplicaPoolID)
+	poolID, err := uuid.FromString(poolTag)
+	if err != nil {
+		metrics.ReplicaLagSeconds.WithLabelValues(pvName, poolTag).Set(math.Inf(1))
+		return fmt.Errorf("VDI %s has an invalid replica pool %q: %w", source.ID, poolTag, err)
+	}
+	lag := metrics.ReplicaLagSeconds.WithLabelValues(pvName, poolID.String())
+
+	now := r.now()
+	// previousLag is the lag while the volume is not copied again.
+	previousLag := math.Inf(1)
+	if len(replicas) > 0 && replicas[0].VDI.PoolID == poolID {
+		previousLag = now.Sub(replicas[0].ReplicatedAt).Seconds()
+		if now.Sub(replicas[0].ReplicatedAt) < r.opts.Interval {
+			lag.Set(previousLag)
+			return nil
+		}
+	}
+
+	// The export of a VDI plugged read-write would be torn by the writes of
+	// its VM: the volume is copied once detached, or plugged read-only.
+	if len(source.VBDs) > 0 {
+		vbds, err := xoClient.IsVDIUsedAnywhere(ctx, source)
+		if err != nil {
+			lag.Set(previousLag)
+			return fmt.Errorf("failed to get the VBDs of VDI %s: %w", source.ID, err)
+		}
+		if vbd := clients.ReadWriteVBD(vbds); vbd != nil {
+			klog.InfoS("Skipping the copy of a volume attached read-write, which cannot be copied consistently", "vdiID", source.ID, "pvName", pvName, "vmID", vbd.VM)
+			metrics.ReplicaCopies.WithLabelValues(poolID.String(), metrics.ResultSkipped).Inc()
+			lag.Set(previousLag)
+			pvRef := &corev1.ObjectReference{Kind: "PersistentVolume", APIVersion: "v1", Name: pvName}
+			r.recorder.Eventf(pvRef, corev1.EventTypeWarning, EventReasonReplicationSkipped,
+				"Volume is attached read-write to VM %s and is not replicated to pool %s while it is: its replica is %s old", vbd.VM, poolID, replicaAge(previousLag))
+			return nil
+		}
+	}
+
+	replica, err := r.copyVolume(ctx, xoClient, source, poolID)
+	metrics.ReplicaCopies.WithLabelValues(poolID.String(), metrics.Result(err)).Inc()
+	if err != nil {
+		lag.Set(previousLag)
+		return fmt.Errorf("failed to replicate VDI %s to pool %s: %w", source.ID, poolID, err)
+	}
+	lag.Set(r.now().Sub(replica.ReplicatedAt).Seconds())
+	for _, previous := range replicas {
+		r.deleteReplica(ctx, xoClient, previous.VDI, "superseded")
+	}
+	return nil
+}
+
+// copyVolume copies the raw content of source into a new replica in the
+// default SR of the pool poolID. The replica is marked pending until the copy
+// completes, and deleted when it fails.
+func (r *Replicator) copyVolume(ctx context.Context, xoClient clients.XoClient, source *payloads.VDI, poolID uuid.UUID) (_ *Replica, err error) {
+	volumeID := clients.ParseTagValue(source.Tags, clients.VDITagKeyVolumeId)
+	pvName := clients.ParseTagValue(source.Tags, clients.VDITagKeyPVName)
+	pool, err := xoClient.Pool().Get(ctx, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool %s: %w", poolID, err)
+	}
+	if pool.DefaultSR == uuid.Nil {
+		return nil, fmt.Errorf("pool %s has no default SR", poolID)
+	}
+
+	start := r.now()
+	pending := clients.BuildTag(clients.VDITagKeyReplicaPending, volumeID)
+	replicaID, err := xoClient.VDI().Create(ctx, payloads.VDICreateParams{
+		SRId:        pool.DefaultSR,
+		VirtualSize: source.Size,
+		// The name label of a replica must not hold the volume ID, which the
+		// driver looks volumes up by when their tags are lost.
+		NameLabel:       r.opts.VDINamePrefix + "replica-" + pvName,
+		NameDescription: fmt.Sprintf("Replica of the Kubernetes volume %s (VDI %s)", pvName, source.ID),
+		Tags:            []string{r.opts.ClusterTag, pending},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a VDI in SR %s: %w", pool.DefaultSR, err)
+	}
+	defer func() {
+		if err != nil {
+			r.deleteReplica(ctx, xoClient, &payloads.VDI{ID: replicaID}, "copy failed")
+		}
+	}()
+
+	klog.V(2).InfoS("Copying volume to its replica", "vdiID", source.ID, "replicaVDIID", replicaID, "poolID", poolID, "size", source.Size)
+	err = xoClient.VDI().Export(ctx, source.ID, payloads.VDIFormatRaw, func(content io.Reader) error {
+		return xoClient.VDI().Import(ctx, replicaID, payloads.VDIFormatRaw, content, source.Size)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy VDI %s to VDI %s: %w", source.ID, replicaID, err)
+	}
+
+	// The replicaOf tag makes the replica complete: it comes after the
+	// others, and before the pending tag goes.
+	tags := []string{
+		clients.BuildTag(clients.VDITagKeyReplicaPVName, pvName),
+		clients.BuildTag(clients.VDITagKeyReplicatedAt, start.UTC().Format(time.RFC3339)),
+		clients.BuildTag(clients.VDITagKeyReplicaOf, volumeID),
+	}
+	for _, tag := range tags {
+		if err := xoClient.VDI().AddTag(ctx, replicaID, tag); err != nil {
+			return nil, fmt.Errorf("failed to tag VDI %s with %s: %w", replicaID, tag, err)
+		}
+	}
+	if err := xoClient.VDI().RemoveTag(ctx, replicaID, pending); err != nil {
+		// The replica is complete, and will not be taken for an incomplete
+		// one.
+		klog.ErrorS(err, "Failed to remove the pending tag of a replica", "vdiID", replicaID)
+	}
+	klog.InfoS("Replicated volume", "vdiID", source.ID, "replicaVDIID", replicaID, "poolID", poolID, "duration", r.now().Sub(start))
+	return &Replica{
+		VDI:          &payloads.VDI{ID: replicaID, SR: pool.DefaultSR, PoolID: poolID, Size: source.Size},
+		VolumeID:     volumeID,
+		PVName:       pvName,
+		ReplicatedAt: start.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// replicaAge formats the lag of a volume for its events.
+func replicaAge(lag float64) string {
+	if math.IsInf(lag, 1) {
+		return "missing"
+	}
+	return time.Duration(lag * float64(time.Second)).Round(time.Second).String()
+}
+
+func (r *Replicator) deleteReplica(ctx context.Context, xoClient clients.XoClient, vdi *payloads.VDI, reason string) {
+	if err := xoClient.VDI().Delete(ctx, vdi.ID); err != nil && !clients.IsNotFoundError(err) {
+		klog.ErrorS(err, "Failed to delete replica", "vdiID", vdi.ID, "reason", reason)
+		return
+	}
+	klog.V(2).InfoS("Deleted replica", "vdiID", vdi.ID, "reason", reason)
+}
+
+// listVolumeIDs returns the volume IDs of the PersistentVolumes of the driver.
+func (r *Replicator) listVolumeIDs(ctx context.Context) (map[string]struct{}, error) {
+	pvs, err := r.kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumes: %w", err)
+	}
+	volumeIDs := make(map[string]struct{}, len(pvs.Items))
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == r.opts.DriverName {
+			// VDIs store the volume ID without the Xen Orchestra instance of
+			// the handle.
+			_, volumeID := clients.SplitVolumeHandle(pv.Spec.CSI.VolumeHandle)
+			volumeIDs[volumeID] = struct{}{}
+		}
+	}
+	return volumeIDs, nil
+}
@@ -0,0 +1,229 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replication
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	testDriverName = "csi.xenorchestra.vates.tech"
+	testClusterTag = "k8s-test"
+)
+
+var (
+	now         = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	sourceID    = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
+	replicaID   = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000001"))
+	oldReplica  = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000002"))
+	replicaPool = uuid.Must(uuid.FromString("cccccccc-0000-0000-0000-000000000001"))
+	replicaSR   = uuid.Must(uuid.FromString("dddddddd-0000-0000-0000-000000000001"))
+)
+
+func newSourceVDI() *payloads.VDI {
+	return &payloads.VDI{ID: sourceID, Size: 4, Tags: []string{
+		testClusterTag,
+		clients.BuildTag(clients.VDITagKeyVolumeId, "vol-1"),
+		clients.BuildTag(clients.VDITagKeyPVName, "pvc-1"),
+		clients.BuildTag(clients.VDITagKeyReplicaPoolID, replicaPool.String()),
+	}}
+}
+
+func newReplicaVDI(id uuid.UUID, volumeID string, replicatedAt time.Time) *payloads.VDI {
+	return &payloads.VDI{ID: id, PoolID: replicaPool, Size: 4, Tags: []string{
+		testClusterTag,
+		clients.BuildTag(clients.VDITagKeyReplicaOf, volumeID),
+		clients.BuildTag(clients.VDITagKeyReplicaPVName, "pvc-1"),
+		clients.BuildTag(clients.VDITagKeyReplicatedAt, replicatedAt.Format(time.RFC3339)),
+	}}
+}
+
+type testReplicator struct {
+	*Replicator
+	xo       *clientsMock.MockXoClient
+	vdi      *xoLibMock.MockVDI
+	recorder *record.FakeRecorder
+}
+
+func newTestReplicator(t *testing.T, vdis []*payloads.VDI, pvs ...*corev1.PersistentVolume) *testReplicator {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	xo := clientsMock.NewMockXoClient(ctrl)
+	vdi := xoLibMock.NewMockVDI(ctrl)
+	pool := xoLibMock.NewMockPool(ctrl)
+	xo.EXPECT().VDI().Return(vdi).AnyTimes()
+	xo.EXPECT().Pool().Return(pool).AnyTimes()
+	pool.EXPECT().Get(gomock.Any(), replicaPool).Return(&payloads.Pool{ID: replicaPool, DefaultSR: replicaSR}, nil).AnyTimes()
+	vdi.EXPECT().GetAll(gomock.Any(), 0, clients.BuildClusterTagFilter(testClusterTag)).Return(vdis, nil)
+
+	kubeClient := fake.NewClientset()
+	for _, pv := range pvs {
+		_, err := kubeClient.CoreV1().PersistentVolumes().Create(context.Background(), pv, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := NewReplicator([]clients.XoClient{xo}, kubeClient, recorder, Options{
+		DriverName:    testDriverName,
+		ClusterTag:    testClusterTag,
+		VDINamePrefix: "csi-",
+		Interval:      time.Hour,
+	})
+	r.now = func() time.Time { return now }
+	return &testReplicator{Replicator: r, xo: xo, vdi: vdi, recorder: recorder}
+}
+
+// expectCopy expects the copy of the source into a new replica, failing the
+// import with importErr.
+func (r *testReplicator) expectCopy(importErr error) {
+	pending := clients.BuildTag(clients.VDITagKeyReplicaPending, "vol-1")
+	r.vdi.EXPECT().Create(gomock.Any(), payloads.VDICreateParams{
+		SRId:            replicaSR,
+		VirtualSize:     4,
+		NameLabel:       "csi-replica-pvc-1",
+		NameDescription: "Replica of the Kubernetes volume pvc-1 (VDI " + sourceID.String() + ")",
+		Tags:            []string{testClusterTag, pending},
+	}).Return(replicaID, nil)
+	r.vdi.EXPECT().Export(gomock.Any(), sourceID, payloads.VDIFormatRaw, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, _ payloads.VDIFormat, fn func(io.Reader) error) error {
+			return fn(strings.NewReader("disk"))
+		})
+	r.vdi.EXPECT().Import(gomock.Any(), replicaID, payloads.VDIFormatRaw, gomock.Any(), int64(4)).Return(importErr)
+	if importErr != nil {
+		r.vdi.EXPECT().Delete(gomock.Any(), replicaID).Return(nil)
+		return
+	}
+	gomock.InOrder(
+		r.vdi.EXPECT().AddTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyReplicaPVName, "pvc-1")).Return(nil),
+		r.vdi.EXPECT().AddTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyReplicatedAt, now.Format(time.RFC3339))).Return(nil),
+		r.vdi.EXPECT().AddTag(gomock.Any(), replicaID, clients.BuildTag(clients.VDITagKeyReplicaOf, "vol-1")).Return(nil),
+		r.vdi.EXPECT().RemoveTag(gomock.Any(), replicaID, pending).Return(nil),
+	)
+}
+
+func lag() float64 {
+	return testutil.ToFloat64(metrics.ReplicaLagSeconds.WithLabelValues("pvc-1", replicaPool.String()))
+}
+
+func TestRunOnce(t *testing.T) {
+	t.Run("CopiesVolumeWithoutReplica", func(t *testing.T) {
+		r := newTestReplicator(t, []*payloads.VDI{newSourceVDI()}, stub.NewPersistentVolume("pvc-1", testDriverName, "vol-1"))
+		r.expectCopy(nil)
+
+		require.NoError(t, r.RunOnce(context.Background()))
+		assert.Zero(t, lag())
+	})
+
+	t.Run("KeepsRecentReplica", func(t *testing.T) {
+		recent := newReplicaVDI(replicaID, "vol-1", now.Add(-10*time.Minute))
+		r := newTestReplicator(t, []*payloads.VDI{newSourceVDI(), recent}, stub.NewPersistentVolume("pvc-1", testDriverName, "vol-1"))
+
+		require.NoError(t, r.RunOnce(context.Background()))
+		assert.Equal(t, 600.0, lag())
+	})
+
+	t.Run("ReplacesOldReplica", func(t *testing.T) {
+		old := newReplicaVDI(oldReplica, "vol-1", now.Add(-2*time.Hour))
+		r := newTestReplicator(t, []*payloads.VDI{newSourceVDI(), old}, stub.NewPersistentVolume("pvc-1", testDriverName, "vol-1"))
+		r.expectCopy(nil)
+		r.vdi.EXPECT().Delete(gomock.Any(), oldReplica).Return(nil)
+
+		require.NoError(t, r.RunOnce(context.Background()))
+		assert.Zero(t, lag())
+	})
+
+	t.Run("KeepsOldReplicaWhenCopyFails", func(t *testing.T) {
+		old := newReplicaVDI(oldReplica, "vol-1", now.Add(-2*time.Hour))
+		r := newTestReplicator(t, []*payloads.VDI{newSourceVDI(), old}, stub.NewPersistentVolume("pvc-1", testDriverName, "vol-1"))
+		r.expectCopy(errors.New("SR full"))
+
+		require.ErrorContains(t, r.RunOnce(context.Background()), "SR full")
+		assert.Equal(t, 7200.0, lag())
+	})
+
+	t.Run("ReportsVolumeWithoutReplica", func(t *testing.T) {
+		r := newTestReplicator(t, []*payloads.VDI{newSourceVDI()}, stub.NewPersistentVolume("pvc-1", testDriverName, "vol-1"))
+		r.expectCopy(errors.New("SR full"))
+
+		require.Error(t, r.RunOnce(context.Background()))
+		assert.True(t, math.IsInf(lag(), 1))
+	})
+
+	t.Run("SkipsVolumeAttachedReadWrite", func(t *testing.T) {
+		source := newSourceVDI()
+		source.VBDs = []uuid.UUID{uuid.Must(uuid.NewV4())}
+		old := newReplicaVDI(oldReplica, "vol-1", now.Add(-2*time.Hour))
+		r := newTestReplicator(t, []*payloads.VDI{source, old}, stub.NewPersistentVolume("pvc-1", testDriverName, "vol-1"))
+		r.xo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), source).Return([]*payloads.VBD{{VM: uuid.Must(uuid.NewV4()), Attached: true}}, nil)
+		skipped := metrics.ReplicaCopies.WithLabelValues(replicaPool.String(), metrics.ResultSkipped)
+		before := testutil.ToFloat64(skipped)
+
+		require.NoError(t, r.RunOnce(context.Background()))
+		assert.Equal(t, 7200.0, lag(), "the old replica is kept")
+		assert.Equal(t, before+1, testutil.ToFloat64(skipped))
+		require.Len(t, r.recorder.Events, 1)
+		event := <-r.recorder.Events
+		assert.Contains(t, event, "Warning "+EventReasonReplicationSkipped)
+		assert.Contains(t, event, "2h0m0s old")
+	})
+
+	t.Run("CopiesVolumeAttachedReadOnly", func(t *testing.T) {
+		source := newSourceVDI()
+		source.VBDs = []uuid.UUID{uuid.Must(uuid.NewV4())}
+		r := newTestReplicator(t, []*payloads.VDI{source}, stub.NewPersistentVolume("pvc-1", testDriverName, "vol-1"))
+		r.xo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), source).Return([]*payloads.VBD{{VM: uuid.Must(uuid.NewV4()), Attached: true, ReadOnly: true}}, nil)
+		r.expectCopy(nil)
+
+		require.NoError(t, r.RunOnce(context.Background()))
+		assert.Zero(t, lag())
+	})
+
+	t.Run("CleansUpReplicas", func(t *testing.T) {
+		pending := &payloads.VDI{ID: uuid.Must(uuid.NewV4()), Tags: []string{testClusterTag, clients.BuildTag(clients.VDITagKeyReplicaPending, "vol-1")}}
+		deleted := newReplicaVDI(uuid.Must(uuid.NewV4()), "vol-2", now)
+		lost := newReplicaVDI(replicaID, "vol-1", now.Add(-3*time.Hour))
+		r := newTestReplicator(t, []*payloads.VDI{pending, deleted, lost}, stub.NewPersistentVolume("pvc-1", testDriverName, "paris/vol-1"))
+		r.vdi.EXPECT().Delete(gomock.Any(), pending.ID).Return(nil)
+		r.vdi.EXPECT().Delete(gomock.Any(), deleted.ID).Return(nil)
+
+		require.NoError(t, r.RunOnce(context.Background()))
+		assert.Equal(t, 10800.0, lag(), "the replica of a lost volume is kept while its PersistentVolume exists")
+	})
+}
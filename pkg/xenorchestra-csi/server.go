@@ -16,24 +16,29 @@ limitations under the License.
 package xenorchestracsi
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"strings"
-	"sync"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"k8s.io/klog/v2"
 )
 
-var stopOnce sync.Once
-
 // NonBlockingGRPCServer defines non-blocking GRPC server interfaces.
 type NonBlockingGRPCServer interface {
-	// Start services at the endpoint.
-	Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer)
+	// Start listens on the endpoint, unix://path or tcp://host:port, and serves the CSI services in the
+	// background. It returns once the server accepts connections.
+	Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) error
+
+	// Wait blocks until the server has stopped and returns the error that
+	// stopped it, if any. The unix socket is removed before it returns.
+	Wait() error
 
 	// Stop stops the gRPC server. It immediately closes all open connections
 	// and listeners. It cancels all active RPCs on the server side and the
@@ -47,95 +52,136 @@ type NonBlockingGRPCServer interface {
 	GracefulStop()
 }
 
-// NewNonBlockingGRPCServer returns an instance of nonBlockingGRPCServer.
-func NewNonBlockingGRPCServer() NonBlockingGRPCServer {
-	return &nonBlockingGRPCServer{}
+// NewNonBlockingGRPCServer returns an instance of nonBlockingGRPCServer. creds
+// secures tcp:// endpoints; nil serves them without TLS.
+func NewNonBlockingGRPCServer(creds credentials.TransportCredentials) NonBlockingGRPCServer {
+	return &nonBlockingGRPCServer{creds: creds, done: make(chan struct{})}
 }
 
 // nonBlockingGRPCServer implements the interface NonBlockingGRPCServer.
 type nonBlockingGRPCServer struct {
 	server *grpc.Server
+	creds  credentials.TransportCredentials
+	// socketPath is the unix socket to remove once the server has stopped.
+	socketPath string
+	// done is closed when Serve returns, after which err is set.
+	done chan struct{}
+	err  error
 }
 
 // Start implements NonBlockingGRPCServer.
-func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
-	if err := s.serve(endpoint, ids, cs, ns); err != nil {
+func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) error {
+	listener, err := s.listen(endpoint, ids, cs, ns)
+	if err != nil {
 		klog.Errorf("failed to start grpc server. Err: %v", err)
+		close(s.done)
+		return err
 	}
+
+	klog.Infof("Listening for connections on address: %#v", listener.Addr())
+	go func() {
+		defer close(s.done)
+		// ErrServerStopped means the server was stopped before serving.
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			klog.Errorf("failed to serve: %v", err)
+			s.err = err
+		}
+		if s.socketPath == "" {
+			return
+		}
+		if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+			klog.Errorf("failed to remove %s, error: %v", s.socketPath, err)
+		}
+	}()
+	return nil
+}
+
+// Wait implements NonBlockingGRPCServer.
+func (s *nonBlockingGRPCServer) Wait() error {
+	<-s.done
+	return s.err
 }
 
 // GracefulStop implements NonBlockingGRPCServer.
 func (s *nonBlockingGRPCServer) GracefulStop() {
-	stopOnce.Do(func() {
-		if s.server != nil {
-			s.server.GracefulStop()
-		}
-		klog.Info("gracefully stopped")
-	})
+	if s.server != nil {
+		s.server.GracefulStop()
+	}
+	klog.Info("gracefully stopped")
 }
 
-// Stop implements NonBlockingGRPCServer.
+// Stop implements NonBlockingGRPCServer. It may be called while GracefulStop
+// is waiting for pending RPCs, which are then cancelled.
 func (s *nonBlockingGRPCServer) Stop() {
-	stopOnce.Do(func() {
-		if s.server != nil {
-			s.server.Stop()
-		}
-		klog.Info("stopped")
-	})
+	if s.server != nil {
+		s.server.Stop()
+	}
+	klog.Info("stopped")
 }
 
-func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
-	cs csi.ControllerServer, ns csi.NodeServer,
-) error {
-	const (
-		unixScheme = "unix"
-		unixPrefix = unixScheme + "://"
-	)
-
-	// CSI driver currently supports only unix path.
-	if !strings.HasPrefix(endpoint, unixPrefix) {
-		err := fmt.Errorf("endpoint must be a unix socket: %s", endpoint)
-		klog.Errorf("%s", err.Error())
-		return err
-	}
-	addr := strings.TrimPrefix(endpoint, unixPrefix)
+const (
+	unixScheme = "unix"
+	tcpScheme  = "tcp"
+)
 
-	// Remove UNIX sock file if present
-	// NOTE: could also be cleaned at server shutdown.
-	if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
-		err = fmt.Errorf("failed to remove %s, error: %v", addr, err)
-		klog.Errorf("%s", err.Error())
-		return err
+// parseEndpoint splits a unix://path or tcp://host:port endpoint into the
+// network and address passed to net.Listen.
+func parseEndpoint(endpoint string) (string, string, error) {
+	scheme, addr, ok := strings.Cut(endpoint, "://")
+	if !ok || addr == "" || (scheme != unixScheme && scheme != tcpScheme) {
+		return "", "", fmt.Errorf("endpoint must be unix://path or tcp://host:port: %s", endpoint)
 	}
+	return scheme, addr, nil
+}
 
-	listener, err := net.Listen(unixScheme, addr)
+// listen opens the unix socket or TCP port and registers the CSI services on
+// a new server, without serving yet.
+func (s *nonBlockingGRPCServer) listen(endpoint string, ids csi.IdentityServer,
+	cs csi.ControllerServer, ns csi.NodeServer,
+) (net.Listener, error) {
+	scheme, addr, err := parseEndpoint(endpoint)
 	if err != nil {
-		err := fmt.Errorf("failed to listen on unix socket %s, error: %v", addr, err)
-		klog.Errorf("%s", err.Error())
-		return err
+		return nil, err
 	}
 
-	server := grpc.NewServer()
-	s.server = server
-
-	// Register the CSI services.
 	// Always require the identity service.
 	if ids == nil {
-		err = fmt.Errorf("identity service is required")
-		klog.Errorf("%s", err.Error())
-		return err
+		return nil, fmt.Errorf("identity service is required")
+	}
+	if cs == nil && ns == nil {
+		return nil, fmt.Errorf("either a controller or node service is required")
 	}
 
-	// Always register the identity service.
-	csi.RegisterIdentityServer(s.server, ids)
-	klog.Info("identity service registered")
+	if scheme == unixScheme {
+		// Remove a UNIX sock file left behind by a process that did not stop
+		// gracefully. The socket is also removed once the server has stopped.
+		if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove %s, error: %v", addr, err)
+		}
+	}
 
-	if cs == nil && ns == nil {
-		err = fmt.Errorf("either a controller or node service is required")
-		klog.Errorf("%s", err.Error())
-		return err
+	listener, err := net.Listen(scheme, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s, error: %v", endpoint, err)
+	}
+	if scheme == unixScheme {
+		s.socketPath = addr
 	}
 
+	// The stats handler creates the RPC spans, continuing the trace of the
+	// caller when it propagates one; the interceptors run inside them.
+	serverOptions := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryInterceptors()...),
+	}
+	if s.creds != nil {
+		serverOptions = append(serverOptions, grpc.Creds(s.creds))
+	}
+	s.server = grpc.NewServer(serverOptions...)
+
+	// Register the CSI services. Always register the identity service.
+	csi.RegisterIdentityServer(s.server, ids)
+	klog.Info("identity service registered")
 	if cs != nil {
 		csi.RegisterControllerServer(s.server, cs)
 		klog.Info("controller service registered")
@@ -144,13 +190,5 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
 		csi.RegisterNodeServer(s.server, ns)
 		klog.Info("node service registered")
 	}
-
-	klog.Infof("Listening for connections on address: %#v", listener.Addr())
-
-	if err := server.Serve(listener); err != nil {
-		klog.Errorf("failed to serve: %v", err)
-		return err
-	}
-
-	return nil
+	return listener, nil
 }
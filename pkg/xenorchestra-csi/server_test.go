@@ -0,0 +1,118 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// slowIdentityServer answers Probe after delay, or when the call is cancelled.
+type slowIdentityServer struct {
+	csi.UnimplementedIdentityServer
+	started chan struct{}
+	delay   time.Duration
+}
+
+func (s *slowIdentityServer) Probe(ctx context.Context, _ *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+	close(s.started)
+	select {
+	case <-time.After(s.delay):
+		return &csi.ProbeResponse{}, nil
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// startSlowServer serves a slowIdentityServer and starts a Probe call, whose
+// result is sent on the returned channel.
+func startSlowServer(t *testing.T, delay time.Duration) (NonBlockingGRPCServer, string, <-chan error) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "csi.sock")
+	ids := &slowIdentityServer{started: make(chan struct{}), delay: delay}
+	server := NewNonBlockingGRPCServer(nil)
+	require.NoError(t, server.Start("unix://"+socket, ids, nil, &xenorchestraCSIDriver{}))
+
+	conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	probed := make(chan error, 1)
+	go func() {
+		_, err := csi.NewIdentityClient(conn).Probe(context.Background(), &csi.ProbeRequest{})
+		probed <- err
+	}()
+	<-ids.started
+	return server, socket, probed
+}
+
+func TestDrainServer(t *testing.T) {
+	t.Run("InFlightCallFinishes", func(t *testing.T) {
+		server, socket, probed := startSlowServer(t, 100*time.Millisecond)
+
+		drainServer(server, 10*time.Second)
+
+		require.NoError(t, <-probed)
+		require.NoError(t, server.Wait())
+		assert.NoFileExists(t, socket)
+	})
+
+	t.Run("TimeoutCancelsInFlightCall", func(t *testing.T) {
+		server, socket, probed := startSlowServer(t, time.Hour)
+
+		start := time.Now()
+		drainServer(server, 50*time.Millisecond)
+
+		assert.Less(t, time.Since(start), 10*time.Second)
+		err := <-probed
+		require.Error(t, err)
+		assert.Contains(t, []codes.Code{codes.Canceled, codes.Unavailable}, status.Code(err))
+		require.NoError(t, server.Wait())
+		_, statErr := os.Stat(socket)
+		assert.True(t, os.IsNotExist(statErr), "the socket is removed")
+	})
+}
+
+func TestNonBlockingGRPCServerStart(t *testing.T) {
+	t.Run("RejectsUnknownScheme", func(t *testing.T) {
+		server := NewNonBlockingGRPCServer(nil)
+		require.Error(t, server.Start("http://127.0.0.1:0", &xenorchestraCSIDriver{}, nil, &xenorchestraCSIDriver{}))
+		require.NoError(t, server.Wait(), "Wait returns at once when Start failed")
+	})
+
+	t.Run("ReplacesStaleSocket", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "csi.sock")
+		require.NoError(t, os.WriteFile(socket, nil, 0o600))
+
+		server := NewNonBlockingGRPCServer(nil)
+		require.NoError(t, server.Start("unix://"+socket, &xenorchestraCSIDriver{}, nil, &xenorchestraCSIDriver{}))
+		server.GracefulStop()
+		require.NoError(t, server.Wait())
+		assert.NoFileExists(t, socket)
+	})
+}
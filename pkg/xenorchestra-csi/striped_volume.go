@@ -0,0 +1,323 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// publishContextKeyDevices lists the devices of the members of a striped
+	// volume on the node, comma separated, in member order.
+	publishContextKeyDevices = "devices"
+	// publishContextKeyVBDs lists the VBDs of the members of a striped volume,
+	// in the order of publishContextKeyDevices.
+	publishContextKeyVBDs = "vbds"
+)
+
+// parseStripes returns the number of VDIs the volumes of a StorageClass are
+// striped over, 1 for regular volumes.
+func parseStripes(params map[string]string) (int, error) {
+	value, found := params[ParameterStripes]
+	if !found || value == "" {
+		return 1, nil
+	}
+	stripes, err := strconv.Atoi(value)
+	if err != nil || stripes < 1 || stripes > MaxStripes {
+		return 0, fmt.Errorf("parameter %q must be an integer between 1 and %d, got %q", ParameterStripes, MaxStripes, value)
+	}
+	return stripes, nil
+}
+
+// stripeMemberSize returns the size of each of the stripes VDIs of a volume
+// of capacityBytes: its share of the capacity, rounded up to the chunk size,
+// and the space of the md superblock.
+func stripeMemberSize(capacityBytes int64, stripes int) int64 {
+	share := (capacityBytes + int64(stripes) - 1) / int64(stripes)
+	chunks := (share + clients.StripeChunkBytes - 1) / clients.StripeChunkBytes
+	return chunks*clients.StripeChunkBytes + clients.StripeDataOffsetBytes
+}
+
+// validateStripeMemberSize checks that the members of a volume of
+// capacityBytes striped over stripes VDIs of sr fit in a VHD. Otherwise, it
+// fails with OutOfRange and the minimum number of VDIs the volume needs.
+func validateStripeMemberSize(capacityBytes int64, stripes int, sr *payloads.StorageRepository) error {
+	memberSize := stripeMemberSize(capacityBytes, stripes)
+	if memberSize <= clients.MaxVHDSizeBytes {
+		return nil
+	}
+	for needed := stripes + 1; needed <= MaxStripes; needed++ {
+		if stripeMemberSize(capacityBytes, needed) <= clients.MaxVHDSizeBytes {
+			return status.Errorf(codes.OutOfRange, "a volume of %d bytes striped over %d VDIs needs VDIs of %d bytes, over the %d bytes limit of the VDIs of SR %s: set parameter %q to at least %d",
+				capacityBytes, stripes, memberSize, int64(clients.MaxVHDSizeBytes), sr.ID, ParameterStripes, needed)
+		}
+	}
+	return status.Errorf(codes.OutOfRange, "a volume of %d bytes does not fit in %d VDIs of at most %d bytes in SR %s",
+		capacityBytes, MaxStripes, int64(clients.MaxVHDSizeBytes), sr.ID)
+}
+
+// stripedDeviceName returns the name of the md array of the striped volume of
+// volumeHandle: its volume ID without dashes, which fits the 32 characters of
+// an md name.
+func stripedDeviceName(volumeHandle string) string {
+	_, volumeId := clients.SplitVolumeHandle(volumeHandle)
+	return strings.ReplaceAll(volumeId, "-", "")
+}
+
+// lookupVolume returns the VDI of the volume volumeId, or the members of the
+// striped volume volumeId in any order.
+func (driver *xenorchestraCSIDriver) lookupVolume(ctx context.Context, volumeId string) (*payloads.VDI, []*payloads.VDI, error) {
+	vdi, err := driver.xo(ctx).GetVDIByVolumeId(ctx, volumeId)
+	switch {
+	case errors.Is(err, clients.ErrVolumeStriped):
+	case err != nil:
+		return nil, nil, err
+	case !clients.IsStripeMember(vdi):
+		return vdi, nil, nil
+	}
+	// A striped volume left with a single member still is one.
+	_, id := clients.SplitVolumeHandle(volumeId)
+	members, err := driver.xo(ctx).VDI().GetAll(ctx, 0, clients.BuildTagFilter(clients.VDITagKeyVolumeId, id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the members of striped volume %s: %w", volumeId, err)
+	}
+	if len(members) == 0 {
+		return nil, nil, fmt.Errorf("%w: volumeId=%s", clients.ErrVolumeNotFound, volumeId)
+	}
+	return nil, members, nil
+}
+
+// createStripedVolume creates the stripes VDIs of the volume volumeName in
+// sr, or the ones a previous call did not create, and returns the volume ID
+// they share.
+func (driver *xenorchestraCSIDriver) createStripedVolume(ctx context.Context, sr *payloads.StorageRepository, volumeName string, capacityBytes int64, stripes int) (string, error) {
+	memberSize := stripeMemberSize(capacityBytes, stripes)
+	existing, err := driver.xo(ctx).VDI().GetAll(ctx, 0, clients.BuildTagFilter(clients.VDITagKeyPVName, volumeName))
+	if err != nil {
+		klog.ErrorS(err, "Failed to check for existing VDIs", "volumeName", volumeName)
+		return "", status.Errorf(codes.Internal, "failed to check for existing VDIs: %v", err)
+	}
+
+	volumeId := ""
+	created := make([]bool, stripes)
+	for _, vdi := range existing {
+		if clients.ParseTagValue(vdi.Tags, clients.VDITagKeyStripeCount) != strconv.Itoa(stripes) {
+			return "", status.Errorf(codes.AlreadyExists, "volume with name %q already exists and is not striped over %d VDIs", volumeName, stripes)
+		}
+		if vdi.Size != memberSize {
+			return "", status.Errorf(codes.AlreadyExists, "volume with name %q already exists with different capacity: VDI %s has %d bytes, requested %d", volumeName, vdi.ID, vdi.Size, memberSize)
+		}
+		id := clients.ParseTagValue(vdi.Tags, clients.VDITagKeyVolumeId)
+		if id == "" || (volumeId != "" && id != volumeId) {
+			return "", status.Errorf(codes.Internal, "existing VDI %s does not carry the volume ID of the other members", vdi.ID)
+		}
+		volumeId = id
+		index, err := strconv.Atoi(clients.ParseTagValue(vdi.Tags, clients.VDITagKeyStripeIndex))
+		if err != nil || index < 0 || index >= stripes || created[index] {
+			return "", status.Errorf(codes.Internal, "existing VDI %s has no valid %s tag", vdi.ID, clients.VDITagKeyStripeIndex)
+		}
+		created[index] = true
+	}
+	if volumeId == "" {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return "", status.Errorf(codes.Internal, "failed to generate volume ID: %v", err)
+		}
+		volumeId = id.String()
+	}
+
+	for index := range stripes {
+		if created[index] {
+			continue
+		}
+		tags := []string{
+			clients.BuildTag(clients.VDITagKeyVolumeId, volumeId),
+			clients.BuildTag(clients.VDITagKeyPVName, volumeName),
+			clients.BuildTag(clients.VDITagKeyManagedBy, driver.Name+"@"+driver.Version),
+			clients.BuildTag(clients.VDITagKeyStripeIndex, strconv.Itoa(index)),
+			clients.BuildTag(clients.VDITagKeyStripeCount, strconv.Itoa(stripes)),
+		}
+		if driver.clusterTag != "" {
+			tags = append(tags, driver.clusterTag)
+		}
+		vdiID, err := driver.xo(ctx).VDI().Create(ctx, payloads.VDICreateParams{
+			SRId:            sr.ID,
+			NameLabel:       clients.BuildStripeMemberNameLabel(driver.vdiNamePrefix, volumeId, volumeName, index),
+			VirtualSize:     memberSize,
+			NameDescription: clients.BuildVDINameDescription(volumeName),
+			Tags:            tags,
+		})
+		if err != nil {
+			klog.ErrorS(err, "Failed to create member VDI", "volumeName", volumeName, "index", index, "memberSize", memberSize)
+			// The SR may have changed since it was cached: read it again on retry.
+			driver.xo(ctx).InvalidateCache(sr.ID, sr.Pool)
+			return "", status.Errorf(codes.Internal, "failed to create member %d of %d: %v", index, stripes, err)
+		}
+		klog.V(5).InfoS("Member VDI created", "vdiID", vdiID, "volumeID", volumeId, "index", index)
+	}
+	return volumeId, nil
+}
+
+// publishStripedVolume attaches all the members of a striped volume to the
+// node VM vmUUID, and returns their devices and VBDs in member order.
+func (driver *xenorchestraCSIDriver) publishStripedVolume(ctx context.Context, volumeId string, vmUUID uuid.UUID, vdis []*payloads.VDI) (*csi.ControllerPublishVolumeResponse, error) {
+	members, err := clients.StripeMembers(vdis)
+	if err != nil {
+		klog.ErrorS(err, "Striped volume is incomplete", "volumeID", volumeId)
+		return nil, status.Errorf(codes.FailedPrecondition, "striped volume %s is incomplete: %v", volumeId, err)
+	}
+
+	nodeVM, err := driver.xo(ctx).VM().GetByID(ctx, vmUUID)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "failed to get VM by ID %s: %v", vmUUID, err)
+	}
+
+	devices := make([]string, 0, len(members))
+	vbdIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		if nodeVM.PoolID != member.PoolID {
+			return nil, status.Errorf(codes.FailedPrecondition, "cannot attach VDI from pool %s to VM in pool %s", member.PoolID, nodeVM.PoolID)
+		}
+		if err := driver.xo(ctx).IsSRAttachedToHost(ctx, member.SR, nodeVM.Container); err != nil {
+			klog.ErrorS(err, "SR is not attached to VM host", "srID", member.SR, "hostID", nodeVM.Container, "vmUUID", vmUUID)
+			return nil, status.Errorf(codes.FailedPrecondition, "SR is not attached to the VM host: %v", err)
+		}
+		vbd, err := driver.attachVDIToNode(ctx, member, volumeId, vmUUID)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *vbd.Device)
+		vbdIDs = append(vbdIDs, vbd.ID.String())
+	}
+	klog.V(5).InfoS("Striped volume attached to VM", "volumeID", volumeId, "vmUUID", vmUUID, "devices", devices)
+
+	return &csi.ControllerPublishVolumeResponse{
+		PublishContext: map[string]string{
+			publishContextKeyDevices: strings.Join(devices, ","),
+			publishContextKeyVBDs:    strings.Join(vbdIDs, ","),
+		},
+	}, nil
+}
+
+// unpublishStripedVolume detaches the members of a striped volume from the
+// node VM vmUUID.
+func (driver *xenorchestraCSIDriver) unpublishStripedVolume(ctx context.Context, vmUUID uuid.UUID, members []*payloads.VDI) error {
+	for _, member := range members {
+		err := driver.vmQueue.Do(ctx, vmUUID, vmOperationDetach, func() error {
+			return driver.xo(ctx).DetachVDIFromVM(ctx, *member, vmUUID)
+		})
+		if err != nil && !errors.Is(err, clients.ErrVBDNotFound) {
+			klog.ErrorS(err, "Failed to detach member VDI from VM", "vdiID", member.ID, "vmUUID", vmUUID)
+			return status.Errorf(codes.Internal, "Failed to detach VDI %s from VM: %v", member.ID, err)
+		}
+		klog.V(5).InfoS("VBD detached from VM", "vdiID", member.ID, "vmUUID", vmUUID)
+	}
+	return nil
+}
+
+// deleteStripedVolume deletes the members of a striped volume, unless one of
+// them is still attached to a VM.
+func (driver *xenorchestraCSIDriver) deleteStripedVolume(ctx context.Context, volumeId string, members []*payloads.VDI) error {
+	for _, member := range members {
+		vbds, err := driver.xo(ctx).IsVDIUsedAnywhere(ctx, member)
+		if err != nil {
+			klog.ErrorS(err, "Failed to check VDI attachments", "vdiID", member.ID)
+			return status.Errorf(codes.Internal, "failed to check VDI attachments for %s: %v", member.ID, err)
+		}
+		for _, vbd := range vbds {
+			if vbd.Attached {
+				klog.ErrorS(nil, "VDI still attached to a VM, refusing deletion", "vdiID", member.ID, "vmID", vbd.VM)
+				return status.Errorf(codes.FailedPrecondition, "VDI %s is still attached to VM %s", member.ID, vbd.VM)
+			}
+		}
+	}
+
+	for _, member := range members {
+		if err := driver.xo(ctx).VDI().Delete(ctx, member.ID); err != nil && !clients.IsNotFoundError(err) {
+			klog.ErrorS(err, "Failed to delete member VDI", "vdiID", member.ID)
+			return status.Errorf(codes.Internal, "failed to delete VDI %s: %v", member.ID, err)
+		}
+		klog.V(5).InfoS("Member VDI deleted", "vdiID", member.ID, "volumeID", volumeId)
+	}
+	return nil
+}
+
+// stageStripedVolume assembles the md array striping the devices of a
+// striped volume, and mounts it at stagingTarget, formatting it first if
+// needed.
+func (driver *xenorchestraCSIDriver) stageStripedVolume(ctx context.Context, volumeId, stagingTarget, fsType string, publishContext map[string]string) error {
+	devices := strings.Split(publishContext[publishContextKeyDevices], ",")
+	vbdIDs := strings.Split(publishContext[publishContextKeyVBDs], ",")
+	if len(vbdIDs) != len(devices) {
+		return status.Errorf(codes.InvalidArgument, "%s and %s in publish context do not have the same length", publishContextKeyDevices, publishContextKeyVBDs)
+	}
+
+	devicePaths := make([]string, 0, len(devices))
+	for i, device := range devices {
+		vbdID, err := uuid.FromString(vbdIDs[i])
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "vbd %q in publish context is not a valid UUID: %v", vbdIDs[i], err)
+		}
+		if err := driver.xo(ctx).IsSRAttachedToVMHost(ctx, vbdID); err != nil {
+			return status.Errorf(codes.Internal, "SR connectivity check failed for VBD %s: %v", vbdID, err)
+		}
+		devicePath := "/dev/" + device
+		if _, err := os.Stat(devicePath); os.IsNotExist(err) {
+			klog.ErrorS(err, "device does not exist on this host despite SR being connected", "devicePath", devicePath)
+			return status.Errorf(codes.Internal, "device %s does not exist on this host despite SR being connected", devicePath)
+		}
+		devicePaths = append(devicePaths, devicePath)
+	}
+
+	arrayPath, err := driver.mounter.AssembleStripedDevice(stripedDeviceName(volumeId), devicePaths)
+	if err != nil {
+		klog.ErrorS(err, "Failed to assemble striped device", "volumeID", volumeId, "devices", devicePaths)
+		return status.Errorf(codes.Internal, "failed to assemble striped device: %v", err)
+	}
+
+	currentDevice, _, err := driver.mounter.GetDeviceNameFromMount(stagingTarget)
+	if err != nil {
+		klog.ErrorS(err, "failed to check if device is already mounted")
+		return status.Errorf(codes.Internal, "failed to check if device is already mounted: %v", err)
+	}
+	if currentDevice != "" && filepath.Base(currentDevice) == filepath.Base(arrayPath) {
+		klog.V(2).InfoS("NodeStageVolume: volume already staged", "device", arrayPath, "target", stagingTarget)
+		return nil
+	}
+
+	klog.V(2).InfoS("Formatting and mounting striped device", "devicePath", arrayPath, "members", devicePaths, "target", stagingTarget, "fsType", fsType)
+	if err := driver.mounter.FormatAndMount(arrayPath, stagingTarget, fsType, []string{}); err != nil {
+		return status.Errorf(codes.Internal, "failed to ensure filesystem: %v", err)
+	}
+	return nil
+}
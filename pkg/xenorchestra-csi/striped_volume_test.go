@@ -0,0 +1,220 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+)
+
+const stripedVolumeID = "dddddddd-0000-0000-0000-000000000004"
+
+var stripedSR = &payloads.StorageRepository{ID: uuid.Must(uuid.FromString("eeeeeeee-0000-0000-0000-000000000005"))}
+
+func newStripedDriver(t *testing.T) (*xenorchestraCSIDriver, *clientsMock.MockXoClient, *xoLibMock.MockVDI) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockXo := clientsMock.NewMockXoClient(ctrl)
+	mockVDI := xoLibMock.NewMockVDI(ctrl)
+	mockXo.EXPECT().VDI().Return(mockVDI).AnyTimes()
+	driver := &xenorchestraCSIDriver{
+		Name:          DriverName,
+		Version:       "test",
+		xoClient:      mockXo,
+		vdiNamePrefix: DefaultVDINamePrefix,
+		clusterTag:    DefaultClusterTag,
+		vmQueue:       NewVMOperationQueue(),
+	}
+	return driver, mockXo, mockVDI
+}
+
+func newStripeMember(index, count int, size int64) *payloads.VDI {
+	return &payloads.VDI{ID: uuid.Must(uuid.NewV4()), Size: size, Tags: []string{
+		clients.BuildTag(clients.VDITagKeyVolumeId, stripedVolumeID),
+		clients.BuildTag(clients.VDITagKeyPVName, "pvc-1"),
+		clients.BuildTag(clients.VDITagKeyStripeIndex, strconv.Itoa(index)),
+		clients.BuildTag(clients.VDITagKeyStripeCount, strconv.Itoa(count)),
+	}}
+}
+
+func TestParseStripes(t *testing.T) {
+	for value, expected := range map[string]int{"": 1, "1": 1, "4": 4, "16": 16} {
+		stripes, err := parseStripes(map[string]string{ParameterStripes: value})
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, stripes, value)
+	}
+	for _, value := range []string{"0", "17", "-2", "two"} {
+		_, err := parseStripes(map[string]string{ParameterStripes: value})
+		assert.Error(t, err, value)
+	}
+}
+
+func TestStripeMemberSize(t *testing.T) {
+	const gib = int64(1) << 30
+	assert.Equal(t, gib+clients.StripeDataOffsetBytes, stripeMemberSize(4*gib, 4))
+	// The share of each member is rounded up to whole chunks.
+	assert.Equal(t, int64(clients.StripeChunkBytes+clients.StripeDataOffsetBytes), stripeMemberSize(3*clients.StripeChunkBytes-1, 3))
+	for _, stripes := range []int{2, 3, 7} {
+		size := stripeMemberSize(5*gib+1, stripes)
+		assert.GreaterOrEqual(t, int64(stripes)*(size-clients.StripeDataOffsetBytes), 5*gib+1, "stripes=%d", stripes)
+	}
+}
+
+func TestValidateStripeMemberSize(t *testing.T) {
+	const tib = int64(1) << 40
+	require.NoError(t, validateStripeMemberSize(6*tib, 4, stripedSR))
+
+	err := validateStripeMemberSize(8*tib, 4, stripedSR)
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+	assert.ErrorContains(t, err, "at least 5")
+
+	err = validateStripeMemberSize(40*tib, 16, stripedSR)
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+}
+
+func TestStripedDeviceName(t *testing.T) {
+	assert.Equal(t, "dddddddd000000000000000000000004", stripedDeviceName("lyon/"+stripedVolumeID))
+}
+
+func TestCreateStripedVolume(t *testing.T) {
+	const capacity = int64(8) << 30
+	memberSize := stripeMemberSize(capacity, 2)
+
+	t.Run("CreatesAllMembers", func(t *testing.T) {
+		driver, _, mockVDI := newStripedDriver(t)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, clients.BuildTagFilter(clients.VDITagKeyPVName, "pvc-1")).Return(nil, nil)
+		var params []payloads.VDICreateParams
+		mockVDI.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, p payloads.VDICreateParams) (uuid.UUID, error) {
+			params = append(params, p)
+			return uuid.Must(uuid.NewV4()), nil
+		}).Times(2)
+
+		volumeID, err := driver.createStripedVolume(context.Background(), stripedSR, "pvc-1", capacity, 2)
+		require.NoError(t, err)
+		require.Len(t, params, 2)
+		for index, p := range params {
+			assert.Equal(t, memberSize, p.VirtualSize)
+			assert.Equal(t, clients.BuildStripeMemberNameLabel(DefaultVDINamePrefix, volumeID, "pvc-1", index), p.NameLabel)
+			assert.Contains(t, p.Tags, clients.BuildTag(clients.VDITagKeyVolumeId, volumeID))
+			assert.Contains(t, p.Tags, clients.BuildTag(clients.VDITagKeyStripeIndex, strconv.Itoa(index)))
+			assert.Contains(t, p.Tags, clients.BuildTag(clients.VDITagKeyStripeCount, "2"))
+			assert.Contains(t, p.Tags, DefaultClusterTag)
+		}
+	})
+
+	t.Run("CompletesInterruptedCreation", func(t *testing.T) {
+		driver, _, mockVDI := newStripedDriver(t)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, gomock.Any()).Return([]*payloads.VDI{newStripeMember(1, 2, memberSize)}, nil)
+		mockVDI.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, p payloads.VDICreateParams) (uuid.UUID, error) {
+			assert.Contains(t, p.Tags, clients.BuildTag(clients.VDITagKeyStripeIndex, "0"))
+			return uuid.Must(uuid.NewV4()), nil
+		})
+
+		volumeID, err := driver.createStripedVolume(context.Background(), stripedSR, "pvc-1", capacity, 2)
+		require.NoError(t, err)
+		assert.Equal(t, stripedVolumeID, volumeID)
+	})
+
+	t.Run("RejectsOtherLayout", func(t *testing.T) {
+		driver, _, mockVDI := newStripedDriver(t)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, gomock.Any()).Return([]*payloads.VDI{newStripeMember(0, 3, memberSize)}, nil)
+
+		_, err := driver.createStripedVolume(context.Background(), stripedSR, "pvc-1", capacity, 2)
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("RejectsOtherCapacity", func(t *testing.T) {
+		driver, _, mockVDI := newStripedDriver(t)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, gomock.Any()).Return([]*payloads.VDI{newStripeMember(0, 2, memberSize/2)}, nil)
+
+		_, err := driver.createStripedVolume(context.Background(), stripedSR, "pvc-1", capacity, 2)
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+}
+
+func TestLookupVolume(t *testing.T) {
+	t.Run("RegularVolume", func(t *testing.T) {
+		driver, mockXo, _ := newStripedDriver(t)
+		vdi := &payloads.VDI{ID: uuid.Must(uuid.NewV4())}
+		mockXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(vdi, nil)
+
+		found, members, err := driver.lookupVolume(context.Background(), "vol-1")
+		require.NoError(t, err)
+		assert.Equal(t, vdi, found)
+		assert.Nil(t, members)
+	})
+
+	t.Run("StripedVolume", func(t *testing.T) {
+		driver, mockXo, mockVDI := newStripedDriver(t)
+		vdis := []*payloads.VDI{newStripeMember(1, 2, 4), newStripeMember(0, 2, 4)}
+		mockXo.EXPECT().GetVDIByVolumeId(gomock.Any(), "lyon/"+stripedVolumeID).Return(nil, clients.ErrVolumeStriped)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, clients.BuildTagFilter(clients.VDITagKeyVolumeId, stripedVolumeID)).Return(vdis, nil)
+
+		found, members, err := driver.lookupVolume(context.Background(), "lyon/"+stripedVolumeID)
+		require.NoError(t, err)
+		assert.Nil(t, found)
+		assert.Equal(t, vdis, members)
+	})
+
+	t.Run("LastMemberOfStripedVolume", func(t *testing.T) {
+		driver, mockXo, mockVDI := newStripedDriver(t)
+		last := newStripeMember(1, 2, 4)
+		mockXo.EXPECT().GetVDIByVolumeId(gomock.Any(), stripedVolumeID).Return(last, nil)
+		mockVDI.EXPECT().GetAll(gomock.Any(), 0, gomock.Any()).Return([]*payloads.VDI{last}, nil)
+
+		found, members, err := driver.lookupVolume(context.Background(), stripedVolumeID)
+		require.NoError(t, err)
+		assert.Nil(t, found)
+		assert.Equal(t, []*payloads.VDI{last}, members)
+	})
+}
+
+func TestDeleteStripedVolume(t *testing.T) {
+	members := []*payloads.VDI{newStripeMember(0, 2, 4), newStripeMember(1, 2, 4)}
+
+	t.Run("DeletesAllMembers", func(t *testing.T) {
+		driver, mockXo, mockVDI := newStripedDriver(t)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+		mockVDI.EXPECT().Delete(gomock.Any(), members[0].ID).Return(nil)
+		mockVDI.EXPECT().Delete(gomock.Any(), members[1].ID).Return(nil)
+
+		require.NoError(t, driver.deleteStripedVolume(context.Background(), stripedVolumeID, members))
+	})
+
+	t.Run("RefusesAttachedMember", func(t *testing.T) {
+		driver, mockXo, _ := newStripedDriver(t)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), members[0]).Return(nil, nil)
+		mockXo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), members[1]).
+			Return([]*payloads.VBD{{ID: uuid.Must(uuid.NewV4()), VM: targetVMID, Attached: true}}, nil)
+
+		err := driver.deleteStripedVolume(context.Background(), stripedVolumeID, members)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
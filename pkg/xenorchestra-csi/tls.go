@@ -0,0 +1,161 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+
+	"k8s.io/klog/v2"
+)
+
+// TLSOptions configures TLS on a tcp:// CSI endpoint.
+type TLSOptions struct {
+	// CertFile and KeyFile hold the PEM server certificate and key. TLS is
+	// disabled when both are empty.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, when set, holds the PEM CA certificates that client
+	// certificates must be signed by. Clients without one are rejected.
+	ClientCAFile string
+}
+
+// Enabled reports whether TLS is configured.
+func (o TLSOptions) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != "" || o.ClientCAFile != ""
+}
+
+// serverCredentials returns the transport credentials of the gRPC server
+// serving endpoint, or nil when it is served without TLS.
+func serverCredentials(endpoint string, opts TLSOptions) (credentials.TransportCredentials, error) {
+	scheme, _, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Enabled() {
+		if scheme == tcpScheme {
+			klog.Warningf("Serving CSI calls on %s without TLS", endpoint)
+		}
+		return nil, nil
+	}
+	if scheme != tcpScheme {
+		return nil, fmt.Errorf("TLS requires a tcp:// endpoint: %s", endpoint)
+	}
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("TLS requires both a certificate and a key file")
+	}
+
+	reloader := &certificateReloader{options: opts}
+	if _, err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	klog.InfoS("Serving CSI calls over TLS", "endpoint", endpoint, "clientCertificates", opts.ClientCAFile != "")
+	return credentials.NewTLS(&tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: reloader.GetConfigForClient,
+	}), nil
+}
+
+// certificateReloader serves the TLS configuration built from its files and
+// rebuilds it on the next handshake once any of them has been modified, such
+// as when Kubernetes updates a mounted Secret.
+type certificateReloader struct {
+	options TLSOptions
+
+	mu       sync.Mutex
+	config   *tls.Config
+	modTimes []time.Time
+}
+
+// GetConfigForClient implements tls.Config.GetConfigForClient. When the
+// modified files cannot be loaded, the previous configuration is kept.
+func (r *certificateReloader) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.statFiles()
+	if err == nil && slices.EqualFunc(modTimes, r.modTimes, time.Time.Equal) {
+		return r.config, nil
+	}
+	config, err := r.reloadLocked()
+	if err != nil {
+		klog.ErrorS(err, "Failed to reload the TLS certificates, keeping the previous ones")
+		return r.config, nil
+	}
+	klog.InfoS("Reloaded the TLS certificates", "certFile", r.options.CertFile)
+	return config, nil
+}
+
+func (r *certificateReloader) reload() (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *certificateReloader) reloadLocked() (*tls.Config, error) {
+	// Stat before reading: a file replaced in between is reloaded next time.
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := tls.LoadX509KeyPair(r.options.CertFile, r.options.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the TLS certificate %s: %w", r.options.CertFile, err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+		NextProtos:   []string{"h2"},
+	}
+	if r.options.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.options.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the client CA file: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate found in %s", r.options.ClientCAFile)
+		}
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	r.config, r.modTimes = config, modTimes
+	return config, nil
+}
+
+func (r *certificateReloader) statFiles() ([]time.Time, error) {
+	var modTimes []time.Time
+	for _, file := range []string{r.options.CertFile, r.options.KeyFile, r.options.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the TLS file: %w", err)
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
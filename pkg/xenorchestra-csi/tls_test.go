@@ -0,0 +1,194 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf named commonName.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, content []byte, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func freeTCPEndpoint(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return "tcp://" + addr
+}
+
+func TestServerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	cert, key := ca.issue(t, "server-1", x509.ExtKeyUsageServerAuth)
+	start := time.Now().Add(-time.Minute)
+	writeFile(t, certFile, cert, start)
+	writeFile(t, keyFile, key, start)
+	writeFile(t, caFile, ca.pem, start)
+	opts := TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}
+
+	t.Run("Validation", func(t *testing.T) {
+		creds, err := serverCredentials("unix:///csi/csi.sock", TLSOptions{})
+		require.NoError(t, err)
+		assert.Nil(t, creds)
+
+		_, err = serverCredentials("unix:///csi/csi.sock", opts)
+		assert.ErrorContains(t, err, "tcp://")
+
+		_, err = serverCredentials("tcp://127.0.0.1:10000", TLSOptions{CertFile: certFile})
+		assert.Error(t, err)
+
+		_, err = serverCredentials("tcp://127.0.0.1:10000", TLSOptions{CertFile: certFile, KeyFile: caFile})
+		assert.Error(t, err)
+	})
+
+	t.Run("MutualTLS", func(t *testing.T) {
+		endpoint := freeTCPEndpoint(t)
+		creds, err := serverCredentials(endpoint, opts)
+		require.NoError(t, err)
+		server := NewNonBlockingGRPCServer(creds)
+		require.NoError(t, server.Start(endpoint, &xenorchestraCSIDriver{Name: DriverName, Version: "test"}, nil, &xenorchestraCSIDriver{}))
+		t.Cleanup(func() {
+			server.Stop()
+			_ = server.Wait()
+		})
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.cert)
+		call := func(clientCertificates ...tls.Certificate) error {
+			creds := credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: clientCertificates})
+			conn, err := grpc.NewClient(endpoint[len("tcp://"):], grpc.WithTransportCredentials(creds))
+			require.NoError(t, err)
+			defer conn.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = csi.NewIdentityClient(conn).GetPluginInfo(ctx, &csi.GetPluginInfoRequest{})
+			return err
+		}
+
+		assert.Error(t, call(), "clients without a certificate are rejected")
+
+		clientCert, clientKey := ca.issue(t, "client-1", x509.ExtKeyUsageClientAuth)
+		clientPair, err := tls.X509KeyPair(clientCert, clientKey)
+		require.NoError(t, err)
+		assert.NoError(t, call(clientPair))
+
+		otherCert, otherKey := newTestCA(t).issue(t, "client-2", x509.ExtKeyUsageClientAuth)
+		otherPair, err := tls.X509KeyPair(otherCert, otherKey)
+		require.NoError(t, err)
+		assert.Error(t, call(otherPair), "clients signed by another CA are rejected")
+	})
+
+	t.Run("ReloadsModifiedCertificate", func(t *testing.T) {
+		reloader := &certificateReloader{options: opts}
+		first, err := reloader.reload()
+		require.NoError(t, err)
+
+		config, err := reloader.GetConfigForClient(nil)
+		require.NoError(t, err)
+		assert.Same(t, first, config, "unmodified files are not reloaded")
+
+		cert, key := ca.issue(t, "server-2", x509.ExtKeyUsageServerAuth)
+		writeFile(t, certFile, cert, start.Add(time.Second))
+		writeFile(t, keyFile, key, start.Add(time.Second))
+		config, err = reloader.GetConfigForClient(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+		require.NoError(t, err)
+		assert.Equal(t, "server-2", leaf.Subject.CommonName)
+
+		writeFile(t, keyFile, []byte("not a key"), start.Add(2*time.Second))
+		broken, err := reloader.GetConfigForClient(nil)
+		require.NoError(t, err)
+		assert.Same(t, config, broken, "invalid files keep the previous certificate")
+	})
+}
@@ -0,0 +1,52 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"context"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/tracing"
+)
+
+// tracingInterceptor adds the volume, node and pool of the CSI call to the
+// RPC span created by the otelgrpc stats handler.
+func tracingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return handler(ctx, req)
+	}
+
+	if r, ok := req.(interface{ GetVolumeId() string }); ok && r.GetVolumeId() != "" {
+		span.SetAttributes(tracing.AttrVolumeID.String(r.GetVolumeId()))
+	}
+	if r, ok := req.(interface{ GetNodeId() string }); ok && r.GetNodeId() != "" {
+		span.SetAttributes(tracing.AttrNodeID.String(r.GetNodeId()))
+	}
+
+	resp, err := handler(ctx, req)
+
+	if r, ok := resp.(interface{ GetVolume() *csi.Volume }); ok && r.GetVolume().GetVolumeId() != "" {
+		span.SetAttributes(tracing.AttrVolumeID.String(r.GetVolume().GetVolumeId()))
+	}
+	if pool, storageType := rpcVolumeLabels(req, resp); pool != "" {
+		span.SetAttributes(tracing.AttrPoolID.String(pool), tracing.AttrStorageType.String(storageType))
+	}
+	return resp, err
+}
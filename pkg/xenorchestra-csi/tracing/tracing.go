@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing sets up OpenTelemetry tracing and provides the helpers used
+// to create the driver spans. Until Setup is called, spans are no-ops.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/vatesfr/xenorchestra-csi-driver"
+
+// Span attribute keys.
+const (
+	AttrVolumeID = attribute.Key("csi.volume_id")
+	AttrNodeID   = attribute.Key("csi.node_id")
+	// AttrRequestID is the ID assigned to the CSI call and logged with it.
+	AttrRequestID = attribute.Key("csi.request_id")
+	// AttrStorageType is the storageType of the volume, shared or local.
+	AttrStorageType = attribute.Key("csi.storage_type")
+	AttrPoolID      = attribute.Key("xo.pool_id")
+	AttrSRID        = attribute.Key("xo.sr_id")
+	AttrHostID      = attribute.Key("xo.host_id")
+	AttrVMID        = attribute.Key("xo.vm_id")
+	AttrVDIID       = attribute.Key("xo.vdi_id")
+	AttrVBDID       = attribute.Key("xo.vbd_id")
+	AttrTaskID      = attribute.Key("xo.task_id")
+)
+
+// Options configures the OTLP exporter.
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector.
+	Endpoint string
+	// Insecure disables TLS towards the collector.
+	Insecure bool
+	// SampleRatio is the fraction of new traces that are sampled. Traces
+	// started by a caller follow the caller's sampling decision.
+	SampleRatio float64
+	// ServiceVersion and NodeName are recorded as resource attributes.
+	ServiceVersion string
+	NodeName       string
+}
+
+// Setup installs a global tracer provider exporting spans to opts.Endpoint
+// and the W3C trace context propagator. The returned function flushes and
+// stops the exporter.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	exporterOptions := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOptions = append(exporterOptions, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName("xenorchestra-csi-driver"),
+		semconv.ServiceVersion(opts.ServiceVersion),
+		semconv.HostName(opts.NodeName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records *err, if any, on span and ends it. It is meant to be deferred
+// with a pointer to a named error result.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// ID returns an attribute holding id, or an invalid attribute, which
+// is dropped, when id is unset.
+func ID(key attribute.Key, id uuid.UUID) attribute.KeyValue {
+	if id == uuid.Nil {
+		return attribute.KeyValue{}
+	}
+	return key.String(id.String())
+}
@@ -0,0 +1,56 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/tracing"
+)
+
+func TestTracingInterceptor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/ControllerPublishVolume"}
+
+	ctx, span := tracer.Start(context.Background(), info.FullMethod)
+	req := &csi.ControllerPublishVolumeRequest{
+		VolumeId:      "volume-1",
+		NodeId:        "node-1",
+		VolumeContext: map[string]string{VolumeContextKeyPoolID: "pool-a", VolumeContextKeyStorageType: StorageTypeLocal},
+	}
+	_, err := tracingInterceptor(ctx, req, info, func(context.Context, any) (any, error) {
+		return &csi.ControllerPublishVolumeResponse{}, nil
+	})
+	require.NoError(t, err)
+	span.End()
+
+	require.Len(t, recorder.Ended(), 1)
+	attributes := recorder.Ended()[0].Attributes()
+	assert.Contains(t, attributes, tracing.AttrVolumeID.String("volume-1"))
+	assert.Contains(t, attributes, tracing.AttrNodeID.String("node-1"))
+	assert.Contains(t, attributes, tracing.AttrPoolID.String("pool-a"))
+	assert.Contains(t, attributes, tracing.AttrStorageType.String(StorageTypeLocal))
+}
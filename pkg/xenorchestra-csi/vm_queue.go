@@ -0,0 +1,109 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+
+	"k8s.io/klog/v2"
+)
+
+// VBD operation names used as the "operation" label of the queue metrics.
+const (
+	vmOperationAttach      = "attach"
+	vmOperationConnect     = "connect"
+	vmOperationDetach      = "detach"
+	vmOperationForceDetach = "force-detach"
+)
+
+// VMOperationQueue runs VBD operations one at a time, in arrival order, per
+// VM. XAPI rejects concurrent plug/unplug operations on the same VM, so when
+// many pods land on a node at once the operations are queued here instead of
+// failing and being retried. Operations on different VMs run in parallel.
+type VMOperationQueue struct {
+	mux sync.Mutex
+	// waiters holds, per VM, the turn channel of every queued operation. The
+	// first one is running; it is closed when the operation may start.
+	waiters map[uuid.UUID][]chan struct{}
+}
+
+func NewVMOperationQueue() *VMOperationQueue {
+	return &VMOperationQueue{
+		waiters: make(map[uuid.UUID][]chan struct{}),
+	}
+}
+
+// Do waits for the turn of vmID and runs fn. It returns ctx.Err() without
+// running fn if ctx is done while waiting.
+func (q *VMOperationQueue) Do(ctx context.Context, vmID uuid.UUID, operation string, fn func() error) error {
+	turn := make(chan struct{})
+	q.mux.Lock()
+	q.waiters[vmID] = append(q.waiters[vmID], turn)
+	depth := len(q.waiters[vmID])
+	if depth == 1 {
+		close(turn)
+	}
+	metrics.VMQueueDepth.WithLabelValues(vmID.String()).Set(float64(depth))
+	q.mux.Unlock()
+
+	start := time.Now()
+	select {
+	case <-turn:
+	case <-ctx.Done():
+		q.leave(vmID, turn)
+		return ctx.Err()
+	}
+	wait := time.Since(start)
+	metrics.VMQueueWaitSeconds.WithLabelValues(operation).Observe(wait.Seconds())
+	if depth > 1 {
+		klog.V(4).InfoS("VBD operation waited for its turn on VM", "vm", vmID, "operation", operation, "wait", wait, "queued", depth-1)
+	}
+
+	defer q.leave(vmID, turn)
+	return fn()
+}
+
+// leave removes turn from the queue of vmID and, if it was running, hands
+// the VM over to the next operation.
+func (q *VMOperationQueue) leave(vmID uuid.UUID, turn chan struct{}) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	waiters := q.waiters[vmID]
+	i := slices.Index(waiters, turn)
+	if i < 0 {
+		return
+	}
+	waiters = slices.Delete(waiters, i, i+1)
+	if len(waiters) == 0 {
+		delete(q.waiters, vmID)
+		metrics.VMQueueDepth.DeleteLabelValues(vmID.String())
+		return
+	}
+	if i == 0 {
+		close(waiters[0])
+	}
+	q.waiters[vmID] = waiters
+	metrics.VMQueueDepth.WithLabelValues(vmID.String()).Set(float64(len(waiters)))
+}
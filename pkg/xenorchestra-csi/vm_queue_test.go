@@ -0,0 +1,115 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// holdVM queues an operation on vmID that runs until the returned function is
+// called, and waits for it to start.
+func holdVM(t *testing.T, q *VMOperationQueue, vmID uuid.UUID) func() {
+	t.Helper()
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- q.Do(context.Background(), vmID, vmOperationAttach, func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	return func() {
+		close(release)
+		require.NoError(t, <-done)
+	}
+}
+
+func TestVMOperationQueue(t *testing.T) {
+	vmA := uuid.Must(uuid.NewV4())
+	vmB := uuid.Must(uuid.NewV4())
+
+	t.Run("SameVMRunsInArrivalOrder", func(t *testing.T) {
+		q := NewVMOperationQueue()
+		release := holdVM(t, q, vmA)
+
+		var (
+			mu    sync.Mutex
+			order []int
+			wg    sync.WaitGroup
+		)
+		for i := range 5 {
+			wg.Go(func() {
+				_ = q.Do(context.Background(), vmA, vmOperationConnect, func() error {
+					mu.Lock()
+					defer mu.Unlock()
+					order = append(order, i)
+					return nil
+				})
+			})
+			// Wait for the operation to be queued before submitting the next one.
+			require.Eventually(t, func() bool {
+				q.mux.Lock()
+				defer q.mux.Unlock()
+				return len(q.waiters[vmA]) == i+2
+			}, time.Second, time.Millisecond)
+		}
+
+		release()
+		wg.Wait()
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, order)
+		assert.Empty(t, q.waiters)
+	})
+
+	t.Run("OtherVMsRunInParallel", func(t *testing.T) {
+		q := NewVMOperationQueue()
+		release := holdVM(t, q, vmA)
+		defer release()
+
+		ran := false
+		require.NoError(t, q.Do(context.Background(), vmB, vmOperationDetach, func() error {
+			ran = true
+			return nil
+		}))
+		assert.True(t, ran)
+	})
+
+	t.Run("CancelledWaiterLeavesQueue", func(t *testing.T) {
+		q := NewVMOperationQueue()
+		release := holdVM(t, q, vmA)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := q.Do(ctx, vmA, vmOperationConnect, func() error {
+			t.Error("cancelled operation must not run")
+			return nil
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		release()
+		require.NoError(t, q.Do(context.Background(), vmA, vmOperationDetach, func() error { return nil }))
+		assert.Empty(t, q.waiters)
+	})
+}
@@ -0,0 +1,67 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VolumeLocks is a set of in-process locks keyed by volume ID (or volume name
+// for CreateVolume). It is shared by all RPCs so that two operations never
+// work on the same volume at the same time, e.g. a Publish racing an Unpublish.
+type VolumeLocks struct {
+	mux   sync.Mutex
+	locks map[string]struct{}
+}
+
+func NewVolumeLocks() *VolumeLocks {
+	return &VolumeLocks{
+		locks: make(map[string]struct{}),
+	}
+}
+
+// TryAcquire locks key and returns true, or returns false without blocking if
+// an operation already holds it.
+func (vl *VolumeLocks) TryAcquire(key string) bool {
+	vl.mux.Lock()
+	defer vl.mux.Unlock()
+	if _, exists := vl.locks[key]; exists {
+		return false
+	}
+	vl.locks[key] = struct{}{}
+	return true
+}
+
+// Release unlocks key.
+func (vl *VolumeLocks) Release(key string) {
+	vl.mux.Lock()
+	defer vl.mux.Unlock()
+	delete(vl.locks, key)
+}
+
+// lockVolume acquires the operation lock of key and returns its release
+// function. As recommended by the CSI spec, it returns an Aborted error when
+// another operation is already in progress for the volume; the CO retries.
+func (driver *xenorchestraCSIDriver) lockVolume(key string) (func(), error) {
+	if !driver.volumeLocks.TryAcquire(key) {
+		return nil, status.Errorf(codes.Aborted, "an operation on volume %s is already in progress", key)
+	}
+	return func() { driver.volumeLocks.Release(key) }, nil
+}
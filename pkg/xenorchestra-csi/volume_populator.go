@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/populator"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+)
+
+// volumePopulatorProvisioner serves the volume populator with the CSI
+// controller of the driver. Its calls go through the interceptors of the CSI
+// calls, which log them, lock their volume and route them between the Xen
+// Orchestra instances.
+type volumePopulatorProvisioner struct {
+	driver *xenorchestraCSIDriver
+}
+
+var _ populator.Provisioner = (*volumePopulatorProvisioner)(nil)
+
+func (p *volumePopulatorProvisioner) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	resp, err := p.driver.invoke(ctx, csi.Controller_CreateVolume_FullMethodName, req, func(ctx context.Context, req any) (any, error) {
+		return p.driver.CreateVolume(ctx, req.(*csi.CreateVolumeRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*csi.CreateVolumeResponse), nil
+}
+
+func (p *volumePopulatorProvisioner) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	resp, err := p.driver.invoke(ctx, csi.Controller_DeleteVolume_FullMethodName, req, func(ctx context.Context, req any) (any, error) {
+		return p.driver.DeleteVolume(ctx, req.(*csi.DeleteVolumeRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*csi.DeleteVolumeResponse), nil
+}
+
+// importVolumeRequest is the request of ImportVolume as seen by
+// xoClientInterceptor, which selects the client of the volume.
+type importVolumeRequest struct {
+	volumeID string
+	secrets  map[string]string
+}
+
+func (r *importVolumeRequest) GetVolumeId() string           { return r.volumeID }
+func (r *importVolumeRequest) GetSecrets() map[string]string { return r.secrets }
+
+// ImportVolume is not a CSI call, so only xoClientInterceptor runs around it.
+func (p *volumePopulatorProvisioner) ImportVolume(ctx context.Context, volumeID string, secrets map[string]string, format payloads.VDIFormat, content io.Reader, size int64) error {
+	release, err := p.driver.lockVolume(volumeID)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	info := &grpc.UnaryServerInfo{Server: p.driver, FullMethod: "ImportVolume"}
+	_, err = xoClientInterceptor(ctx, &importVolumeRequest{volumeID: volumeID, secrets: secrets}, info, func(ctx context.Context, _ any) (any, error) {
+		vdi, err := p.driver.xo(ctx).GetVDIByVolumeId(ctx, volumeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the VDI of volume %s: %w", volumeID, err)
+		}
+		return nil, p.driver.xo(ctx).VDI().Import(ctx, vdi.ID, format, content, size)
+	})
+	return err
+}
+
+// invoke runs handler through the interceptors of the CSI calls, as if the
+// gRPC server received req for method.
+func (driver *xenorchestraCSIDriver) invoke(ctx context.Context, method string, req any, handler grpc.UnaryHandler) (any, error) {
+	info := &grpc.UnaryServerInfo{Server: driver, FullMethod: method}
+	interceptors := unaryInterceptors()
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req any) (any, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	return handler(ctx, req)
+}
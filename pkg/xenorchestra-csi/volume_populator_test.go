@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+)
+
+func TestVolumePopulatorProvisioner(t *testing.T) {
+	newProvisioner := func(t *testing.T) (*volumePopulatorProvisioner, *clientsMock.MockXoClient) {
+		ctrl := gomock.NewController(t)
+		paris := clientsMock.NewMockXoClient(ctrl)
+		lyon := clientsMock.NewMockXoClient(ctrl)
+		instances := newXoInstances([]*xoInstance{newTestXoInstance("paris", paris), newTestXoInstance("lyon", lyon)})
+		driver := &xenorchestraCSIDriver{xoClient: instances.list[0].client(), instances: instances, volumeLocks: NewVolumeLocks()}
+		return &volumePopulatorProvisioner{driver: driver}, lyon
+	}
+
+	t.Run("ImportVolumeUsesTheInstanceOfTheVolume", func(t *testing.T) {
+		provisioner, lyon := newProvisioner(t)
+		vdi := &payloads.VDI{ID: uuid.Must(uuid.NewV4())}
+		mockVDI := xoLibMock.NewMockVDI(gomock.NewController(t))
+		lyon.EXPECT().GetVDIByVolumeId(gomock.Any(), "lyon/vol-1").Return(vdi, nil)
+		lyon.EXPECT().VDI().Return(mockVDI)
+		mockVDI.EXPECT().Import(gomock.Any(), vdi.ID, payloads.VDIFormatVHD, gomock.Any(), int64(4)).Return(nil)
+
+		err := provisioner.ImportVolume(context.Background(), "lyon/vol-1", nil, payloads.VDIFormatVHD, strings.NewReader("disk"), 4)
+		require.NoError(t, err)
+	})
+
+	t.Run("ImportVolumeLocksTheVolume", func(t *testing.T) {
+		provisioner, _ := newProvisioner(t)
+		release, err := provisioner.driver.lockVolume("lyon/vol-1")
+		require.NoError(t, err)
+		defer release()
+
+		err = provisioner.ImportVolume(context.Background(), "lyon/vol-1", nil, payloads.VDIFormatRaw, strings.NewReader("disk"), 4)
+		assert.Equal(t, codes.Aborted, status.Code(err))
+	})
+
+	t.Run("CallsGoThroughTheInterceptors", func(t *testing.T) {
+		provisioner, _ := newProvisioner(t)
+		// xoClientInterceptor rejects the volumes of unknown instances.
+		_, err := provisioner.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "marseille/vol-1"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `"marseille"`)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
@@ -0,0 +1,258 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package volumemigration copies the VDI of a volume to an SR of another pool,
+// which Xen Orchestra cannot migrate a VDI to, keeping the volume ID tag the
+// driver looks the volume up by so that its volume handle does not change.
+package volumemigration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+
+	"k8s.io/klog/v2"
+)
+
+// Options configures Copy.
+type Options struct {
+	// VolumeHandle is the volume handle of the volume to migrate.
+	VolumeHandle string
+	// TargetSR is the SR to copy the VDI of the volume to. It belongs to the
+	// same Xen Orchestra instance as the volume, in another pool.
+	TargetSR uuid.UUID
+}
+
+// ErrAttached is returned by Copy when the VDI of the volume is attached to a
+// VM: its content would change while it is copied.
+var ErrAttached = errors.New("the volume is attached")
+
+// Migration is a volume whose VDI was copied to the target SR.
+type Migration struct {
+	Instance string
+	// Source is the VDI the volume was copied from, which DeleteSource
+	// deletes. It is nil when the volume already was in the target SR and
+	// no VDI is marked as copied to it.
+	Source *payloads.VDI
+	// Target is the VDI of the volume in the target SR, carrying its volume
+	// ID tag.
+	Target *payloads.VDI
+	SR     *payloads.StorageRepository
+	Pool   *payloads.Pool
+
+	client clients.XoClient
+}
+
+// Copy copies the VDI of the detached volume to the target SR, checks that
+// the copy holds the same content, and moves the volume ID tag to it. The
+// source VDI is kept until DeleteSource. When the volume already is in the
+// target SR, such as when a previous migration stopped after the copy, the
+// Source is the VDI marked as copied to it, if it still exists.
+func Copy(ctx context.Context, instances []clients.XoInstance, opts Options) (*Migration, error) {
+	instance, vdi, err := clients.FindVolume(ctx, instances, opts.VolumeHandle)
+	if err != nil {
+		return nil, err
+	}
+	sr, err := instance.Client.SR().Get(ctx, opts.TargetSR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SR %s: %w", opts.TargetSR, err)
+	}
+	pool, err := instance.Client.Pool().Get(ctx, sr.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool %s: %w", sr.Pool, err)
+	}
+	migration := &Migration{Instance: instance.Name, Target: vdi, SR: sr, Pool: pool, client: instance.Client}
+
+	if vdi.SR == sr.ID {
+		klog.InfoS("Volume already in the target SR", "volumeHandle", opts.VolumeHandle, "vdiID", vdi.ID, "srID", sr.ID)
+		if migration.Source, err = migratedSource(ctx, instance.Client, vdi); err != nil {
+			return nil, err
+		}
+		return migration, nil
+	}
+	if vdi.PoolID == sr.Pool {
+		return nil, fmt.Errorf("VDI %s of volume %s already is in pool %s of SR %s, migrate it to the SR with Xen Orchestra", vdi.ID, opts.VolumeHandle, pool.NameLabel, sr.ID)
+	}
+	if err := checkDetached(ctx, instance.Client, vdi); err != nil {
+		return nil, err
+	}
+
+	_, volumeID := clients.SplitVolumeHandle(opts.VolumeHandle)
+	target, err := copyVDI(ctx, instance.Client, vdi, sr, clients.BuildTag(clients.VDITagKeyVolumeId, volumeID))
+	if err != nil {
+		return nil, err
+	}
+	migration.Source, migration.Target = vdi, target
+	return migration, nil
+}
+
+// DeleteSource deletes the VDI the volume was copied from. Call it once the
+// PersistentVolume of the volume references the pool of the target SR.
+func (m *Migration) DeleteSource(ctx context.Context) error {
+	if m.Source == nil {
+		return nil
+	}
+	if err := m.client.VDI().Delete(ctx, m.Source.ID); err != nil {
+		return fmt.Errorf("failed to delete VDI %s: %w", m.Source.ID, err)
+	}
+	klog.InfoS("Deleted the source VDI of the migrated volume", "vdiID", m.Source.ID, "newVDIID", m.Target.ID)
+	return nil
+}
+
+// migratedSource returns the VDI marked as copied to target by a previous
+// migration, or nil when there is none.
+func migratedSource(ctx context.Context, xoClient clients.XoClient, target *payloads.VDI) (*payloads.VDI, error) {
+	vdis, err := xoClient.VDI().GetAll(ctx, 0, clients.BuildTagFilter(clients.VDITagKeyMigratedTo, target.ID.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the VDIs copied to VDI %s: %w", target.ID, err)
+	}
+	switch len(vdis) {
+	case 0:
+		return nil, nil
+	case 1:
+		klog.InfoS("Found the source VDI of an interrupted migration", "vdiID", vdis[0].ID, "newVDIID", target.ID)
+		return vdis[0], nil
+	}
+	return nil, fmt.Errorf("%d VDIs are marked as copied to VDI %s, delete the sources by hand", len(vdis), target.ID)
+}
+
+// checkDetached returns ErrAttached when a VBD links vdi to a VM, plugged or
+// not.
+func checkDetached(ctx context.Context, xoClient clients.XoClient, vdi *payloads.VDI) error {
+	vbds, err := xoClient.IsVDIUsedAnywhere(ctx, vdi)
+	if err != nil {
+		return fmt.Errorf("failed to get the VBDs of VDI %s: %w", vdi.ID, err)
+	}
+	if len(vbds) > 0 {
+		return fmt.Errorf("%w to VM %s: stop the pods using it, or detach it first", ErrAttached, vbds[0].VM)
+	}
+	return nil
+}
+
+// copyVDI creates a VDI in sr with the name and tags of source but
+// volumeIDTag, copies the content of source into it, and moves volumeIDTag
+// from source to the copy once the content matches, after marking source as
+// copied to it. The copy is deleted when any step fails.
+func copyVDI(ctx context.Context, xoClient clients.XoClient, source *payloads.VDI, sr *payloads.StorageRepository, volumeIDTag string) (_ *payloads.VDI, err error) {
+	// The copy does not carry the volume ID tag before it holds the content,
+	// so that the driver keeps finding the source.
+	tags := slices.DeleteFunc(slices.Clone(source.Tags), func(tag string) bool { return tag == volumeIDTag })
+	targetID, err := xoClient.VDI().Create(ctx, payloads.VDICreateParams{
+		SRId:            sr.ID,
+		VirtualSize:     source.Size,
+		NameLabel:       source.NameLabel,
+		NameDescription: source.NameDescription,
+		Tags:            tags,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a VDI in SR %s: %w", sr.ID, err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if deleteErr := xoClient.VDI().Delete(ctx, targetID); deleteErr != nil {
+			klog.ErrorS(deleteErr, "Failed to delete the copy of a VDI after a failed migration", "vdiID", targetID)
+		}
+	}()
+
+	klog.InfoS("Copying VDI", "vdiID", source.ID, "newVDIID", targetID, "srID", sr.ID, "size", source.Size)
+	sum, err := transfer(ctx, xoClient, source, targetID)
+	if err != nil {
+		return nil, err
+	}
+	copySum, err := digest(ctx, xoClient, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sum, copySum) {
+		return nil, fmt.Errorf("the content of VDI %s does not match VDI %s: sha256 %x, expected %x", targetID, source.ID, copySum, sum)
+	}
+
+	// Once the volume ID tag is moved, the source is only found through the
+	// marker: it keeps its PV name tag, so the orphaned VDI garbage collector
+	// never deletes it.
+	migratedTo := clients.BuildTag(clients.VDITagKeyMigratedTo, targetID.String())
+	if err := xoClient.VDI().AddTag(ctx, source.ID, migratedTo); err != nil {
+		return nil, fmt.Errorf("failed to add tag %s to VDI %s: %w", migratedTo, source.ID, err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if removeErr := xoClient.VDI().RemoveTag(ctx, source.ID, migratedTo); removeErr != nil {
+			klog.ErrorS(removeErr, "Failed to remove the migration marker of a VDI, remove it by hand", "vdiID", source.ID, "tag", migratedTo)
+		}
+	}()
+
+	if slices.Contains(source.Tags, volumeIDTag) {
+		if err := xoClient.VDI().RemoveTag(ctx, source.ID, volumeIDTag); err != nil {
+			return nil, fmt.Errorf("failed to remove tag %s from VDI %s: %w", volumeIDTag, source.ID, err)
+		}
+	}
+	if err := xoClient.VDI().AddTag(ctx, targetID, volumeIDTag); err != nil {
+		if slices.Contains(source.Tags, volumeIDTag) {
+			if restoreErr := xoClient.VDI().AddTag(ctx, source.ID, volumeIDTag); restoreErr != nil {
+				klog.ErrorS(restoreErr, "Failed to restore the volume ID tag of a VDI, add it back by hand", "vdiID", source.ID, "tag", volumeIDTag)
+			}
+		}
+		return nil, fmt.Errorf("failed to add tag %s to VDI %s: %w", volumeIDTag, targetID, err)
+	}
+
+	target, err := xoClient.VDI().Get(ctx, targetID)
+	if err != nil {
+		// The copy carries the volume ID tag: keep it.
+		klog.ErrorS(err, "Failed to get the copy of a VDI", "vdiID", targetID)
+		return &payloads.VDI{ID: targetID, SR: sr.ID, PoolID: sr.Pool, Size: source.Size}, nil
+	}
+	return target, nil
+}
+
+// transfer streams the raw content of source into the VDI targetID, and
+// returns its sha256 digest. Raw exports have the known size the import
+// endpoint requires: the virtual size of the VDI.
+func transfer(ctx context.Context, xoClient clients.XoClient, source *payloads.VDI, targetID uuid.UUID) ([]byte, error) {
+	hash := sha256.New()
+	err := xoClient.VDI().Export(ctx, source.ID, payloads.VDIFormatRaw, func(content io.Reader) error {
+		return xoClient.VDI().Import(ctx, targetID, payloads.VDIFormatRaw, io.TeeReader(content, hash), source.Size)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy VDI %s to VDI %s: %w", source.ID, targetID, err)
+	}
+	return hash.Sum(nil), nil
+}
+
+// digest returns the sha256 digest of the raw content of the VDI id.
+func digest(ctx context.Context, xoClient clients.XoClient, id uuid.UUID) ([]byte, error) {
+	hash := sha256.New()
+	err := xoClient.VDI().Export(ctx, id, payloads.VDIFormatRaw, func(content io.Reader) error {
+		_, err := io.Copy(hash, content)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read back VDI %s: %w", id, err)
+	}
+	return hash.Sum(nil), nil
+}
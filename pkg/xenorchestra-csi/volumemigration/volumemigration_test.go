@@ -0,0 +1,207 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volumemigration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+)
+
+var (
+	sourceID   = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000001"))
+	targetID   = uuid.Must(uuid.FromString("aaaaaaaa-0000-0000-0000-000000000002"))
+	sourceSRID = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000001"))
+	targetSRID = uuid.Must(uuid.FromString("bbbbbbbb-0000-0000-0000-000000000002"))
+	sourcePool = uuid.Must(uuid.FromString("cccccccc-0000-0000-0000-000000000001"))
+	targetPool = uuid.Must(uuid.FromString("cccccccc-0000-0000-0000-000000000002"))
+	vmID       = uuid.Must(uuid.FromString("dddddddd-0000-0000-0000-000000000001"))
+)
+
+const (
+	volumeIDTag   = "k8s:volumeId:vol-1"
+	migratedToTag = "k8s:migratedTo:aaaaaaaa-0000-0000-0000-000000000002"
+)
+
+type testInstance struct {
+	clients.XoInstance
+	xo  *clientsMock.MockXoClient
+	vdi *xoLibMock.MockVDI
+	// disks holds the content of the VDIs, exported and imported as is.
+	disks map[uuid.UUID]string
+}
+
+func newTestInstance(t *testing.T) *testInstance {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	i := &testInstance{disks: map[uuid.UUID]string{sourceID: "disk content"}}
+	i.XoInstance, i.xo, i.vdi = stub.NewXoInstance(ctrl, "")
+	sr := xoLibMock.NewMockSR(ctrl)
+	pool := xoLibMock.NewMockPool(ctrl)
+	i.xo.EXPECT().SR().Return(sr).AnyTimes()
+	i.xo.EXPECT().Pool().Return(pool).AnyTimes()
+	sr.EXPECT().Get(gomock.Any(), targetSRID).Return(&payloads.StorageRepository{ID: targetSRID, Pool: targetPool}, nil).AnyTimes()
+	pool.EXPECT().Get(gomock.Any(), targetPool).Return(&payloads.Pool{ID: targetPool, NameLabel: "lyon"}, nil).AnyTimes()
+	i.vdi.EXPECT().Export(gomock.Any(), gomock.Any(), payloads.VDIFormatRaw, gomock.Any()).
+		DoAndReturn(func(_ context.Context, id uuid.UUID, _ payloads.VDIFormat, fn func(io.Reader) error) error {
+			return fn(strings.NewReader(i.disks[id]))
+		}).AnyTimes()
+	return i
+}
+
+func (i *testInstance) expectImport(store func(string) string) {
+	i.vdi.EXPECT().Import(gomock.Any(), targetID, payloads.VDIFormatRaw, gomock.Any(), int64(12)).
+		DoAndReturn(func(_ context.Context, id uuid.UUID, _ payloads.VDIFormat, content io.Reader, _ int64) error {
+			data, err := io.ReadAll(content)
+			i.disks[id] = store(string(data))
+			return err
+		})
+}
+
+func TestCopy(t *testing.T) {
+	source := &payloads.VDI{
+		ID: sourceID, SR: sourceSRID, PoolID: sourcePool, Size: 12,
+		NameLabel: "csi-vol-1", Tags: []string{volumeIDTag, "k8s:pvName:pvc-1", "k8s-cluster"},
+	}
+	opts := Options{VolumeHandle: "vol-1", TargetSR: targetSRID}
+
+	t.Run("CopiesAndMovesTheVolumeIDTag", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(source, nil)
+		i.xo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), source).Return(nil, nil)
+		i.vdi.EXPECT().Create(gomock.Any(), payloads.VDICreateParams{
+			SRId: targetSRID, VirtualSize: 12, NameLabel: "csi-vol-1",
+			Tags: []string{"k8s:pvName:pvc-1", "k8s-cluster"},
+		}).Return(targetID, nil)
+		i.expectImport(func(data string) string { return data })
+		gomock.InOrder(
+			i.vdi.EXPECT().AddTag(gomock.Any(), sourceID, migratedToTag).Return(nil),
+			i.vdi.EXPECT().RemoveTag(gomock.Any(), sourceID, volumeIDTag).Return(nil),
+			i.vdi.EXPECT().AddTag(gomock.Any(), targetID, volumeIDTag).Return(nil),
+		)
+		i.vdi.EXPECT().Get(gomock.Any(), targetID).Return(&payloads.VDI{ID: targetID, SR: targetSRID}, nil)
+
+		migration, err := Copy(context.Background(), []clients.XoInstance{i.XoInstance}, opts)
+		require.NoError(t, err)
+		assert.Equal(t, "disk content", i.disks[targetID])
+		assert.Same(t, source, migration.Source)
+		assert.Equal(t, targetID, migration.Target.ID)
+		assert.Equal(t, "lyon", migration.Pool.NameLabel)
+
+		i.vdi.EXPECT().Delete(gomock.Any(), sourceID).Return(nil)
+		require.NoError(t, migration.DeleteSource(context.Background()))
+	})
+
+	t.Run("DeletesTheCopyWhenItDiffers", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(source, nil)
+		i.xo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), source).Return(nil, nil)
+		i.vdi.EXPECT().Create(gomock.Any(), gomock.Any()).Return(targetID, nil)
+		i.expectImport(func(string) string { return "corrupted" })
+		i.vdi.EXPECT().Delete(gomock.Any(), targetID).Return(nil)
+
+		_, err := Copy(context.Background(), []clients.XoInstance{i.XoInstance}, opts)
+		require.ErrorContains(t, err, "does not match")
+	})
+
+	t.Run("RestoresTheTagWhenMovingItFails", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(source, nil)
+		i.xo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), source).Return(nil, nil)
+		i.vdi.EXPECT().Create(gomock.Any(), gomock.Any()).Return(targetID, nil)
+		i.expectImport(func(data string) string { return data })
+		i.vdi.EXPECT().AddTag(gomock.Any(), sourceID, migratedToTag).Return(nil)
+		i.vdi.EXPECT().RemoveTag(gomock.Any(), sourceID, volumeIDTag).Return(nil)
+		i.vdi.EXPECT().AddTag(gomock.Any(), targetID, volumeIDTag).Return(errors.New("xo down"))
+		i.vdi.EXPECT().AddTag(gomock.Any(), sourceID, volumeIDTag).Return(nil)
+		i.vdi.EXPECT().RemoveTag(gomock.Any(), sourceID, migratedToTag).Return(nil)
+		i.vdi.EXPECT().Delete(gomock.Any(), targetID).Return(nil)
+
+		_, err := Copy(context.Background(), []clients.XoInstance{i.XoInstance}, opts)
+		require.ErrorContains(t, err, "xo down")
+	})
+
+	t.Run("RefusesAttachedVolume", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(source, nil)
+		i.xo.EXPECT().IsVDIUsedAnywhere(gomock.Any(), source).Return([]*payloads.VBD{{VM: vmID}}, nil)
+
+		_, err := Copy(context.Background(), []clients.XoInstance{i.XoInstance}, opts)
+		require.ErrorIs(t, err, ErrAttached)
+		assert.Contains(t, err.Error(), vmID.String())
+	})
+
+	t.Run("RefusesTargetInTheSamePool", func(t *testing.T) {
+		i := newTestInstance(t)
+		samePool := *source
+		samePool.PoolID = targetPool
+		i.xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(&samePool, nil)
+
+		_, err := Copy(context.Background(), []clients.XoInstance{i.XoInstance}, opts)
+		require.ErrorContains(t, err, "already is in pool lyon")
+	})
+
+	t.Run("ResumesVolumeInTheTargetSR", func(t *testing.T) {
+		i := newTestInstance(t)
+		copied := &payloads.VDI{ID: targetID, SR: targetSRID, PoolID: targetPool}
+		i.xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(copied, nil)
+		i.vdi.EXPECT().GetAll(gomock.Any(), 0, clients.BuildTagFilter(clients.VDITagKeyMigratedTo, targetID.String())).Return(nil, nil)
+
+		migration, err := Copy(context.Background(), []clients.XoInstance{i.XoInstance}, opts)
+		require.NoError(t, err)
+		assert.Nil(t, migration.Source)
+		assert.Same(t, copied, migration.Target)
+		require.NoError(t, migration.DeleteSource(context.Background()), "there is no source to delete")
+	})
+
+	t.Run("ResumesDeletionOfTheMarkedSource", func(t *testing.T) {
+		i := newTestInstance(t)
+		copied := &payloads.VDI{ID: targetID, SR: targetSRID, PoolID: targetPool}
+		marked := &payloads.VDI{ID: sourceID, SR: sourceSRID, Tags: []string{migratedToTag, "k8s:pvName:pvc-1"}}
+		i.xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(copied, nil)
+		i.vdi.EXPECT().GetAll(gomock.Any(), 0, clients.BuildTagFilter(clients.VDITagKeyMigratedTo, targetID.String())).
+			Return([]*payloads.VDI{marked}, nil)
+
+		migration, err := Copy(context.Background(), []clients.XoInstance{i.XoInstance}, opts)
+		require.NoError(t, err)
+		assert.Same(t, marked, migration.Source)
+
+		i.vdi.EXPECT().Delete(gomock.Any(), sourceID).Return(nil)
+		require.NoError(t, migration.DeleteSource(context.Background()))
+	})
+
+	t.Run("VolumeNotFound", func(t *testing.T) {
+		i := newTestInstance(t)
+		i.xo.EXPECT().GetVDIByVolumeId(gomock.Any(), "vol-1").Return(nil, clients.ErrVolumeNotFound)
+
+		_, err := Copy(context.Background(), []clients.XoInstance{i.XoInstance}, opts)
+		require.ErrorIs(t, err, clients.ErrVolumeNotFound)
+	})
+}
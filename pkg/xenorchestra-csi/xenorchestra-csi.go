@@ -17,14 +17,24 @@ package xenorchestracsi
 
 import (
 	"context"
+	"fmt"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 
 	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/orphan"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/populator"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/replication"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/tracing"
 	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
 
 	kube "k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
@@ -41,19 +51,63 @@ type xenorchestraCSIDriver struct {
 	NodeID            string
 	Version           string
 	endpoint          string
+	tls               TLSOptions
 	vdiNamePrefix     string
 	clusterTag        string
 	kubernetesPoolTag string
+	// forceDetachPausedSuspended allows taking volumes over from Paused or
+	// Suspended VMs, in addition to Halted ones.
+	forceDetachPausedSuspended bool
 	csi.UnimplementedControllerServer
 	csi.UnimplementedNodeServer
 	csi.UnimplementedIdentityServer
 	nodeMetadata clients.NodeMetadataGetter
 	xoClient     clients.XoClient
 	mounter      clients.Mounter
+	kubeClient   kube.Interface
+	recorder     record.EventRecorder
+	volumeLocks  *VolumeLocks
+	vmQueue      *VMOperationQueue
+	// instances are the Xen Orchestra instances of the configuration file.
+	// Each reloads its configuration and runs its event feed. xoClient is the
+	// client of the first one. It is nil when the driver is built with a
+	// fixed client.
+	instances *xoInstances
+	// secretXoClients serves the calls passing Xen Orchestra credentials in
+	// their CSI secrets. It is nil when the driver is built with a fixed
+	// client, in which case these credentials are ignored.
+	secretXoClients *secretXoClients
+
+	// readiness runs the checks of Probe.
+	readiness *readinessProbe
+
+	// shutdownDrainTimeout bounds the in-flight calls once Run is asked to stop.
+	shutdownDrainTimeout time.Duration
+	// metricsAddress is the address of the Prometheus endpoint, empty if disabled.
+	metricsAddress string
+	// managedVolumes reports the PersistentVolumes of the driver, nil if
+	// disabled.
+	managedVolumes *managedVolumesCollector
+	// tracing configures the OTLP exporter; tracing is disabled when its
+	// endpoint is empty.
+	tracing tracing.Options
+
+	leaderElectionNamespace string
+	orphanGCInterval        time.Duration
+	orphanGCOptions         orphan.Options
+	orphanCollector         *orphan.Collector
+
+	replicationOptions replication.Options
+	replicator         *replication.Replicator
+
+	volumePopulatorInterval time.Duration
+	volumePopulator         *populator.Populator
 }
 
 // NewDriverWithDependencies is the internal constructor shared by NewDriver and NewStubDriver.
-func NewDriverWithDependencies(options *DriverOptions, nodeMetadata clients.NodeMetadataGetter, xoClient clients.XoClient, mounter clients.Mounter) Driver {
+// kubeClient may be nil, in which case Kubernetes events are dropped and the
+// background workers that need the Kubernetes API are disabled.
+func NewDriverWithDependencies(options *DriverOptions, nodeMetadata clients.NodeMetadataGetter, xoClient clients.XoClient, mounter clients.Mounter, kubeClient kube.Interface) Driver {
 	if options.DriverName == "" {
 		klog.Fatal("no driver name provided")
 	}
@@ -68,51 +122,142 @@ func NewDriverWithDependencies(options *DriverOptions, nodeMetadata clients.Node
 	klog.Infof("VDI name prefix: %q", options.VDINamePrefix)
 	klog.Infof("Cluster tag: %q", options.ClusterTag)
 	klog.Infof("Kubernetes pool tag: %q", options.KubernetesPoolTag)
-	return &xenorchestraCSIDriver{
-		Name:              options.DriverName,
-		Version:           driverVersion,
-		endpoint:          options.Endpoint,
-		vdiNamePrefix:     options.VDINamePrefix,
-		clusterTag:        options.ClusterTag,
-		kubernetesPoolTag: options.KubernetesPoolTag,
-		nodeMetadata:      nodeMetadata,
-		xoClient:          xoClient,
-		mounter:           mounter,
+	klog.Infof("Force-detach from Paused/Suspended VMs: %t", options.ForceDetachPausedSuspended)
+	recorder := NewEventRecorder(kubeClient, options.DriverName)
+	driver := &xenorchestraCSIDriver{
+		Name:     options.DriverName,
+		Version:  driverVersion,
+		endpoint: options.Endpoint,
+		tls: TLSOptions{
+			CertFile:     options.TLSCertFile,
+			KeyFile:      options.TLSKeyFile,
+			ClientCAFile: options.TLSClientCAFile,
+		},
+		vdiNamePrefix:              options.VDINamePrefix,
+		clusterTag:                 options.ClusterTag,
+		kubernetesPoolTag:          options.KubernetesPoolTag,
+		forceDetachPausedSuspended: options.ForceDetachPausedSuspended,
+		nodeMetadata:               nodeMetadata,
+		xoClient:                   xoClient,
+		mounter:                    mounter,
+		kubeClient:                 kubeClient,
+		recorder:                   recorder,
+		volumeLocks:                NewVolumeLocks(),
+		vmQueue:                    NewVMOperationQueue(),
+		shutdownDrainTimeout:       options.ShutdownDrainTimeout,
+		metricsAddress:             options.MetricsAddress,
+		tracing: tracing.Options{
+			Endpoint:       options.TracingEndpoint,
+			Insecure:       options.TracingInsecure,
+			SampleRatio:    options.TracingSampleRatio,
+			ServiceVersion: driverVersion,
+			NodeName:       options.NodeName,
+		},
+		leaderElectionNamespace: options.LeaderElectionNamespace,
+	}
+
+	var probedMetadata clients.NodeMetadataGetter
+	if options.ProbeNodeMetadata {
+		probedMetadata = nodeMetadata
+	}
+	driver.readiness = newReadinessProbe(xoClient, probedMetadata, options.ProbeCacheTTL)
+
+	if options.ManagedVolumesMetric {
+		switch {
+		case options.MetricsAddress == "":
+			klog.Warning("Managed volumes metric disabled: it requires --metrics-address")
+		case kubeClient == nil:
+			klog.Warning("Managed volumes metric disabled: no Kubernetes client available")
+		default:
+			driver.managedVolumes = &managedVolumesCollector{driverName: options.DriverName}
+			metrics.Registry.MustRegister(driver.managedVolumes)
+		}
+	}
+
+	if options.OrphanGCInterval > 0 {
+		switch {
+		case kubeClient == nil:
+			klog.Warning("Orphaned VDI garbage collector disabled: no Kubernetes client available")
+		case options.ClusterTag == "":
+			klog.Warning("Orphaned VDI garbage collector disabled: it requires a non-empty --cluster-tag")
+		default:
+			klog.Infof("Orphaned VDI garbage collector: interval=%s gracePeriod=%s delete=%t",
+				options.OrphanGCInterval, options.OrphanGCGracePeriod, options.OrphanGCDelete)
+			driver.orphanGCInterval = options.OrphanGCInterval
+			driver.orphanGCOptions = orphan.Options{
+				DriverName:  options.DriverName,
+				ClusterTag:  options.ClusterTag,
+				GracePeriod: options.OrphanGCGracePeriod,
+				Delete:      options.OrphanGCDelete,
+			}
+			driver.orphanCollector = orphan.NewCollector([]clients.XoClient{xoClient}, kubeClient, recorder, driver.orphanGCOptions)
+		}
+	}
+
+	if options.ReplicationInterval > 0 {
+		switch {
+		case kubeClient == nil:
+			klog.Warning("Volume replication disabled: no Kubernetes client available")
+		case options.ClusterTag == "":
+			klog.Warning("Volume replication disabled: it requires a non-empty --cluster-tag")
+		default:
+			klog.Infof("Volume replication: interval=%s", options.ReplicationInterval)
+			driver.replicationOptions = replication.Options{
+				DriverName:    options.DriverName,
+				ClusterTag:    options.ClusterTag,
+				VDINamePrefix: options.VDINamePrefix,
+				Interval:      options.ReplicationInterval,
+			}
+			driver.replicator = replication.NewReplicator([]clients.XoClient{xoClient}, kubeClient, recorder, driver.replicationOptions)
+		}
 	}
+	return driver
 }
 
 func NewDriver(options *DriverOptions) Driver {
 	// Configure Kubernetes client
-	kubeConfig, err := rest.InClusterConfig()
+	kclient, err := NewKubeClient("")
 	if err != nil {
-		klog.Fatalf("failed to get in-cluster config: %v", err)
+		klog.Fatalf("%v", err)
 	}
-	kclient, err := kube.NewForConfig(kubeConfig)
-	if err != nil {
-		klog.Fatalf("failed to create kubernetes client: %v", err)
+
+	xoOptions := clients.XoClientOptions{
+		AttachTimeout: options.XoAttachTimeout,
+		TaskTimeout:   options.XoTaskTimeout,
+		PollInterval:  options.XoPollInterval,
+		CacheTTL:      options.XoCacheTTL,
 	}
+	klog.Infof("XO client: attachTimeout=%s taskTimeout=%s pollInterval=%s cacheTTL=%s eventFeed=%t",
+		options.XoAttachTimeout, options.XoTaskTimeout, options.XoPollInterval, options.XoCacheTTL, options.XoEventFeed)
 
 	// Try to load XO config from mounted file first, then fallback to env
-	xoConfig, err := LoadXOConfigFromFile(options.ConfigFile)
+	instanceConfigs, err := LoadXoInstances(options.ConfigFile)
 	if err != nil {
-		klog.Warningf("Failed to load config from file %s: %v, falling back to environment variables", options.ConfigFile, err)
-		// Load XO config from environment variables if file loading fails
-		xoConfig, err = xok8s.LoadXOConfigFromEnv()
+		klog.Fatalf("%v", err)
+	}
+	var list []*xoInstance
+	for _, config := range instanceConfigs {
+		reloader, err := newXoConfigReloader(options.ConfigFile, config, options.XoConfigReloadInterval, xoClientBuilder(xoOptions, options.XoEventFeed))
 		if err != nil {
-			klog.Fatalf("Failed to load config from environment variables: %v. Please ensure either a valid config file is mounted or the required environment variables (XOA_URL and XOA_TOKEN) are set", err)
+			klog.Fatalf("%v", err)
 		}
+		if config.Name != "" {
+			klog.Infof("Xen Orchestra instance %q: url=%s pools=%v", config.Name, config.URL, config.Pools)
+		}
+		list = append(list, &xoInstance{name: config.Name, pools: config.Pools, config: reloader})
 	}
-	xoSDKClient, err := xok8s.NewXOClient(&xoConfig)
-	if err != nil {
-		klog.Fatalf("failed to create Xen Orchestra client: %v", err)
-	}
+	instances := newXoInstances(list)
 
 	// Select the NodeMetadata implementation based on the configured source.
 	var nodeMetadataGetter clients.NodeMetadataGetter
 	switch options.NodeMetadataSource {
 	case NodeMetadataSourceXoAPI:
 		klog.Info("Node metadata source: xo-api (CCM not required)")
-		nodeMetadataGetter = clients.NewNodeMetadataFromXoClient(kclient, xoSDKClient, options.NodeName)
+		fromXoClient := clients.NewNodeMetadataFromXoClient(kclient, options.NodeName)
+		for _, instance := range instances.list {
+			instance.config.onReload = fromXoClient.AddXoClient(instance.name, instance.config.initial.sdkClient)
+		}
+		nodeMetadataGetter = fromXoClient
 	default:
 		if options.NodeMetadataSource != NodeMetadataSourceKubernetes {
 			klog.Fatalf("Unknown node-metadata-source %q", options.NodeMetadataSource)
@@ -121,16 +266,176 @@ func NewDriver(options *DriverOptions) Driver {
 		nodeMetadataGetter = clients.NewNodeMetadataFromKubernetes(kclient, options.NodeName)
 	}
 
-	return NewDriverWithDependencies(options, nodeMetadataGetter, clients.NewXoClient(xoSDKClient.Client), clients.NewSafeMounter())
+	driver := NewDriverWithDependencies(options, nodeMetadataGetter, instances.list[0].client(), clients.NewSafeMounter(), kclient)
+	d := driver.(*xenorchestraCSIDriver)
+	d.instances = instances
+	d.secretXoClients = newSecretXoClients(func(config xok8s.XoConfig) (clients.XoClient, error) {
+		generation, err := xoClientBuilder(xoOptions, false)(config)
+		if err != nil {
+			return nil, err
+		}
+		return generation.Client, nil
+	})
+	var xoClients []clients.XoClient
+	for i, instance := range instances.list {
+		xoClients = append(xoClients, instance.client())
+		if i > 0 {
+			d.readiness.add(fmt.Sprintf("Xen Orchestra API (%s)", instance.name), instance.client().Ping)
+		}
+		d.readiness.add(instanceCheckName("Xen Orchestra configuration", instance.name), func(context.Context) error { return instance.config.Err() })
+	}
+	if d.orphanCollector != nil && len(xoClients) > 1 {
+		d.orphanCollector = orphan.NewCollector(xoClients, d.kubeClient, d.recorder, d.orphanGCOptions)
+	}
+	if d.replicator != nil && len(xoClients) > 1 {
+		d.replicator = replication.NewReplicator(xoClients, d.kubeClient, d.recorder, d.replicationOptions)
+	}
+	if options.VolumePopulatorInterval > 0 {
+		dynamicClient, err := NewDynamicClient("")
+		if err != nil {
+			klog.Fatalf("%v", err)
+		}
+		klog.Infof("Volume populator: interval=%s maxConcurrency=%d", options.VolumePopulatorInterval, options.VolumePopulatorMaxConcurrency)
+		d.volumePopulatorInterval = options.VolumePopulatorInterval
+		d.volumePopulator = populator.NewPopulator(kclient, dynamicClient, &volumePopulatorProvisioner{driver: d}, d.recorder, populator.Options{
+			DriverName:     options.DriverName,
+			MaxConcurrency: options.VolumePopulatorMaxConcurrency,
+		})
+	}
+	return driver
+}
+
+// instanceCheckName returns the name of the Probe check of the Xen Orchestra
+// instance named instance.
+func instanceCheckName(check, instance string) string {
+	if instance == "" {
+		return check
+	}
+	return fmt.Sprintf("%s (%s)", check, instance)
 }
 
-// Run implements Driver.
+// Run implements Driver. It serves the CSI services until ctx is done or the
+// process receives SIGTERM or SIGINT. It then stops accepting calls, lets the
+// in-flight ones finish for at most the drain timeout, cancels the remaining
+// ones and stops the background workers before returning.
 func (driver *xenorchestraCSIDriver) Run(ctx context.Context) error {
-	// controllerServer := driver.GetController()
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	creds, err := serverCredentials(driver.endpoint, driver.tls)
+	if err != nil {
+		return err
+	}
+
+	if driver.tracing.Endpoint != "" {
+		shutdown, err := tracing.Setup(ctx, driver.tracing)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				klog.ErrorS(err, "Failed to flush traces")
+			}
+		}()
+		klog.Infof("Tracing: endpoint=%s sampleRatio=%g", driver.tracing.Endpoint, driver.tracing.SampleRatio)
+	}
+
+	// The XO event feed and the metrics endpoint keep running while the server
+	// drains, since in-flight calls wait on the former and report to the
+	// latter. They are stopped once the server has stopped.
+	serving, stopServing := context.WithCancel(context.WithoutCancel(ctx))
+	var workers sync.WaitGroup
+	defer func() {
+		stopServing()
+		workers.Wait()
+	}()
+
+	if driver.instances != nil {
+		for _, instance := range driver.instances.list {
+			workers.Go(func() { instance.config.Run(serving) })
+		}
+	}
 
-	// Start the nonblocking GRPC
-	grpc := NewNonBlockingGRPCServer()
-	grpc.Start(driver.endpoint, driver, driver, driver)
+	if driver.metricsAddress != "" {
+		workers.Go(func() {
+			if err := metrics.Serve(serving, driver.metricsAddress); err != nil {
+				klog.ErrorS(err, "Metrics endpoint stopped")
+			}
+		})
+	}
+
+	// The PersistentVolumes are reported by the leader only, as long as the
+	// metrics endpoint serves, so that they are not counted once per replica.
+	if driver.managedVolumes != nil {
+		workers.Go(func() {
+			runLeaderElected(serving, driver.kubeClient, driver.leaderElectionNamespace, leaseName(driver.Name, "managed-volumes"), func(ctx context.Context) {
+				driver.managedVolumes.Run(ctx, driver.kubeClient)
+			})
+		})
+	}
+
+	// The garbage collector stops right away, releasing its Lease to another
+	// replica.
+	if driver.orphanCollector != nil {
+		workers.Go(func() {
+			runLeaderElected(ctx, driver.kubeClient, driver.leaderElectionNamespace, leaseName(driver.Name, "orphan-gc"), func(ctx context.Context) {
+				driver.orphanCollector.Run(ctx, driver.orphanGCInterval)
+			})
+		})
+	}
+
+	// The replicator cancels its copies right away, and the next leader
+	// deletes the incomplete replicas.
+	if driver.replicator != nil {
+		workers.Go(func() {
+			runLeaderElected(ctx, driver.kubeClient, driver.leaderElectionNamespace, leaseName(driver.Name, "replication"), func(ctx context.Context) {
+				driver.replicator.Run(ctx)
+			})
+		})
+	}
 
-	return nil
+	// The populator cancels its imports right away, deleting their volumes,
+	// so that another replica starts them over.
+	if driver.volumePopulator != nil {
+		workers.Go(func() {
+			runLeaderElected(ctx, driver.kubeClient, driver.leaderElectionNamespace, leaseName(driver.Name, "volume-populator"), func(ctx context.Context) {
+				driver.volumePopulator.Run(ctx, driver.volumePopulatorInterval)
+			})
+		})
+	}
+
+	server := NewNonBlockingGRPCServer(creds)
+	if err := server.Start(driver.endpoint, driver, driver, driver); err != nil {
+		return err
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.Wait() }()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	klog.InfoS("Shutting down, draining in-flight CSI calls", "timeout", driver.shutdownDrainTimeout)
+	drainServer(server, driver.shutdownDrainTimeout)
+	return <-served
+}
+
+// drainServer stops server gracefully, cancelling the calls still running
+// after timeout.
+func drainServer(server NonBlockingGRPCServer, timeout time.Duration) {
+	drained := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		klog.InfoS("Drain timeout expired, cancelling the remaining CSI calls", "timeout", timeout)
+		server.Stop()
+		<-drained
+	}
 }
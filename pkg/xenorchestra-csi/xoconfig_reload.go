@@ -0,0 +1,296 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	"k8s.io/klog/v2"
+)
+
+// xoConfigCheckTimeout bounds the authenticated call checking a reloaded
+// configuration before it is applied.
+const xoConfigCheckTimeout = 30 * time.Second
+
+// xoGeneration is the client built from one Xen Orchestra configuration.
+type xoGeneration struct {
+	*clients.XoClientGeneration
+	sdkClient *xok8s.XoClient
+	// events is the event feed of the client, nil when disabled.
+	events *clients.XoEvents
+}
+
+// newXoGeneration builds the client of config.
+type newXoGeneration func(config xok8s.XoConfig) (*xoGeneration, error)
+
+// xoClientBuilder returns the function building the clients of NewDriver.
+func xoClientBuilder(options clients.XoClientOptions, eventFeed bool) newXoGeneration {
+	return func(config xok8s.XoConfig) (*xoGeneration, error) {
+		sdkClient, err := xok8s.NewXOClient(&config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Xen Orchestra client: %w", err)
+		}
+		generation := &xoGeneration{sdkClient: sdkClient}
+		if eventFeed {
+			generation.events = clients.NewXoEvents(config)
+			options.Events = generation.events
+		}
+		generation.XoClientGeneration = &clients.XoClientGeneration{Client: clients.NewXoClientWithOptions(sdkClient.Client, options)}
+		return generation, nil
+	}
+}
+
+// xoConfigReloader watches the configuration of a Xen Orchestra instance in
+// the configuration file and, once it changes, swaps the driver XO client for
+// one built from the new content. The new configuration must answer an
+// authenticated call first: otherwise the previous client is kept and the
+// error is reported by logs, metrics and Probe until a working configuration
+// is applied.
+type xoConfigReloader struct {
+	configFile string
+	// instance is the name of the watched instance, empty for the single
+	// unnamed instance of a configuration file without an instances list.
+	instance string
+	interval time.Duration
+	build    newXoGeneration
+	client   *clients.ReloadableXoClient
+	// onReload is called with the SDK client of every applied configuration.
+	onReload func(*xok8s.XoClient)
+
+	mu      sync.Mutex
+	initial *xoGeneration
+	applied []byte
+	err     error
+}
+
+// newXoConfigReloader builds the initial client from initial, loaded from
+// configFile or from the environment when the file cannot be read. In the
+// latter case, or when interval is zero, the configuration is never reloaded.
+func newXoConfigReloader(configFile string, initial XoInstanceConfig, interval time.Duration, build newXoGeneration) (*xoConfigReloader, error) {
+	r := &xoConfigReloader{configFile: configFile, instance: initial.Name, interval: interval, build: build}
+	generation, err := build(initial.XoConfig)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := r.read(); err != nil {
+		r.interval = 0
+	}
+	applied, err := yaml.Marshal(initial.XoConfig)
+	if err != nil {
+		return nil, err
+	}
+	r.applied, r.initial = applied, generation
+	r.client = clients.NewReloadableXoClient(generation.XoClientGeneration)
+	metrics.XOConfigLastReloadSuccessful.WithLabelValues(r.instance).Set(1)
+	return r, nil
+}
+
+// read returns the configuration of the instance in the configuration file,
+// and its serialized form to detect changes.
+func (r *xoConfigReloader) read() ([]byte, xok8s.XoConfig, error) {
+	instances, err := LoadXoInstancesFromFile(r.configFile)
+	if err != nil {
+		return nil, xok8s.XoConfig{}, err
+	}
+	for _, instance := range instances {
+		if instance.Name == r.instance {
+			content, err := yaml.Marshal(instance.XoConfig)
+			return content, instance.XoConfig, err
+		}
+	}
+	return nil, xok8s.XoConfig{}, fmt.Errorf("instance %q is no longer listed in %s, restart the driver to remove it", r.instance, r.configFile)
+}
+
+// Client returns the client following the reloads.
+func (r *xoConfigReloader) Client() *clients.ReloadableXoClient {
+	return r.client
+}
+
+// Err returns why the current content of the configuration file could not be
+// applied, or nil when it is in use.
+func (r *xoConfigReloader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Run runs the event feed of the current client and checks the configuration
+// file every interval until ctx is done.
+func (r *xoConfigReloader) Run(ctx context.Context) {
+	var feeds sync.WaitGroup
+	defer feeds.Wait()
+	r.initial.Stop = r.startEvents(ctx, &feeds, r.initial.events)
+
+	if r.interval <= 0 {
+		<-ctx.Done()
+		return
+	}
+	klog.InfoS("Watching the Xen Orchestra configuration", "file", r.configFile, "instance", r.instance, "interval", r.interval)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reload(ctx, func(events *clients.XoEvents) func() { return r.startEvents(ctx, &feeds, events) })
+		}
+	}
+}
+
+// startEvents runs events, if any, until ctx is done or the returned function
+// is called.
+func (r *xoConfigReloader) startEvents(ctx context.Context, feeds *sync.WaitGroup, events *clients.XoEvents) func() {
+	if events == nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	feeds.Go(func() { events.Run(ctx) })
+	return cancel
+}
+
+// reload applies the configuration of the instance if it differs from the one
+// in use.
+func (r *xoConfigReloader) reload(ctx context.Context, startEvents func(*clients.XoEvents) func()) {
+	content, config, err := r.read()
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.mu.Lock()
+	unchanged := bytes.Equal(content, r.applied)
+	r.mu.Unlock()
+	if unchanged {
+		// A rejected change may have been reverted.
+		r.setErr(nil)
+		return
+	}
+
+	generation, err := r.check(ctx, config)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	generation.Stop = startEvents(generation.events)
+	r.client.Swap(generation.XoClientGeneration)
+	if r.onReload != nil {
+		r.onReload(generation.sdkClient)
+	}
+
+	r.mu.Lock()
+	r.applied = content
+	r.mu.Unlock()
+	r.setErr(nil)
+	metrics.XOConfigReloads.WithLabelValues(r.instance, metrics.ResultSuccess).Inc()
+	klog.InfoS("Reloaded the Xen Orchestra configuration", "file", r.configFile, "instance", r.instance)
+}
+
+// check builds the client of config and makes sure that Xen Orchestra accepts
+// it.
+func (r *xoConfigReloader) check(ctx context.Context, config xok8s.XoConfig) (*xoGeneration, error) {
+	generation, err := r.build(config)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, xoConfigCheckTimeout)
+	defer cancel()
+	if err := generation.Client.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("Xen Orchestra rejected the new configuration: %w", err)
+	}
+	return generation, nil
+}
+
+func (r *xoConfigReloader) fail(err error) {
+	metrics.XOConfigReloads.WithLabelValues(r.instance, metrics.ResultError).Inc()
+	klog.ErrorS(err, "Failed to reload the Xen Orchestra configuration, keeping the previous one", "file", r.configFile, "instance", r.instance)
+	r.setErr(err)
+}
+
+func (r *xoConfigReloader) setErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+	if err != nil {
+		metrics.XOConfigLastReloadSuccessful.WithLabelValues(r.instance).Set(0)
+	} else {
+		metrics.XOConfigLastReloadSuccessful.WithLabelValues(r.instance).Set(1)
+	}
+}
+
+type xoClientKey struct{}
+
+// xo returns the XO client of the CSI call of ctx: the client current when
+// the call started, even if the configuration has been reloaded since.
+func (driver *xenorchestraCSIDriver) xo(ctx context.Context) clients.XoClient {
+	if client, ok := ctx.Value(xoClientKey{}).(clients.XoClient); ok {
+		return client
+	}
+	return driver.xoClient
+}
+
+// xoClientInterceptor selects the XO client of a CSI call: the client of the
+// Xen Orchestra credentials passed in its secrets, if any, or else the current
+// client of the instance storing its volume, pinned for the whole call so that
+// a call in flight during a reload keeps using the previous client.
+// CreateVolume picks its instance itself, from the pool it selects.
+func xoClientInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	driver, ok := info.Server.(*xenorchestraCSIDriver)
+	if !ok {
+		return handler(ctx, req)
+	}
+	if withSecrets, ok := req.(interface{ GetSecrets() map[string]string }); ok && driver.secretXoClients != nil {
+		client, err := driver.secretXoClients.get(withSecrets.GetSecrets())
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			return handler(context.WithValue(ctx, xoClientKey{}, client), req)
+		}
+	}
+	reloadable, ok := driver.xoClient.(*clients.ReloadableXoClient)
+	if driver.instances != nil {
+		switch withVolume := req.(type) {
+		case *csi.CreateVolumeRequest:
+			return handler(ctx, req)
+		case interface{ GetVolumeId() string }:
+			if withVolume.GetVolumeId() != "" {
+				instance, err := driver.instances.forVolume(ctx, withVolume.GetVolumeId())
+				if err != nil {
+					return nil, err
+				}
+				reloadable, ok = instance.client(), true
+			}
+		}
+	}
+	if !ok {
+		return handler(ctx, req)
+	}
+	client, release := reloadable.Pin()
+	defer release()
+	return handler(context.WithValue(ctx, xoClientKey{}, client), req)
+}
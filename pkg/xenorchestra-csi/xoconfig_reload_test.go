@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/metrics"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+)
+
+// newTestXoConfigReloader returns a reloader of a config file holding token
+// "token-1", whose clients accept every token but "revoked".
+func newTestXoConfigReloader(t *testing.T) (*xoConfigReloader, string, map[string]clients.XoClient) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	built := map[string]clients.XoClient{}
+	build := func(config xok8s.XoConfig) (*xoGeneration, error) {
+		client := clientsMock.NewMockXoClient(ctrl)
+		if config.Token == "revoked" {
+			client.EXPECT().Ping(gomock.Any()).Return(errors.New("invalid token")).AnyTimes()
+		} else {
+			client.EXPECT().Ping(gomock.Any()).Return(nil).AnyTimes()
+		}
+		built[config.Token] = client
+		return &xoGeneration{XoClientGeneration: &clients.XoClientGeneration{Client: client}}, nil
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeXoConfig(t, configFile, "token-1")
+	instances, err := LoadXoInstancesFromFile(configFile)
+	require.NoError(t, err)
+	reloader, err := newXoConfigReloader(configFile, instances[0], time.Minute, build)
+	require.NoError(t, err)
+	return reloader, configFile, built
+}
+
+func writeXoConfig(t *testing.T, configFile, token string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(configFile, []byte("url: https://xo.example.com\ntoken: "+token+"\n"), 0o600))
+}
+
+func noEvents(*clients.XoEvents) func() { return nil }
+
+func TestXoConfigReloader(t *testing.T) {
+	t.Run("AppliesChangedFile", func(t *testing.T) {
+		reloader, configFile, built := newTestXoConfigReloader(t)
+		assert.Same(t, built["token-1"], reloader.Client().Current())
+
+		reloader.reload(context.Background(), noEvents)
+		assert.Same(t, built["token-1"], reloader.Client().Current(), "an unchanged file is not reloaded")
+		assert.Len(t, built, 1)
+
+		writeXoConfig(t, configFile, "token-2")
+		reloader.reload(context.Background(), noEvents)
+		assert.Same(t, built["token-2"], reloader.Client().Current())
+		assert.NoError(t, reloader.Err())
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.XOConfigLastReloadSuccessful.WithLabelValues("")))
+	})
+
+	t.Run("RejectedChangeKeepsPreviousClient", func(t *testing.T) {
+		reloader, configFile, built := newTestXoConfigReloader(t)
+		failures := testutil.ToFloat64(metrics.XOConfigReloads.WithLabelValues("", metrics.ResultError))
+
+		writeXoConfig(t, configFile, "revoked")
+		reloader.reload(context.Background(), noEvents)
+		assert.Same(t, built["token-1"], reloader.Client().Current())
+		assert.ErrorContains(t, reloader.Err(), "invalid token")
+		assert.Equal(t, 0.0, testutil.ToFloat64(metrics.XOConfigLastReloadSuccessful.WithLabelValues("")))
+		assert.Equal(t, failures+1, testutil.ToFloat64(metrics.XOConfigReloads.WithLabelValues("", metrics.ResultError)))
+
+		// Reverting the change clears the error.
+		writeXoConfig(t, configFile, "token-1")
+		reloader.reload(context.Background(), noEvents)
+		assert.NoError(t, reloader.Err())
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.XOConfigLastReloadSuccessful.WithLabelValues("")))
+	})
+
+	t.Run("InvalidFileKeepsPreviousClient", func(t *testing.T) {
+		reloader, configFile, built := newTestXoConfigReloader(t)
+
+		require.NoError(t, os.WriteFile(configFile, []byte("url: [\n"), 0o600))
+		reloader.reload(context.Background(), noEvents)
+		assert.Same(t, built["token-1"], reloader.Client().Current())
+		assert.Error(t, reloader.Err())
+	})
+
+	t.Run("ProbeReportsRejectedChange", func(t *testing.T) {
+		reloader, configFile, _ := newTestXoConfigReloader(t)
+		driver := &xenorchestraCSIDriver{xoClient: reloader.Client(), readiness: newReadinessProbe(reloader.Client(), nil, 0)}
+		driver.readiness.add("Xen Orchestra configuration", func(context.Context) error { return reloader.Err() })
+
+		writeXoConfig(t, configFile, "revoked")
+		reloader.reload(context.Background(), noEvents)
+		err := driver.readiness.Check(context.Background())
+		assert.ErrorContains(t, err, "Xen Orchestra configuration check failed")
+	})
+}
+
+func TestXoClientInterceptor(t *testing.T) {
+	reloader, configFile, built := newTestXoConfigReloader(t)
+	stopped := make(chan struct{})
+	reloader.initial.Stop = func() { close(stopped) }
+	driver := &xenorchestraCSIDriver{xoClient: reloader.Client()}
+	info := &grpc.UnaryServerInfo{Server: driver, FullMethod: "/csi.v1.Controller/ControllerPublishVolume"}
+
+	_, err := xoClientInterceptor(context.Background(), nil, info, func(ctx context.Context, _ any) (any, error) {
+		assert.Same(t, built["token-1"], driver.xo(ctx))
+
+		writeXoConfig(t, configFile, "token-2")
+		reloader.reload(context.Background(), noEvents)
+		assert.Same(t, built["token-2"], driver.xoClient.(*clients.ReloadableXoClient).Current())
+		assert.Same(t, built["token-1"], driver.xo(ctx), "a call in flight keeps its client")
+
+		select {
+		case <-stopped:
+			t.Error("the previous client was stopped while still in use")
+		case <-time.After(50 * time.Millisecond):
+		}
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the previous client was not stopped once released")
+	}
+	assert.Same(t, built["token-2"], driver.xo(context.Background()).(*clients.ReloadableXoClient).Current())
+}
@@ -0,0 +1,175 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/topology"
+
+	"k8s.io/klog/v2"
+)
+
+// xoInstance is a Xen Orchestra instance whose volumes the driver manages.
+type xoInstance struct {
+	// name is empty for the single instance of a configuration file without
+	// an instances list.
+	name string
+	// pools are the pools listed for the instance in the configuration file.
+	pools  []uuid.UUID
+	config *xoConfigReloader
+}
+
+// client returns the client of the instance, which follows its reloads.
+func (i *xoInstance) client() *clients.ReloadableXoClient {
+	return i.config.Client()
+}
+
+// volumeHandle returns the volume handle of volumeID in the instance. i may be
+// nil for the volumes of a call not routed between instances.
+func (i *xoInstance) volumeHandle(volumeID string) string {
+	if i == nil {
+		return volumeID
+	}
+	return clients.JoinVolumeHandle(i.name, volumeID)
+}
+
+// xoInstances routes the CSI calls between the Xen Orchestra instances of the
+// configuration file: by pool when creating a volume, and by the instance in
+// the volume handle afterwards. The volumes whose handle carries no instance,
+// created before several instances were configured, are searched in every
+// instance once, then looked up in an index.
+type xoInstances struct {
+	list []*xoInstance
+
+	mu sync.Mutex
+	// volumes maps the volume handles without an instance to the instance
+	// storing them.
+	volumes map[string]*xoInstance
+}
+
+func newXoInstances(list []*xoInstance) *xoInstances {
+	return &xoInstances{list: list, volumes: map[string]*xoInstance{}}
+}
+
+func (s *xoInstances) byName(name string) *xoInstance {
+	for _, instance := range s.list {
+		if instance.name == name {
+			return instance
+		}
+	}
+	return nil
+}
+
+// forPool returns the instance managing poolID: the one listing it in the
+// configuration file, or else the first instance knowing it.
+func (s *xoInstances) forPool(ctx context.Context, poolID uuid.UUID) (*xoInstance, error) {
+	if len(s.list) == 1 {
+		return s.list[0], nil
+	}
+	for _, instance := range s.list {
+		if slices.Contains(instance.pools, poolID) {
+			return instance, nil
+		}
+	}
+	for _, instance := range s.list {
+		if len(instance.pools) > 0 {
+			continue
+		}
+		if _, err := instance.client().Pool().Get(ctx, poolID); err != nil {
+			if clients.IsNotFoundError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to look up pool %s in Xen Orchestra instance %q: %w", poolID, instance.name, err)
+		}
+		return instance, nil
+	}
+	return nil, fmt.Errorf("pool %s is not managed by any configured Xen Orchestra instance", poolID)
+}
+
+// forVolume returns the instance storing the volume of handle. When no
+// instance stores a volume whose handle carries none, the first instance is
+// returned so that the call reports the volume as missing.
+func (s *xoInstances) forVolume(ctx context.Context, handle string) (*xoInstance, error) {
+	name, _ := clients.SplitVolumeHandle(handle)
+	if name != "" {
+		instance := s.byName(name)
+		if instance == nil {
+			return nil, status.Errorf(codes.NotFound, "volume %s belongs to Xen Orchestra instance %q, which is not configured", handle, name)
+		}
+		return instance, nil
+	}
+	if len(s.list) == 1 {
+		return s.list[0], nil
+	}
+
+	s.mu.Lock()
+	instance, found := s.volumes[handle]
+	s.mu.Unlock()
+	if found {
+		return instance, nil
+	}
+	for _, instance := range s.list {
+		_, err := instance.client().GetVDIByVolumeId(ctx, handle)
+		if errors.Is(err, clients.ErrVolumeNotFound) {
+			continue
+		}
+		if err != nil && !errors.Is(err, clients.ErrVolumeIdAmbiguous) && !errors.Is(err, clients.ErrVolumeStriped) {
+			return nil, status.Errorf(codes.Internal, "failed to look up volume %s in Xen Orchestra instance %q: %v", handle, instance.name, err)
+		}
+		klog.V(4).InfoS("Indexed volume of Xen Orchestra instance", "volumeID", handle, "instance", instance.name)
+		s.mu.Lock()
+		s.volumes[handle] = instance
+		s.mu.Unlock()
+		return instance, nil
+	}
+	return s.list[0], nil
+}
+
+// taggedPoolIDs returns the pools tagged with tag in every instance.
+func (s *xoInstances) taggedPoolIDs(ctx context.Context, tag string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, instance := range s.list {
+		instanceIDs, err := topology.TaggedPoolIDs(ctx, instance.client().Pool(), tag)
+		if err != nil {
+			return nil, fmt.Errorf("Xen Orchestra instance %q: %w", instance.name, err)
+		}
+		ids = append(ids, instanceIDs...)
+	}
+	return ids, nil
+}
+
+// routedInstances returns the instances to route a call between, or nil when
+// the call uses a single client: the driver was built with a fixed client, or
+// the call passes Xen Orchestra credentials in its secrets.
+func (driver *xenorchestraCSIDriver) routedInstances(ctx context.Context) *xoInstances {
+	if driver.instances == nil {
+		return nil
+	}
+	if _, pinned := ctx.Value(xoClientKey{}).(clients.XoClient); pinned {
+		return nil
+	}
+	return driver.instances
+}
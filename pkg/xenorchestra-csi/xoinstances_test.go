@@ -0,0 +1,134 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	"github.com/vatesfr/xenorchestra-go-sdk/pkg/payloads"
+	xoLibMock "github.com/vatesfr/xenorchestra-go-sdk/pkg/services/library/mock"
+)
+
+func newTestXoInstance(name string, client clients.XoClient, pools ...uuid.UUID) *xoInstance {
+	return &xoInstance{
+		name:   name,
+		pools:  pools,
+		config: &xoConfigReloader{instance: name, client: clients.NewReloadableXoClient(&clients.XoClientGeneration{Client: client})},
+	}
+}
+
+func TestXoInstances(t *testing.T) {
+	poolParis := uuid.Must(uuid.NewV4())
+	poolLyon := uuid.Must(uuid.NewV4())
+	legacyID := uuid.Must(uuid.NewV4()).String()
+
+	newInstances := func(t *testing.T) (*xoInstances, *clientsMock.MockXoClient, *clientsMock.MockXoClient) {
+		ctrl := gomock.NewController(t)
+		paris := clientsMock.NewMockXoClient(ctrl)
+		lyon := clientsMock.NewMockXoClient(ctrl)
+		return newXoInstances([]*xoInstance{
+			newTestXoInstance("paris", paris, poolParis),
+			newTestXoInstance("lyon", lyon),
+		}), paris, lyon
+	}
+
+	t.Run("ForPoolListed", func(t *testing.T) {
+		instances, _, _ := newInstances(t)
+		instance, err := instances.forPool(context.Background(), poolParis)
+		require.NoError(t, err)
+		assert.Equal(t, "paris", instance.name)
+	})
+
+	t.Run("ForPoolAsksInstancesWithoutPools", func(t *testing.T) {
+		instances, _, lyon := newInstances(t)
+		ctrl := gomock.NewController(t)
+		pools := xoLibMock.NewMockPool(ctrl)
+		lyon.EXPECT().Pool().Return(pools).AnyTimes()
+		pools.EXPECT().Get(gomock.Any(), poolLyon).Return(&payloads.Pool{ID: poolLyon}, nil)
+		pools.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, errors.New("API error: 404 Not Found"))
+
+		instance, err := instances.forPool(context.Background(), poolLyon)
+		require.NoError(t, err)
+		assert.Equal(t, "lyon", instance.name)
+
+		_, err = instances.forPool(context.Background(), uuid.Must(uuid.NewV4()))
+		assert.ErrorContains(t, err, "not managed by any configured Xen Orchestra instance")
+	})
+
+	t.Run("ForVolumeWithInstance", func(t *testing.T) {
+		instances, _, _ := newInstances(t)
+		instance, err := instances.forVolume(context.Background(), "lyon/"+legacyID)
+		require.NoError(t, err)
+		assert.Equal(t, "lyon", instance.name)
+
+		_, err = instances.forVolume(context.Background(), "marseille/"+legacyID)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("ForVolumeWithoutInstanceIsSearchedOnce", func(t *testing.T) {
+		instances, paris, lyon := newInstances(t)
+		paris.EXPECT().GetVDIByVolumeId(gomock.Any(), legacyID).Return(nil, clients.ErrVolumeNotFound)
+		lyon.EXPECT().GetVDIByVolumeId(gomock.Any(), legacyID).Return(&payloads.VDI{}, nil)
+
+		for range 2 {
+			instance, err := instances.forVolume(context.Background(), legacyID)
+			require.NoError(t, err)
+			assert.Equal(t, "lyon", instance.name)
+		}
+	})
+
+	t.Run("ForMissingVolumeWithoutInstance", func(t *testing.T) {
+		instances, paris, lyon := newInstances(t)
+		paris.EXPECT().GetVDIByVolumeId(gomock.Any(), legacyID).Return(nil, clients.ErrVolumeNotFound)
+		lyon.EXPECT().GetVDIByVolumeId(gomock.Any(), legacyID).Return(nil, clients.ErrVolumeNotFound)
+
+		instance, err := instances.forVolume(context.Background(), legacyID)
+		require.NoError(t, err)
+		assert.Equal(t, "paris", instance.name, "the first instance reports the volume as missing")
+	})
+}
+
+func TestXoClientInterceptorInstances(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paris := clientsMock.NewMockXoClient(ctrl)
+	lyon := clientsMock.NewMockXoClient(ctrl)
+	instances := newXoInstances([]*xoInstance{newTestXoInstance("paris", paris), newTestXoInstance("lyon", lyon)})
+	driver := &xenorchestraCSIDriver{xoClient: instances.list[0].client(), instances: instances}
+	info := &grpc.UnaryServerInfo{Server: driver, FullMethod: "/csi.v1.Controller/ControllerPublishVolume"}
+
+	var client clients.XoClient
+	_, err := xoClientInterceptor(context.Background(), &csi.ControllerPublishVolumeRequest{VolumeId: "lyon/" + uuid.Must(uuid.NewV4()).String()}, info,
+		func(ctx context.Context, _ any) (any, error) {
+			client = driver.xo(ctx)
+			return nil, nil
+		})
+	require.NoError(t, err)
+	assert.Same(t, lyon, client)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package xenorchestracsi
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+
+	"k8s.io/klog/v2"
+)
+
+// Keys of the CSI secrets holding Xen Orchestra credentials. They match the
+// fields of the configuration file.
+const (
+	secretKeyURL      = "url"
+	secretKeyInsecure = "insecure"
+	secretKeyUsername = "username"
+	secretKeyPassword = "password"
+	secretKeyToken    = "token"
+)
+
+// secretXoClientIdleTimeout is how long the client of a credential set is
+// kept once no call uses it anymore.
+const secretXoClientIdleTimeout = time.Hour
+
+// xoConfigFromSecrets returns the Xen Orchestra configuration carried by the
+// secrets of a CSI call. ok is false when the secrets hold no Xen Orchestra
+// credentials, in which case the default client is used.
+func xoConfigFromSecrets(secrets map[string]string) (config xok8s.XoConfig, ok bool, err error) {
+	config = xok8s.XoConfig{
+		URL:      secrets[secretKeyURL],
+		Username: secrets[secretKeyUsername],
+		Password: secrets[secretKeyPassword],
+		Token:    secrets[secretKeyToken],
+	}
+	if config == (xok8s.XoConfig{}) {
+		return config, false, nil
+	}
+	if insecure, found := secrets[secretKeyInsecure]; found {
+		config.Insecure, err = strconv.ParseBool(insecure)
+		if err != nil {
+			return config, true, status.Errorf(codes.InvalidArgument, "invalid %q in the Xen Orchestra secret: %v", secretKeyInsecure, err)
+		}
+	}
+	if !strings.HasPrefix(config.URL, "http://") && !strings.HasPrefix(config.URL, "https://") {
+		return config, true, status.Errorf(codes.InvalidArgument, "the Xen Orchestra secret must set %q to an http:// or https:// URL", secretKeyURL)
+	}
+	switch {
+	case config.Token != "" && (config.Username != "" || config.Password != ""):
+		return config, true, status.Errorf(codes.InvalidArgument, "the Xen Orchestra secret must set either %q or %q and %q, not both", secretKeyToken, secretKeyUsername, secretKeyPassword)
+	case config.Token == "" && (config.Username == "" || config.Password == ""):
+		return config, true, status.Errorf(codes.InvalidArgument, "the Xen Orchestra secret must set either %q or %q and %q", secretKeyToken, secretKeyUsername, secretKeyPassword)
+	}
+	return config, true, nil
+}
+
+// secretXoClients caches the XO clients built from the credentials passed in
+// the CSI secrets, one per credential set.
+type secretXoClients struct {
+	build func(config xok8s.XoConfig) (clients.XoClient, error)
+	now   func() time.Time
+
+	mu      sync.Mutex
+	clients map[[sha256.Size]byte]*secretXoClient
+}
+
+type secretXoClient struct {
+	client   clients.XoClient
+	lastUsed time.Time
+}
+
+func newSecretXoClients(build func(config xok8s.XoConfig) (clients.XoClient, error)) *secretXoClients {
+	return &secretXoClients{build: build, now: time.Now, clients: map[[sha256.Size]byte]*secretXoClient{}}
+}
+
+// get returns the client of the credentials carried by secrets, or nil when
+// they carry none.
+func (s *secretXoClients) get(secrets map[string]string) (clients.XoClient, error) {
+	config, ok, err := xoConfigFromSecrets(secrets)
+	if !ok || err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256([]byte(strings.Join([]string{
+		config.URL, strconv.FormatBool(config.Insecure), config.Username, config.Password, config.Token,
+	}, "\x00")))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, cached := range s.clients {
+		if now.Sub(cached.lastUsed) > secretXoClientIdleTimeout {
+			delete(s.clients, k)
+		}
+	}
+	if cached, found := s.clients[key]; found {
+		cached.lastUsed = now
+		return cached.client, nil
+	}
+
+	client, err := s.build(config)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create Xen Orchestra client from the CSI secret: %v", err)
+	}
+	s.clients[key] = &secretXoClient{client: client, lastUsed: now}
+	klog.V(2).InfoS("Created Xen Orchestra client for CSI secret credentials", "url", config.URL, "username", config.Username)
+	return client, nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xenorchestracsi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients"
+	clientsMock "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/mock"
+	xok8s "github.com/vatesfr/xenorchestra-k8s-common"
+)
+
+func TestXoConfigFromSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets map[string]string
+		want    xok8s.XoConfig
+		ok      bool
+		wantErr bool
+	}{
+		{
+			name: "NoSecrets",
+		},
+		{
+			name:    "OtherSecrets",
+			secrets: map[string]string{"chap-password": "x"},
+		},
+		{
+			name:    "Token",
+			secrets: map[string]string{"url": "https://xo.team-a.example.com", "token": "a", "insecure": "true"},
+			want:    xok8s.XoConfig{URL: "https://xo.team-a.example.com", Token: "a", Insecure: true},
+			ok:      true,
+		},
+		{
+			name:    "UsernamePassword",
+			secrets: map[string]string{"url": "http://xo", "username": "team-a", "password": "p"},
+			want:    xok8s.XoConfig{URL: "http://xo", Username: "team-a", Password: "p"},
+			ok:      true,
+		},
+		{
+			name:    "MissingURL",
+			secrets: map[string]string{"token": "a"},
+			ok:      true,
+			wantErr: true,
+		},
+		{
+			name:    "MissingPassword",
+			secrets: map[string]string{"url": "https://xo", "username": "team-a"},
+			ok:      true,
+			wantErr: true,
+		},
+		{
+			name:    "TokenAndPassword",
+			secrets: map[string]string{"url": "https://xo", "token": "a", "username": "team-a", "password": "p"},
+			ok:      true,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidInsecure",
+			secrets: map[string]string{"url": "https://xo", "token": "a", "insecure": "maybe"},
+			ok:      true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, ok, err := xoConfigFromSecrets(tt.secrets)
+			assert.Equal(t, tt.ok, ok)
+			if tt.wantErr {
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			if ok {
+				assert.Equal(t, tt.want, config)
+			}
+		})
+	}
+}
+
+func TestSecretXoClients(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var built []xok8s.XoConfig
+	secretClients := newSecretXoClients(func(config xok8s.XoConfig) (clients.XoClient, error) {
+		built = append(built, config)
+		return clientsMock.NewMockXoClient(ctrl), nil
+	})
+	now := time.Now()
+	secretClients.now = func() time.Time { return now }
+	teamA := map[string]string{"url": "https://xo", "token": "a"}
+	teamB := map[string]string{"url": "https://xo", "token": "b"}
+
+	t.Run("OneClientPerCredentialSet", func(t *testing.T) {
+		a, err := secretClients.get(teamA)
+		require.NoError(t, err)
+		again, err := secretClients.get(map[string]string{"url": "https://xo", "token": "a"})
+		require.NoError(t, err)
+		assert.Same(t, a, again)
+
+		b, err := secretClients.get(teamB)
+		require.NoError(t, err)
+		assert.NotSame(t, a, b)
+		assert.Len(t, built, 2)
+	})
+
+	t.Run("NoCredentials", func(t *testing.T) {
+		client, err := secretClients.get(nil)
+		require.NoError(t, err)
+		assert.Nil(t, client)
+	})
+
+	t.Run("IdleClientsAreDropped", func(t *testing.T) {
+		a, err := secretClients.get(teamA)
+		require.NoError(t, err)
+		now = now.Add(secretXoClientIdleTimeout / 2)
+		again, err := secretClients.get(teamA)
+		require.NoError(t, err)
+		assert.Same(t, a, again, "a client in use is kept")
+
+		now = now.Add(secretXoClientIdleTimeout + time.Second)
+		rebuilt, err := secretClients.get(teamA)
+		require.NoError(t, err)
+		assert.NotSame(t, a, rebuilt)
+		assert.Len(t, secretClients.clients, 1, "the idle client of team B is dropped")
+	})
+}
+
+func TestXoClientInterceptorSecrets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defaultClient := clientsMock.NewMockXoClient(ctrl)
+	secretClient := clientsMock.NewMockXoClient(ctrl)
+	driver := &xenorchestraCSIDriver{
+		xoClient: clients.NewReloadableXoClient(&clients.XoClientGeneration{Client: defaultClient}),
+		secretXoClients: newSecretXoClients(func(xok8s.XoConfig) (clients.XoClient, error) {
+			return secretClient, nil
+		}),
+	}
+	info := &grpc.UnaryServerInfo{Server: driver, FullMethod: "/csi.v1.Controller/CreateVolume"}
+	clientOf := func(req any) (clients.XoClient, error) {
+		var client clients.XoClient
+		_, err := xoClientInterceptor(context.Background(), req, info, func(ctx context.Context, _ any) (any, error) {
+			client = driver.xo(ctx)
+			return nil, nil
+		})
+		return client, err
+	}
+
+	client, err := clientOf(&csi.CreateVolumeRequest{Secrets: map[string]string{"url": "https://xo", "token": "a"}})
+	require.NoError(t, err)
+	assert.Same(t, secretClient, client)
+
+	client, err = clientOf(&csi.DeleteVolumeRequest{})
+	require.NoError(t, err)
+	assert.Same(t, defaultClient, client, "calls without secrets use the default client")
+
+	_, err = clientOf(&csi.ControllerPublishVolumeRequest{Secrets: map[string]string{"token": "a"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
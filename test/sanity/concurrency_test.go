@@ -0,0 +1,182 @@
+/*
+Copyright (c) 2026 Vates
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sanity_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	xenorchestracsi "github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi"
+	"github.com/vatesfr/xenorchestra-csi-driver/pkg/xenorchestra-csi/clients/stub"
+)
+
+const blockedStagingPath = "/staging/blocked"
+
+// blockingMounter holds NodeUnstageVolume calls on blockedStagingPath until
+// release is closed, keeping a volume operation in flight.
+type blockingMounter struct {
+	*FakeMounter
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingMounter) GetDeviceNameFromMount(mountPath string) (string, int, error) {
+	if mountPath == blockedStagingPath {
+		m.entered <- struct{}{}
+		<-m.release
+	}
+	return m.FakeMounter.GetDeviceNameFromMount(mountPath)
+}
+
+func newConcurrencyTestDriver(t *testing.T) (xenorchestracsi.Driver, *blockingMounter) {
+	t.Helper()
+	mounter := &blockingMounter{
+		FakeMounter: NewFakeMounter(),
+		entered:     make(chan struct{}, 1),
+		release:     make(chan struct{}),
+	}
+	driver, _ := NewFakeDriver(t, &xenorchestracsi.DriverOptions{
+		DriverName:        driverName,
+		NodeName:          nodeName,
+		Endpoint:          "unix:///tmp/xenorchestra-csi-concurrency-test.sock",
+		KubernetesPoolTag: xenorchestracsi.DefaultKubernetesPoolTag,
+		VDINamePrefix:     xenorchestracsi.DefaultVDINamePrefix,
+		ClusterTag:        xenorchestracsi.DefaultClusterTag,
+	}, mounter)
+	return driver, mounter
+}
+
+func TestConcurrentOperationsOnSameVolume(t *testing.T) {
+	driver, mounter := newConcurrencyTestDriver(t)
+	ctx := context.Background()
+	volumeID := uuid.Must(uuid.NewV4()).String()
+	mountCapability := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+	}
+
+	// Hold the volume with a NodeUnstageVolume blocked in the mounter.
+	inFlight := make(chan error, 1)
+	go func() {
+		_, err := driver.NodeUnstageVolume(ctx, &csi.NodeUnstageVolumeRequest{VolumeId: volumeID, StagingTargetPath: blockedStagingPath})
+		inFlight <- err
+	}()
+	<-mounter.entered
+
+	t.Run("SameVolumeIsAborted", func(t *testing.T) {
+		calls := map[string]func() error{
+			"ControllerPublishVolume": func() error {
+				_, err := driver.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+					VolumeId: volumeID, NodeId: stub.NodeId, VolumeCapability: mountCapability,
+				})
+				return err
+			},
+			"ControllerUnpublishVolume": func() error {
+				_, err := driver.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{VolumeId: volumeID, NodeId: stub.NodeId})
+				return err
+			},
+			"DeleteVolume": func() error {
+				_, err := driver.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID})
+				return err
+			},
+			"NodeStageVolume": func() error {
+				_, err := driver.NodeStageVolume(ctx, &csi.NodeStageVolumeRequest{
+					VolumeId: volumeID, StagingTargetPath: "/staging/other", VolumeCapability: mountCapability,
+				})
+				return err
+			},
+			"NodeUnstageVolume": func() error {
+				_, err := driver.NodeUnstageVolume(ctx, &csi.NodeUnstageVolumeRequest{VolumeId: volumeID, StagingTargetPath: "/staging/other"})
+				return err
+			},
+			"NodePublishVolume": func() error {
+				_, err := driver.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{
+					VolumeId: volumeID, StagingTargetPath: "/staging/other", TargetPath: "/target", VolumeCapability: mountCapability,
+				})
+				return err
+			},
+			"NodeUnpublishVolume": func() error {
+				_, err := driver.NodeUnpublishVolume(ctx, &csi.NodeUnpublishVolumeRequest{VolumeId: volumeID, TargetPath: "/target"})
+				return err
+			},
+		}
+		for name, call := range calls {
+			t.Run(name, func(t *testing.T) {
+				assert.Equal(t, codes.Aborted, status.Code(call()))
+			})
+		}
+	})
+
+	t.Run("OtherVolumeProceeds", func(t *testing.T) {
+		_, err := driver.NodeUnstageVolume(ctx, &csi.NodeUnstageVolumeRequest{
+			VolumeId: uuid.Must(uuid.NewV4()).String(), StagingTargetPath: "/staging/other",
+		})
+		require.NoError(t, err)
+	})
+
+	close(mounter.release)
+	require.NoError(t, <-inFlight)
+
+	t.Run("LockIsReleased", func(t *testing.T) {
+		_, err := driver.NodeUnstageVolume(ctx, &csi.NodeUnstageVolumeRequest{VolumeId: volumeID, StagingTargetPath: "/staging/other"})
+		require.NoError(t, err)
+	})
+}
+
+func TestConcurrentCreateVolumeRetries(t *testing.T) {
+	driver, _ := newConcurrencyTestDriver(t)
+	req := &csi.CreateVolumeRequest{
+		Name:          "pvc-" + uuid.Must(uuid.NewV4()).String(),
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 1 << 30},
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+		}},
+		Parameters: map[string]string{xenorchestracsi.ParameterPoolID: stub.PoolId},
+	}
+
+	// Every concurrent retry either succeeds with the same volume or is
+	// aborted; none may create a second VDI.
+	const retries = 20
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		volumeIDs = map[string]struct{}{}
+	)
+	for range retries {
+		wg.Go(func() {
+			resp, err := driver.CreateVolume(context.Background(), req)
+			if err != nil {
+				assert.Equal(t, codes.Aborted, status.Code(err), "unexpected error: %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			volumeIDs[resp.GetVolume().GetVolumeId()] = struct{}{}
+		})
+	}
+	wg.Wait()
+	assert.Len(t, volumeIDs, 1)
+}
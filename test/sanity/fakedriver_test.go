@@ -30,7 +30,7 @@ var vdiStore = struct {
 // for all other external dependencies. It is intended exclusively for use in
 // tests. The returned MockXoClient can be used to set up additional
 // expectations in individual test cases.
-func NewFakeDriver(t *testing.T, options *xenorchestracsi.DriverOptions, fakeMounter *FakeMounter) (xenorchestracsi.Driver, *clientsMock.MockXoClient) {
+func NewFakeDriver(t *testing.T, options *xenorchestracsi.DriverOptions, fakeMounter clients.Mounter) (xenorchestracsi.Driver, *clientsMock.MockXoClient) {
 	ctrl := gomock.NewController(t)
 
 	mockPool := newMockPool(ctrl)
@@ -43,6 +43,7 @@ func NewFakeDriver(t *testing.T, options *xenorchestracsi.DriverOptions, fakeMou
 	mockXoClient.EXPECT().VDI().Return(mockVDI).AnyTimes()
 	mockXoClient.EXPECT().VM().Return(mockVM).AnyTimes()
 	mockXoClient.EXPECT().SR().Return(mockSR).AnyTimes()
+	mockXoClient.EXPECT().Ping(gomock.Any()).Return(nil).AnyTimes()
 
 	mockXoClient.EXPECT().CreateNewVolume(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
 		func(_ context.Context, srID uuid.UUID, namePrefix string, capacityBytes int64, volumeName string, _ string, _ string) (uuid.UUID, uuid.UUID, error) {
@@ -116,7 +117,7 @@ func NewFakeDriver(t *testing.T, options *xenorchestracsi.DriverOptions, fakeMou
 		ID:     uuid.Must(uuid.NewV4()),
 		Device: &device,
 	}, nil).AnyTimes()
-	mockXoClient.EXPECT().DisconnectVBDFromVM(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockXoClient.EXPECT().DetachVDIFromVM(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	mockXoClient.EXPECT().FindLocalSRForHost(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, hostID uuid.UUID) (*payloads.StorageRepository, error) {
 		localSR := payloads.StorageRepository{
 			ID:          uuid.FromStringOrNil(stub.LocalSRId),
@@ -158,6 +159,7 @@ func NewFakeDriver(t *testing.T, options *xenorchestracsi.DriverOptions, fakeMou
 		stub.NewNodeMetadataGetterStub(),
 		mockXoClient,
 		fakeMounter,
+		nil,
 	), mockXoClient
 }
 
@@ -81,6 +81,21 @@ func (s *FakeMounter) IsMountPoint(target string) (bool, error) {
 	return false, nil
 }
 
+// AssembleStripedDevice returns a fake md device path.
+func (s *FakeMounter) AssembleStripedDevice(name string, devices []string) (string, error) {
+	return "/dev/md/" + name, nil
+}
+
+// StripedDevicePath reports that no array is active.
+func (s *FakeMounter) StripedDevicePath(name string) (string, error) {
+	return "", nil
+}
+
+// StopStripedDevice does nothing: no array is ever assembled.
+func (s *FakeMounter) StopStripedDevice(path string) error {
+	return nil
+}
+
 // CheckPath checks if a path exists in the mounted directories.
 func (s *FakeMounter) CheckPath(path string) (csisanity.PathKind, error) {
 	s.mu.Lock()